@@ -0,0 +1,58 @@
+// Package clock abstracts the current time so time-based logic can run in a configurable
+// timezone and be tested deterministically.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock, reporting times in Location (the server timezone when nil).
+type System struct {
+	Location *time.Location
+}
+
+// Now returns the current wall-clock time in the clock's location.
+func (c System) Now() time.Time {
+	if c.Location == nil {
+		return time.Now()
+	}
+	return time.Now().In(c.Location)
+}
+
+// Fake is a manually driven clock for tests. It is safe for concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake clock stopped at t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+// Now returns the time the clock is stopped at.
+func (c *Fake) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *Fake) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
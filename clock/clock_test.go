@@ -0,0 +1,40 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSystem_UsesLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("could not load timezone: %v", err)
+	}
+
+	if got := (System{Location: tokyo}).Now().Location(); got != tokyo {
+		t.Errorf("expected location %v, got %v", tokyo, got)
+	}
+	if got := (System{}).Now().Location(); got != time.Local {
+		t.Errorf("expected server location, got %v", got)
+	}
+}
+
+func TestFake_SetAndAdvance(t *testing.T) {
+	start := time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	if !c.Now().Equal(start) {
+		t.Fatalf("expected %v, got %v", start, c.Now())
+	}
+
+	c.Advance(time.Hour)
+	if got := c.Now(); got.Year() != 2026 || got.Month() != time.January {
+		t.Errorf("expected clock to cross into January 2026, got %v", got)
+	}
+
+	later := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)
+	c.Set(later)
+	if !c.Now().Equal(later) {
+		t.Errorf("expected %v after Set, got %v", later, c.Now())
+	}
+}
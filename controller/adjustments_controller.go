@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetAdjustmentsByUserIDHandler handles GET /users/{id}/adjustments and returns the monthly
+// adjustment entries applied to the user's balance.
+func GetAdjustmentsByUserIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := service.GetAdjustmentsByUserID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem fetching adjustments")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// GetAdjustmentSettingsHandler handles GET /users/{id}/adjustment-settings and returns when, and whether,
+// monthly adjustments are applied to the user.
+func GetAdjustmentSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := service.GetAdjustmentSettings(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem fetching adjustment settings")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *settings)
+}
+
+// UpdateAdjustmentSettingsHandler handles PATCH /users/{id}/adjustment-settings and applies a partial
+// update to the user's adjustment settings.
+func UpdateAdjustmentSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update *model.AdjustmentSettingsUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	settings, err := service.UpdateAdjustmentSettings(ctx, id, update)
+	if err != nil {
+		writeError(w, err, "problem when updating adjustment settings")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *settings)
+}
+
+// PreviewAdjustmentsHandler handles GET /admin/adjustments/preview and returns the adjustments the
+// scheduler would apply right now, without applying them.
+func PreviewAdjustmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	entries, err := service.PreviewPendingAdjustments(ctx)
+	if err != nil {
+		writeError(w, err, "problem when previewing adjustments")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// TriggerAdjustmentHandler handles POST /admin/adjustments/{yearMonth} and applies that month's
+// adjustment to every enabled user who has not received it yet.
+func TriggerAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	entries, err := service.TriggerMonthlyAdjustment(ctx, r.PathValue("yearMonth"))
+	if err != nil {
+		writeError(w, err, "problem when applying adjustment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// RollbackAdjustmentHandler handles POST /admin/adjustments/{yearMonth}/rollback and reverses the
+// balance changes recorded for that month.
+func RollbackAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	entries, err := service.RollbackMonthlyAdjustment(ctx, r.PathValue("yearMonth"))
+	if err != nil {
+		writeError(w, err, "problem when rolling back adjustment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
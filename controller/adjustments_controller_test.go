@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAdjustmentHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "adjustments-owner")
+	stranger := newPrincipal(t, "adjustments-stranger")
+	user := fmt.Sprintf("/users/%d", owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own adjustments", "GET /users/{id}/adjustments", GetAdjustmentsByUserIDHandler, user + "/adjustments", "", owner, http.StatusOK},
+		{"someone else's adjustments", "GET /users/{id}/adjustments", GetAdjustmentsByUserIDHandler, user + "/adjustments", "", stranger, http.StatusForbidden},
+		{"own settings", "GET /users/{id}/adjustment-settings", GetAdjustmentSettingsHandler, user + "/adjustment-settings", "", owner, http.StatusOK},
+		{"someone else's settings", "GET /users/{id}/adjustment-settings", GetAdjustmentSettingsHandler, user + "/adjustment-settings", "", stranger, http.StatusForbidden},
+		{"updating own settings", "PATCH /users/{id}/adjustment-settings", UpdateAdjustmentSettingsHandler, user + "/adjustment-settings", `{"pay_day":5}`, owner, http.StatusOK},
+		{"updating someone else's", "PATCH /users/{id}/adjustment-settings", UpdateAdjustmentSettingsHandler, user + "/adjustment-settings", `{"enabled":false}`, stranger, http.StatusForbidden},
+		{"user previewing", "GET /admin/adjustments/preview", PreviewAdjustmentsHandler, "/admin/adjustments/preview", "", owner, http.StatusForbidden},
+		{"admin previewing", "GET /admin/adjustments/preview", PreviewAdjustmentsHandler, "/admin/adjustments/preview", "", admin, http.StatusOK},
+		{"user triggering", "POST /admin/adjustments/{yearMonth}", TriggerAdjustmentHandler, "/admin/adjustments/2024-01", "", owner, http.StatusForbidden},
+		{"admin triggering", "POST /admin/adjustments/{yearMonth}", TriggerAdjustmentHandler, "/admin/adjustments/2024-01", "", admin, http.StatusOK},
+		{"user rolling back", "POST /admin/adjustments/{yearMonth}/rollback", RollbackAdjustmentHandler, "/admin/adjustments/2024-01/rollback", "", owner, http.StatusForbidden},
+		{"admin rolling back", "POST /admin/adjustments/{yearMonth}/rollback", RollbackAdjustmentHandler, "/admin/adjustments/2024-01/rollback", "", admin, http.StatusOK},
+	})
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetAPITokensHandler handles GET /users/{id}/tokens and returns the user's API tokens, without the tokens themselves.
+func GetAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := service.GetAPITokens(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching api tokens")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// CreateAPITokenHandler handles POST /users/{id}/tokens and creates an API token for the user. The token
+// is only part of this response.
+func CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var req model.APITokenRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	token, err := service.CreateAPIToken(ctx, id, req)
+	if err != nil {
+		writeError(w, err, "problem when creating api token")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *token)
+}
+
+// RevokeAPITokenHandler handles DELETE /users/{id}/tokens/{tokenID} and revokes the API token.
+func RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	tokenID, ok := GetID(r.PathValue("tokenID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.RevokeAPIToken(ctx, id, tokenID)
+	if err != nil {
+		writeError(w, err, "problem when revoking api token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
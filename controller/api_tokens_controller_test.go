@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestAPITokenHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "api-tokens-owner")
+	stranger := newPrincipal(t, "api-tokens-stranger")
+
+	token, err := service.CreateAPIToken(adminCtx, owner.UserID, model.APITokenRequest{Name: "backup"})
+	if err != nil {
+		t.Fatalf("failed to create api token: %v", err)
+	}
+	tokens := fmt.Sprintf("/users/%d/tokens", owner.UserID)
+	one := fmt.Sprintf("%s/%d", tokens, token.ID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own tokens", "GET /users/{id}/tokens", GetAPITokensHandler, tokens, "", owner, http.StatusOK},
+		{"someone else's tokens", "GET /users/{id}/tokens", GetAPITokensHandler, tokens, "", stranger, http.StatusForbidden},
+		{"creating own token", "POST /users/{id}/tokens", CreateAPITokenHandler, tokens, `{"name":"import","scope":"read-only"}`, owner, http.StatusCreated},
+		{"creating with an unknown scope", "POST /users/{id}/tokens", CreateAPITokenHandler, tokens, `{"name":"import","scope":"admin"}`, owner, http.StatusBadRequest},
+		{"creating for someone else", "POST /users/{id}/tokens", CreateAPITokenHandler, tokens, `{"name":"mine"}`, stranger, http.StatusForbidden},
+		{"revoking someone else's", "DELETE /users/{id}/tokens/{tokenID}", RevokeAPITokenHandler, one, "", stranger, http.StatusForbidden},
+		{"revoking own token", "DELETE /users/{id}/tokens/{tokenID}", RevokeAPITokenHandler, one, "", owner, http.StatusOK},
+		{"revoking it again", "DELETE /users/{id}/tokens/{tokenID}", RevokeAPITokenHandler, one, "", owner, http.StatusNotFound},
+	})
+}
+
+func TestRequireSession_AcceptsAPITokens(t *testing.T) {
+	user := newPrincipal(t, "bearer-user")
+	other := newPrincipal(t, "bearer-other")
+
+	full, err := service.CreateAPIToken(adminCtx, user.UserID, model.APITokenRequest{Name: "import"})
+	if err != nil {
+		t.Fatalf("failed to create api token: %v", err)
+	}
+	readOnly, err := service.CreateAPIToken(adminCtx, user.UserID, model.APITokenRequest{Name: "backup", Scope: model.ScopeReadOnly})
+	if err != nil {
+		t.Fatalf("failed to create api token: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", GetUserByIDHandler)
+	mux.HandleFunc("PATCH /users/{id}", UpdateUserByIDHandler)
+	handler := RequireSession(mux)
+
+	own := fmt.Sprintf("/users/%d", user.UserID)
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"full token reading", http.MethodGet, own, "Bearer " + full.Token, http.StatusOK},
+		{"full token writing", http.MethodPatch, own, "Bearer " + full.Token, http.StatusOK},
+		{"lower-case scheme", http.MethodGet, own, "bearer " + full.Token, http.StatusOK},
+		{"someone else's user", http.MethodGet, fmt.Sprintf("/users/%d", other.UserID), "Bearer " + full.Token, http.StatusForbidden},
+		{"read-only token reading", http.MethodGet, own, "Bearer " + readOnly.Token, http.StatusOK},
+		{"read-only token writing", http.MethodPatch, own, "Bearer " + readOnly.Token, http.StatusForbidden},
+		{"unknown token", http.MethodGet, own, "Bearer fingo_unknown", http.StatusUnauthorized},
+		{"other scheme", http.MethodGet, own, "Basic " + full.Token, http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"user_name":"bearer-renamed"}`))
+		req.Header.Set("Authorization", tc.authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: %s %s status = %d, want %d", tc.name, tc.method, tc.path, rec.Code, tc.wantStatus)
+		}
+	}
+}
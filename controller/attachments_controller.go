@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+	"time"
+)
+
+// maxUploadOverhead is how much room the multipart framing around an attachment gets on top of its size.
+const maxUploadOverhead = 1 << 20
+
+// CreateAttachmentHandler handles POST /transactions/{id}/attachments and stores the file sent in the
+// "file" field of a multipart form as an attachment of the transaction.
+func CreateAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxAttachmentSize+maxUploadOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("could not read the uploaded file: %v", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorMessage(w, model.KindTooLarge, "attachment too large")
+			return
+		}
+		writeErrorMessage(w, model.KindValidation, "a multipart form with a file field is required")
+		return
+	}
+	defer file.Close()
+
+	// FormFile has read the whole body by now, so a slow upload does not eat into the database deadline.
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	attachment, err := service.CreateAttachment(ctx, id, header.Filename, file)
+	if err != nil {
+		writeError(w, err, "problem when storing attachment")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *attachment)
+}
+
+// GetAttachmentsHandler handles GET /transactions/{id}/attachments and returns the attachments of the transaction.
+func GetAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := service.GetAttachments(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching attachments")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// DownloadAttachmentHandler handles GET /transactions/{id}/attachments/{attachmentID} and sends the
+// content of the attachment.
+func DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := GetID(r.PathValue("attachmentID"), w, r)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := service.OpenAttachment(ctx, id, attachmentID)
+	if err != nil {
+		writeError(w, err, "problem when reading attachment")
+		return
+	}
+	defer content.Close()
+
+	modified, _ := time.Parse(dbsqlite.TimestampLayout, attachment.CreatedAt)
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, attachment.FileName, modified, content)
+}
+
+// DeleteAttachmentHandler handles DELETE /transactions/{id}/attachments/{attachmentID} and removes the attachment.
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := GetID(r.PathValue("attachmentID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.DeleteAttachment(ctx, id, attachmentID)
+	if err != nil {
+		writeError(w, err, "problem when deleting attachment")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+// png is the smallest content sniffed as a PNG image.
+const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestAttachmentHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "attachments-owner")
+	member := newPrincipal(t, "attachments-member")
+	stranger := newPrincipal(t, "attachments-stranger")
+
+	household, err := service.CreateHousehold(service.WithPrincipal(adminCtx, owner), model.Household{Name: "Receipts"})
+	if err != nil {
+		t.Fatalf("failed to create household: %v", err)
+	}
+	if _, err := service.SetHouseholdMember(adminCtx, model.HouseholdMember{HouseholdID: household.ID, UserID: member.UserID}); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+
+	tx, err := service.CreateTransaction(adminCtx, model.Transaction{Desc: "laptop", Amount: 500000, IsDebt: true, UserID: owner.UserID})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	receipt, err := service.CreateAttachment(adminCtx, tx.ID, "receipt.png", strings.NewReader(png))
+	if err != nil {
+		t.Fatalf("failed to create attachment: %v", err)
+	}
+	list := fmt.Sprintf("/transactions/%d/attachments", tx.ID)
+	one := fmt.Sprintf("%s/%d", list, receipt.ID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own attachments", "GET /transactions/{id}/attachments", GetAttachmentsHandler, list, "", owner, http.StatusOK},
+		{"household member's attachments", "GET /transactions/{id}/attachments", GetAttachmentsHandler, list, "", member, http.StatusOK},
+		{"someone else's attachments", "GET /transactions/{id}/attachments", GetAttachmentsHandler, list, "", stranger, http.StatusForbidden},
+		{"downloading own attachment", "GET /transactions/{id}/attachments/{attachmentID}", DownloadAttachmentHandler, one, "", owner, http.StatusOK},
+		{"downloading a household member's", "GET /transactions/{id}/attachments/{attachmentID}", DownloadAttachmentHandler, one, "", member, http.StatusOK},
+		{"downloading someone else's", "GET /transactions/{id}/attachments/{attachmentID}", DownloadAttachmentHandler, one, "", stranger, http.StatusForbidden},
+		{"household member deleting", "DELETE /transactions/{id}/attachments/{attachmentID}", DeleteAttachmentHandler, one, "", member, http.StatusForbidden},
+		{"deleting someone else's", "DELETE /transactions/{id}/attachments/{attachmentID}", DeleteAttachmentHandler, one, "", stranger, http.StatusForbidden},
+		{"deleting own attachment", "DELETE /transactions/{id}/attachments/{attachmentID}", DeleteAttachmentHandler, one, "", owner, http.StatusOK},
+	})
+
+	for _, tc := range []struct {
+		as         model.Principal
+		wantStatus int
+	}{
+		{stranger, http.StatusForbidden},
+		{member, http.StatusForbidden},
+		{owner, http.StatusCreated},
+	} {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "scan.png")
+		part.Write([]byte(png))
+		form.Close()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /transactions/{id}/attachments", CreateAttachmentHandler)
+		req := httptest.NewRequest(http.MethodPost, list, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req = req.WithContext(service.WithPrincipal(req.Context(), tc.as))
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("POST %s as user %d status = %d, want %d", list, tc.as.UserID, rec.Code, tc.wantStatus)
+		}
+	}
+}
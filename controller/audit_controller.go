@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetAuditLogHandler handles GET /audit and returns the audit log, oldest first. The optional entity query
+// parameter (user, transaction or goal) keeps the entries of that entity, and id those of one record of it.
+func GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	q := r.URL.Query()
+	var id int64
+	if v := q.Get("id"); v != "" {
+		var ok bool
+		if id, ok = GetID(v, w, r); !ok {
+			return
+		}
+	}
+
+	entries, err := service.GetAuditLog(ctx, q.Get("entity"), id)
+	if err != nil {
+		writeError(w, err, "problem when fetching the audit log")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestGetAuditLogHandler(t *testing.T) {
+	user := newPrincipal(t, "audit-user")
+	path := fmt.Sprintf("/audit?entity=user&id=%d", user.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"regular user", "GET /audit", GetAuditLogHandler, path, "", user, http.StatusForbidden},
+		{"unknown entity", "GET /audit", GetAuditLogHandler, "/audit?entity=budget", "", admin, http.StatusBadRequest},
+		{"invalid id", "GET /audit", GetAuditLogHandler, "/audit?entity=user&id=abc", "", admin, http.StatusBadRequest},
+	})
+
+	rec := serve(t, handlerCase{pattern: "GET /audit", handler: GetAuditLogHandler, path: path, as: admin})
+	var entries []model.AuditEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(entries) != 1 || entries[0].Action != model.AuditActionCreate || entries[0].EntityID != user.UserID {
+		t.Errorf("GET %s as an admin = %d %+v, want the creation of the user", path, rec.Code, entries)
+	}
+}
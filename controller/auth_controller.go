@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionCookieName is the name of the cookie carrying the session token.
+const sessionCookieName = "fingo_session"
+
+var (
+	cookiesMu     sync.RWMutex
+	secureCookies = true
+)
+
+// SetSecureCookies sets whether the session cookie is only sent over HTTPS. It is on by default and
+// should only be turned off when fingo is served over plain HTTP on a trusted network.
+func SetSecureCookies(secure bool) {
+	cookiesMu.Lock()
+	defer cookiesMu.Unlock()
+	secureCookies = secure
+}
+
+// currentSecureCookies reports whether the session cookie is only sent over HTTPS.
+func currentSecureCookies() bool {
+	cookiesMu.RLock()
+	defer cookiesMu.RUnlock()
+	return secureCookies
+}
+
+// sessionCookie builds the session cookie holding token until expires. An empty token clears the cookie.
+func sessionCookie(token string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   currentSecureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// sessionToken returns the session token sent with the request, if any.
+func sessionToken(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// bearerToken returns the token sent in an "Authorization: Bearer" header, if any.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+type sessionKey struct{}
+
+// SessionFromRequest returns the session of the signed-in user making the request. It is only set on
+// requests that went through RequireSession with a valid session.
+func SessionFromRequest(r *http.Request) (*model.Session, bool) {
+	s, ok := r.Context().Value(sessionKey{}).(*model.Session)
+	return s, ok
+}
+
+// isPublicRequest reports whether a request may be served without signing in: the login page, the
+// assets it uses, and signing in and out.
+func isPublicRequest(r *http.Request) bool {
+	switch {
+	case r.Method == http.MethodGet && (r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/static/")):
+		return true
+	case r.Method == http.MethodPost && (r.URL.Path == "/auth/login" || r.URL.Path == "/auth/logout"):
+		return true
+	}
+	return false
+}
+
+// isSetupRequest reports whether a request is one of those that set up the first account: creating a
+// user and giving it credentials.
+func isSetupRequest(r *http.Request) bool {
+	if r.Method == http.MethodPost && r.URL.Path == "/users" {
+		return true
+	}
+	return r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/users/") && strings.HasSuffix(r.URL.Path, "/credentials")
+}
+
+// inSetup reports whether the request sets up the first account while nobody is able to sign in yet.
+func inSetup(r *http.Request) (bool, error) {
+	if !isSetupRequest(r) {
+		return false, nil
+	}
+
+	ctx, cancel := dbsqlite.NewDBContext()
+	defer cancel()
+
+	hasAccounts, err := service.HasAccounts(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !hasAccounts, nil
+}
+
+// RequireSession wraps the application handler so only signed-in users reach it, on behalf of whom the
+// service layer then decides what they may see and change. Browsers sign in with a session cookie and
+// scripts with an API token in an "Authorization: Bearer" header. Requests for the application page
+// without either are redirected to the login page; any other request is refused with 401. The login page,
+// its assets, signing in and out, and the setup of the first account are served to everyone.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token, ok := bearerToken(r); ok {
+			serveWithAPIToken(next, w, r, token)
+			return
+		}
+
+		ctx, cancel := dbsqlite.NewDBContext()
+		session, err := service.Authenticate(ctx, sessionToken(r))
+		cancel()
+		if err == nil {
+			ctx := context.WithValue(r.Context(), sessionKey{}, session)
+			ctx = service.WithPrincipal(ctx, model.Principal{UserID: session.UserID, Role: session.Role})
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			writeError(w, err, "problem when checking the session")
+			return
+		}
+
+		setup, err := inSetup(r)
+		if err != nil {
+			writeError(w, err, "problem when checking the session")
+			return
+		}
+		if setup {
+			// Nobody can sign in yet, so whoever sets up the first account does it as an admin
+			next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), model.Principal{Role: model.RoleAdmin})))
+			return
+		}
+
+		if r.Method == http.MethodGet && r.URL.Path == "/" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		writeErrorMessage(w, model.KindUnauthorized, "authentication required")
+	})
+}
+
+// serveWithAPIToken serves a request signed with an API token on behalf of the user the token belongs to.
+// Read-only tokens may only make GET requests.
+func serveWithAPIToken(next http.Handler, w http.ResponseWriter, r *http.Request, token string) {
+	ctx, cancel := dbsqlite.NewDBContext()
+	t, err := service.AuthenticateAPIToken(ctx, token)
+	cancel()
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		writeErrorMessage(w, model.KindUnauthorized, "invalid or expired api token")
+		return
+	}
+	if err != nil {
+		writeError(w, err, "problem when checking the api token")
+		return
+	}
+
+	if t.Scope == model.ScopeReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeErrorMessage(w, model.KindForbidden, "the api token is read-only")
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), model.Principal{UserID: t.UserID, Role: t.Role})))
+}
+
+// LoginHandler handles POST /auth/login, checks the login and password in the request body and starts
+// a session kept in a cookie.
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := dbsqlite.NewDBContext()
+	defer cancel()
+
+	var req model.LoginRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	token, session, err := service.Login(ctx, req)
+	if err != nil {
+		writeError(w, err, "problem when signing in")
+		return
+	}
+
+	expires, _ := time.Parse(dbsqlite.TimestampLayout, session.ExpiresAt)
+	http.SetCookie(w, sessionCookie(token, expires))
+	writeJSON(w, http.StatusOK, *session)
+}
+
+// LogoutHandler handles POST /auth/logout, ends the current session and clears its cookie.
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := dbsqlite.NewDBContext()
+	defer cancel()
+
+	if err := service.Logout(ctx, sessionToken(r)); err != nil {
+		writeError(w, err, "problem when signing out")
+		return
+	}
+
+	http.SetCookie(w, sessionCookie("", time.Unix(0, 0)))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "signed out"})
+}
+
+// GetSessionHandler handles GET /auth/session and returns the session of the signed-in user.
+func GetSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, ok := SessionFromRequest(r)
+	if !ok {
+		writeErrorMessage(w, model.KindUnauthorized, "authentication required")
+		return
+	}
+	writeJSON(w, http.StatusOK, *session)
+}
+
+// GetCredentialsHandler handles GET /users/{id}/credentials and returns the login of the user.
+func GetCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	c, err := service.GetCredentials(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching credentials")
+		return
+	}
+	writeJSON(w, http.StatusOK, *c)
+}
+
+// SetCredentialsHandler handles PUT /users/{id}/credentials and sets the login and password of the user.
+func SetCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update model.CredentialsUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	c, err := service.SetCredentials(ctx, id, update)
+	if err != nil {
+		writeError(w, err, "problem when setting credentials")
+		return
+	}
+	writeJSON(w, http.StatusOK, *c)
+}
+
+// SetRoleHandler handles PUT /users/{id}/role and makes the user an admin or a regular user. Only admins
+// may change roles.
+func SetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update model.RoleUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	c, err := service.SetRole(ctx, id, update)
+	if err != nil {
+		writeError(w, err, "problem when setting the role")
+		return
+	}
+	writeJSON(w, http.StatusOK, *c)
+}
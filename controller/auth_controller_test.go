@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestCredentialsAndRoleHandlers_Authorization(t *testing.T) {
+	first := newPrincipal(t, "roles-first")
+	second := newPrincipal(t, "roles-second")
+	credentials := func(p model.Principal) string { return fmt.Sprintf("/users/%d/credentials", p.UserID) }
+	role := func(p model.Principal) string { return fmt.Sprintf("/users/%d/role", p.UserID) }
+	body := func(login string) string { return fmt.Sprintf(`{"login":%q,"password":"correct horse"}`, login) }
+
+	runHandlerCases(t, []handlerCase{
+		{"setting own credentials", "PUT /users/{id}/credentials", SetCredentialsHandler, credentials(first), body("roles-first"), first, http.StatusOK},
+		{"setting someone else's", "PUT /users/{id}/credentials", SetCredentialsHandler, credentials(second), body("roles-hijack"), first, http.StatusForbidden},
+		{"admin setting credentials", "PUT /users/{id}/credentials", SetCredentialsHandler, credentials(second), body("roles-second"), admin, http.StatusOK},
+		{"own credentials", "GET /users/{id}/credentials", GetCredentialsHandler, credentials(first), "", first, http.StatusOK},
+		{"someone else's credentials", "GET /users/{id}/credentials", GetCredentialsHandler, credentials(second), "", first, http.StatusForbidden},
+		{"user promoting themselves", "PUT /users/{id}/role", SetRoleHandler, role(first), `{"role":"admin"}`, first, http.StatusForbidden},
+		{"unknown role", "PUT /users/{id}/role", SetRoleHandler, role(first), `{"role":"owner"}`, admin, http.StatusBadRequest},
+		{"promoting the first", "PUT /users/{id}/role", SetRoleHandler, role(first), `{"role":"admin"}`, admin, http.StatusOK},
+		{"promoting the second", "PUT /users/{id}/role", SetRoleHandler, role(second), `{"role":"admin"}`, admin, http.StatusOK},
+		{"demoting one of two admins", "PUT /users/{id}/role", SetRoleHandler, role(first), `{"role":"user"}`, admin, http.StatusOK},
+		{"demoting the last admin", "PUT /users/{id}/role", SetRoleHandler, role(second), `{"role":"user"}`, admin, http.StatusBadRequest},
+	})
+}
+
+func TestRequireSession_ActsOnBehalfOfTheSignedInUser(t *testing.T) {
+	user := newPrincipal(t, "session-user")
+	other := newPrincipal(t, "session-other")
+	if _, err := service.SetCredentials(adminCtx, user.UserID, model.CredentialsUpdate{Login: "session-user", Password: "correct horse"}); err != nil {
+		t.Fatalf("failed to set credentials: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	LoginHandler(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"login":"session-user","password":"correct horse"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /auth/login status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	cookies := rec.Result().Cookies()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", GetUserByIDHandler)
+	mux.HandleFunc("GET /auth/session", GetSessionHandler)
+	mux.HandleFunc("POST /auth/logout", LogoutHandler)
+	handler := RequireSession(mux)
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/auth/session", http.StatusOK},
+		{http.MethodGet, fmt.Sprintf("/users/%d", user.UserID), http.StatusOK},
+		{http.MethodGet, fmt.Sprintf("/users/%d", other.UserID), http.StatusForbidden},
+		{http.MethodPost, "/auth/logout", http.StatusOK},
+		{http.MethodGet, fmt.Sprintf("/users/%d", user.UserID), http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.wantStatus)
+		}
+	}
+}
@@ -1,9 +1,6 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
-	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
 	"natan/fingo/service"
 	"net/http"
@@ -11,7 +8,7 @@ import (
 
 // GetGoalByIDHandler handles GET /goals/{id} and returns the goal with the given ID.
 func GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	idStr := r.PathValue("id")
@@ -22,23 +19,22 @@ func GetGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	goal, err := service.GetGoalByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "goal not found"})
+		writeError(w, err, "problem when fetching goal")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, *goal)
 }
 
-// GetAllGoalsHandler handles GET /goals and returns all goals.
+// GetAllGoalsHandler handles GET /goals and returns all goals. The optional tags query parameter, a
+// comma-separated list, keeps the goals carrying every listed tag.
 func GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
-	goalsList, err := service.GetAllGoals(ctx)
+	goalsList, err := service.GetAllGoals(ctx, service.ParseTagFilter(r.URL.Query().Get("tags")))
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when fetching goals"})
+		writeError(w, err, "problem when fetching goals")
 		return
 	}
 
@@ -47,19 +43,16 @@ func GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
 
 // CreateGoalHandler handles POST /goals and creates a new goal from the request body.
 func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 	var goal model.Goal
 
-	if err := json.NewDecoder(r.Body).Decode(&goal); err != nil {
-		log.Printf("could not decode into body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &goal) {
 		return
 	}
 	goalRec, err := service.CreateGoal(ctx, goal)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when creating goal"})
+		writeError(w, err, "problem when creating goal")
 		return
 	}
 	writeJSON(w, http.StatusCreated, *goalRec)
@@ -67,7 +60,7 @@ func CreateGoalHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateGoalByIDHandler handles PATCH /goals/{id} and applies a partial update to the given goal.
 func UpdateGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 	var goalUpdate *model.GoalUpdate
 	idStr := r.PathValue("id")
@@ -76,16 +69,13 @@ func UpdateGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&goalUpdate); err != nil {
-		log.Printf("could not decode into body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &goalUpdate) {
 		return
 	}
 
 	goal, err := service.UpdateGoalByID(ctx, id, goalUpdate)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when updating goal"})
+		writeError(w, err, "problem when updating goal")
 		return
 	}
 
@@ -94,7 +84,7 @@ func UpdateGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 // DeleteGoalByIDHandler handles DELETE /goals/{id} and removes the goal with the given ID.
 func DeleteGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	idStr := r.PathValue("id")
@@ -105,10 +95,126 @@ func DeleteGoalByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := service.DeleteGoalByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when deleting goal"})
+		writeError(w, err, "problem when deleting goal")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
 }
+
+// GetGoalParticipantsHandler handles GET /goals/{id}/participants and returns the participants of a shared goal.
+func GetGoalParticipantsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	participants, err := service.GetGoalParticipants(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching goal participants")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, participants)
+}
+
+// SetGoalParticipantHandler handles PUT /goals/{id}/participants/{userID} and adds the user to the goal
+// or updates their target share.
+func SetGoalParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	goalID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	var participant model.GoalParticipant
+	if !decodeBody(w, r, &participant) {
+		return
+	}
+	participant.GoalID = goalID
+	participant.UserID = userID
+
+	participantRec, err := service.SetGoalParticipant(ctx, participant)
+	if err != nil {
+		writeError(w, err, "problem when setting goal participant")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *participantRec)
+}
+
+// DeleteGoalParticipantHandler handles DELETE /goals/{id}/participants/{userID} and removes the user from the goal.
+func DeleteGoalParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	goalID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.RemoveGoalParticipant(ctx, goalID, userID)
+	if err != nil {
+		writeError(w, err, "problem when removing goal participant")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// GetGoalContributionsHandler handles GET /goals/{id}/contributions and returns the contributions made towards a goal.
+func GetGoalContributionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	contributions, err := service.GetGoalContributions(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching goal contributions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contributions)
+}
+
+// CreateGoalContributionHandler handles POST /goals/{id}/contributions and records a contribution attributed to a user.
+func CreateGoalContributionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var contribution model.GoalContribution
+	if !decodeBody(w, r, &contribution) {
+		return
+	}
+	contribution.GoalID = id
+
+	contributionRec, err := service.CreateGoalContribution(ctx, contribution)
+	if err != nil {
+		writeError(w, err, "problem when creating goal contribution")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *contributionRec)
+}
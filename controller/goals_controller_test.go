@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestGoalHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "goals-owner")
+	member := newPrincipal(t, "goals-member")
+	joining := newPrincipal(t, "goals-joining")
+	stranger := newPrincipal(t, "goals-stranger")
+
+	goal, err := service.CreateGoal(adminCtx, model.Goal{Name: "car", Price: 150000, UserID: owner.UserID})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := service.SetGoalParticipant(adminCtx, model.GoalParticipant{GoalID: goal.ID, UserID: member.UserID, TargetShare: 50000}); err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+
+	path := fmt.Sprintf("/goals/%d", goal.ID)
+	participants := path + "/participants"
+	contributions := path + "/contributions"
+	create := fmt.Sprintf(`{"name":"bike","price":80000,"user_id":%d}`, owner.UserID)
+	contribute := func(p model.Principal) string { return fmt.Sprintf(`{"user_id":%d,"amount":1000}`, p.UserID) }
+
+	runHandlerCases(t, []handlerCase{
+		{"own goal", "GET /goals/{id}", GetGoalByIDHandler, path, "", owner, http.StatusOK},
+		{"shared goal", "GET /goals/{id}", GetGoalByIDHandler, path, "", member, http.StatusOK},
+		{"someone else's goal", "GET /goals/{id}", GetGoalByIDHandler, path, "", stranger, http.StatusForbidden},
+		{"all goals", "GET /goals", GetAllGoalsHandler, "/goals", "", stranger, http.StatusOK},
+		{"creating for themselves", "POST /goals", CreateGoalHandler, "/goals", create, owner, http.StatusCreated},
+		{"creating for someone else", "POST /goals", CreateGoalHandler, "/goals", create, stranger, http.StatusForbidden},
+		{"updating own goal", "PATCH /goals/{id}", UpdateGoalByIDHandler, path, `{"name":"new car"}`, owner, http.StatusOK},
+		{"participant updating", "PATCH /goals/{id}", UpdateGoalByIDHandler, path, `{"name":"my car"}`, member, http.StatusForbidden},
+		{"participants of a shared goal", "GET /goals/{id}/participants", GetGoalParticipantsHandler, participants, "", member, http.StatusOK},
+		{"participants of someone else's goal", "GET /goals/{id}/participants", GetGoalParticipantsHandler, participants, "", stranger, http.StatusForbidden},
+		{"participant adding someone", "PUT /goals/{id}/participants/{userID}", SetGoalParticipantHandler, fmt.Sprintf("%s/%d", participants, joining.UserID), `{"target_share":1000}`, member, http.StatusForbidden},
+		{"owner adding someone", "PUT /goals/{id}/participants/{userID}", SetGoalParticipantHandler, fmt.Sprintf("%s/%d", participants, joining.UserID), `{"target_share":1000}`, owner, http.StatusOK},
+		{"contributions of a shared goal", "GET /goals/{id}/contributions", GetGoalContributionsHandler, contributions, "", member, http.StatusOK},
+		{"contributions of someone else's goal", "GET /goals/{id}/contributions", GetGoalContributionsHandler, contributions, "", stranger, http.StatusForbidden},
+		{"contributing in their own name", "POST /goals/{id}/contributions", CreateGoalContributionHandler, contributions, contribute(member), member, http.StatusCreated},
+		{"contributing in someone else's name", "POST /goals/{id}/contributions", CreateGoalContributionHandler, contributions, contribute(owner), member, http.StatusForbidden},
+		{"stranger removing a participant", "DELETE /goals/{id}/participants/{userID}", DeleteGoalParticipantHandler, fmt.Sprintf("%s/%d", participants, joining.UserID), "", stranger, http.StatusForbidden},
+		{"participant leaving", "DELETE /goals/{id}/participants/{userID}", DeleteGoalParticipantHandler, fmt.Sprintf("%s/%d", participants, joining.UserID), "", joining, http.StatusOK},
+		{"participant leaving again", "DELETE /goals/{id}/participants/{userID}", DeleteGoalParticipantHandler, fmt.Sprintf("%s/%d", participants, joining.UserID), "", joining, http.StatusNotFound},
+		{"participant deleting", "DELETE /goals/{id}", DeleteGoalByIDHandler, path, "", member, http.StatusForbidden},
+		{"deleting own goal", "DELETE /goals/{id}", DeleteGoalByIDHandler, path, "", owner, http.StatusOK},
+		{"deleting it again", "DELETE /goals/{id}", DeleteGoalByIDHandler, path, "", owner, http.StatusNotFound},
+	})
+}
@@ -1,10 +1,16 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/service"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // writeJSON writes a JSON-encoded response with the given status code.
@@ -21,14 +27,101 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 func GetID(idStr string, w http.ResponseWriter, r *http.Request) (int64, bool) {
 	if idStr == "" {
 		log.Println("could not get id in the URI")
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "empty id"})
+		writeError(w, model.ValidationError(model.FieldError{Field: "id", Message: "is required"}), "")
 		return 0, false
 	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		log.Printf("could not convert the path variable to id: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+		writeError(w, model.ValidationError(model.FieldError{Field: "id", Message: "must be an integer"}), "")
 		return 0, false
 	}
 	return id, true
 }
+
+// maxBodySize is the largest JSON request body accepted, in bytes.
+const maxBodySize = 1 << 20
+
+// decodeBody decodes the JSON request body into v, rejecting bodies larger than maxBodySize, fields v does
+// not have and anything after the JSON value. Writes the error response and returns false if the body is
+// refused.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON body")
+	}
+	if err == nil {
+		return true
+	}
+
+	log.Printf("could not decode request body: %v", err)
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeErrorMessage(w, model.KindTooLarge, "request body too large")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeError(w, model.ValidationError(model.FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}), "")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeError(w, model.ValidationError(model.FieldError{Field: field, Message: "is not a known field"}), "")
+	default:
+		writeErrorMessage(w, model.KindValidation, "invalid body")
+	}
+	return false
+}
+
+// requestContext creates the database context of a handler, carrying the principal RequireSession attached
+// to the request so the service layer can tell what it may see and change.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := dbsqlite.NewDBContext()
+	if p, ok := service.PrincipalFromContext(r.Context()); ok {
+		ctx = service.WithPrincipal(ctx, p)
+	}
+	return ctx, cancel
+}
+
+// errorCodeInternal is the code of the errors clients can do nothing about.
+const errorCodeInternal = "internal_error"
+
+// errorStatus maps the kinds of domain errors to the status of their responses.
+var errorStatus = map[model.ErrorKind]int{
+	model.KindValidation:   http.StatusBadRequest,
+	model.KindNotFound:     http.StatusNotFound,
+	model.KindConflict:     http.StatusConflict,
+	model.KindForbidden:    http.StatusForbidden,
+	model.KindUnauthorized: http.StatusUnauthorized,
+	model.KindTooLarge:     http.StatusRequestEntityTooLarge,
+}
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Fields  []model.FieldError `json:"fields,omitempty"`
+}
+
+// writeError logs err and writes the response of the failed request. Domain errors are answered with the
+// status and code of their kind, their message and the fields at fault; any other error is answered with
+// a 500 and the failure message, keeping its details out of the response.
+func writeError(w http.ResponseWriter, err error, failure string) {
+	log.Println(err)
+
+	var domainErr *model.Error
+	if errors.As(err, &domainErr) {
+		if status, ok := errorStatus[domainErr.Kind]; ok {
+			writeJSON(w, status, errorResponse{Code: string(domainErr.Kind), Message: err.Error(), Fields: domainErr.Fields})
+			return
+		}
+	}
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Code: errorCodeInternal, Message: failure})
+}
+
+// writeErrorMessage writes the response of a request the controller itself refused, such as one whose body
+// does not decode, with the status and code of kind.
+func writeErrorMessage(w http.ResponseWriter, kind model.ErrorKind, message string) {
+	writeJSON(w, errorStatus[kind], errorResponse{Code: string(kind), Message: message})
+}
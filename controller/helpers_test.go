@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantFields  int
+	}{
+		{"not found", model.NotFoundError("goal", sql.ErrNoRows), http.StatusNotFound, "not_found", "goal not found", 0},
+		{"wrapped sentinel", fmt.Errorf("%w: name is required", service.ErrInvalidHousehold), http.StatusBadRequest, "validation_failed", "invalid household: name is required", 0},
+		{"conflict", service.ErrDuplicateTag, http.StatusConflict, "conflict", "tag already exists", 0},
+		{"forbidden", service.ErrForbidden, http.StatusForbidden, "forbidden", "not allowed", 0},
+		{"fields", model.ValidationError(model.FieldError{Field: "name", Message: "is required"}, model.FieldError{Field: "price", Message: "must not be negative"}), http.StatusBadRequest, "validation_failed", "invalid request", 2},
+		{"anything else", errors.New("disk I/O error"), http.StatusInternalServerError, "internal_error", "problem when testing", 0},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		writeError(rec, tc.err, "problem when testing")
+
+		var body errorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: could not decode response: %v", tc.name, err)
+		}
+		if rec.Code != tc.wantStatus || body.Code != tc.wantCode || body.Message != tc.wantMessage || len(body.Fields) != tc.wantFields {
+			t.Errorf("%s: writeError() = %d %+v, want %d %s %q with %d field(s)", tc.name, rec.Code, body, tc.wantStatus, tc.wantCode, tc.wantMessage, tc.wantFields)
+		}
+	}
+}
+
+func TestGetID_InvalidIDNamesTheField(t *testing.T) {
+	rec := serve(t, handlerCase{pattern: "GET /goals/{id}", handler: GetGoalByIDHandler, path: "/goals/abc", as: admin})
+
+	var body errorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Code != "validation_failed" || len(body.Fields) != 1 || body.Fields[0].Field != "id" {
+		t.Errorf("GET /goals/abc = %d %+v, want a validation error on id", rec.Code, body)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	owner := newPrincipal(t, "decode-body-owner")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{"valid", fmt.Sprintf(`{"name":"bike","price":1000,"deadline":"2030-01-01","user_id":%d}`, owner.UserID), http.StatusCreated, ""},
+		{"unknown field", fmt.Sprintf(`{"name":"bike","colour":"red","user_id":%d}`, owner.UserID), http.StatusBadRequest, "colour"},
+		{"wrong type", `{"name":"bike","user_id":"me"}`, http.StatusBadRequest, "user_id"},
+		{"trailing data", fmt.Sprintf(`{"name":"bike","user_id":%d} {}`, owner.UserID), http.StatusBadRequest, ""},
+		{"too large", fmt.Sprintf(`{"name":"bike","description":"%s"}`, strings.Repeat("x", maxBodySize)), http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tc := range tests {
+		rec := serve(t, handlerCase{pattern: "POST /goals", handler: CreateGoalHandler, path: "/goals", body: tc.body, as: owner})
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: POST /goals status = %d, want %d (body %s)", tc.name, rec.Code, tc.wantStatus, rec.Body.String())
+			continue
+		}
+		if tc.wantField == "" {
+			continue
+		}
+
+		var body errorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: could not decode response: %v", tc.name, err)
+		}
+		if len(body.Fields) != 1 || body.Fields[0].Field != tc.wantField {
+			t.Errorf("%s: POST /goals fields = %+v, want an error on %s", tc.name, body.Fields, tc.wantField)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetHouseholdsHandler handles GET /households and returns the households the caller can see: their own,
+// or every household for admins.
+func GetHouseholdsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	households, err := service.GetHouseholds(ctx)
+	if err != nil {
+		writeError(w, err, "problem when fetching households")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, households)
+}
+
+// CreateHouseholdHandler handles POST /households and creates a household owned by the caller.
+func CreateHouseholdHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	var household model.Household
+	if !decodeBody(w, r, &household) {
+		return
+	}
+
+	householdRec, err := service.CreateHousehold(ctx, household)
+	if err != nil {
+		writeError(w, err, "problem when creating household")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *householdRec)
+}
+
+// GetHouseholdByIDHandler handles GET /households/{id} and returns the household with its members.
+func GetHouseholdByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	household, err := service.GetHouseholdByID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching household")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *household)
+}
+
+// UpdateHouseholdByIDHandler handles PATCH /households/{id} and renames the household.
+func UpdateHouseholdByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update model.HouseholdUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	household, err := service.UpdateHouseholdByID(ctx, id, &update)
+	if err != nil {
+		writeError(w, err, "problem when updating household")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *household)
+}
+
+// DeleteHouseholdByIDHandler handles DELETE /households/{id} and deletes the household. Its members keep
+// their users and records.
+func DeleteHouseholdByIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.DeleteHouseholdByID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when deleting household")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// SetHouseholdMemberHandler handles PUT /households/{id}/members/{userID} and changes the role of the user
+// in the household. Only admins may add users this way; anyone else is invited.
+func SetHouseholdMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	householdID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	var member model.HouseholdMember
+	if !decodeBody(w, r, &member) {
+		return
+	}
+	member.HouseholdID = householdID
+	member.UserID = userID
+
+	memberRec, err := service.SetHouseholdMember(ctx, member)
+	if err != nil {
+		writeError(w, err, "problem when setting household member")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *memberRec)
+}
+
+// DeleteHouseholdMemberHandler handles DELETE /households/{id}/members/{userID} and removes the user from
+// the household.
+func DeleteHouseholdMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	householdID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.RemoveHouseholdMember(ctx, householdID, userID)
+	if err != nil {
+		writeError(w, err, "problem when removing household member")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// InviteHouseholdMemberHandler handles POST /households/{id}/invites and invites the user in the body to
+// join the household.
+func InviteHouseholdMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	householdID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var invite model.HouseholdInvite
+	if !decodeBody(w, r, &invite) {
+		return
+	}
+	invite.HouseholdID = householdID
+
+	created, err := service.InviteHouseholdMember(ctx, invite)
+	if err != nil {
+		writeError(w, err, "problem when inviting household member")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *created)
+}
+
+// GetHouseholdInvitesHandler handles GET /users/{id}/household-invites and returns the user's pending
+// household invites.
+func GetHouseholdInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	invites, err := service.GetHouseholdInvites(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem fetching household invites")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// AcceptHouseholdInviteHandler handles POST /households/{id}/invites/{userID}/accept and makes the invited
+// user a member of the household.
+func AcceptHouseholdInviteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	householdID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	member, err := service.AcceptHouseholdInvite(ctx, householdID, userID)
+	if err != nil {
+		writeError(w, err, "problem when accepting household invite")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *member)
+}
+
+// DeleteHouseholdInviteHandler handles DELETE /households/{id}/invites/{userID} and declines or withdraws
+// the user's invite to the household.
+func DeleteHouseholdInviteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	householdID, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	userID, ok := GetID(r.PathValue("userID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.DeleteHouseholdInvite(ctx, householdID, userID)
+	if err != nil {
+		writeError(w, err, "problem when deleting household invite")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// GetHouseholdBalanceHandler handles GET /households/{id}/balance and returns the combined balance and
+// monthly figures of the household members.
+func GetHouseholdBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := service.GetHouseholdBalance(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when building household balance")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *balance)
+}
+
+// GetHouseholdCashflowReportHandler handles GET /households/{id}/reports/cashflow and returns the cash flow
+// of the household members added up per period. It takes the same query parameters as the per-user report.
+func GetHouseholdCashflowReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	report, err := service.GetHouseholdCashflowReport(ctx, id, q.Get("from"), q.Get("to"), q.Get("granularity"))
+	if err != nil {
+		writeError(w, err, "problem building household cash flow report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *report)
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestHouseholdHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "households-owner")
+	member := newPrincipal(t, "households-member")
+	joining := newPrincipal(t, "households-joining")
+	stranger := newPrincipal(t, "households-stranger")
+
+	household, err := service.CreateHousehold(service.WithPrincipal(adminCtx, owner), model.Household{Name: "Home"})
+	if err != nil {
+		t.Fatalf("failed to create household: %v", err)
+	}
+	if _, err := service.SetHouseholdMember(adminCtx, model.HouseholdMember{HouseholdID: household.ID, UserID: member.UserID}); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+
+	path := fmt.Sprintf("/households/%d", household.ID)
+	members := path + "/members"
+	invites := path + "/invites"
+	invite := fmt.Sprintf(`{"user_id":%d}`, joining.UserID)
+	accept := fmt.Sprintf("%s/%d/accept", invites, joining.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own household", "GET /households/{id}", GetHouseholdByIDHandler, path, "", member, http.StatusOK},
+		{"someone else's household", "GET /households/{id}", GetHouseholdByIDHandler, path, "", stranger, http.StatusForbidden},
+		{"missing household", "GET /households/{id}", GetHouseholdByIDHandler, "/households/999999", "", admin, http.StatusNotFound},
+		{"joining a second household", "POST /households", CreateHouseholdHandler, "/households", `{"name":"Other"}`, member, http.StatusConflict},
+		{"household without a name", "POST /households", CreateHouseholdHandler, "/households", `{"name":""}`, stranger, http.StatusBadRequest},
+		{"member renaming", "PATCH /households/{id}", UpdateHouseholdByIDHandler, path, `{"name":"Ours"}`, member, http.StatusForbidden},
+		{"owner renaming", "PATCH /households/{id}", UpdateHouseholdByIDHandler, path, `{"name":"Ours"}`, owner, http.StatusOK},
+		{"member adding someone", "PUT /households/{id}/members/{userID}", SetHouseholdMemberHandler, fmt.Sprintf("%s/%d", members, joining.UserID), `{}`, member, http.StatusForbidden},
+		{"owner adding someone", "PUT /households/{id}/members/{userID}", SetHouseholdMemberHandler, fmt.Sprintf("%s/%d", members, joining.UserID), `{}`, owner, http.StatusForbidden},
+		{"member inviting someone", "POST /households/{id}/invites", InviteHouseholdMemberHandler, invites, invite, member, http.StatusForbidden},
+		{"owner inviting someone", "POST /households/{id}/invites", InviteHouseholdMemberHandler, invites, invite, owner, http.StatusCreated},
+		{"own invites", "GET /users/{id}/household-invites", GetHouseholdInvitesHandler, fmt.Sprintf("/users/%d/household-invites", joining.UserID), "", joining, http.StatusOK},
+		{"someone else's invites", "GET /users/{id}/household-invites", GetHouseholdInvitesHandler, fmt.Sprintf("/users/%d/household-invites", joining.UserID), "", owner, http.StatusForbidden},
+		{"accepting someone else's invite", "POST /households/{id}/invites/{userID}/accept", AcceptHouseholdInviteHandler, accept, "", owner, http.StatusForbidden},
+		{"accepting own invite", "POST /households/{id}/invites/{userID}/accept", AcceptHouseholdInviteHandler, accept, "", joining, http.StatusOK},
+		{"accepting it again", "POST /households/{id}/invites/{userID}/accept", AcceptHouseholdInviteHandler, accept, "", joining, http.StatusNotFound},
+		{"declining a missing invite", "DELETE /households/{id}/invites/{userID}", DeleteHouseholdInviteHandler, fmt.Sprintf("%s/%d", invites, stranger.UserID), "", stranger, http.StatusNotFound},
+		{"last owner stepping down", "PUT /households/{id}/members/{userID}", SetHouseholdMemberHandler, fmt.Sprintf("%s/%d", members, owner.UserID), `{"role":"member"}`, owner, http.StatusBadRequest},
+		{"member balance", "GET /households/{id}/balance", GetHouseholdBalanceHandler, path + "/balance", "", member, http.StatusOK},
+		{"someone else's balance", "GET /households/{id}/balance", GetHouseholdBalanceHandler, path + "/balance", "", stranger, http.StatusForbidden},
+		{"member cash flow", "GET /households/{id}/reports/cashflow", GetHouseholdCashflowReportHandler, path + "/reports/cashflow", "", member, http.StatusOK},
+		{"cash flow with a bad granularity", "GET /households/{id}/reports/cashflow", GetHouseholdCashflowReportHandler, path + "/reports/cashflow?granularity=hourly", "", member, http.StatusBadRequest},
+		{"member reading a housemate", "GET /users/{id}", GetUserByIDHandler, fmt.Sprintf("/users/%d", owner.UserID), "", member, http.StatusOK},
+		{"member updating a housemate", "PATCH /users/{id}", UpdateUserByIDHandler, fmt.Sprintf("/users/%d", owner.UserID), `{"user_name":"mine"}`, member, http.StatusForbidden},
+		{"stranger removing a member", "DELETE /households/{id}/members/{userID}", DeleteHouseholdMemberHandler, fmt.Sprintf("%s/%d", members, joining.UserID), "", stranger, http.StatusForbidden},
+		{"member leaving", "DELETE /households/{id}/members/{userID}", DeleteHouseholdMemberHandler, fmt.Sprintf("%s/%d", members, joining.UserID), "", joining, http.StatusOK},
+		{"member deleting", "DELETE /households/{id}", DeleteHouseholdByIDHandler, path, "", member, http.StatusForbidden},
+	})
+
+	rec := serve(t, handlerCase{pattern: "GET /users", handler: GetAllUsersHandler, path: "/users", as: member})
+	var users []model.User
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(users) != 2 {
+		t.Errorf("GET /users as a household member = %d %+v, want both members", rec.Code, users)
+	}
+
+	runHandlerCases(t, []handlerCase{
+		{"owner deleting", "DELETE /households/{id}", DeleteHouseholdByIDHandler, path, "", owner, http.StatusOK},
+	})
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"natan/fingo/service"
+	"net/http"
+	"time"
+)
+
+// jobTriggerTimeout bounds how long a manually triggered job may run.
+const jobTriggerTimeout = 5 * time.Minute
+
+// GetJobsHandler handles GET /admin/jobs and returns the registered background jobs with their
+// schedules, next run and last run.
+func GetJobsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	list, err := service.GetJobs(ctx)
+	if err != nil {
+		writeError(w, err, "problem fetching jobs")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetJobRunsHandler handles GET /admin/jobs/{name}/runs and returns the most recent runs of a job.
+func GetJobRunsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	runs, err := service.GetJobRuns(ctx, r.PathValue("name"))
+	if err != nil {
+		writeError(w, err, "problem fetching job runs")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// TriggerJobHandler handles POST /admin/jobs/{name}/run, runs the job right away and returns the
+// recorded run, whose status tells whether the job succeeded.
+func TriggerJobHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), jobTriggerTimeout)
+	defer cancel()
+
+	run, err := service.TriggerJob(ctx, r.PathValue("name"))
+	if err != nil {
+		writeError(w, err, "problem when running job")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *run)
+}
+
+// GetSchedulerStatusHandler handles GET /admin/scheduler and tells whether this instance holds the
+// lease to run the scheduled jobs, along with the current lease holder.
+func GetSchedulerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	status, err := service.GetSchedulerStatus(ctx)
+	if err != nil {
+		writeError(w, err, "problem fetching scheduler status")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *status)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/service"
+)
+
+func TestJobHandlers_Authorization(t *testing.T) {
+	user := newPrincipal(t, "jobs-user")
+	runs := "/admin/jobs/" + service.JobSessionCleanup + "/runs"
+	run := "/admin/jobs/" + service.JobSessionCleanup + "/run"
+
+	runHandlerCases(t, []handlerCase{
+		{"user listing jobs", "GET /admin/jobs", GetJobsHandler, "/admin/jobs", "", user, http.StatusForbidden},
+		{"admin listing jobs", "GET /admin/jobs", GetJobsHandler, "/admin/jobs", "", admin, http.StatusOK},
+		{"user running a job", "POST /admin/jobs/{name}/run", TriggerJobHandler, run, "", user, http.StatusForbidden},
+		{"admin running a job", "POST /admin/jobs/{name}/run", TriggerJobHandler, run, "", admin, http.StatusOK},
+		{"user listing runs", "GET /admin/jobs/{name}/runs", GetJobRunsHandler, runs, "", user, http.StatusForbidden},
+		{"admin listing runs", "GET /admin/jobs/{name}/runs", GetJobRunsHandler, runs, "", admin, http.StatusOK},
+		{"user reading the scheduler", "GET /admin/scheduler", GetSchedulerStatusHandler, "/admin/scheduler", "", user, http.StatusForbidden},
+		{"admin reading the scheduler", "GET /admin/scheduler", GetSchedulerStatusHandler, "/admin/scheduler", "", admin, http.StatusOK},
+	})
+}
+
+func TestTriggerJobHandler_AnotherInstanceHoldsTheLease(t *testing.T) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `DELETE FROM scheduler_leases`); err != nil {
+		t.Fatalf("could not clear leases: %v", err)
+	}
+	if leader, _, err := dbsqlite.AcquireLease(ctx, "scheduler", "other-instance", time.Now(), time.Hour, db); err != nil || !leader {
+		t.Fatalf("AcquireLease() = %v, %v", leader, err)
+	}
+	t.Cleanup(func() { _ = dbsqlite.ReleaseLease(ctx, "scheduler", "other-instance", db) })
+
+	runHandlerCases(t, []handlerCase{
+		{"running a job led elsewhere", "POST /admin/jobs/{name}/run", TriggerJobHandler, "/admin/jobs/" + service.JobSessionCleanup + "/run", "", admin, http.StatusConflict},
+	})
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestMain(m *testing.M) {
+	_ = os.Remove("fingo.db")
+	if err := dbsqlite.CheckAndCreate(); err != nil {
+		log.Fatalf("could not create test database: %v", err)
+	}
+
+	dir, err := os.MkdirTemp("", "fingo-attachments")
+	if err != nil {
+		log.Fatalf("could not create attachments directory: %v", err)
+	}
+	service.SetAttachmentsDir(dir)
+	if err := service.RegisterDefaultJobs(context.Background()); err != nil {
+		log.Fatalf("could not register jobs: %v", err)
+	}
+
+	code := m.Run()
+
+	_ = os.RemoveAll(dir)
+	_ = os.Remove("fingo.db")
+	os.Exit(code)
+}
+
+// admin is the principal of an admin who is not one of the users created by the tests.
+var admin = model.Principal{Role: model.RoleAdmin}
+
+// adminCtx is the context the tests set up their data with.
+var adminCtx = service.WithPrincipal(context.Background(), admin)
+
+// handlerCase is a request served by a single handler registered under its pattern, on behalf of a principal.
+type handlerCase struct {
+	name       string
+	pattern    string
+	handler    http.HandlerFunc
+	path       string
+	body       string
+	as         model.Principal
+	wantStatus int
+}
+
+// serve sends the request of tc to its handler the way RequireSession would after checking the session.
+func serve(t *testing.T, tc handlerCase) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tc.pattern, tc.handler)
+
+	method, _, _ := strings.Cut(tc.pattern, " ")
+	req := httptest.NewRequest(method, tc.path, strings.NewReader(tc.body))
+	req = req.WithContext(service.WithPrincipal(req.Context(), tc.as))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// runHandlerCases serves every case in order, checking the status of each response.
+func runHandlerCases(t *testing.T, tests []handlerCase) {
+	t.Helper()
+
+	for _, tc := range tests {
+		rec := serve(t, tc)
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s: %s %s status = %d, want %d (body %s)", tc.name, tc.pattern, tc.path, rec.Code, tc.wantStatus, rec.Body.String())
+		}
+	}
+}
+
+// newPrincipal creates a user and returns the principal of a regular user signed in as them.
+func newPrincipal(t *testing.T, name string) model.Principal {
+	t.Helper()
+
+	u, err := service.CreateUser(adminCtx, model.User{UserName: name, CurrentAmount: 100000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return model.Principal{UserID: u.ID, Role: model.RoleUser}
+}
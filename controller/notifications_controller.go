@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetNotificationsByUserIDHandler handles GET /users/{id}/notifications and returns the user's notifications.
+// The optional query parameter unread=true restricts the result to unread notifications.
+func GetNotificationsByUserIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := service.GetNotificationsByUserID(ctx, id, unreadOnly)
+	if err != nil {
+		writeError(w, err, "problem fetching notifications")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notifications)
+}
+
+// UpdateNotificationsByUserIDHandler handles PATCH /users/{id}/notifications and marks the given
+// notifications, or all of them when no IDs are sent, as read or unread.
+func UpdateNotificationsByUserIDHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update *model.NotificationReadUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	rows, err := service.SetNotificationsRead(ctx, id, update)
+	if err != nil {
+		writeError(w, err, "problem when updating notifications")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// GetNotificationChannelsHandler handles GET /users/{id}/notification-channels and returns the addresses the
+// user's notifications are delivered to.
+func GetNotificationChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	channels, err := service.GetNotificationChannels(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem fetching notification channels")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *channels)
+}
+
+// UpdateNotificationChannelsHandler handles PATCH /users/{id}/notification-channels and applies a partial
+// update to the addresses the user's notifications are delivered to.
+func UpdateNotificationChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var update *model.NotificationChannelsUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	channels, err := service.UpdateNotificationChannels(ctx, id, update)
+	if err != nil {
+		writeError(w, err, "problem when updating notification channels")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *channels)
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestNotificationHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "notifications-owner")
+	stranger := newPrincipal(t, "notifications-stranger")
+	path := fmt.Sprintf("/users/%d/notifications", owner.UserID)
+	channels := fmt.Sprintf("/users/%d/notification-channels", owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own notifications", "GET /users/{id}/notifications", GetNotificationsByUserIDHandler, path, "", owner, http.StatusOK},
+		{"someone else's notifications", "GET /users/{id}/notifications", GetNotificationsByUserIDHandler, path, "", stranger, http.StatusForbidden},
+		{"marking own as read", "PATCH /users/{id}/notifications", UpdateNotificationsByUserIDHandler, path, `{"read":true}`, owner, http.StatusOK},
+		{"marking someone else's", "PATCH /users/{id}/notifications", UpdateNotificationsByUserIDHandler, path, `{"read":true}`, stranger, http.StatusForbidden},
+		{"own channels", "GET /users/{id}/notification-channels", GetNotificationChannelsHandler, channels, "", owner, http.StatusOK},
+		{"someone else's channels", "GET /users/{id}/notification-channels", GetNotificationChannelsHandler, channels, "", stranger, http.StatusForbidden},
+		{"setting own e-mail", "PATCH /users/{id}/notification-channels", UpdateNotificationChannelsHandler, channels, `{"email":"owner@example.com"}`, owner, http.StatusOK},
+		{"setting an invalid webhook", "PATCH /users/{id}/notification-channels", UpdateNotificationChannelsHandler, channels, `{"webhook_url":"ftp://example.com"}`, owner, http.StatusBadRequest},
+		{"redirecting someone else's", "PATCH /users/{id}/notification-channels", UpdateNotificationChannelsHandler, channels, `{"email":"stranger@example.com"}`, stranger, http.StatusForbidden},
+	})
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+	"strconv"
+)
+
+// GetCashflowReportHandler handles GET /users/{id}/reports/cashflow and returns the user's income,
+// expenses, net and ending balance per period. The optional query parameters from and to (YYYY-MM-DD)
+// bound the report and granularity picks day, week, month (the default) or year periods.
+func GetCashflowReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	report, err := service.GetCashflowReport(ctx, id, q.Get("from"), q.Get("to"), q.Get("granularity"))
+	if err != nil {
+		writeError(w, err, "problem building cash flow report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *report)
+}
+
+// GetCategoryReportHandler handles GET /users/{id}/reports/categories and returns the user's income,
+// expenses and net per category, counting each split of a split transaction under its own category. The
+// optional query parameters from and to (YYYY-MM-DD, inclusive) bound the transactions counted.
+func GetCategoryReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	report, err := service.GetCategoryReport(ctx, id, q.Get("from"), q.Get("to"))
+	if err != nil {
+		writeError(w, err, "problem building category report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *report)
+}
+
+// GetDashboardHandler handles GET /users/{id}/dashboard and returns the user's balance, month-to-date
+// figures compared with the previous month, largest expenses and goals progress.
+func GetDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	dashboard, err := service.GetDashboard(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem building dashboard")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *dashboard)
+}
+
+// GetProjectionHandler handles GET /users/{id}/projection and forecasts the user's balance at the end of
+// each of the next months. The optional query parameter months sets how many months (3 by default).
+func GetProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	months := 0
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeErrorMessage(w, model.KindValidation, "months must be a positive integer")
+			return
+		}
+		months = n
+	}
+
+	projection, err := service.GetProjection(ctx, id, months)
+	if err != nil {
+		writeError(w, err, "problem building projection")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *projection)
+}
+
+// GetInsightsHandler handles GET /users/{id}/insights and flags the user's recent expenses that look
+// unusual: amounts far above the usual at a merchant, possible duplicate charges and new merchants.
+// The optional query parameter days sets how far back expenses are analysed (30 by default).
+func GetInsightsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	days := 0
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeErrorMessage(w, model.KindValidation, "days must be a positive integer")
+			return
+		}
+		days = n
+	}
+
+	insights, err := service.GetInsights(ctx, id, days)
+	if err != nil {
+		writeError(w, err, "problem analysing expenses")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *insights)
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReportHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "reports-owner")
+	stranger := newPrincipal(t, "reports-stranger")
+	user := fmt.Sprintf("/users/%d", owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own cashflow", "GET /users/{id}/reports/cashflow", GetCashflowReportHandler, user + "/reports/cashflow", "", owner, http.StatusOK},
+		{"someone else's cashflow", "GET /users/{id}/reports/cashflow", GetCashflowReportHandler, user + "/reports/cashflow", "", stranger, http.StatusForbidden},
+		{"own categories", "GET /users/{id}/reports/categories", GetCategoryReportHandler, user + "/reports/categories", "", owner, http.StatusOK},
+		{"someone else's categories", "GET /users/{id}/reports/categories", GetCategoryReportHandler, user + "/reports/categories", "", stranger, http.StatusForbidden},
+		{"own dashboard", "GET /users/{id}/dashboard", GetDashboardHandler, user + "/dashboard", "", owner, http.StatusOK},
+		{"someone else's dashboard", "GET /users/{id}/dashboard", GetDashboardHandler, user + "/dashboard", "", stranger, http.StatusForbidden},
+		{"own projection", "GET /users/{id}/projection", GetProjectionHandler, user + "/projection", "", owner, http.StatusOK},
+		{"someone else's projection", "GET /users/{id}/projection", GetProjectionHandler, user + "/projection", "", stranger, http.StatusForbidden},
+		{"own insights", "GET /users/{id}/insights", GetInsightsHandler, user + "/insights", "", owner, http.StatusOK},
+		{"someone else's insights", "GET /users/{id}/insights", GetInsightsHandler, user + "/insights", "", stranger, http.StatusForbidden},
+		{"admin reads any dashboard", "GET /users/{id}/dashboard", GetDashboardHandler, user + "/dashboard", "", admin, http.StatusOK},
+	})
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetRulesHandler handles GET /users/{id}/rules and returns the user's categorisation rules in evaluation order.
+func GetRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := service.GetRules(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching rules")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// CreateRuleHandler handles POST /users/{id}/rules and creates a categorisation rule from the request body.
+// Rules are enabled unless the body says otherwise.
+func CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	rule := model.Rule{Enabled: true}
+	if !decodeBody(w, r, &rule) {
+		return
+	}
+
+	ruleRec, err := service.CreateRule(ctx, id, rule)
+	if err != nil {
+		writeError(w, err, "problem when creating rule")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *ruleRec)
+}
+
+// UpdateRuleHandler handles PATCH /users/{id}/rules/{ruleID} and applies a partial update to the rule.
+func UpdateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	ruleID, ok := GetID(r.PathValue("ruleID"), w, r)
+	if !ok {
+		return
+	}
+
+	var update model.RuleUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	rule, err := service.UpdateRule(ctx, id, ruleID, &update)
+	if err != nil {
+		writeError(w, err, "problem when updating rule")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *rule)
+}
+
+// DeleteRuleHandler handles DELETE /users/{id}/rules/{ruleID} and removes the rule.
+func DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	ruleID, ok := GetID(r.PathValue("ruleID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.DeleteRule(ctx, id, ruleID)
+	if err != nil {
+		writeError(w, err, "problem when deleting rule")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// ApplyRulesHandler handles POST /users/{id}/rules/apply and reclassifies the user's transactions with their
+// enabled rules. With the query parameter dry_run=true nothing is changed and the response previews the changes.
+func ApplyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	dryRun := false
+	switch r.URL.Query().Get("dry_run") {
+	case "", "false":
+	case "true":
+		dryRun = true
+	default:
+		writeErrorMessage(w, model.KindValidation, "dry_run must be true or false")
+		return
+	}
+
+	result, err := service.ApplyRules(ctx, id, dryRun)
+	if err != nil {
+		writeError(w, err, "problem when applying rules")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *result)
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestRuleHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "rules-owner")
+	stranger := newPrincipal(t, "rules-stranger")
+
+	rule, err := service.CreateRule(adminCtx, owner.UserID, model.Rule{Name: "coffee", DescriptionContains: "coffee", Category: "food"})
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	rules := fmt.Sprintf("/users/%d/rules", owner.UserID)
+	one := fmt.Sprintf("%s/%d", rules, rule.ID)
+	create := `{"name":"rent","description_contains":"rent","category":"housing"}`
+
+	runHandlerCases(t, []handlerCase{
+		{"own rules", "GET /users/{id}/rules", GetRulesHandler, rules, "", owner, http.StatusOK},
+		{"someone else's rules", "GET /users/{id}/rules", GetRulesHandler, rules, "", stranger, http.StatusForbidden},
+		{"creating own rule", "POST /users/{id}/rules", CreateRuleHandler, rules, create, owner, http.StatusCreated},
+		{"creating for someone else", "POST /users/{id}/rules", CreateRuleHandler, rules, create, stranger, http.StatusForbidden},
+		{"updating own rule", "PATCH /users/{id}/rules/{ruleID}", UpdateRuleHandler, one, `{"category":"drinks"}`, owner, http.StatusOK},
+		{"updating someone else's", "PATCH /users/{id}/rules/{ruleID}", UpdateRuleHandler, one, `{"category":"mine"}`, stranger, http.StatusForbidden},
+		{"applying own rules", "POST /users/{id}/rules/apply", ApplyRulesHandler, rules + "/apply?dry_run=true", "", owner, http.StatusOK},
+		{"applying someone else's", "POST /users/{id}/rules/apply", ApplyRulesHandler, rules + "/apply", "", stranger, http.StatusForbidden},
+		{"deleting someone else's", "DELETE /users/{id}/rules/{ruleID}", DeleteRuleHandler, one, "", stranger, http.StatusForbidden},
+		{"deleting own rule", "DELETE /users/{id}/rules/{ruleID}", DeleteRuleHandler, one, "", owner, http.StatusOK},
+	})
+}
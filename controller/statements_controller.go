@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"natan/fingo/model"
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetStatementHandler handles GET /users/{id}/statements/{yearMonth} and returns the user's printable
+// statement for the month. The optional query parameter format picks html (the default) or pdf.
+func GetStatementHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "pdf" {
+		writeErrorMessage(w, model.KindValidation, "format must be html or pdf")
+		return
+	}
+
+	statement, err := service.GetStatement(ctx, id, r.PathValue("yearMonth"))
+	if err != nil {
+		writeError(w, err, "problem building statement")
+		return
+	}
+
+	// The statement is rendered in memory first so a failure can still be reported as an error response
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "pdf" {
+		contentType = "application/pdf"
+		err = service.RenderStatementPDF(&body, statement)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="statement-%s.pdf"`, statement.YearMonth))
+	} else {
+		err = service.RenderStatementHTML(&body, statement)
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		writeError(w, err, "problem rendering statement")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := body.WriteTo(w); err != nil {
+		log.Println(err)
+	}
+}
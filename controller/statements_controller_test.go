@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatementHandler_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "statements-owner")
+	stranger := newPrincipal(t, "statements-stranger")
+	path := fmt.Sprintf("/users/%d/statements/2024-01", owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own statement", "GET /users/{id}/statements/{yearMonth}", GetStatementHandler, path, "", owner, http.StatusOK},
+		{"someone else's statement", "GET /users/{id}/statements/{yearMonth}", GetStatementHandler, path, "", stranger, http.StatusForbidden},
+	})
+}
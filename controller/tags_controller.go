@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"natan/fingo/service"
+	"net/http"
+)
+
+// tagRequest is the body of the requests creating or renaming a tag.
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+// GetTagsHandler handles GET /users/{id}/tags and returns the user's tags with how often each is used.
+func GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := service.GetTags(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when fetching tags")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// CreateTagHandler handles POST /users/{id}/tags and creates a tag from the request body.
+func CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	var body tagRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	tag, err := service.CreateTag(ctx, id, body.Name)
+	if err != nil {
+		writeError(w, err, "problem when creating tag")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, *tag)
+}
+
+// RenameTagHandler handles PATCH /users/{id}/tags/{tagID} and renames the tag.
+func RenameTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	tagID, ok := GetID(r.PathValue("tagID"), w, r)
+	if !ok {
+		return
+	}
+
+	var body tagRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	tag, err := service.RenameTag(ctx, id, tagID, body.Name)
+	if err != nil {
+		writeError(w, err, "problem when renaming tag")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *tag)
+}
+
+// DeleteTagHandler handles DELETE /users/{id}/tags/{tagID}, removing the tag from every transaction and goal.
+func DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+	tagID, ok := GetID(r.PathValue("tagID"), w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := service.DeleteTag(ctx, id, tagID)
+	if err != nil {
+		writeError(w, err, "problem when deleting tag")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
+}
+
+// GetTagReportHandler handles GET /users/{id}/reports/tags and returns the income, expenses and net of the
+// transactions carrying each tag, and the goals tagged with it. The optional query parameters from and to
+// (YYYY-MM-DD, inclusive) bound the transactions counted.
+func GetTagReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	report, err := service.GetTagReport(ctx, id, q.Get("from"), q.Get("to"))
+	if err != nil {
+		writeError(w, err, "problem building tag report")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, *report)
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/service"
+)
+
+func TestTagHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "tags-owner")
+	stranger := newPrincipal(t, "tags-stranger")
+
+	tag, err := service.CreateTag(adminCtx, owner.UserID, "trips")
+	if err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	tags := fmt.Sprintf("/users/%d/tags", owner.UserID)
+	one := fmt.Sprintf("%s/%d", tags, tag.ID)
+	report := fmt.Sprintf("/users/%d/reports/tags", owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own tags", "GET /users/{id}/tags", GetTagsHandler, tags, "", owner, http.StatusOK},
+		{"someone else's tags", "GET /users/{id}/tags", GetTagsHandler, tags, "", stranger, http.StatusForbidden},
+		{"creating own tag", "POST /users/{id}/tags", CreateTagHandler, tags, `{"name":"home"}`, owner, http.StatusCreated},
+		{"creating for someone else", "POST /users/{id}/tags", CreateTagHandler, tags, `{"name":"work"}`, stranger, http.StatusForbidden},
+		{"renaming own tag", "PATCH /users/{id}/tags/{tagID}", RenameTagHandler, one, `{"name":"travel"}`, owner, http.StatusOK},
+		{"renaming someone else's", "PATCH /users/{id}/tags/{tagID}", RenameTagHandler, one, `{"name":"mine"}`, stranger, http.StatusForbidden},
+		{"own tag report", "GET /users/{id}/reports/tags", GetTagReportHandler, report, "", owner, http.StatusOK},
+		{"someone else's tag report", "GET /users/{id}/reports/tags", GetTagReportHandler, report, "", stranger, http.StatusForbidden},
+		{"deleting someone else's", "DELETE /users/{id}/tags/{tagID}", DeleteTagHandler, one, "", stranger, http.StatusForbidden},
+		{"deleting own tag", "DELETE /users/{id}/tags/{tagID}", DeleteTagHandler, one, "", owner, http.StatusOK},
+	})
+}
@@ -1,9 +1,6 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
-	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
 	"natan/fingo/service"
 	"net/http"
@@ -11,7 +8,7 @@ import (
 
 // GetTransactionByIDHandler handles GET /transactions/{id} and returns the transaction with the given ID.
 func GetTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	idStr := r.PathValue("id")
@@ -22,23 +19,22 @@ func GetTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	transaction, err := service.GetTransactionByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "transaction not found"})
+		writeError(w, err, "problem when fetching transaction")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, *transaction)
 }
 
-// GetAllTransactionsHandler handles GET /transactions and returns all transactions.
+// GetAllTransactionsHandler handles GET /transactions and returns all transactions. The optional tags query
+// parameter, a comma-separated list, keeps the transactions carrying every listed tag.
 func GetAllTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
-	transactionsList, err := service.GetAllTransactions(ctx)
+	transactionsList, err := service.GetAllTransactions(ctx, service.ParseTagFilter(r.URL.Query().Get("tags")))
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem fetching transactions"})
+		writeError(w, err, "problem fetching transactions")
 		return
 	}
 
@@ -47,20 +43,17 @@ func GetAllTransactionsHandler(w http.ResponseWriter, r *http.Request) {
 
 // CreateTransactionHandler handles POST /transactions and creates a new transaction from the request body.
 func CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	var transaction model.Transaction
-	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
-		log.Printf("could not decode request body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &transaction) {
 		return
 	}
 
 	transactionRec, err := service.CreateTransaction(ctx, transaction)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when creating transaction"})
+		writeError(w, err, "problem when creating transaction")
 		return
 	}
 
@@ -69,7 +62,7 @@ func CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateTransactionByIDHandler handles PATCH /transactions/{id} and applies a partial update to the given transaction.
 func UpdateTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	var transactionUpdate *model.TransactionUpdate
@@ -79,16 +72,13 @@ func UpdateTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&transactionUpdate); err != nil {
-		log.Printf("could not decode request body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &transactionUpdate) {
 		return
 	}
 
 	transaction, err := service.UpdateTransactionByID(ctx, id, transactionUpdate)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when updating transaction"})
+		writeError(w, err, "problem when updating transaction")
 		return
 	}
 
@@ -97,7 +87,7 @@ func UpdateTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 // DeleteTransactionByIDHandler handles DELETE /transactions/{id} and removes the transaction with the given ID.
 func DeleteTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	idStr := r.PathValue("id")
@@ -108,8 +98,7 @@ func DeleteTransactionByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := service.DeleteTransactionByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when deleting transaction"})
+		writeError(w, err, "problem when deleting transaction")
 		return
 	}
 
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestTransactionHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "transactions-owner")
+	stranger := newPrincipal(t, "transactions-stranger")
+
+	tx, err := service.CreateTransaction(adminCtx, model.Transaction{Desc: "rent", Amount: 90000, IsDebt: true, UserID: owner.UserID})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	path := fmt.Sprintf("/transactions/%d", tx.ID)
+	create := fmt.Sprintf(`{"description":"coffee","amount":450,"is_debt":true,"user_id":%d}`, owner.UserID)
+
+	runHandlerCases(t, []handlerCase{
+		{"own transaction", "GET /transactions/{id}", GetTransactionByIDHandler, path, "", owner, http.StatusOK},
+		{"someone else's transaction", "GET /transactions/{id}", GetTransactionByIDHandler, path, "", stranger, http.StatusForbidden},
+		{"admin reads any transaction", "GET /transactions/{id}", GetTransactionByIDHandler, path, "", admin, http.StatusOK},
+		{"missing transaction", "GET /transactions/{id}", GetTransactionByIDHandler, "/transactions/999999", "", owner, http.StatusNotFound},
+		{"creating for themselves", "POST /transactions", CreateTransactionHandler, "/transactions", create, owner, http.StatusCreated},
+		{"creating for someone else", "POST /transactions", CreateTransactionHandler, "/transactions", create, stranger, http.StatusForbidden},
+		{"updating own transaction", "PATCH /transactions/{id}", UpdateTransactionByIDHandler, path, `{"description":"rent june"}`, owner, http.StatusOK},
+		{"updating someone else's", "PATCH /transactions/{id}", UpdateTransactionByIDHandler, path, `{"description":"mine"}`, stranger, http.StatusForbidden},
+		{"deleting someone else's", "DELETE /transactions/{id}", DeleteTransactionByIDHandler, path, "", stranger, http.StatusForbidden},
+		{"deleting own transaction", "DELETE /transactions/{id}", DeleteTransactionByIDHandler, path, "", owner, http.StatusOK},
+	})
+}
+
+func TestGetAllTransactionsHandler_ScopedToPrincipal(t *testing.T) {
+	owner := newPrincipal(t, "tx-listing-owner")
+	other := newPrincipal(t, "tx-listing-other")
+	for _, p := range []model.Principal{owner, other} {
+		if _, err := service.CreateTransaction(adminCtx, model.Transaction{Desc: "bread", Amount: 300, IsDebt: true, UserID: p.UserID}); err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+	}
+
+	rec := serve(t, handlerCase{pattern: "GET /transactions", handler: GetAllTransactionsHandler, path: "/transactions", as: owner})
+	var transactions []model.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&transactions); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(transactions) != 1 || transactions[0].UserID != owner.UserID {
+		t.Errorf("GET /transactions as a regular user = %d %+v, want only their own", rec.Code, transactions)
+	}
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"natan/fingo/service"
+	"net/http"
+)
+
+// GetTrashHandler handles GET /trash and returns the deleted users, transactions and goals that can still be
+// restored: everybody's for admins, the caller's own otherwise.
+func GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	trash, err := service.GetTrash(ctx)
+	if err != nil {
+		writeError(w, err, "problem when fetching the trash")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, trash)
+}
+
+// RestoreUserHandler handles POST /users/{id}/restore and takes a deleted user, with the transactions and
+// goals deleted along with them, out of the trash.
+func RestoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	user, err := service.RestoreUserByID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when restoring user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// RestoreTransactionHandler handles POST /transactions/{id}/restore and takes a deleted transaction out of
+// the trash, applying it to the owner's balance again.
+func RestoreTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	transaction, err := service.RestoreTransactionByID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when restoring transaction")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transaction)
+}
+
+// RestoreGoalHandler handles POST /goals/{id}/restore and takes a deleted goal out of the trash.
+func RestoreGoalHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	id, ok := GetID(r.PathValue("id"), w, r)
+	if !ok {
+		return
+	}
+
+	goal, err := service.RestoreGoalByID(ctx, id)
+	if err != nil {
+		writeError(w, err, "problem when restoring goal")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/service"
+)
+
+func TestTrashHandlers(t *testing.T) {
+	owner := newPrincipal(t, "trash-owner")
+	other := newPrincipal(t, "trash-other")
+
+	tx, err := service.CreateTransaction(adminCtx, model.Transaction{Desc: "coffee", Amount: 500, IsDebt: true, UserID: owner.UserID})
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	goal, err := service.CreateGoal(adminCtx, model.Goal{Name: "camera", Price: 50000, UserID: owner.UserID})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := service.DeleteTransactionByID(adminCtx, tx.ID); err != nil {
+		t.Fatalf("failed to delete transaction: %v", err)
+	}
+	if _, err := service.DeleteGoalByID(adminCtx, goal.ID); err != nil {
+		t.Fatalf("failed to delete goal: %v", err)
+	}
+
+	rec := serve(t, handlerCase{pattern: "GET /trash", handler: GetTrashHandler, path: "/trash", as: owner})
+	var trash model.Trash
+	if err := json.NewDecoder(rec.Body).Decode(&trash); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(trash.Transactions) != 1 || len(trash.Goals) != 1 {
+		t.Errorf("GET /trash as the owner = %d %+v, want their transaction and goal", rec.Code, trash)
+	}
+
+	txPath := fmt.Sprintf("/transactions/%d/restore", tx.ID)
+	goalPath := fmt.Sprintf("/goals/%d/restore", goal.ID)
+	runHandlerCases(t, []handlerCase{
+		{"transaction of another user", "POST /transactions/{id}/restore", RestoreTransactionHandler, txPath, "", other, http.StatusForbidden},
+		{"transaction not in the trash", "POST /transactions/{id}/restore", RestoreTransactionHandler, "/transactions/999999999/restore", "", admin, http.StatusNotFound},
+		{"invalid transaction id", "POST /transactions/{id}/restore", RestoreTransactionHandler, "/transactions/abc/restore", "", admin, http.StatusBadRequest},
+		{"restore transaction", "POST /transactions/{id}/restore", RestoreTransactionHandler, txPath, "", owner, http.StatusOK},
+		{"transaction already restored", "POST /transactions/{id}/restore", RestoreTransactionHandler, txPath, "", owner, http.StatusNotFound},
+		{"restore goal", "POST /goals/{id}/restore", RestoreGoalHandler, goalPath, "", owner, http.StatusOK},
+	})
+
+	// A transaction deleted with its owner can only come back with them
+	if _, err := service.DeleteUserByID(adminCtx, owner.UserID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	userPath := fmt.Sprintf("/users/%d/restore", owner.UserID)
+	runHandlerCases(t, []handlerCase{
+		{"transaction of a deleted user", "POST /transactions/{id}/restore", RestoreTransactionHandler, txPath, "", admin, http.StatusConflict},
+		{"user of another user", "POST /users/{id}/restore", RestoreUserHandler, userPath, "", other, http.StatusForbidden},
+		{"restore user", "POST /users/{id}/restore", RestoreUserHandler, userPath, "", admin, http.StatusOK},
+		{"user already restored", "POST /users/{id}/restore", RestoreUserHandler, userPath, "", admin, http.StatusNotFound},
+	})
+}
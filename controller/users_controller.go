@@ -1,9 +1,6 @@
 package controller
 
 import (
-	"encoding/json"
-	"log"
-	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
 	"natan/fingo/service"
 	"net/http"
@@ -11,7 +8,7 @@ import (
 
 // GetUserByIDHandler handles GET /users/{id} and returns the user with the given ID.
 func GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	id, ok := GetID(r.PathValue("id"), w, r)
@@ -21,8 +18,7 @@ func GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	user, err := service.GetUserByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "user not found"})
+		writeError(w, err, "problem when fetching user")
 		return
 	}
 	writeJSON(w, http.StatusOK, *user)
@@ -30,19 +26,18 @@ func GetUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetAllUsersHandler handles GET /users and returns all users.
 func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	usersList, err := service.GetAllUsers(ctx)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem fetching users"})
+		writeError(w, err, "problem fetching users")
 		return
 	}
 	writeJSON(w, http.StatusOK, usersList)
 }
 func GetAllTransactionsByUserIDHandler(w http.ResponseWriter, r *http.Request){
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 	
 	id, ok := GetID(r.PathValue("id"),w ,r)
@@ -50,10 +45,9 @@ func GetAllTransactionsByUserIDHandler(w http.ResponseWriter, r *http.Request){
 		return
 	}
 	
-	transactionsList, err := service.GetAllTransactionsByUserID(ctx, id)
+	transactionsList, err := service.GetAllTransactionsByUserID(ctx, id, service.ParseTagFilter(r.URL.Query().Get("tags")))
 	if err != nil{
-		log.Println(err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "transactions not found for user"})
+		writeError(w, err, "problem when fetching transactions for user")
 		return
 	}
 	
@@ -61,7 +55,7 @@ func GetAllTransactionsByUserIDHandler(w http.ResponseWriter, r *http.Request){
 }
 
 func GetAllGoalsByUserIDHandler(w http.ResponseWriter, r *http.Request){
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 	
 	id, ok := GetID(r.PathValue("id"), w,r)
@@ -69,31 +63,41 @@ func GetAllGoalsByUserIDHandler(w http.ResponseWriter, r *http.Request){
 		return
 	}
 	
-	goalsList, err := service.GetAllGoalsByUserID(ctx, id)
+	goalsList, err := service.GetAllGoalsByUserID(ctx, id, service.ParseTagFilter(r.URL.Query().Get("tags")))
 	if err != nil{
-		log.Println(err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "goals not found for user"})
+		writeError(w, err, "problem when fetching goals for user")
 		return
 	}
 	writeJSON(w, http.StatusOK, goalsList)
 }
 
+// LegacyUserListingHandler handles GET /users/{collection}/{id}, the paths the per-user transaction and goal
+// listings had before they moved under /users/{id}. It cannot be two routes because net/http rejects
+// /users/transactions/{id} next to /users/{id}/transactions.
+func LegacyUserListingHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("collection") {
+	case "transactions":
+		GetAllTransactionsByUserIDHandler(w, r)
+	case "goals":
+		GetAllGoalsByUserIDHandler(w, r)
+	default:
+		writeErrorMessage(w, model.KindNotFound, "not found")
+	}
+}
+
 // CreateUserHandler handles POST /users and creates a new user from the request body.
 func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	var user model.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		log.Printf("could not decode request body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &user) {
 		return
 	}
 
 	userRec, err := service.CreateUser(ctx, user)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "problem when creating user"})
+		writeError(w, err, "problem when creating user")
 		return
 	}
 	writeJSON(w, http.StatusCreated, *userRec)
@@ -101,7 +105,7 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUserByIDHandler handles PATCH /users/{id} and applies a partial update to the given user.
 func UpdateUserByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	id, ok := GetID(r.PathValue("id"), w, r)
@@ -110,16 +114,13 @@ func UpdateUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var userUpdate *model.UserUpdate
-	if err := json.NewDecoder(r.Body).Decode(&userUpdate); err != nil {
-		log.Printf("could not decode request body: %v", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+	if !decodeBody(w, r, &userUpdate) {
 		return
 	}
 
 	user, err := service.UpdateUserByID(ctx, id, userUpdate)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when updating user"})
+		writeError(w, err, "problem when updating user")
 		return
 	}
 	writeJSON(w, http.StatusOK, *user)
@@ -127,7 +128,7 @@ func UpdateUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 // DeleteUserByIDHandler handles DELETE /users/{id} and removes the user with the given ID.
 func DeleteUserByIDHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := dbsqlite.NewDBContext()
+	ctx, cancel := requestContext(r)
 	defer cancel()
 
 	id, ok := GetID(r.PathValue("id"), w, r)
@@ -137,8 +138,7 @@ func DeleteUserByIDHandler(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := service.DeleteUserByID(ctx, id)
 	if err != nil {
-		log.Println(err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "problem when deleting user"})
+		writeError(w, err, "problem when deleting user")
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]int64{"rows_affected": rows})
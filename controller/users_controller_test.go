@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestUserHandlers_Authorization(t *testing.T) {
+	owner := newPrincipal(t, "users-owner")
+	stranger := newPrincipal(t, "users-stranger")
+	leaving := newPrincipal(t, "users-leaving")
+
+	user := fmt.Sprintf("/users/%d", owner.UserID)
+	runHandlerCases(t, []handlerCase{
+		{"own user", "GET /users/{id}", GetUserByIDHandler, user, "", owner, http.StatusOK},
+		{"someone else's user", "GET /users/{id}", GetUserByIDHandler, user, "", stranger, http.StatusForbidden},
+		{"admin reads any user", "GET /users/{id}", GetUserByIDHandler, user, "", admin, http.StatusOK},
+		{"own transactions", "GET /users/{id}/transactions", GetAllTransactionsByUserIDHandler, user + "/transactions", "", owner, http.StatusOK},
+		{"someone else's transactions", "GET /users/{id}/transactions", GetAllTransactionsByUserIDHandler, user + "/transactions", "", stranger, http.StatusForbidden},
+		{"own goals", "GET /users/{id}/goals", GetAllGoalsByUserIDHandler, user + "/goals", "", owner, http.StatusOK},
+		{"someone else's goals", "GET /users/{id}/goals", GetAllGoalsByUserIDHandler, user + "/goals", "", stranger, http.StatusForbidden},
+		{"own transactions on the old path", "GET /users/{collection}/{id}", LegacyUserListingHandler, fmt.Sprintf("/users/transactions/%d", owner.UserID), "", owner, http.StatusOK},
+		{"someone else's goals on the old path", "GET /users/{collection}/{id}", LegacyUserListingHandler, fmt.Sprintf("/users/goals/%d", owner.UserID), "", stranger, http.StatusForbidden},
+		{"unknown listing on the old path", "GET /users/{collection}/{id}", LegacyUserListingHandler, fmt.Sprintf("/users/budgets/%d", owner.UserID), "", owner, http.StatusNotFound},
+		{"user creating a user", "POST /users", CreateUserHandler, "/users", `{"user_name":"users-new"}`, owner, http.StatusForbidden},
+		{"admin creating a user", "POST /users", CreateUserHandler, "/users", `{"user_name":"users-new"}`, admin, http.StatusCreated},
+		{"updating own user", "PATCH /users/{id}", UpdateUserByIDHandler, user, `{"user_name":"users-renamed"}`, owner, http.StatusOK},
+		{"updating someone else", "PATCH /users/{id}", UpdateUserByIDHandler, user, `{"user_name":"users-hijacked"}`, stranger, http.StatusForbidden},
+		{"deleting someone else", "DELETE /users/{id}", DeleteUserByIDHandler, user, "", stranger, http.StatusForbidden},
+		{"deleting own user", "DELETE /users/{id}", DeleteUserByIDHandler, fmt.Sprintf("/users/%d", leaving.UserID), "", leaving, http.StatusOK},
+	})
+}
+
+func TestGetAllUsersHandler_ScopedToPrincipal(t *testing.T) {
+	owner := newPrincipal(t, "listing-owner")
+	newPrincipal(t, "listing-other")
+
+	rec := serve(t, handlerCase{pattern: "GET /users", handler: GetAllUsersHandler, path: "/users", as: owner})
+	var users []model.User
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(users) != 1 || users[0].ID != owner.UserID {
+		t.Errorf("GET /users as a regular user = %d %+v, want only their own user", rec.Code, users)
+	}
+
+	rec = serve(t, handlerCase{pattern: "GET /users", handler: GetAllUsersHandler, path: "/users", as: admin})
+	users = nil
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(users) < 2 {
+		t.Errorf("GET /users as an admin = %d with %d users, want everybody", rec.Code, len(users))
+	}
+}
@@ -0,0 +1,90 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+// DefaultAdjustmentSettings returns the settings used for users that never configured them:
+// enabled, due on the first day of the month, in the application timezone.
+func DefaultAdjustmentSettings(userID int64) model.AdjustmentSettings {
+	return model.AdjustmentSettings{UserID: userID, Enabled: true, PayDay: 1}
+}
+
+// GetAdjustmentSettings retrieves the monthly adjustment settings of a user.
+// Users without a settings row get DefaultAdjustmentSettings.
+func GetAdjustmentSettings(ctx context.Context, userID int64, db *sql.DB) (*model.AdjustmentSettings, error) {
+	const selectStmt = `SELECT user_id, enabled, pay_day, timezone, COALESCE(last_period, '') FROM user_adjustment_settings WHERE user_id = ?`
+
+	var s model.AdjustmentSettings
+	row := db.QueryRowContext(ctx, selectStmt, userID)
+	if err := row.Scan(&s.UserID, &s.Enabled, &s.PayDay, &s.Timezone, &s.LastPeriod); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			defaults := DefaultAdjustmentSettings(userID)
+			return &defaults, nil
+		}
+		return nil, fmt.Errorf("could not scan the row into adjustment settings struct: %w", err)
+	}
+
+	return &s, nil
+}
+
+// GetAllAdjustmentSettings retrieves every stored adjustment settings row keyed by user ID.
+// Users missing from the map use DefaultAdjustmentSettings.
+func GetAllAdjustmentSettings(ctx context.Context, db *sql.DB) (map[int64]model.AdjustmentSettings, error) {
+	const query = `SELECT user_id, enabled, pay_day, timezone, COALESCE(last_period, '') FROM user_adjustment_settings`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query to return all adjustment settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[int64]model.AdjustmentSettings)
+	for rows.Next() {
+		var s model.AdjustmentSettings
+		if err := rows.Scan(&s.UserID, &s.Enabled, &s.PayDay, &s.Timezone, &s.LastPeriod); err != nil {
+			return nil, fmt.Errorf("could not scan the data into adjustment settings struct: %w", err)
+		}
+		settings[s.UserID] = s
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return settings, nil
+}
+
+// UpsertAdjustmentSettings stores the enabled flag, pay day and timezone of a user.
+// The last processed period is left untouched.
+func UpsertAdjustmentSettings(ctx context.Context, settings model.AdjustmentSettings, db *sql.DB) (*model.AdjustmentSettings, error) {
+	const upsertStmt = `
+	INSERT INTO user_adjustment_settings(user_id, enabled, pay_day, timezone) VALUES (?, ?, ?, ?)
+	ON CONFLICT(user_id) DO UPDATE SET enabled = excluded.enabled, pay_day = excluded.pay_day, timezone = excluded.timezone;`
+
+	_, err := db.ExecContext(ctx, upsertStmt, settings.UserID, settings.Enabled, settings.PayDay, settings.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("could not upsert adjustment settings: %w", err)
+	}
+
+	return GetAdjustmentSettings(ctx, settings.UserID, db)
+}
+
+// SetAdjustmentLastPeriod records the last period handled for a user without applying any adjustment.
+// Used to establish a per-user baseline.
+func SetAdjustmentLastPeriod(ctx context.Context, db *sql.DB, userID int64, yearMonth string) error {
+	const upsertStmt = `
+	INSERT INTO user_adjustment_settings(user_id, last_period) VALUES (?, ?)
+	ON CONFLICT(user_id) DO UPDATE SET last_period = excluded.last_period;`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, userID, yearMonth); err != nil {
+		return fmt.Errorf("could not record last adjustment period for user %d: %w", userID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package dbsqlite
+
+import (
+	"context"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestAdjustmentSettings_DefaultsAndUpsert(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "settings"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	s, err := GetAdjustmentSettings(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetAdjustmentSettings() returned error: %v", err)
+	}
+	if *s != DefaultAdjustmentSettings(u.ID) {
+		t.Fatalf("expected defaults, got %+v", s)
+	}
+
+	if err := SetAdjustmentLastPeriod(ctx, db, u.ID, "2025-06"); err != nil {
+		t.Fatalf("SetAdjustmentLastPeriod() returned error: %v", err)
+	}
+
+	s, err = UpsertAdjustmentSettings(ctx, model.AdjustmentSettings{UserID: u.ID, Enabled: false, PayDay: 5, Timezone: "America/Sao_Paulo"}, db)
+	if err != nil {
+		t.Fatalf("UpsertAdjustmentSettings() returned error: %v", err)
+	}
+	if s.Enabled || s.PayDay != 5 || s.Timezone != "America/Sao_Paulo" {
+		t.Errorf("settings not stored: %+v", s)
+	}
+	if s.LastPeriod != "2025-06" {
+		t.Errorf("upsert must keep last_period, got %q", s.LastPeriod)
+	}
+
+	all, err := GetAllAdjustmentSettings(ctx, db)
+	if err != nil {
+		t.Fatalf("GetAllAdjustmentSettings() returned error: %v", err)
+	}
+	if all[u.ID] != *s {
+		t.Errorf("GetAllAdjustmentSettings() mismatch: got %+v want %+v", all[u.ID], *s)
+	}
+}
+
+func TestApplyUserMonthlyAdjustment(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	alice, err := CreateUser(ctx, model.User{UserName: "Alice", CurrentAmount: utils.Money(10000), MonthlyInputs: utils.Money(5000), MonthlyOutputs: utils.Money(3000)}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	bob, err := CreateUser(ctx, model.User{UserName: "Bob", CurrentAmount: utils.Money(100), MonthlyInputs: utils.Money(100)}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	entry, err := ApplyUserMonthlyAdjustment(ctx, db, alice.ID, "2025-07")
+	if err != nil {
+		t.Fatalf("ApplyUserMonthlyAdjustment() returned error: %v", err)
+	}
+	if entry.BalanceBefore != 10000 || entry.BalanceAfter != 12000 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if _, err := ApplyUserMonthlyAdjustment(ctx, db, alice.ID, "2025-07"); err == nil {
+		t.Fatalf("expected error when applying the same month twice")
+	}
+
+	got, err := GetUserByID(ctx, alice.ID, db)
+	if err != nil {
+		t.Fatalf("GetUserByID() returned error: %v", err)
+	}
+	if got.CurrentAmount != 12000 {
+		t.Errorf("expected 12000 after single application, got %d", got.CurrentAmount)
+	}
+
+	other, err := GetUserByID(ctx, bob.ID, db)
+	if err != nil {
+		t.Fatalf("GetUserByID() returned error: %v", err)
+	}
+	if other.CurrentAmount != 100 {
+		t.Errorf("other users must not be adjusted, got %d", other.CurrentAmount)
+	}
+
+	s, err := GetAdjustmentSettings(ctx, alice.ID, db)
+	if err != nil {
+		t.Fatalf("GetAdjustmentSettings() returned error: %v", err)
+	}
+	if s.LastPeriod != "2025-07" || !s.Enabled || s.PayDay != 1 {
+		t.Errorf("expected last_period advanced with default settings, got %+v", s)
+	}
+
+	processed, err := IsMonthProcessed(ctx, db, "2025-07")
+	if err != nil {
+		t.Fatalf("IsMonthProcessed() returned error: %v", err)
+	}
+	if !processed {
+		t.Errorf("expected month to be logged")
+	}
+
+	if _, err := ApplyUserMonthlyAdjustment(ctx, db, 999999, "2025-07"); err == nil {
+		t.Errorf("expected error for missing user")
+	}
+}
+
+func TestRollbackMonthlyAdjustment(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "rollback", CurrentAmount: utils.Money(1000), MonthlyInputs: utils.Money(300), MonthlyOutputs: utils.Money(100)}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if _, err := ApplyMonthlyAdjustmentToUsers(ctx, db, "2025-07", []int64{u.ID}); err != nil {
+		t.Fatalf("ApplyMonthlyAdjustmentToUsers() returned error: %v", err)
+	}
+	if _, err := ApplyMonthlyAdjustmentToUsers(ctx, db, "2025-08", []int64{u.ID}); err != nil {
+		t.Fatalf("ApplyMonthlyAdjustmentToUsers() returned error: %v", err)
+	}
+
+	reversed, err := RollbackMonthlyAdjustment(ctx, db, "2025-07")
+	if err != nil {
+		t.Fatalf("RollbackMonthlyAdjustment() returned error: %v", err)
+	}
+	if len(reversed) != 1 || reversed[0].BalanceAfter-reversed[0].BalanceBefore != 200 {
+		t.Fatalf("unexpected reversed entries: %+v", reversed)
+	}
+
+	got, err := GetUserByID(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetUserByID() returned error: %v", err)
+	}
+	if got.CurrentAmount != 1200 {
+		t.Errorf("expected 1200 after rolling back one of two months, got %d", got.CurrentAmount)
+	}
+
+	processed, err := IsMonthProcessed(ctx, db, "2025-07")
+	if err != nil {
+		t.Fatalf("IsMonthProcessed() returned error: %v", err)
+	}
+	if processed {
+		t.Errorf("expected 2025-07 removed from the log")
+	}
+
+	s, err := GetAdjustmentSettings(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetAdjustmentSettings() returned error: %v", err)
+	}
+	if s.LastPeriod != "2025-08" {
+		t.Errorf("rollback must keep last_period, got %q", s.LastPeriod)
+	}
+
+	if _, err := RollbackMonthlyAdjustment(ctx, db, "2025-07"); err == nil {
+		t.Errorf("expected error rolling back an unknown month")
+	}
+}
+
+func TestApplyMonthlyAdjustmentToUsers_AllOrNothing(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "atomic", CurrentAmount: utils.Money(1000), MonthlyInputs: utils.Money(300)}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if _, err := ApplyMonthlyAdjustmentToUsers(ctx, db, "2025-07", []int64{u.ID, 999999}); err == nil {
+		t.Fatalf("expected error when one of the users does not exist")
+	}
+
+	got, err := GetUserByID(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetUserByID() returned error: %v", err)
+	}
+	if got.CurrentAmount != 1000 {
+		t.Errorf("expected balance untouched after failed batch, got %d", got.CurrentAmount)
+	}
+}
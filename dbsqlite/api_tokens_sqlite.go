@@ -0,0 +1,111 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"natan/fingo/model"
+)
+
+// apiTokenColumns lists the columns of api_tokens in the order scanAPIToken reads them.
+const apiTokenColumns = `t.id, t.user_id, t.name, t.token_hash, t.scope, t.created_at, COALESCE(t.expires_at, ''), COALESCE(t.last_used_at, '')`
+
+// scanAPIToken scans a row selected with apiTokenColumns, followed by any extra destinations.
+func scanAPIToken(row interface{ Scan(...any) error }, extra ...any) (*model.APIToken, error) {
+	var t model.APIToken
+	dest := append([]any{&t.ID, &t.UserID, &t.Name, &t.TokenHash, &t.Scope, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateAPIToken stores a new API token and returns it with its ID.
+func CreateAPIToken(ctx context.Context, t model.APIToken, db *sql.DB) (*model.APIToken, error) {
+	const insertStmt = `
+	INSERT INTO api_tokens(user_id, name, token_hash, scope, created_at, expires_at) VALUES (?, ?, ?, ?, ?, NULLIF(?, ''))`
+
+	res, err := db.ExecContext(ctx, insertStmt, t.UserID, t.Name, t.TokenHash, t.Scope, t.CreatedAt, t.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into api_tokens table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the api token: %w", err)
+	}
+	t.ID = id
+
+	return &t, nil
+}
+
+// GetAPITokensByUserID returns the API tokens of a user, oldest first.
+func GetAPITokensByUserID(ctx context.Context, userID int64, db *sql.DB) ([]model.APIToken, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens t WHERE t.user_id = ? ORDER BY t.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not query the api tokens of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	tokens := []model.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan the row into api token struct: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// GetActiveAPIToken retrieves the API token with the given hash, with the current role of its user,
+// unless it expired by now.
+func GetActiveAPIToken(ctx context.Context, tokenHash string, now time.Time, db *sql.DB) (*model.APIToken, error) {
+	query := `
+	SELECT ` + apiTokenColumns + `, COALESCE(c.role, '')
+	FROM api_tokens t JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+	LEFT JOIN credentials c ON c.user_id = t.user_id
+	WHERE t.token_hash = ? AND (t.expires_at IS NULL OR t.expires_at > ?)`
+
+	var role string
+	t, err := scanAPIToken(db.QueryRowContext(ctx, query, tokenHash, formatTimestamp(now)), &role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("api token", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into api token struct: %w", err)
+	}
+	t.Role = role
+
+	return t, nil
+}
+
+// TouchAPIToken records that the API token was used at now.
+func TouchAPIToken(ctx context.Context, id int64, now time.Time, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, formatTimestamp(now), id); err != nil {
+		return fmt.Errorf("could not record the use of api token %d: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteAPIToken deletes an API token of a user and returns the number of affected rows.
+func DeleteAPIToken(ctx context.Context, userID, id int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for api token: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,73 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestAPITokens(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "scripter"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, _ := CreateUser(ctx, model.User{UserName: "other"}, db)
+	if _, err := SetCredentials(ctx, model.Credentials{UserID: u.ID, Login: "scripter", PasswordHash: "hash", Role: model.RoleAdmin}, db); err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+
+	now := time.Date(2030, 5, 10, 12, 0, 0, 0, time.UTC)
+	forever, err := CreateAPIToken(ctx, model.APIToken{UserID: u.ID, Name: "backup", TokenHash: "forever", Scope: model.ScopeReadOnly, CreatedAt: formatTimestamp(now)}, db)
+	if err != nil {
+		t.Fatalf("CreateAPIToken() returned error: %v", err)
+	}
+	hourly, err := CreateAPIToken(ctx, model.APIToken{UserID: u.ID, Name: "import", TokenHash: "hourly", Scope: model.ScopeFull,
+		CreatedAt: formatTimestamp(now), ExpiresAt: formatTimestamp(now.Add(time.Hour))}, db)
+	if err != nil {
+		t.Fatalf("CreateAPIToken() returned error: %v", err)
+	}
+	if _, err := CreateAPIToken(ctx, model.APIToken{UserID: other.ID, Name: "dup", TokenHash: "hourly", Scope: model.ScopeFull, CreatedAt: formatTimestamp(now)}, db); err == nil {
+		t.Error("CreateAPIToken() with a hash in use returned no error")
+	}
+
+	got, err := GetActiveAPIToken(ctx, "forever", now.AddDate(10, 0, 0), db)
+	if err != nil || got.ID != forever.ID || got.Scope != model.ScopeReadOnly || got.ExpiresAt != "" || got.Role != model.RoleAdmin {
+		t.Errorf("GetActiveAPIToken() for a token without expiry = %+v, %v", got, err)
+	}
+	if _, err := GetActiveAPIToken(ctx, "hourly", now.Add(time.Hour), db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetActiveAPIToken() at its expiry error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := TouchAPIToken(ctx, hourly.ID, now.Add(time.Minute), db); err != nil {
+		t.Fatalf("TouchAPIToken() returned error: %v", err)
+	}
+	tokens, err := GetAPITokensByUserID(ctx, u.ID, db)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("GetAPITokensByUserID() = %+v, %v", tokens, err)
+	}
+	if tokens[0].Name != "backup" || tokens[1].LastUsedAt != formatTimestamp(now.Add(time.Minute)) {
+		t.Errorf("GetAPITokensByUserID() = %+v", tokens)
+	}
+
+	if rows, err := DeleteAPIToken(ctx, other.ID, forever.ID, db); err != nil || rows != 0 {
+		t.Errorf("DeleteAPIToken() of another user's token = %d, %v; want 0", rows, err)
+	}
+	if rows, err := DeleteAPIToken(ctx, u.ID, forever.ID, db); err != nil || rows != 1 {
+		t.Errorf("DeleteAPIToken() = %d, %v; want 1", rows, err)
+	}
+
+	if _, err := DeleteUserByID(ctx, u.ID, db); err != nil {
+		t.Fatalf("DeleteUserByID() returned error: %v", err)
+	}
+	if _, err := GetActiveAPIToken(ctx, "hourly", now, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetActiveAPIToken() after deleting the user error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,106 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+const selectAttachmentColumns = `SELECT id, transaction_id, file_name, content_type, size, sha256, created_at FROM attachments`
+
+// CreateAttachment inserts the metadata of an attachment whose content is already stored.
+func CreateAttachment(ctx context.Context, a model.Attachment, db *sql.DB) (*model.Attachment, error) {
+	const insertStmt = `INSERT INTO attachments(transaction_id, file_name, content_type, size, sha256) VALUES (?, ?, ?, ?, ?)`
+
+	res, err := db.ExecContext(ctx, insertStmt, a.TransactionID, a.FileName, a.ContentType, a.Size, a.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into attachments table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the new attachment: %w", err)
+	}
+
+	return GetAttachmentByID(ctx, id, db)
+}
+
+// GetAttachmentByID retrieves the metadata of an attachment by its ID.
+func GetAttachmentByID(ctx context.Context, id int64, db *sql.DB) (*model.Attachment, error) {
+	var a model.Attachment
+	row := db.QueryRowContext(ctx, selectAttachmentColumns+" WHERE id = ?", id)
+	if err := row.Scan(&a.ID, &a.TransactionID, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("attachment", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into attachment struct: %w", err)
+	}
+
+	return &a, nil
+}
+
+// GetAttachmentsByTransactionID retrieves the metadata of the attachments of a transaction, oldest first.
+func GetAttachmentsByTransactionID(ctx context.Context, transactionID int64, db *sql.DB) ([]model.Attachment, error) {
+	rows, err := db.QueryContext(ctx, selectAttachmentColumns+" WHERE transaction_id = ? ORDER BY id", transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for attachments using transaction_id: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		var a model.Attachment
+		if err := rows.Scan(&a.ID, &a.TransactionID, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the row into attachment struct: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// DeleteAttachmentByID deletes the metadata of an attachment. The stored content is left to the caller.
+func DeleteAttachmentByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for attachment: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// DeleteAttachmentsByTransactionID deletes the metadata of every attachment of a transaction.
+// The stored content is left to the caller.
+func DeleteAttachmentsByTransactionID(ctx context.Context, transactionID int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM attachments WHERE transaction_id = ?`, transactionID)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete the attachments of transaction %d: %w", transactionID, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// CountAttachmentsBySHA256 returns how many attachments share the content with the given hash.
+func CountAttachmentsBySHA256(ctx context.Context, hash string, db *sql.DB) (int, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM attachments WHERE sha256 = ?`, hash).Scan(&count); err != nil {
+		return 0, fmt.Errorf("could not count attachments by hash: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,58 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestAttachments(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "receipts"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	tx, _ := CreateTransaction(ctx, model.Transaction{Desc: "dinner", Amount: 8000, IsDebt: true, UserID: u.ID}, db)
+	other, _ := CreateTransaction(ctx, model.Transaction{Desc: "lunch", Amount: 3000, IsDebt: true, UserID: u.ID}, db)
+
+	receipt, err := CreateAttachment(ctx, model.Attachment{TransactionID: tx.ID, FileName: "receipt.pdf", ContentType: "application/pdf", Size: 120, SHA256: "aa11"}, db)
+	if err != nil {
+		t.Fatalf("CreateAttachment() returned error: %v", err)
+	}
+	if receipt.ID == 0 || receipt.CreatedAt == "" || receipt.FileName != "receipt.pdf" || receipt.Size != 120 {
+		t.Errorf("CreateAttachment() = %+v", receipt)
+	}
+	photo, _ := CreateAttachment(ctx, model.Attachment{TransactionID: tx.ID, FileName: "photo.png", ContentType: "image/png", Size: 80, SHA256: "bb22"}, db)
+	if _, err := CreateAttachment(ctx, model.Attachment{TransactionID: other.ID, FileName: "copy.pdf", ContentType: "application/pdf", Size: 120, SHA256: "aa11"}, db); err != nil {
+		t.Fatalf("CreateAttachment() returned error: %v", err)
+	}
+
+	list, err := GetAttachmentsByTransactionID(ctx, tx.ID, db)
+	if err != nil || len(list) != 2 || list[0].ID != receipt.ID || list[1].ID != photo.ID {
+		t.Errorf("GetAttachmentsByTransactionID() = %+v, %v", list, err)
+	}
+	if count, err := CountAttachmentsBySHA256(ctx, "aa11", db); err != nil || count != 2 {
+		t.Errorf("CountAttachmentsBySHA256() = %d, %v; want 2", count, err)
+	}
+
+	rows, err := DeleteAttachmentByID(ctx, photo.ID, db)
+	if err != nil || rows != 1 {
+		t.Fatalf("DeleteAttachmentByID() = %d, %v; want 1, nil", rows, err)
+	}
+	if _, err := GetAttachmentByID(ctx, photo.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAttachmentByID() after delete error = %v, want sql.ErrNoRows", err)
+	}
+
+	if rows, err := DeleteAttachmentsByTransactionID(ctx, tx.ID, db); err != nil || rows != 1 {
+		t.Errorf("DeleteAttachmentsByTransactionID() = %d, %v; want 1, nil", rows, err)
+	}
+	if count, _ := CountAttachmentsBySHA256(ctx, "aa11", db); count != 1 {
+		t.Errorf("CountAttachmentsBySHA256() after deleting one transaction's attachments = %d, want 1", count)
+	}
+}
@@ -0,0 +1,83 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"natan/fingo/model"
+)
+
+// CreateAuditEntry appends an entry to the audit log and returns it with its ID.
+func CreateAuditEntry(ctx context.Context, e model.AuditEntry, db *sql.DB) (*model.AuditEntry, error) {
+	const insertStmt = `
+	INSERT INTO audit_log(entity, entity_id, action, actor_id, actor_role, before, after, created_at)
+	VALUES (?, ?, ?, NULLIF(?, 0), ?, NULLIF(?, ''), NULLIF(?, ''), ?)`
+
+	res, err := db.ExecContext(ctx, insertStmt, e.Entity, e.EntityID, e.Action, e.ActorID, e.ActorRole, string(e.Before), string(e.After), e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into audit_log table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the audit entry: %w", err)
+	}
+	e.ID = id
+
+	return &e, nil
+}
+
+// GetAuditEntries returns the audit log entries matching the filter, oldest first.
+func GetAuditEntries(ctx context.Context, filter model.AuditFilter, db *sql.DB) ([]model.AuditEntry, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.Entity != "" {
+		conditions = append(conditions, "entity = ?")
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+
+	query := `
+	SELECT id, entity, entity_id, action, COALESCE(actor_id, 0), actor_role, COALESCE(before, ''), COALESCE(after, ''), created_at
+	FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query the audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var (
+			e             model.AuditEntry
+			before, after string
+		)
+		if err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.ActorID, &e.ActorRole, &before, &after, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the row into audit entry struct: %w", err)
+		}
+		if before != "" {
+			e.Before = []byte(before)
+		}
+		if after != "" {
+			e.After = []byte(after)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not iterate over the audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,46 @@
+package dbsqlite
+
+import (
+	"context"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestAuditEntries(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	entries := []model.AuditEntry{
+		{Entity: model.AuditEntityUser, EntityID: 1, Action: model.AuditActionCreate, ActorRole: model.RoleAdmin, After: []byte(`{"id":1}`), CreatedAt: "2030-05-10 12:00:00"},
+		{Entity: model.AuditEntityTransaction, EntityID: 1, Action: model.AuditActionUpdate, ActorID: 1, ActorRole: model.RoleUser, Before: []byte(`{"amount":1}`), After: []byte(`{"amount":2}`), CreatedAt: "2030-05-10 12:01:00"},
+		{Entity: model.AuditEntityUser, EntityID: 1, Action: model.AuditActionDelete, ActorID: 1, ActorRole: model.RoleUser, Before: []byte(`{"id":1}`), CreatedAt: "2030-05-10 12:02:00"},
+	}
+	for _, e := range entries {
+		if _, err := CreateAuditEntry(ctx, e, db); err != nil {
+			t.Fatalf("CreateAuditEntry() returned error: %v", err)
+		}
+	}
+
+	all, err := GetAuditEntries(ctx, model.AuditFilter{}, db)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("GetAuditEntries() = %+v, %v, want every entry", all, err)
+	}
+
+	users, err := GetAuditEntries(ctx, model.AuditFilter{Entity: model.AuditEntityUser, EntityID: 1}, db)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("GetAuditEntries() for user 1 = %+v, %v", users, err)
+	}
+	created, deleted := users[0], users[1]
+	if created.Action != model.AuditActionCreate || created.ActorID != 0 || created.Before != nil || string(created.After) != `{"id":1}` {
+		t.Errorf("creation entry = %+v", created)
+	}
+	if deleted.Action != model.AuditActionDelete || deleted.ActorID != 1 || string(deleted.Before) != `{"id":1}` || deleted.After != nil {
+		t.Errorf("deletion entry = %+v", deleted)
+	}
+
+	if none, err := GetAuditEntries(ctx, model.AuditFilter{Entity: model.AuditEntityGoal}, db); err != nil || len(none) != 0 {
+		t.Errorf("GetAuditEntries() for goals = %+v, %v, want none", none, err)
+	}
+}
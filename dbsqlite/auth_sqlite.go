@@ -0,0 +1,165 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"natan/fingo/model"
+)
+
+// SetCredentials stores the login and password hash of a user, replacing any previous ones. The role is
+// only used for new credentials; replacing them keeps the role the user had.
+func SetCredentials(ctx context.Context, c model.Credentials, db *sql.DB) (*model.Credentials, error) {
+	const upsertStmt = `
+	INSERT INTO credentials(user_id, login, password_hash, role, updated_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(user_id) DO UPDATE SET login = excluded.login, password_hash = excluded.password_hash, updated_at = excluded.updated_at`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, c.UserID, c.Login, c.PasswordHash, c.Role); err != nil {
+		return nil, fmt.Errorf("could not store the credentials of user %d: %w", c.UserID, err)
+	}
+
+	return GetCredentialsByUserID(ctx, c.UserID, db)
+}
+
+// getCredentials retrieves the credentials matching a single column, unless their user is deleted.
+func getCredentials(ctx context.Context, column string, value any, db *sql.DB) (*model.Credentials, error) {
+	query := `
+	SELECT c.user_id, c.login, c.password_hash, c.role, c.updated_at
+	FROM credentials c JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL
+	WHERE c.` + column + ` = ?`
+
+	var c model.Credentials
+	if err := db.QueryRowContext(ctx, query, value).Scan(&c.UserID, &c.Login, &c.PasswordHash, &c.Role, &c.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("credentials", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into credentials struct: %w", err)
+	}
+
+	return &c, nil
+}
+
+// GetCredentialsByUserID retrieves the credentials of a user.
+func GetCredentialsByUserID(ctx context.Context, userID int64, db *sql.DB) (*model.Credentials, error) {
+	return getCredentials(ctx, "user_id", userID, db)
+}
+
+// GetCredentialsByLogin retrieves the credentials with the given login.
+func GetCredentialsByLogin(ctx context.Context, login string, db *sql.DB) (*model.Credentials, error) {
+	return getCredentials(ctx, "login", login, db)
+}
+
+// CountCredentials returns how many users are able to sign in.
+func CountCredentials(ctx context.Context, db *sql.DB) (int, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM credentials c JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("could not count credentials: %w", err)
+	}
+	return count, nil
+}
+
+// SetCredentialsRole changes the role of a user who has credentials.
+func SetCredentialsRole(ctx context.Context, userID int64, role string, db *sql.DB) (*model.Credentials, error) {
+	res, err := db.ExecContext(ctx, `UPDATE credentials SET role = ? WHERE user_id = ?`, role, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not change the role of user %d: %w", userID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return nil, model.NotFoundError("credentials", sql.ErrNoRows)
+	}
+
+	return GetCredentialsByUserID(ctx, userID, db)
+}
+
+// CountCredentialsByRole returns how many users sign in with the given role.
+func CountCredentialsByRole(ctx context.Context, role string, db *sql.DB) (int, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM credentials c JOIN users u ON u.id = c.user_id AND u.deleted_at IS NULL WHERE c.role = ?`, role).Scan(&count); err != nil {
+		return 0, fmt.Errorf("could not count credentials by role: %w", err)
+	}
+	return count, nil
+}
+
+// CreateSession stores a new session.
+func CreateSession(ctx context.Context, s model.Session, db *sql.DB) error {
+	const insertStmt = `INSERT INTO sessions(token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`
+
+	if _, err := db.ExecContext(ctx, insertStmt, s.TokenHash, s.UserID, s.CreatedAt, s.ExpiresAt); err != nil {
+		return fmt.Errorf("could not execute insert into sessions table: %w", err)
+	}
+	return nil
+}
+
+// GetActiveSession retrieves the session with the given token hash, with the current role of its user,
+// unless it expired by now.
+func GetActiveSession(ctx context.Context, tokenHash string, now time.Time, db *sql.DB) (*model.Session, error) {
+	const selectStmt = `
+	SELECT s.token_hash, s.user_id, COALESCE(c.role, ''), s.created_at, s.expires_at
+	FROM sessions s JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL
+	LEFT JOIN credentials c ON c.user_id = s.user_id
+	WHERE s.token_hash = ? AND s.expires_at > ?`
+
+	var s model.Session
+	row := db.QueryRowContext(ctx, selectStmt, tokenHash, formatTimestamp(now))
+	if err := row.Scan(&s.TokenHash, &s.UserID, &s.Role, &s.CreatedAt, &s.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("session", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into session struct: %w", err)
+	}
+
+	return &s, nil
+}
+
+// DeleteSession deletes the session with the given token hash.
+func DeleteSession(ctx context.Context, tokenHash string, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for session: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// DeleteSessionsByUserID deletes every session of a user, signing them out everywhere.
+func DeleteSessionsByUserID(ctx context.Context, userID int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete the sessions of user %d: %w", userID, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// DeleteExpiredSessions deletes the sessions that expired by now and returns how many there were.
+func DeleteExpiredSessions(ctx context.Context, now time.Time, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, formatTimestamp(now))
+	if err != nil {
+		return 0, fmt.Errorf("could not delete expired sessions: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
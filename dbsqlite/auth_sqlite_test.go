@@ -0,0 +1,118 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestCredentials(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "signer"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, _ := CreateUser(ctx, model.User{UserName: "other"}, db)
+
+	if count, err := CountCredentials(ctx, db); err != nil || count != 0 {
+		t.Errorf("CountCredentials() = %d, %v; want 0", count, err)
+	}
+
+	c, err := SetCredentials(ctx, model.Credentials{UserID: u.ID, Login: "signer", PasswordHash: "hash-1", Role: model.RoleAdmin}, db)
+	if err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+	if c.Login != "signer" || c.PasswordHash != "hash-1" || c.Role != model.RoleAdmin || c.UpdatedAt == "" {
+		t.Errorf("SetCredentials() = %+v", c)
+	}
+
+	// Setting them again replaces the login and the hash but keeps the role
+	if _, err := SetCredentials(ctx, model.Credentials{UserID: u.ID, Login: "signer2", PasswordHash: "hash-2", Role: model.RoleUser}, db); err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+	got, err := GetCredentialsByLogin(ctx, "signer2", db)
+	if err != nil || got.UserID != u.ID || got.PasswordHash != "hash-2" || got.Role != model.RoleAdmin {
+		t.Errorf("GetCredentialsByLogin() = %+v, %v", got, err)
+	}
+	if _, err := GetCredentialsByLogin(ctx, "signer", db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetCredentialsByLogin() for the old login error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := SetCredentials(ctx, model.Credentials{UserID: other.ID, Login: "signer2", PasswordHash: "hash-3", Role: model.RoleUser}, db); err == nil {
+		t.Error("SetCredentials() with a login in use returned no error")
+	}
+	if count, _ := CountCredentials(ctx, db); count != 1 {
+		t.Errorf("CountCredentials() = %d, want 1", count)
+	}
+
+	demoted, err := SetCredentialsRole(ctx, u.ID, model.RoleUser, db)
+	if err != nil || demoted.Role != model.RoleUser {
+		t.Errorf("SetCredentialsRole() = %+v, %v", demoted, err)
+	}
+	if count, err := CountCredentialsByRole(ctx, model.RoleAdmin, db); err != nil || count != 0 {
+		t.Errorf("CountCredentialsByRole() = %d, %v; want 0", count, err)
+	}
+	if _, err := SetCredentialsRole(ctx, other.ID, model.RoleAdmin, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("SetCredentialsRole() for a user without credentials error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := DeleteUserByID(ctx, u.ID, db); err != nil {
+		t.Fatalf("DeleteUserByID() returned error: %v", err)
+	}
+	if _, err := GetCredentialsByUserID(ctx, u.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetCredentialsByUserID() after deleting the user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "browser"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	now := time.Date(2030, 5, 10, 12, 0, 0, 0, time.UTC)
+	for _, s := range []model.Session{
+		{TokenHash: "live", UserID: u.ID, CreatedAt: formatTimestamp(now), ExpiresAt: formatTimestamp(now.Add(time.Hour))},
+		{TokenHash: "other", UserID: u.ID, CreatedAt: formatTimestamp(now), ExpiresAt: formatTimestamp(now.Add(time.Hour))},
+		{TokenHash: "stale", UserID: u.ID, CreatedAt: formatTimestamp(now.Add(-2 * time.Hour)), ExpiresAt: formatTimestamp(now.Add(-time.Hour))},
+	} {
+		if err := CreateSession(ctx, s, db); err != nil {
+			t.Fatalf("CreateSession() returned error: %v", err)
+		}
+	}
+
+	if _, err := SetCredentials(ctx, model.Credentials{UserID: u.ID, Login: "browser", PasswordHash: "hash", Role: model.RoleAdmin}, db); err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+	s, err := GetActiveSession(ctx, "live", now, db)
+	if err != nil || s.UserID != u.ID || s.Role != model.RoleAdmin {
+		t.Errorf("GetActiveSession() = %+v, %v", s, err)
+	}
+	if _, err := GetActiveSession(ctx, "stale", now, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetActiveSession() for an expired session error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := GetActiveSession(ctx, "live", now.Add(time.Hour), db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetActiveSession() at its expiry error = %v, want sql.ErrNoRows", err)
+	}
+
+	if rows, err := DeleteExpiredSessions(ctx, now, db); err != nil || rows != 1 {
+		t.Errorf("DeleteExpiredSessions() = %d, %v; want 1", rows, err)
+	}
+	if rows, err := DeleteSession(ctx, "live", db); err != nil || rows != 1 {
+		t.Errorf("DeleteSession() = %d, %v; want 1", rows, err)
+	}
+	if rows, err := DeleteSessionsByUserID(ctx, u.ID, db); err != nil || rows != 1 {
+		t.Errorf("DeleteSessionsByUserID() = %d, %v; want 1", rows, err)
+	}
+}
@@ -8,5 +8,11 @@ import (
 // NewDBContext creates a new context with a timeout for database operations.
 // This ensures that long-running queries are canceled after the specified duration.
 func NewDBContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 2*time.Second)
+	return WithDBTimeout(context.Background())
+}
+
+// WithDBTimeout derives from parent a context with the timeout of NewDBContext, so the database operations
+// of a background job also stop when the job is canceled.
+func WithDBTimeout(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, 2*time.Second)
 }
@@ -0,0 +1,146 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// UpsertGoalParticipant adds a user to a goal or updates their target share if they already participate.
+func UpsertGoalParticipant(ctx context.Context, participant model.GoalParticipant, db *sql.DB) (*model.GoalParticipant, error) {
+	const upsertStmt = `
+	INSERT INTO goal_participants(goal_id, user_id, target_share) VALUES (?, ?, ?)
+	ON CONFLICT(goal_id, user_id) DO UPDATE SET target_share = excluded.target_share;`
+
+	_, err := db.ExecContext(ctx, upsertStmt, participant.GoalID, participant.UserID, participant.TargetShare)
+	if err != nil {
+		return nil, fmt.Errorf("could not upsert goal participant: %w", err)
+	}
+
+	return GetGoalParticipant(ctx, participant.GoalID, participant.UserID, db)
+}
+
+// GetGoalParticipant retrieves a single participant of a goal together with their contributed total.
+func GetGoalParticipant(ctx context.Context, goalID, userID int64, db *sql.DB) (*model.GoalParticipant, error) {
+	const selectStmt = `
+	SELECT p.goal_id, p.user_id, p.target_share, p.joined_at,
+		(SELECT COALESCE(SUM(c.amount), 0) FROM goal_contributions c WHERE c.goal_id = p.goal_id AND c.user_id = p.user_id)
+	FROM goal_participants p WHERE p.goal_id = ? AND p.user_id = ?`
+
+	var participant model.GoalParticipant
+	row := db.QueryRowContext(ctx, selectStmt, goalID, userID)
+	if err := row.Scan(&participant.GoalID, &participant.UserID, &participant.TargetShare, &participant.JoinedAt, &participant.Contributed); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("goal participant", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into goal participant struct: %w", err)
+	}
+
+	return &participant, nil
+}
+
+// GetGoalParticipants retrieves all participants of a goal together with their contributed totals.
+func GetGoalParticipants(ctx context.Context, goalID int64, db *sql.DB) ([]model.GoalParticipant, error) {
+	const query = `
+	SELECT p.goal_id, p.user_id, p.target_share, p.joined_at,
+		(SELECT COALESCE(SUM(c.amount), 0) FROM goal_contributions c WHERE c.goal_id = p.goal_id AND c.user_id = p.user_id)
+	FROM goal_participants p WHERE p.goal_id = ? ORDER BY p.user_id`
+
+	rows, err := db.QueryContext(ctx, query, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for goal participants: %w", err)
+	}
+	defer rows.Close()
+
+	var participants []model.GoalParticipant
+	for rows.Next() {
+		var participant model.GoalParticipant
+		if err := rows.Scan(&participant.GoalID, &participant.UserID, &participant.TargetShare, &participant.JoinedAt, &participant.Contributed); err != nil {
+			return nil, fmt.Errorf("could not scan the data into goal participant struct: %w", err)
+		}
+		participants = append(participants, participant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return participants, nil
+}
+
+// SumGoalTargetShares returns the sum of the target shares of a goal, ignoring the given user.
+// Passing 0 as excludeUserID sums every participant.
+func SumGoalTargetShares(ctx context.Context, goalID, excludeUserID int64, db *sql.DB) (utils.Money, error) {
+	const query = `SELECT COALESCE(SUM(target_share), 0) FROM goal_participants WHERE goal_id = ? AND user_id != ?`
+
+	var total utils.Money
+	if err := db.QueryRowContext(ctx, query, goalID, excludeUserID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("could not sum target shares for goal %d: %w", goalID, err)
+	}
+
+	return total, nil
+}
+
+// DeleteGoalParticipant removes a user from a goal and returns the number of affected rows.
+// Contributions already made by the user are kept.
+func DeleteGoalParticipant(ctx context.Context, goalID, userID int64, db *sql.DB) (int64, error) {
+	const deleteStmt = `DELETE FROM goal_participants WHERE goal_id = ? AND user_id = ?`
+
+	res, err := db.ExecContext(ctx, deleteStmt, goalID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for goal participant: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// CreateGoalContribution inserts a contribution made by a user towards a goal.
+func CreateGoalContribution(ctx context.Context, contribution model.GoalContribution, db *sql.DB) (*model.GoalContribution, error) {
+	const insertStmt = `INSERT INTO goal_contributions(goal_id, user_id, amount, note) VALUES (?, ?, ?, ?)`
+
+	res, err := db.ExecContext(ctx, insertStmt, contribution.GoalID, contribution.UserID, contribution.Amount, contribution.Note)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into goal_contributions table: %w", err)
+	}
+
+	if id, err := res.LastInsertId(); err == nil {
+		contribution.ID = id
+	}
+
+	return &contribution, nil
+}
+
+// GetGoalContributions retrieves all contributions made towards a goal, oldest first.
+func GetGoalContributions(ctx context.Context, goalID int64, db *sql.DB) ([]model.GoalContribution, error) {
+	const query = `SELECT id, goal_id, user_id, amount, COALESCE(note, ''), created_at FROM goal_contributions WHERE goal_id = ? ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, query, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for goal contributions: %w", err)
+	}
+	defer rows.Close()
+
+	var contributions []model.GoalContribution
+	for rows.Next() {
+		var contribution model.GoalContribution
+		if err := rows.Scan(&contribution.ID, &contribution.GoalID, &contribution.UserID, &contribution.Amount, &contribution.Note, &contribution.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into goal contribution struct: %w", err)
+		}
+		contributions = append(contributions, contribution)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return contributions, nil
+}
@@ -0,0 +1,206 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestGoalParticipants_TableDriven(t *testing.T) {
+	tests := []struct {
+		name   string
+		testFn func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64)
+	}{
+		{
+			name: "UpsertGoalParticipant inserts and then updates the share",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				p, err := UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID, TargetShare: 40000}, db)
+				if err != nil {
+					t.Fatalf("UpsertGoalParticipant() returned error: %v", err)
+				}
+				if p.TargetShare != 40000 {
+					t.Fatalf("expected share 40000, got %d", p.TargetShare)
+				}
+
+				p, err = UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID, TargetShare: 60000}, db)
+				if err != nil {
+					t.Fatalf("UpsertGoalParticipant() second call returned error: %v", err)
+				}
+				if p.TargetShare != 60000 {
+					t.Fatalf("expected updated share 60000, got %d", p.TargetShare)
+				}
+
+				participants, err := GetGoalParticipants(ctx, goalID, db)
+				if err != nil {
+					t.Fatalf("GetGoalParticipants() returned error: %v", err)
+				}
+				if len(participants) != 1 {
+					t.Fatalf("expected 1 participant, got %d", len(participants))
+				}
+			},
+		},
+		{
+			name: "GetGoalParticipants attributes contributions per user",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				for _, uid := range []int64{ownerID, partnerID} {
+					if _, err := UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: uid, TargetShare: 50000}, db); err != nil {
+						t.Fatalf("UpsertGoalParticipant() returned error: %v", err)
+					}
+				}
+				contributions := []model.GoalContribution{
+					{GoalID: goalID, UserID: ownerID, Amount: 1000},
+					{GoalID: goalID, UserID: ownerID, Amount: 2500},
+					{GoalID: goalID, UserID: partnerID, Amount: 700, Note: "first deposit"},
+				}
+				for _, c := range contributions {
+					if _, err := CreateGoalContribution(ctx, c, db); err != nil {
+						t.Fatalf("CreateGoalContribution() returned error: %v", err)
+					}
+				}
+
+				participants, err := GetGoalParticipants(ctx, goalID, db)
+				if err != nil {
+					t.Fatalf("GetGoalParticipants() returned error: %v", err)
+				}
+				got := map[int64]utils.Money{}
+				for _, p := range participants {
+					got[p.UserID] = p.Contributed
+				}
+				if got[ownerID] != 3500 || got[partnerID] != 700 {
+					t.Fatalf("unexpected contributed totals: %v", got)
+				}
+
+				list, err := GetGoalContributions(ctx, goalID, db)
+				if err != nil {
+					t.Fatalf("GetGoalContributions() returned error: %v", err)
+				}
+				if len(list) != 3 {
+					t.Fatalf("expected 3 contributions, got %d", len(list))
+				}
+				if list[2].Note != "first deposit" {
+					t.Errorf("note mismatch: got %q", list[2].Note)
+				}
+			},
+		},
+		{
+			name: "SumGoalTargetShares excludes the given user",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				_, _ = UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: ownerID, TargetShare: 30000}, db)
+				_, _ = UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID, TargetShare: 20000}, db)
+
+				total, err := SumGoalTargetShares(ctx, goalID, 0, db)
+				if err != nil {
+					t.Fatalf("SumGoalTargetShares() returned error: %v", err)
+				}
+				if total != 50000 {
+					t.Errorf("expected total 50000, got %d", total)
+				}
+
+				others, err := SumGoalTargetShares(ctx, goalID, partnerID, db)
+				if err != nil {
+					t.Fatalf("SumGoalTargetShares() returned error: %v", err)
+				}
+				if others != 30000 {
+					t.Errorf("expected 30000 without partner, got %d", others)
+				}
+			},
+		},
+		{
+			name: "GetAllGoalsByUserID includes shared goals",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				goals, err := GetAllGoalsByUserID(ctx, partnerID, db)
+				if err != nil {
+					t.Fatalf("GetAllGoalsByUserID() returned error: %v", err)
+				}
+				if len(goals) != 0 {
+					t.Fatalf("expected no goals before joining, got %d", len(goals))
+				}
+
+				if _, err := UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID}, db); err != nil {
+					t.Fatalf("UpsertGoalParticipant() returned error: %v", err)
+				}
+
+				goals, err = GetAllGoalsByUserID(ctx, partnerID, db)
+				if err != nil {
+					t.Fatalf("GetAllGoalsByUserID() returned error: %v", err)
+				}
+				if len(goals) != 1 || goals[0].ID != goalID {
+					t.Fatalf("expected shared goal %d, got %+v", goalID, goals)
+				}
+				if goals[0].UserID != ownerID {
+					t.Errorf("shared goal should keep its owner %d, got %d", ownerID, goals[0].UserID)
+				}
+			},
+		},
+		{
+			name: "DeleteGoalParticipant removes membership",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				_, _ = UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID}, db)
+
+				rows, err := DeleteGoalParticipant(ctx, goalID, partnerID, db)
+				if err != nil {
+					t.Fatalf("DeleteGoalParticipant() returned error: %v", err)
+				}
+				if rows != 1 {
+					t.Fatalf("expected 1 row deleted, got %d", rows)
+				}
+
+				_, err = GetGoalParticipant(ctx, goalID, partnerID, db)
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("expected sql.ErrNoRows after delete, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "DeleteGoalByID cascades to participants and contributions",
+			testFn: func(t *testing.T, ctx context.Context, db *sql.DB, ownerID, partnerID, goalID int64) {
+				_, _ = UpsertGoalParticipant(ctx, model.GoalParticipant{GoalID: goalID, UserID: partnerID}, db)
+				_, _ = CreateGoalContribution(ctx, model.GoalContribution{GoalID: goalID, UserID: partnerID, Amount: 100}, db)
+
+				if _, err := DeleteGoalByID(ctx, goalID, db); err != nil {
+					t.Fatalf("DeleteGoalByID() returned error: %v", err)
+				}
+
+				participants, err := GetGoalParticipants(ctx, goalID, db)
+				if err != nil {
+					t.Fatalf("GetGoalParticipants() returned error: %v", err)
+				}
+				contributions, err := GetGoalContributions(ctx, goalID, db)
+				if err != nil {
+					t.Fatalf("GetGoalContributions() returned error: %v", err)
+				}
+				if len(participants) != 0 || len(contributions) != 0 {
+					t.Fatalf("expected cascade delete, got %d participants and %d contributions", len(participants), len(contributions))
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			db, teardown := setupDB(t)
+			defer teardown()
+			ctx := context.Background()
+
+			owner, err := CreateUser(ctx, model.User{UserName: "owner"}, db)
+			if err != nil {
+				t.Fatalf("failed to create owner: %v", err)
+			}
+			partner, err := CreateUser(ctx, model.User{UserName: "partner"}, db)
+			if err != nil {
+				t.Fatalf("failed to create partner: %v", err)
+			}
+			goal, err := CreateGoal(ctx, model.Goal{Name: "Trip", Price: 100000, UserID: owner.ID, Deadline: "2026-12-01"}, db)
+			if err != nil {
+				t.Fatalf("failed to create goal: %v", err)
+			}
+
+			tc.testFn(t, ctx, db, owner.ID, partner.ID, goal.ID)
+		})
+	}
+}
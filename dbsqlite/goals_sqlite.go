@@ -11,7 +11,7 @@ import (
 
 // GetAllGoals retrieves all goals from the database.
 func GetAllGoals(ctx context.Context, db *sql.DB) ([]model.Goal, error) {
-	const query = "SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals"
+	const query = "SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals WHERE deleted_at IS NULL"
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -36,10 +36,12 @@ func GetAllGoals(ctx context.Context, db *sql.DB) ([]model.Goal, error) {
 	return goalsList, nil
 }
 
+// GetAllGoalsByUserID retrieves the goals owned by the user and the shared goals they participate in.
 func GetAllGoalsByUserID(ctx context.Context, id int64, db *sql.DB)([]model.Goal, error){
-	const query = "SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals WHERE user_id = ?"
+	const query = `SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals
+	WHERE (user_id = ? OR id IN (SELECT goal_id FROM goal_participants WHERE user_id = ?)) AND deleted_at IS NULL ORDER BY id`
 	
-	rows, err := db.QueryContext(ctx, query, id)
+	rows, err := db.QueryContext(ctx, query, id, id)
 	if err != nil{
 		return nil, fmt.Errorf("could not execute the query to return all goals using user_id: %w", err)
 	}
@@ -65,13 +67,13 @@ func GetAllGoalsByUserID(ctx context.Context, id int64, db *sql.DB)([]model.Goal
 
 // GetGoalByID retrieves a single goal by its ID.
 func GetGoalByID(ctx context.Context, id int64, db *sql.DB) (*model.Goal, error) {
-	const selectStmt = "SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals WHERE id = ?"
+	const selectStmt = "SELECT id, name, description, price, pros, cons, user_id, created_at, deadline FROM goals WHERE id = ? AND deleted_at IS NULL"
 
 	row := db.QueryRowContext(ctx, selectStmt, id)
 	var goal model.Goal
 	if err := row.Scan(&goal.ID, &goal.Name, &goal.Desc, &goal.Price, &goal.Pros, &goal.Cons, &goal.UserID, &goal.CreatedAt, &goal.Deadline); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("goal not found: %w", err)
+			return nil, model.NotFoundError("goal", err)
 		}
 		return nil, fmt.Errorf("could not scan the row into goal struct: %w", err)
 	}
@@ -116,7 +118,7 @@ func DeleteGoalByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
 // Only non-nil fields in GoalUpdate are written; existing values are preserved for nil fields.
 func UpdateGoalPartialByID(ctx context.Context, id int64, update *model.GoalUpdate, db *sql.DB) (*model.Goal, error) {
 	if update == nil {
-		return nil, fmt.Errorf("update data cannot be nil")
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
 	}
 
 	_, err := GetGoalByID(ctx, id, db)
@@ -161,7 +163,7 @@ func UpdateGoalPartialByID(ctx context.Context, id int64, update *model.GoalUpda
 		return GetGoalByID(ctx, id, db)
 	}
 
-	updateStmt := fmt.Sprintf("UPDATE goals SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	updateStmt := fmt.Sprintf("UPDATE goals SET %s WHERE id = ? AND deleted_at IS NULL", strings.Join(setParts, ", "))
 	args = append(args, id)
 
 	res, err := db.ExecContext(ctx, updateStmt, args...)
@@ -175,7 +177,7 @@ func UpdateGoalPartialByID(ctx context.Context, id int64, update *model.GoalUpda
 	}
 
 	if affected == 0 {
-		return nil, sql.ErrNoRows
+		return nil, model.NotFoundError("goal", sql.ErrNoRows)
 	}
 
 	return GetGoalByID(ctx, id, db)
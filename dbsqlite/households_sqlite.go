@@ -0,0 +1,336 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+// CreateHousehold creates a household with the given user as its owner, all or nothing.
+func CreateHousehold(ctx context.Context, name string, ownerID int64, db *sql.DB) (*model.Household, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO households(name) VALUES (?)`, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into households table: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the household: %w", err)
+	}
+
+	const insertMember = `INSERT INTO household_members(household_id, user_id, role) VALUES (?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, insertMember, id, ownerID, model.HouseholdRoleOwner); err != nil {
+		return nil, fmt.Errorf("could not add user %d to household %d: %w", ownerID, id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return GetHouseholdByID(ctx, id, db)
+}
+
+// GetHouseholdByID retrieves a household, without its members.
+func GetHouseholdByID(ctx context.Context, id int64, db *sql.DB) (*model.Household, error) {
+	var h model.Household
+	row := db.QueryRowContext(ctx, `SELECT id, name, created_at FROM households WHERE id = ?`, id)
+	if err := row.Scan(&h.ID, &h.Name, &h.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("household", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into household struct: %w", err)
+	}
+
+	return &h, nil
+}
+
+// GetHouseholdByUserID retrieves the household a user belongs to, without its members.
+func GetHouseholdByUserID(ctx context.Context, userID int64, db *sql.DB) (*model.Household, error) {
+	const query = `
+	SELECT h.id, h.name, h.created_at
+	FROM households h JOIN household_members m ON m.household_id = h.id
+	WHERE m.user_id = ?`
+
+	var h model.Household
+	if err := db.QueryRowContext(ctx, query, userID).Scan(&h.ID, &h.Name, &h.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError(fmt.Sprintf("household of user %d", userID), err)
+		}
+		return nil, fmt.Errorf("could not scan the row into household struct: %w", err)
+	}
+
+	return &h, nil
+}
+
+// GetAllHouseholds retrieves every household, without their members.
+func GetAllHouseholds(ctx context.Context, db *sql.DB) ([]model.Household, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, name, created_at FROM households ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for households: %w", err)
+	}
+	defer rows.Close()
+
+	households := []model.Household{}
+	for rows.Next() {
+		var h model.Household
+		if err := rows.Scan(&h.ID, &h.Name, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into household struct: %w", err)
+		}
+		households = append(households, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return households, nil
+}
+
+// RenameHousehold changes the name of a household.
+func RenameHousehold(ctx context.Context, id int64, name string, db *sql.DB) (*model.Household, error) {
+	res, err := db.ExecContext(ctx, `UPDATE households SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not rename household %d: %w", id, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return nil, model.NotFoundError("household", sql.ErrNoRows)
+	}
+
+	return GetHouseholdByID(ctx, id, db)
+}
+
+// DeleteHouseholdByID deletes a household and its memberships, leaving its users without one.
+func DeleteHouseholdByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM households WHERE id = ?`, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for household: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// selectHouseholdMembers selects the members of households with the names of their users.
+const selectHouseholdMembers = `
+	SELECT m.household_id, m.user_id, u.user_name, m.role, m.joined_at
+	FROM household_members m JOIN users u ON u.id = m.user_id AND u.deleted_at IS NULL`
+
+// GetHouseholdMember retrieves the membership of a user in a household.
+func GetHouseholdMember(ctx context.Context, householdID, userID int64, db *sql.DB) (*model.HouseholdMember, error) {
+	var m model.HouseholdMember
+	row := db.QueryRowContext(ctx, selectHouseholdMembers+` WHERE m.household_id = ? AND m.user_id = ?`, householdID, userID)
+	if err := row.Scan(&m.HouseholdID, &m.UserID, &m.UserName, &m.Role, &m.JoinedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("household member", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into household member struct: %w", err)
+	}
+
+	return &m, nil
+}
+
+// GetHouseholdMembers retrieves the members of a household, owners first.
+func GetHouseholdMembers(ctx context.Context, householdID int64, db *sql.DB) ([]model.HouseholdMember, error) {
+	query := selectHouseholdMembers + ` WHERE m.household_id = ? ORDER BY m.role = 'owner' DESC, m.user_id`
+
+	rows, err := db.QueryContext(ctx, query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for household members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.HouseholdMember{}
+	for rows.Next() {
+		var m model.HouseholdMember
+		if err := rows.Scan(&m.HouseholdID, &m.UserID, &m.UserName, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into household member struct: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpsertHouseholdMember adds a user to a household or changes their role if they already belong to it.
+// Adding a user who belongs to another household fails.
+func UpsertHouseholdMember(ctx context.Context, m model.HouseholdMember, db *sql.DB) (*model.HouseholdMember, error) {
+	const upsertStmt = `
+	INSERT INTO household_members(household_id, user_id, role) VALUES (?, ?, ?)
+	ON CONFLICT(household_id, user_id) DO UPDATE SET role = excluded.role`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, m.HouseholdID, m.UserID, m.Role); err != nil {
+		return nil, fmt.Errorf("could not upsert household member: %w", err)
+	}
+
+	return GetHouseholdMember(ctx, m.HouseholdID, m.UserID, db)
+}
+
+// DeleteHouseholdMember removes a user from a household and returns the number of affected rows.
+func DeleteHouseholdMember(ctx context.Context, householdID, userID int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM household_members WHERE household_id = ? AND user_id = ?`, householdID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for household member: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// selectHouseholdInvites selects invites with the names of their households.
+const selectHouseholdInvites = `
+	SELECT i.household_id, h.name, i.user_id, i.role, COALESCE(i.invited_by, 0), i.created_at
+	FROM household_invites i JOIN households h ON h.id = i.household_id`
+
+// UpsertHouseholdInvite invites a user to a household, or changes the role of their pending invite.
+func UpsertHouseholdInvite(ctx context.Context, invite model.HouseholdInvite, db *sql.DB) (*model.HouseholdInvite, error) {
+	const upsertStmt = `
+	INSERT INTO household_invites(household_id, user_id, role, invited_by) VALUES (?, ?, ?, ?)
+	ON CONFLICT(household_id, user_id) DO UPDATE SET role = excluded.role, invited_by = excluded.invited_by`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, invite.HouseholdID, invite.UserID, invite.Role, invite.InvitedBy); err != nil {
+		return nil, fmt.Errorf("could not upsert household invite: %w", err)
+	}
+
+	return GetHouseholdInvite(ctx, invite.HouseholdID, invite.UserID, db)
+}
+
+// GetHouseholdInvite retrieves the pending invite of a user to a household.
+func GetHouseholdInvite(ctx context.Context, householdID, userID int64, db *sql.DB) (*model.HouseholdInvite, error) {
+	var i model.HouseholdInvite
+	row := db.QueryRowContext(ctx, selectHouseholdInvites+` WHERE i.household_id = ? AND i.user_id = ?`, householdID, userID)
+	if err := row.Scan(&i.HouseholdID, &i.HouseholdName, &i.UserID, &i.Role, &i.InvitedBy, &i.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("household invite", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into household invite struct: %w", err)
+	}
+
+	return &i, nil
+}
+
+// GetHouseholdInvitesByUserID retrieves the pending invites of a user, oldest first.
+func GetHouseholdInvitesByUserID(ctx context.Context, userID int64, db *sql.DB) ([]model.HouseholdInvite, error) {
+	rows, err := db.QueryContext(ctx, selectHouseholdInvites+` WHERE i.user_id = ? ORDER BY i.created_at, i.household_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for household invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []model.HouseholdInvite{}
+	for rows.Next() {
+		var i model.HouseholdInvite
+		if err := rows.Scan(&i.HouseholdID, &i.HouseholdName, &i.UserID, &i.Role, &i.InvitedBy, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into household invite struct: %w", err)
+		}
+		invites = append(invites, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return invites, nil
+}
+
+// AcceptHouseholdInvite makes the invited user a member of the household with the role of the invite and
+// drops every other invite of theirs, all or nothing. Accepting fails if the user belongs to a household.
+func AcceptHouseholdInvite(ctx context.Context, householdID, userID int64, db *sql.DB) (*model.HouseholdMember, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var role string
+	row := tx.QueryRowContext(ctx, `SELECT role FROM household_invites WHERE household_id = ? AND user_id = ?`, householdID, userID)
+	if err := row.Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("household invite", err)
+		}
+		return nil, fmt.Errorf("could not scan the role of the household invite: %w", err)
+	}
+
+	const insertMember = `INSERT INTO household_members(household_id, user_id, role) VALUES (?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, insertMember, householdID, userID, role); err != nil {
+		return nil, fmt.Errorf("could not add user %d to household %d: %w", userID, householdID, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM household_invites WHERE user_id = ?`, userID); err != nil {
+		return nil, fmt.Errorf("could not delete the household invites of user %d: %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return GetHouseholdMember(ctx, householdID, userID, db)
+}
+
+// DeleteHouseholdInvite withdraws or declines the invite of a user to a household and returns the number of
+// affected rows.
+func DeleteHouseholdInvite(ctx context.Context, householdID, userID int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM household_invites WHERE household_id = ? AND user_id = ?`, householdID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for household invite: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetHouseholdUserIDs returns the IDs of the users in the same household as the given user, the user
+// included. Users in the trash are left out. A user without a household only shares it with themselves.
+func GetHouseholdUserIDs(ctx context.Context, userID int64, db *sql.DB) ([]int64, error) {
+	const query = `
+	SELECT m.user_id FROM household_members m JOIN users u ON u.id = m.user_id AND u.deleted_at IS NULL
+	WHERE m.household_id = (SELECT household_id FROM household_members WHERE user_id = ?)
+	ORDER BY m.user_id`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not query the household of user %d: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("could not scan household member id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(ids) == 0 {
+		ids = []int64{userID}
+	}
+	return ids, nil
+}
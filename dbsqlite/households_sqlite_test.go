@@ -0,0 +1,131 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestHouseholds(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	parent, err := CreateUser(ctx, model.User{UserName: "parent"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	child, _ := CreateUser(ctx, model.User{UserName: "child"}, db)
+	neighbour, _ := CreateUser(ctx, model.User{UserName: "neighbour"}, db)
+
+	home, err := CreateHousehold(ctx, "Home", parent.ID, db)
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+	if home.Name != "Home" || home.CreatedAt == "" {
+		t.Errorf("CreateHousehold() = %+v", home)
+	}
+	next, err := CreateHousehold(ctx, "Next door", neighbour.ID, db)
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+
+	if _, err := UpsertHouseholdMember(ctx, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID, Role: model.HouseholdRoleMember}, db); err != nil {
+		t.Fatalf("UpsertHouseholdMember() returned error: %v", err)
+	}
+	if _, err := UpsertHouseholdMember(ctx, model.HouseholdMember{HouseholdID: next.ID, UserID: child.ID, Role: model.HouseholdRoleMember}, db); err == nil {
+		t.Error("UpsertHouseholdMember() into a second household returned no error")
+	}
+	promoted, err := UpsertHouseholdMember(ctx, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID, Role: model.HouseholdRoleOwner}, db)
+	if err != nil || promoted.Role != model.HouseholdRoleOwner || promoted.UserName != "child" {
+		t.Errorf("UpsertHouseholdMember() changing the role = %+v, %v", promoted, err)
+	}
+
+	members, err := GetHouseholdMembers(ctx, home.ID, db)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("GetHouseholdMembers() = %+v, %v", members, err)
+	}
+	if got, err := GetHouseholdByUserID(ctx, child.ID, db); err != nil || got.ID != home.ID {
+		t.Errorf("GetHouseholdByUserID() = %+v, %v", got, err)
+	}
+	if ids, err := GetHouseholdUserIDs(ctx, child.ID, db); err != nil || !reflect.DeepEqual(ids, []int64{parent.ID, child.ID}) {
+		t.Errorf("GetHouseholdUserIDs() = %v, %v", ids, err)
+	}
+
+	if renamed, err := RenameHousehold(ctx, home.ID, "The house", db); err != nil || renamed.Name != "The house" {
+		t.Errorf("RenameHousehold() = %+v, %v", renamed, err)
+	}
+	if households, err := GetAllHouseholds(ctx, db); err != nil || len(households) != 2 {
+		t.Errorf("GetAllHouseholds() = %+v, %v", households, err)
+	}
+
+	if rows, err := DeleteHouseholdMember(ctx, home.ID, child.ID, db); err != nil || rows != 1 {
+		t.Errorf("DeleteHouseholdMember() = %d, %v", rows, err)
+	}
+	if ids, err := GetHouseholdUserIDs(ctx, child.ID, db); err != nil || !reflect.DeepEqual(ids, []int64{child.ID}) {
+		t.Errorf("GetHouseholdUserIDs() without a household = %v, %v", ids, err)
+	}
+
+	if rows, err := DeleteHouseholdByID(ctx, home.ID, db); err != nil || rows != 1 {
+		t.Errorf("DeleteHouseholdByID() = %d, %v", rows, err)
+	}
+	if _, err := GetHouseholdByUserID(ctx, parent.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetHouseholdByUserID() after deleting the household error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestHouseholdInvites(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	parent, err := CreateUser(ctx, model.User{UserName: "inviting-parent"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	neighbour, _ := CreateUser(ctx, model.User{UserName: "inviting-neighbour"}, db)
+	child, _ := CreateUser(ctx, model.User{UserName: "invited-child"}, db)
+	home, _ := CreateHousehold(ctx, "Home", parent.ID, db)
+	next, _ := CreateHousehold(ctx, "Next door", neighbour.ID, db)
+
+	for _, h := range []*model.Household{home, next} {
+		if _, err := UpsertHouseholdInvite(ctx, model.HouseholdInvite{HouseholdID: h.ID, UserID: child.ID, Role: model.HouseholdRoleMember, InvitedBy: parent.ID}, db); err != nil {
+			t.Fatalf("UpsertHouseholdInvite() returned error: %v", err)
+		}
+	}
+	invites, err := GetHouseholdInvitesByUserID(ctx, child.ID, db)
+	if err != nil || len(invites) != 2 || invites[0].HouseholdName != "Home" {
+		t.Fatalf("GetHouseholdInvitesByUserID() = %+v, %v; want both invites", invites, err)
+	}
+
+	// A pending invite grants nothing
+	if ids, _ := GetHouseholdUserIDs(ctx, child.ID, db); !reflect.DeepEqual(ids, []int64{child.ID}) {
+		t.Errorf("GetHouseholdUserIDs() of an invited user = %v, want only themselves", ids)
+	}
+
+	member, err := AcceptHouseholdInvite(ctx, home.ID, child.ID, db)
+	if err != nil || member.Role != model.HouseholdRoleMember {
+		t.Fatalf("AcceptHouseholdInvite() = %+v, %v", member, err)
+	}
+	if invites, _ := GetHouseholdInvitesByUserID(ctx, child.ID, db); len(invites) != 0 {
+		t.Errorf("invites left after accepting one = %+v, want none", invites)
+	}
+	if _, err := AcceptHouseholdInvite(ctx, next.ID, child.ID, db); !errors.Is(err, model.KindNotFound) {
+		t.Errorf("AcceptHouseholdInvite() of a dropped invite error = %v, want not found", err)
+	}
+	if rows, err := DeleteHouseholdInvite(ctx, next.ID, child.ID, db); err != nil || rows != 0 {
+		t.Errorf("DeleteHouseholdInvite() of a dropped invite = %d, %v", rows, err)
+	}
+
+	// Members in the trash are not in the household any more
+	if _, err := SoftDeleteUser(ctx, parent.ID, "2030-01-01 00:00:00", db); err != nil {
+		t.Fatalf("SoftDeleteUser() returned error: %v", err)
+	}
+	if ids, err := GetHouseholdUserIDs(ctx, child.ID, db); err != nil || !reflect.DeepEqual(ids, []int64{child.ID}) {
+		t.Errorf("GetHouseholdUserIDs() with a member in the trash = %v, %v; want only %d", ids, err, child.ID)
+	}
+}
@@ -0,0 +1,159 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"natan/fingo/model"
+)
+
+// TimestampLayout is the layout of the timestamps written by CURRENT_TIMESTAMP, always in UTC.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+const jobRunColumns = "id, job_name, triggered_by, COALESCE(scheduled_for, ''), started_at, COALESCE(finished_at, ''), status, COALESCE(error, ''), COALESCE(instance, '')"
+
+// formatTimestamp converts t to the UTC layout used by timestamp columns, so they compare as text.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampLayout)
+}
+
+// scanJobRuns reads all rows of a job_runs query into a slice.
+func scanJobRuns(rows *sql.Rows) ([]model.JobRun, error) {
+	var runs []model.JobRun
+	for rows.Next() {
+		var run model.JobRun
+		if err := rows.Scan(&run.ID, &run.JobName, &run.Trigger, &run.ScheduledFor, &run.StartedAt, &run.FinishedAt, &run.Status, &run.Error, &run.Instance); err != nil {
+			return nil, fmt.Errorf("could not scan the data into job run struct: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return runs, nil
+}
+
+// EnsureJob registers a job with its schedule. New jobs first run at nextRunAt; for known jobs the
+// next run is kept unless the schedule changed, in which case it moves to nextRunAt.
+func EnsureJob(ctx context.Context, name, schedule string, nextRunAt time.Time, db *sql.DB) error {
+	const upsertStmt = `
+		INSERT INTO jobs(name, schedule, next_run_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			next_run_at = CASE WHEN jobs.schedule <> excluded.schedule THEN excluded.next_run_at ELSE jobs.next_run_at END,
+			schedule = excluded.schedule`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, name, schedule, formatTimestamp(nextRunAt)); err != nil {
+		return fmt.Errorf("could not register job %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetJob retrieves a job by name together with its most recent run.
+func GetJob(ctx context.Context, name string, db *sql.DB) (*model.Job, error) {
+	const query = `SELECT name, schedule, next_run_at FROM jobs WHERE name = ?`
+
+	var job model.Job
+	if err := db.QueryRowContext(ctx, query, name).Scan(&job.Name, &job.Schedule, &job.NextRunAt); err != nil {
+		return nil, fmt.Errorf("could not get job %q: %w", name, err)
+	}
+
+	runs, err := GetJobRuns(ctx, name, 1, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) > 0 {
+		job.LastRun = &runs[0]
+	}
+
+	return &job, nil
+}
+
+// ClaimJobRun moves the next run of a job to next if it is due at now. Only one caller can claim a
+// given slot, even across processes sharing the database. Returns the claimed slot and whether the
+// claim succeeded.
+func ClaimJobRun(ctx context.Context, name string, now, next time.Time, db *sql.DB) (string, bool, error) {
+	var slot string
+	if err := db.QueryRowContext(ctx, `SELECT next_run_at FROM jobs WHERE name = ?`, name).Scan(&slot); err != nil {
+		return "", false, fmt.Errorf("could not get next run of job %q: %w", name, err)
+	}
+
+	if slot > formatTimestamp(now) {
+		return "", false, nil
+	}
+
+	res, err := db.ExecContext(ctx, `UPDATE jobs SET next_run_at = ? WHERE name = ? AND next_run_at = ?`, formatTimestamp(next), name, slot)
+	if err != nil {
+		return "", false, fmt.Errorf("could not claim run of job %q: %w", name, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return "", false, fmt.Errorf("could not get rows affected for claim: %w", err)
+	}
+
+	return slot, rows == 1, nil
+}
+
+// CreateJobRun records the start of a job run and returns its ID.
+func CreateJobRun(ctx context.Context, run model.JobRun, startedAt time.Time, db *sql.DB) (int64, error) {
+	const insertStmt = `INSERT INTO job_runs(job_name, triggered_by, scheduled_for, started_at, status, instance) VALUES (?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''))`
+
+	res, err := db.ExecContext(ctx, insertStmt, run.JobName, run.Trigger, run.ScheduledFor, formatTimestamp(startedAt), model.JobRunRunning, run.Instance)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute insert into job_runs table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("could not get last insert id: %w", err)
+	}
+
+	return id, nil
+}
+
+// FinishJobRun records the outcome of a job run. An empty errMsg is stored as no error.
+func FinishJobRun(ctx context.Context, id int64, status, errMsg string, finishedAt time.Time, db *sql.DB) error {
+	const updateStmt = `UPDATE job_runs SET status = ?, error = NULLIF(?, ''), finished_at = ? WHERE id = ?`
+
+	if _, err := db.ExecContext(ctx, updateStmt, status, errMsg, formatTimestamp(finishedAt), id); err != nil {
+		return fmt.Errorf("could not finish job run %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// GetJobRuns retrieves up to limit runs of a job, newest first.
+func GetJobRuns(ctx context.Context, name string, limit int, db *sql.DB) ([]model.JobRun, error) {
+	query := "SELECT " + jobRunColumns + " FROM job_runs WHERE job_name = ? ORDER BY id DESC LIMIT ?"
+
+	rows, err := db.QueryContext(ctx, query, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for job runs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanJobRuns(rows)
+}
+
+// MarkInterruptedJobRuns marks the runs started by instance that never finished, for example because
+// the process stopped while they were running, as interrupted. Returns the number of affected rows.
+func MarkInterruptedJobRuns(ctx context.Context, instance string, finishedAt time.Time, db *sql.DB) (int64, error) {
+	const updateStmt = `UPDATE job_runs SET status = ?, error = 'the process stopped before the run finished', finished_at = ? WHERE status = ? AND instance = ?`
+
+	res, err := db.ExecContext(ctx, updateStmt, model.JobRunInterrupted, formatTimestamp(finishedAt), model.JobRunRunning, instance)
+	if err != nil {
+		return 0, fmt.Errorf("could not mark interrupted job runs: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
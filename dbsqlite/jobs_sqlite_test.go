@@ -0,0 +1,167 @@
+package dbsqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestJobs_EnsureAndClaim(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	start := time.Date(2030, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := EnsureJob(ctx, "report", "@hourly", start, db); err != nil {
+		t.Fatalf("EnsureJob() returned error: %v", err)
+	}
+
+	// Registering again with the same schedule keeps the next run
+	if err := EnsureJob(ctx, "report", "@hourly", start.Add(5*time.Hour), db); err != nil {
+		t.Fatalf("EnsureJob() returned error: %v", err)
+	}
+	job, err := GetJob(ctx, "report", db)
+	if err != nil {
+		t.Fatalf("GetJob() returned error: %v", err)
+	}
+	if job.NextRunAt != "2030-03-01 10:00:00" || job.LastRun != nil {
+		t.Fatalf("unexpected job after re-registration: %+v", job)
+	}
+
+	// Not due yet
+	if _, claimed, err := ClaimJobRun(ctx, "report", start.Add(-time.Minute), start.Add(time.Hour), db); err != nil || claimed {
+		t.Fatalf("ClaimJobRun() before the slot = %v, %v; want false, nil", claimed, err)
+	}
+
+	slot, claimed, err := ClaimJobRun(ctx, "report", start, start.Add(time.Hour), db)
+	if err != nil || !claimed || slot != "2030-03-01 10:00:00" {
+		t.Fatalf("ClaimJobRun() = %q, %v, %v; want the 10:00 slot claimed", slot, claimed, err)
+	}
+
+	// The slot can only be claimed once
+	if _, claimed, err := ClaimJobRun(ctx, "report", start, start.Add(time.Hour), db); err != nil || claimed {
+		t.Fatalf("second ClaimJobRun() = %v, %v; want false, nil", claimed, err)
+	}
+
+	// A schedule change moves the next run
+	if err := EnsureJob(ctx, "report", "@daily", start.Add(14*time.Hour), db); err != nil {
+		t.Fatalf("EnsureJob() returned error: %v", err)
+	}
+	job, _ = GetJob(ctx, "report", db)
+	if job.Schedule != "@daily" || job.NextRunAt != "2030-03-02 00:00:00" {
+		t.Errorf("unexpected job after schedule change: %+v", job)
+	}
+}
+
+func TestJobRuns_RecordAndInterrupt(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	now := time.Date(2030, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := EnsureJob(ctx, "cleanup", "@daily", now, db); err != nil {
+		t.Fatalf("EnsureJob() returned error: %v", err)
+	}
+
+	first, err := CreateJobRun(ctx, model.JobRun{JobName: "cleanup", Trigger: model.JobTriggerSchedule, ScheduledFor: "2030-03-01 10:00:00", Instance: "a"}, now, db)
+	if err != nil {
+		t.Fatalf("CreateJobRun() returned error: %v", err)
+	}
+	if err := FinishJobRun(ctx, first, model.JobRunFailed, "boom", now.Add(time.Second), db); err != nil {
+		t.Fatalf("FinishJobRun() returned error: %v", err)
+	}
+	second, err := CreateJobRun(ctx, model.JobRun{JobName: "cleanup", Trigger: model.JobTriggerManual, Instance: "a"}, now.Add(time.Minute), db)
+	if err != nil {
+		t.Fatalf("CreateJobRun() returned error: %v", err)
+	}
+	other, err := CreateJobRun(ctx, model.JobRun{JobName: "cleanup", Trigger: model.JobTriggerManual, Instance: "b"}, now.Add(time.Minute), db)
+	if err != nil {
+		t.Fatalf("CreateJobRun() returned error: %v", err)
+	}
+
+	// Only the runs of the given instance are interrupted
+	n, err := MarkInterruptedJobRuns(ctx, "a", now.Add(time.Hour), db)
+	if err != nil || n != 1 {
+		t.Fatalf("MarkInterruptedJobRuns() = %d, %v; want 1, nil", n, err)
+	}
+
+	runs, err := GetJobRuns(ctx, "cleanup", 10, db)
+	if err != nil {
+		t.Fatalf("GetJobRuns() returned error: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(runs))
+	}
+	if runs[0].ID != other || runs[0].Status != model.JobRunRunning || runs[0].Instance != "b" {
+		t.Errorf("unexpected run of the other instance: %+v", runs[0])
+	}
+	if runs[1].ID != second || runs[1].Status != model.JobRunInterrupted || runs[1].ScheduledFor != "" {
+		t.Errorf("unexpected interrupted run: %+v", runs[1])
+	}
+	if runs[2].ID != first || runs[2].Status != model.JobRunFailed || runs[2].Error != "boom" || runs[2].FinishedAt != "2030-03-01 10:00:01" {
+		t.Errorf("unexpected oldest run: %+v", runs[2])
+	}
+
+	job, _ := GetJob(ctx, "cleanup", db)
+	if job.LastRun == nil || job.LastRun.ID != other {
+		t.Errorf("expected last run %d, got %+v", other, job.LastRun)
+	}
+}
+
+func TestLeases_AcquireRenewAndTakeOver(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	now := time.Date(2030, 3, 1, 10, 0, 0, 0, time.UTC)
+	ttl := time.Minute
+
+	steps := []struct {
+		name         string
+		holder       string
+		at           time.Time
+		wantAcquired bool
+		wantPrevious string
+	}{
+		{"free_lease", "a", now, true, ""},
+		{"held_by_other", "b", now.Add(30 * time.Second), false, ""},
+		{"renewed_by_holder", "a", now.Add(50 * time.Second), true, ""},
+		{"still_held_after_first_ttl", "b", now.Add(70 * time.Second), false, ""},
+		{"taken_over_after_expiry", "b", now.Add(110 * time.Second), true, "a"},
+		{"old_holder_locked_out", "a", now.Add(120 * time.Second), false, ""},
+	}
+
+	for _, step := range steps {
+		acquired, previous, err := AcquireLease(ctx, "scheduler", step.holder, step.at, ttl, db)
+		if err != nil {
+			t.Fatalf("%s: AcquireLease() returned error: %v", step.name, err)
+		}
+		if acquired != step.wantAcquired || previous != step.wantPrevious {
+			t.Fatalf("%s: AcquireLease() = %v, %q; want %v, %q", step.name, acquired, previous, step.wantAcquired, step.wantPrevious)
+		}
+	}
+
+	lease, err := GetLease(ctx, "scheduler", db)
+	if err != nil {
+		t.Fatalf("GetLease() returned error: %v", err)
+	}
+	if lease.Holder != "b" || lease.ExpiresAt != "2030-03-01 10:02:50" {
+		t.Errorf("unexpected lease: %+v", lease)
+	}
+
+	// Releasing by a non-holder is a no-op, releasing by the holder frees the lease right away
+	if err := ReleaseLease(ctx, "scheduler", "a", db); err != nil {
+		t.Fatalf("ReleaseLease() returned error: %v", err)
+	}
+	if acquired, _, _ := AcquireLease(ctx, "scheduler", "a", now.Add(130*time.Second), ttl, db); acquired {
+		t.Fatal("lease acquired while still held by b")
+	}
+	if err := ReleaseLease(ctx, "scheduler", "b", db); err != nil {
+		t.Fatalf("ReleaseLease() returned error: %v", err)
+	}
+	if acquired, previous, _ := AcquireLease(ctx, "scheduler", "a", now.Add(130*time.Second), ttl, db); !acquired || previous != "" {
+		t.Errorf("AcquireLease() after release = %v, %q; want true, \"\"", acquired, previous)
+	}
+}
@@ -0,0 +1,68 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"natan/fingo/model"
+)
+
+// AcquireLease grants the named lease to holder until now plus ttl, if the lease is free, expired or
+// already held by holder, in which case it is renewed. The grant is a single conditional write, so
+// only one of several instances competing for an expired lease gets it. Returns whether holder now
+// holds the lease and, when it was taken over, the previous holder.
+func AcquireLease(ctx context.Context, name, holder string, now time.Time, ttl time.Duration, db *sql.DB) (bool, string, error) {
+	previous := ""
+	err := db.QueryRowContext(ctx, `SELECT holder FROM scheduler_leases WHERE name = ?`, name).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, "", fmt.Errorf("could not get lease %q: %w", name, err)
+	}
+
+	const upsertStmt = `
+		INSERT INTO scheduler_leases(name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE scheduler_leases.holder = excluded.holder OR scheduler_leases.expires_at <= ?`
+
+	res, err := db.ExecContext(ctx, upsertStmt, name, holder, formatTimestamp(now.Add(ttl)), formatTimestamp(now))
+	if err != nil {
+		return false, "", fmt.Errorf("could not acquire lease %q: %w", name, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, "", fmt.Errorf("could not get rows affected for lease: %w", err)
+	}
+
+	if rows == 0 {
+		return false, "", nil
+	}
+	if previous == holder {
+		previous = ""
+	}
+	return true, previous, nil
+}
+
+// ReleaseLease gives up the named lease if it is held by holder, so another instance can take it
+// over without waiting for it to expire.
+func ReleaseLease(ctx context.Context, name, holder string, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM scheduler_leases WHERE name = ? AND holder = ?`, name, holder); err != nil {
+		return fmt.Errorf("could not release lease %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetLease retrieves the named lease.
+func GetLease(ctx context.Context, name string, db *sql.DB) (*model.Lease, error) {
+	const query = `SELECT name, holder, expires_at FROM scheduler_leases WHERE name = ?`
+
+	var lease model.Lease
+	if err := db.QueryRowContext(ctx, query, name).Scan(&lease.Name, &lease.Holder, &lease.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("could not get lease %q: %w", name, err)
+	}
+
+	return &lease, nil
+}
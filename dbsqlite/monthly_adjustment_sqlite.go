@@ -3,7 +3,10 @@ package dbsqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"natan/fingo/model"
 )
 
 // GetLastProcessedMonth retrieves the most recent year_month from the monthly_adjustments_log.
@@ -36,40 +39,207 @@ func IsMonthProcessed(ctx context.Context, db *sql.DB, yearMonth string) (bool,
 	return count > 0, nil
 }
 
-// ApplyMonthlyAdjustment updates current_amount for all users by adding monthly_inputs
-// and subtracting monthly_outputs, then records the year_month in the log.
-// The entire operation runs inside a transaction to ensure atomicity.
-func ApplyMonthlyAdjustment(ctx context.Context, db *sql.DB, yearMonth string) error {
+// ApplyUserMonthlyAdjustment applies the monthly adjustment of a single user for the given year_month,
+// records the itemised entry, advances the user's last processed period and marks the month in the log.
+// The entire operation runs inside a transaction; applying the same month twice fails on the entry's
+// UNIQUE constraint and leaves the balance untouched.
+func ApplyUserMonthlyAdjustment(ctx context.Context, db *sql.DB, userID int64, yearMonth string) (*model.AdjustmentEntry, error) {
+	entries, err := ApplyMonthlyAdjustmentToUsers(ctx, db, yearMonth, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entries[0], nil
+}
+
+// ApplyMonthlyAdjustmentToUsers applies the monthly adjustment for the given year_month to every listed user
+// inside a single transaction, so either all users are adjusted or none is.
+func ApplyMonthlyAdjustmentToUsers(ctx context.Context, db *sql.DB, yearMonth string, userIDs []int64) ([]model.AdjustmentEntry, error) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("could not begin transaction for monthly adjustment: %w", err)
+		return nil, fmt.Errorf("could not begin transaction for monthly adjustment: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Update all users: current_amount = current_amount + monthly_inputs - monthly_outputs
-	const updateStmt = `
-		UPDATE users
-		SET current_amount = current_amount + monthly_inputs - monthly_outputs;
+	var entries []model.AdjustmentEntry
+	for _, userID := range userIDs {
+		entry, err := applyUserAdjustmentTx(ctx, tx, userID, yearMonth)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	const logStmt = `INSERT OR IGNORE INTO monthly_adjustments_log(year_month) VALUES (?);`
+	if _, err := tx.ExecContext(ctx, logStmt, yearMonth); err != nil {
+		return nil, fmt.Errorf("could not record monthly adjustment for %s: %w", yearMonth, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit monthly adjustment transaction: %w", err)
+	}
+
+	return entries, nil
+}
+
+// applyUserAdjustmentTx adjusts one user's balance within tx, records the entry and moves the
+// user's last processed period forward if yearMonth is newer than it.
+func applyUserAdjustmentTx(ctx context.Context, tx *sql.Tx, userID int64, yearMonth string) (*model.AdjustmentEntry, error) {
+	entry := model.AdjustmentEntry{UserID: userID, YearMonth: yearMonth}
+
+	const selectStmt = `SELECT current_amount, monthly_inputs, monthly_outputs FROM users WHERE id = ? AND deleted_at IS NULL;`
+	if err := tx.QueryRowContext(ctx, selectStmt, userID).Scan(&entry.BalanceBefore, &entry.InputsApplied, &entry.OutputsApplied); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("user", err)
+		}
+		return nil, fmt.Errorf("could not load user %d for monthly adjustment: %w", userID, err)
+	}
+	entry.BalanceAfter = entry.BalanceBefore + entry.InputsApplied - entry.OutputsApplied
+
+	const updateStmt = `UPDATE users SET current_amount = ? WHERE id = ?;`
+	if _, err := tx.ExecContext(ctx, updateStmt, entry.BalanceAfter, userID); err != nil {
+		return nil, fmt.Errorf("could not apply monthly adjustment to user %d: %w", userID, err)
+	}
+
+	const entryStmt = `
+		INSERT INTO monthly_adjustment_entries(user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`
+	res, err := tx.ExecContext(ctx, entryStmt, userID, yearMonth, entry.InputsApplied, entry.OutputsApplied, entry.BalanceBefore, entry.BalanceAfter)
+	if err != nil {
+		return nil, fmt.Errorf("could not record monthly adjustment entry for user %d in %s: %w", userID, yearMonth, err)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+
+	const periodStmt = `
+		INSERT INTO user_adjustment_settings(user_id, last_period) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET last_period = excluded.last_period
+		WHERE last_period IS NULL OR last_period < excluded.last_period;
 	`
+	if _, err := tx.ExecContext(ctx, periodStmt, userID, yearMonth); err != nil {
+		return nil, fmt.Errorf("could not advance last adjustment period for user %d: %w", userID, err)
+	}
+
+	return &entry, nil
+}
+
+// RollbackMonthlyAdjustment reverses every entry recorded for year_month: each user's balance is moved back
+// by the entry's net change, the entries are deleted and the month is removed from monthly_adjustments_log.
+// Users' last processed periods are kept, so the scheduler does not re-apply the month on its own.
+// The entire operation runs inside a transaction. Returns the reversed entries.
+func RollbackMonthlyAdjustment(ctx context.Context, db *sql.DB, yearMonth string) ([]model.AdjustmentEntry, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction for monthly adjustment rollback: %w", err)
+	}
+	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, updateStmt)
+	const selectStmt = `
+	SELECT id, user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after, applied_at
+	FROM monthly_adjustment_entries WHERE year_month = ? ORDER BY user_id;`
+
+	rows, err := tx.QueryContext(ctx, selectStmt, yearMonth)
 	if err != nil {
-		return fmt.Errorf("could not apply monthly adjustment to users: %w", err)
+		return nil, fmt.Errorf("could not load adjustment entries for %s: %w", yearMonth, err)
+	}
+
+	var entries []model.AdjustmentEntry
+	for rows.Next() {
+		var entry model.AdjustmentEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.YearMonth, &entry.InputsApplied, &entry.OutputsApplied, &entry.BalanceBefore, &entry.BalanceAfter, &entry.AppliedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("could not scan the data into adjustment entry struct: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	const updateStmt = `UPDATE users SET current_amount = current_amount - ? WHERE id = ?;`
+	for _, entry := range entries {
+		if _, err := tx.ExecContext(ctx, updateStmt, entry.BalanceAfter-entry.BalanceBefore, entry.UserID); err != nil {
+			return nil, fmt.Errorf("could not reverse monthly adjustment for user %d: %w", entry.UserID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM monthly_adjustment_entries WHERE year_month = ?;`, yearMonth); err != nil {
+		return nil, fmt.Errorf("could not delete adjustment entries for %s: %w", yearMonth, err)
 	}
 
-	// Record that this month has been processed
-	const insertStmt = `INSERT INTO monthly_adjustments_log(year_month) VALUES (?);`
+	res, err := tx.ExecContext(ctx, `DELETE FROM monthly_adjustments_log WHERE year_month = ?;`, yearMonth)
+	if err != nil {
+		return nil, fmt.Errorf("could not remove %s from the monthly adjustments log: %w", yearMonth, err)
+	}
 
-	_, err = tx.ExecContext(ctx, insertStmt, yearMonth)
+	logged, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("could not record monthly adjustment for %s: %w", yearMonth, err)
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if logged == 0 && len(entries) == 0 {
+		return nil, model.NotFoundError(fmt.Sprintf("monthly adjustment for %s", yearMonth), sql.ErrNoRows)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("could not commit monthly adjustment transaction: %w", err)
+		return nil, fmt.Errorf("could not commit monthly adjustment rollback: %w", err)
 	}
 
-	return nil
+	return entries, nil
+}
+
+// GetAdjustedUserIDs returns the IDs of the users that already have an entry for year_month.
+func GetAdjustedUserIDs(ctx context.Context, db *sql.DB, yearMonth string) (map[int64]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT user_id FROM monthly_adjustment_entries WHERE year_month = ?;`, yearMonth)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for adjusted users in %s: %w", yearMonth, err)
+	}
+	defer rows.Close()
+
+	adjusted := make(map[int64]bool)
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("could not scan adjusted user id: %w", err)
+		}
+		adjusted[userID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return adjusted, nil
+}
+
+// GetAdjustmentEntriesByUserID retrieves the monthly adjustment entries recorded for a user, newest month first.
+func GetAdjustmentEntriesByUserID(ctx context.Context, userID int64, db *sql.DB) ([]model.AdjustmentEntry, error) {
+	const query = `
+	SELECT id, user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after, applied_at
+	FROM monthly_adjustment_entries WHERE user_id = ? ORDER BY year_month DESC;`
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for adjustment entries using user_id: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.AdjustmentEntry
+	for rows.Next() {
+		var entry model.AdjustmentEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.YearMonth, &entry.InputsApplied, &entry.OutputsApplied, &entry.BalanceBefore, &entry.BalanceAfter, &entry.AppliedAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into adjustment entry struct: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
 }
 
 // RecordMonthWithoutAdjustment records a year_month in the log without applying
@@ -25,13 +25,34 @@ func setupTestDB(t *testing.T) *sql.DB {
 			user_name TEXT NOT NULL,
 			current_amount REAL NOT NULL,
 			monthly_inputs REAL NOT NULL,
-			monthly_outputs REAL NOT NULL
+			monthly_outputs REAL NOT NULL,
+			deleted_at TEXT
 		);
 		CREATE TABLE monthly_adjustments_log(
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			year_month TEXT NOT NULL UNIQUE,
 			applied_at TEXT DEFAULT CURRENT_TIMESTAMP
 		);
+		CREATE TABLE monthly_adjustment_entries(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			year_month TEXT NOT NULL,
+			inputs_applied REAL NOT NULL,
+			outputs_applied REAL NOT NULL,
+			balance_before REAL NOT NULL,
+			balance_after REAL NOT NULL,
+			applied_at TEXT DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, year_month),
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+		CREATE TABLE user_adjustment_settings(
+			user_id INTEGER PRIMARY KEY,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			pay_day INTEGER NOT NULL DEFAULT 1,
+			timezone TEXT NOT NULL DEFAULT '',
+			last_period TEXT,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
 	`)
 	if err != nil {
 		t.Fatalf("could not create tables: %v", err)
@@ -177,7 +198,7 @@ func TestIsMonthProcessed(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_SingleUser(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_SingleUser(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -188,7 +209,7 @@ func TestApplyMonthlyAdjustment_SingleUser(t *testing.T) {
 	// Expected after adjustment: 10000 + 5000 - 3000 = 12000
 	id := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
 
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -208,7 +229,7 @@ func TestApplyMonthlyAdjustment_SingleUser(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_MultipleUsers(t *testing.T) {
+func TestApplyMonthlyAdjustmentToUsers_MultipleUsers(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -222,7 +243,7 @@ func TestApplyMonthlyAdjustment_MultipleUsers(t *testing.T) {
 	// Carol: 0 + 1000 - 500 = 500
 	carolID := insertTestUser(t, db, "Carol", 0, 1000, 500)
 
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyMonthlyAdjustmentToUsers(ctx, db, "2025-07", []int64{aliceID, bobID, carolID})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -245,7 +266,7 @@ func TestApplyMonthlyAdjustment_MultipleUsers(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_NegativeBalance(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_NegativeBalance(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -255,7 +276,7 @@ func TestApplyMonthlyAdjustment_NegativeBalance(t *testing.T) {
 	// User where outputs > inputs: 1000 + 2000 - 5000 = -2000
 	id := insertTestUser(t, db, "Dave", 1000, 2000, 5000)
 
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -266,35 +287,35 @@ func TestApplyMonthlyAdjustment_NegativeBalance(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_DuplicateMonthFails(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_DuplicateMonthFails(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	insertTestUser(t, db, "Alice", 10000, 5000, 3000)
+	id := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
 
 	// First application should succeed
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err != nil {
 		t.Fatalf("unexpected error on first apply: %v", err)
 	}
 
 	// Second application of the same month should fail (UNIQUE constraint)
-	err = ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err = ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err == nil {
 		t.Fatal("expected error when applying the same month twice, got nil")
 	}
 
 	// Verify the amount was only adjusted once (10000 + 5000 - 3000 = 12000)
-	amount := getUserCurrentAmount(t, db, 1)
+	amount := getUserCurrentAmount(t, db, id)
 	if amount != 12000 {
 		t.Errorf("expected current_amount 12000 (single adjustment), got %d", amount)
 	}
 }
 
-func TestApplyMonthlyAdjustment_MultiMonthCatchup(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_MultiMonthCatchup(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -307,7 +328,7 @@ func TestApplyMonthlyAdjustment_MultiMonthCatchup(t *testing.T) {
 	// Simulate processing 3 missed months
 	months := []string{"2025-05", "2025-06", "2025-07"}
 	for _, month := range months {
-		err := ApplyMonthlyAdjustment(ctx, db, month)
+		_, err := ApplyUserMonthlyAdjustment(ctx, db, id, month)
 		if err != nil {
 			t.Fatalf("unexpected error applying month %s: %v", month, err)
 		}
@@ -338,12 +359,12 @@ func TestGetLastProcessedMonth_ReturnsLatest(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	insertTestUser(t, db, "Alice", 10000, 5000, 3000)
+	id := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
 
 	// Apply months out of order
 	months := []string{"2025-03", "2025-05", "2025-04"}
 	for _, month := range months {
-		err := ApplyMonthlyAdjustment(ctx, db, month)
+		_, err := ApplyUserMonthlyAdjustment(ctx, db, id, month)
 		if err != nil {
 			t.Fatalf("unexpected error applying month %s: %v", month, err)
 		}
@@ -360,7 +381,7 @@ func TestGetLastProcessedMonth_ReturnsLatest(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_ZeroInputsAndOutputs(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_ZeroInputsAndOutputs(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -370,7 +391,7 @@ func TestApplyMonthlyAdjustment_ZeroInputsAndOutputs(t *testing.T) {
 	// User with zero monthly inputs and outputs: amount should not change
 	id := insertTestUser(t, db, "Eve", 5000, 0, 0)
 
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -381,15 +402,15 @@ func TestApplyMonthlyAdjustment_ZeroInputsAndOutputs(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_NoUsers(t *testing.T) {
+func TestApplyMonthlyAdjustmentToUsers_NoUsers(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	// No users in the database — should succeed without error
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	// No users to adjust — should succeed without error
+	_, err := ApplyMonthlyAdjustmentToUsers(ctx, db, "2025-07", nil)
 	if err != nil {
 		t.Fatalf("unexpected error when no users exist: %v", err)
 	}
@@ -404,7 +425,7 @@ func TestApplyMonthlyAdjustment_NoUsers(t *testing.T) {
 	}
 }
 
-func TestApplyMonthlyAdjustment_TransactionAtomicity(t *testing.T) {
+func TestApplyUserMonthlyAdjustment_TransactionAtomicity(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 
@@ -414,17 +435,87 @@ func TestApplyMonthlyAdjustment_TransactionAtomicity(t *testing.T) {
 	id := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
 
 	// First apply succeeds
-	err := ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	// Attempting the same month again should fail, and the user amount
 	// should remain at the value after the first (successful) adjustment
-	_ = ApplyMonthlyAdjustment(ctx, db, "2025-07")
+	_, _ = ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
 
 	amount := getUserCurrentAmount(t, db, id)
 	if amount != 12000 {
 		t.Errorf("expected current_amount to remain 12000 after failed duplicate, got %d", amount)
 	}
 }
+
+func TestApplyMonthlyAdjustmentToUsers_RecordsEntriesPerUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	alice := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
+	bob := insertTestUser(t, db, "Bob", 0, 1000, 4000)
+
+	for _, month := range []string{"2025-07", "2025-08"} {
+		if _, err := ApplyMonthlyAdjustmentToUsers(ctx, db, month, []int64{alice, bob}); err != nil {
+			t.Fatalf("unexpected error applying %s: %v", month, err)
+		}
+	}
+
+	entries, err := GetAdjustmentEntriesByUserID(ctx, alice, db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries for Alice, got %d", len(entries))
+	}
+
+	latest := entries[0]
+	if latest.YearMonth != "2025-08" {
+		t.Errorf("expected newest month first, got %q", latest.YearMonth)
+	}
+	if latest.InputsApplied != 5000 || latest.OutputsApplied != 3000 {
+		t.Errorf("unexpected inputs/outputs applied: %+v", latest)
+	}
+	if latest.BalanceBefore != 12000 || latest.BalanceAfter != 14000 {
+		t.Errorf("expected balance 12000 -> 14000, got %d -> %d", latest.BalanceBefore, latest.BalanceAfter)
+	}
+
+	bobEntries, err := GetAdjustmentEntriesByUserID(ctx, bob, db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bobEntries) != 2 || bobEntries[0].BalanceAfter != -6000 {
+		t.Errorf("expected Bob to end at -6000, got %+v", bobEntries)
+	}
+	if amount := getUserCurrentAmount(t, db, bob); amount != int64(bobEntries[0].BalanceAfter) {
+		t.Errorf("entry balance_after %d does not match current_amount %d", bobEntries[0].BalanceAfter, amount)
+	}
+}
+
+func TestApplyUserMonthlyAdjustment_DuplicateMonthKeepsSingleEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	id := insertTestUser(t, db, "Alice", 10000, 5000, 3000)
+
+	if _, err := ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = ApplyUserMonthlyAdjustment(ctx, db, id, "2025-07")
+
+	entries, err := GetAdjustmentEntriesByUserID(ctx, id, db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the failed duplicate run to be rolled back, got %d entries", len(entries))
+	}
+}
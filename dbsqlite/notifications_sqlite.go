@@ -0,0 +1,263 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+const notificationColumns = "id, user_id, kind, message, dedup_key, is_read, created_at, COALESCE(delivered_at, '')"
+
+// scanNotifications reads all rows of a notifications query into a slice.
+func scanNotifications(rows *sql.Rows) ([]model.Notification, error) {
+	var notifications []model.Notification
+	for rows.Next() {
+		var n model.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Message, &n.DedupKey, &n.Read, &n.CreatedAt, &n.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("could not scan the data into notification struct: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// CreateNotification inserts a notification unless one with the same dedup key already exists.
+// Returns true if a new row was created.
+func CreateNotification(ctx context.Context, notification model.Notification, db *sql.DB) (bool, error) {
+	const insertStmt = `INSERT OR IGNORE INTO notifications(user_id, kind, message, dedup_key) VALUES (?, ?, ?, ?)`
+
+	res, err := db.ExecContext(ctx, insertStmt, notification.UserID, notification.Kind, notification.Message, notification.DedupKey)
+	if err != nil {
+		return false, fmt.Errorf("could not execute insert into notifications table: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected for insert: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// GetNotificationsByUserID retrieves the notifications of a user, newest first.
+// If unreadOnly is true, notifications already marked as read are skipped.
+func GetNotificationsByUserID(ctx context.Context, userID int64, unreadOnly bool, db *sql.DB) ([]model.Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = ?"
+	if unreadOnly {
+		query += " AND is_read = 0"
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for notifications using user_id: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// GetPendingDeliveries retrieves up to limit notifications due to be sent through channel at now, oldest
+// first: those not delivered through it yet, with fewer than maxAttempts failed attempts, whose retry time
+// has come. Notifications already delivered through every channel are skipped.
+func GetPendingDeliveries(ctx context.Context, channel, now string, maxAttempts, limit int, db *sql.DB) ([]model.PendingDelivery, error) {
+	const query = `
+	SELECT n.id, n.user_id, n.kind, n.message, n.dedup_key, n.is_read, n.created_at, COALESCE(n.delivered_at, ''), COALESCE(d.attempts, 0)
+	FROM notifications n
+	LEFT JOIN notification_deliveries d ON d.notification_id = n.id AND d.channel = ?1
+	WHERE n.delivered_at IS NULL
+	AND (d.notification_id IS NULL OR (d.delivered_at IS NULL AND d.attempts < ?2 AND COALESCE(d.next_attempt_at, '') <= ?3))
+	ORDER BY n.id LIMIT ?4`
+
+	rows, err := db.QueryContext(ctx, query, channel, maxAttempts, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for pending deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []model.PendingDelivery
+	for rows.Next() {
+		p := model.PendingDelivery{Channel: channel}
+		n := &p.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Message, &n.DedupKey, &n.Read, &n.CreatedAt, &n.DeliveredAt, &p.Attempts); err != nil {
+			return nil, fmt.Errorf("could not scan the data into pending delivery struct: %w", err)
+		}
+		pending = append(pending, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return pending, nil
+}
+
+// RecordDeliveryFailure counts a failed attempt to send a notification through channel, keeping the error
+// and the time from which it may be retried.
+func RecordDeliveryFailure(ctx context.Context, notificationID int64, channel, lastError, nextAttemptAt string, db *sql.DB) error {
+	const upsertStmt = `
+	INSERT INTO notification_deliveries(notification_id, channel, attempts, last_error, next_attempt_at) VALUES (?, ?, 1, ?, ?)
+	ON CONFLICT(notification_id, channel) DO UPDATE SET
+		attempts = attempts + 1, last_error = excluded.last_error, next_attempt_at = excluded.next_attempt_at`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, notificationID, channel, lastError, nextAttemptAt); err != nil {
+		return fmt.Errorf("could not record failed delivery of notification %d via %s: %w", notificationID, channel, err)
+	}
+
+	return nil
+}
+
+// RecordDeliverySuccess records that a notification was sent through channel at deliveredAt.
+func RecordDeliverySuccess(ctx context.Context, notificationID int64, channel, deliveredAt string, db *sql.DB) error {
+	const upsertStmt = `
+	INSERT INTO notification_deliveries(notification_id, channel, attempts, delivered_at) VALUES (?, ?, 1, ?)
+	ON CONFLICT(notification_id, channel) DO UPDATE SET attempts = attempts + 1, delivered_at = excluded.delivered_at`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, notificationID, channel, deliveredAt); err != nil {
+		return fmt.Errorf("could not record delivery of notification %d via %s: %w", notificationID, channel, err)
+	}
+
+	return nil
+}
+
+// MarkNotificationDelivered records the delivery time of a notification once every one of channels is done
+// with it: it was sent through the channel, or the channel failed maxAttempts times. Returns true if the
+// notification was marked.
+func MarkNotificationDelivered(ctx context.Context, id int64, channels []string, maxAttempts int, deliveredAt string, db *sql.DB) (bool, error) {
+	placeholders := make([]string, len(channels))
+	args := []interface{}{deliveredAt, id, id, maxAttempts}
+	for i, channel := range channels {
+		placeholders[i] = "?"
+		args = append(args, channel)
+	}
+	args = append(args, len(channels))
+
+	updateStmt := fmt.Sprintf(`
+	UPDATE notifications SET delivered_at = ? WHERE id = ? AND delivered_at IS NULL AND (
+		SELECT COUNT(*) FROM notification_deliveries
+		WHERE notification_id = ? AND (delivered_at IS NOT NULL OR attempts >= ?) AND channel IN (%s)
+	) = ?`, strings.Join(placeholders, ", "))
+
+	res, err := db.ExecContext(ctx, updateStmt, args...)
+	if err != nil {
+		return false, fmt.Errorf("could not mark notification %d as delivered: %w", id, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// SettleNotifications marks every notification still in the outbox as delivered at settledAt, without
+// sending it. Returns the number of notifications settled.
+func SettleNotifications(ctx context.Context, settledAt string, db *sql.DB) (int64, error) {
+	const updateStmt = `UPDATE notifications SET delivered_at = ? WHERE delivered_at IS NULL`
+
+	res, err := db.ExecContext(ctx, updateStmt, settledAt)
+	if err != nil {
+		return 0, fmt.Errorf("could not settle the notification outbox: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetNotificationChannels retrieves the addresses notifications of a user are delivered to. Users that never
+// configured them get empty addresses.
+func GetNotificationChannels(ctx context.Context, userID int64, db *sql.DB) (*model.NotificationChannels, error) {
+	const selectStmt = `SELECT user_id, email, webhook_url FROM user_notification_channels WHERE user_id = ?`
+
+	c := model.NotificationChannels{UserID: userID}
+	row := db.QueryRowContext(ctx, selectStmt, userID)
+	if err := row.Scan(&c.UserID, &c.Email, &c.WebhookURL); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("could not scan the row into notification channels struct: %w", err)
+	}
+
+	return &c, nil
+}
+
+// UpsertNotificationChannels stores the e-mail address and webhook URL of a user.
+func UpsertNotificationChannels(ctx context.Context, channels model.NotificationChannels, db *sql.DB) (*model.NotificationChannels, error) {
+	const upsertStmt = `
+	INSERT INTO user_notification_channels(user_id, email, webhook_url) VALUES (?, ?, ?)
+	ON CONFLICT(user_id) DO UPDATE SET email = excluded.email, webhook_url = excluded.webhook_url;`
+
+	if _, err := db.ExecContext(ctx, upsertStmt, channels.UserID, channels.Email, channels.WebhookURL); err != nil {
+		return nil, fmt.Errorf("could not upsert notification channels: %w", err)
+	}
+
+	return GetNotificationChannels(ctx, channels.UserID, db)
+}
+
+// SetNotificationsRead sets the read state of notifications belonging to a user.
+// An empty ids slice updates every notification of the user. Returns the number of affected rows.
+func SetNotificationsRead(ctx context.Context, userID int64, ids []int64, read bool, db *sql.DB) (int64, error) {
+	updateStmt := "UPDATE notifications SET is_read = ? WHERE user_id = ?"
+	args := []interface{}{read, userID}
+
+	if len(ids) > 0 {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		updateStmt += fmt.Sprintf(" AND id IN (%s)", strings.Join(placeholders, ", "))
+	}
+
+	res, err := db.ExecContext(ctx, updateStmt, args...)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute update of notifications read state: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetDebtTotalsSince returns, per user, the sum of debt transactions created at or after the given timestamp.
+// The timestamp uses the "YYYY-MM-DD HH:MM:SS" layout of the created_at column.
+func GetDebtTotalsSince(ctx context.Context, since string, db *sql.DB) (map[int64]utils.Money, error) {
+	const query = `SELECT user_id, COALESCE(SUM(amount), 0) FROM transactions WHERE is_debt = 1 AND created_at >= ? AND deleted_at IS NULL GROUP BY user_id`
+
+	rows, err := db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for debt totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[int64]utils.Money)
+	for rows.Next() {
+		var userID int64
+		var total utils.Money
+		if err := rows.Scan(&userID, &total); err != nil {
+			return nil, fmt.Errorf("could not scan debt total: %w", err)
+		}
+		totals[userID] = total
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return totals, nil
+}
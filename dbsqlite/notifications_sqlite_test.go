@@ -0,0 +1,218 @@
+package dbsqlite
+
+import (
+	"context"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestNotifications_CreateListAndRead(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "notified"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	n := model.Notification{UserID: u.ID, Kind: model.NotificationGoalDeadline, Message: "soon", DedupKey: "k1"}
+	created, err := CreateNotification(ctx, n, db)
+	if err != nil || !created {
+		t.Fatalf("CreateNotification() = %v, %v; want true, nil", created, err)
+	}
+	created, err = CreateNotification(ctx, n, db)
+	if err != nil || created {
+		t.Fatalf("CreateNotification() with duplicate key = %v, %v; want false, nil", created, err)
+	}
+	n.DedupKey = "k2"
+	if _, err := CreateNotification(ctx, n, db); err != nil {
+		t.Fatalf("CreateNotification() returned error: %v", err)
+	}
+
+	list, err := GetNotificationsByUserID(ctx, u.ID, false, db)
+	if err != nil {
+		t.Fatalf("GetNotificationsByUserID() returned error: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(list))
+	}
+	if list[0].ID < list[1].ID {
+		t.Errorf("expected newest notification first")
+	}
+
+	rows, err := SetNotificationsRead(ctx, u.ID, []int64{list[0].ID}, true, db)
+	if err != nil {
+		t.Fatalf("SetNotificationsRead() returned error: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("expected 1 row affected, got %d", rows)
+	}
+
+	unread, err := GetNotificationsByUserID(ctx, u.ID, true, db)
+	if err != nil {
+		t.Fatalf("GetNotificationsByUserID() returned error: %v", err)
+	}
+	if len(unread) != 1 || unread[0].ID != list[1].ID {
+		t.Fatalf("expected only notification %d unread, got %+v", list[1].ID, unread)
+	}
+
+}
+
+func TestNotificationDeliveries_PerChannel(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "delivered"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for _, key := range []string{"d1", "d2"} {
+		if _, err := CreateNotification(ctx, model.Notification{UserID: u.ID, Kind: model.NotificationGoalDeadline, Message: "soon", DedupKey: key}, db); err != nil {
+			t.Fatalf("CreateNotification() returned error: %v", err)
+		}
+	}
+
+	const now, later = "2030-01-01 10:00:00", "2030-01-01 10:05:00"
+	pending, err := GetPendingDeliveries(ctx, "smtp", now, 2, 10, db)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("GetPendingDeliveries() = %+v, %v; want 2", pending, err)
+	}
+	first, second := pending[0].Notification.ID, pending[1].Notification.ID
+
+	// The first notification reaches smtp but not the webhook, which must not send it through smtp again
+	if err := RecordDeliverySuccess(ctx, first, "smtp", now, db); err != nil {
+		t.Fatalf("RecordDeliverySuccess() returned error: %v", err)
+	}
+	if err := RecordDeliveryFailure(ctx, first, "webhook", "down", later, db); err != nil {
+		t.Fatalf("RecordDeliveryFailure() returned error: %v", err)
+	}
+	if done, err := MarkNotificationDelivered(ctx, first, []string{"smtp", "webhook"}, 2, now, db); err != nil || done {
+		t.Fatalf("MarkNotificationDelivered() with a channel missing = %v, %v; want false", done, err)
+	}
+	if pending, _ := GetPendingDeliveries(ctx, "smtp", later, 2, 10, db); len(pending) != 1 || pending[0].Notification.ID != second {
+		t.Errorf("pending smtp deliveries = %+v, want only notification %d", pending, second)
+	}
+
+	// The webhook is retried only once its delay is over, and given up on after the last attempt
+	if pending, _ := GetPendingDeliveries(ctx, "webhook", now, 2, 10, db); len(pending) != 1 || pending[0].Notification.ID != second {
+		t.Errorf("pending webhook deliveries before the retry = %+v, want only notification %d", pending, second)
+	}
+	pending, _ = GetPendingDeliveries(ctx, "webhook", later, 2, 10, db)
+	if len(pending) != 2 || pending[0].Notification.ID != first || pending[0].Attempts != 1 {
+		t.Fatalf("pending webhook deliveries after the retry delay = %+v", pending)
+	}
+	if err := RecordDeliveryFailure(ctx, first, "webhook", "down", later, db); err != nil {
+		t.Fatalf("RecordDeliveryFailure() returned error: %v", err)
+	}
+	if pending, _ := GetPendingDeliveries(ctx, "webhook", "2031-01-01 00:00:00", 2, 10, db); len(pending) != 1 || pending[0].Notification.ID != second {
+		t.Errorf("pending webhook deliveries after the last attempt = %+v, want only notification %d", pending, second)
+	}
+
+	// Once every channel has it the notification leaves the outbox
+	for _, channel := range []string{"smtp", "webhook"} {
+		if err := RecordDeliverySuccess(ctx, second, channel, now, db); err != nil {
+			t.Fatalf("RecordDeliverySuccess() returned error: %v", err)
+		}
+	}
+	if done, err := MarkNotificationDelivered(ctx, second, []string{"smtp", "webhook"}, 2, now, db); err != nil || !done {
+		t.Fatalf("MarkNotificationDelivered() = %v, %v; want true", done, err)
+	}
+	if pending, _ := GetPendingDeliveries(ctx, "sms", now, 2, 10, db); len(pending) != 1 || pending[0].Notification.ID != first {
+		t.Errorf("pending deliveries through a new channel = %+v, want only notification %d", pending, first)
+	}
+
+	// A channel that gave up counts as done with the notification
+	if done, err := MarkNotificationDelivered(ctx, first, []string{"smtp", "webhook"}, 2, later, db); err != nil || !done {
+		t.Errorf("MarkNotificationDelivered() after the webhook gave up = %v, %v; want true", done, err)
+	}
+}
+
+func TestSettleNotifications(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "settled"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for _, key := range []string{"s1", "s2"} {
+		if _, err := CreateNotification(ctx, model.Notification{UserID: u.ID, Kind: model.NotificationNegativeBalance, Message: "low", DedupKey: key}, db); err != nil {
+			t.Fatalf("CreateNotification() returned error: %v", err)
+		}
+	}
+
+	if settled, err := SettleNotifications(ctx, "2030-01-01 10:00:00", db); err != nil || settled != 2 {
+		t.Fatalf("SettleNotifications() = %d, %v; want 2", settled, err)
+	}
+	if pending, _ := GetPendingDeliveries(ctx, "smtp", "2030-01-01 10:00:00", 2, 10, db); len(pending) != 0 {
+		t.Errorf("pending deliveries after settling = %+v, want none", pending)
+	}
+	if settled, err := SettleNotifications(ctx, "2030-01-02 10:00:00", db); err != nil || settled != 0 {
+		t.Errorf("SettleNotifications() again = %d, %v; want 0", settled, err)
+	}
+}
+
+func TestNotificationChannels_DefaultsAndUpsert(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "reachable"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	c, err := GetNotificationChannels(ctx, u.ID, db)
+	if err != nil || *c != (model.NotificationChannels{UserID: u.ID}) {
+		t.Fatalf("GetNotificationChannels() = %+v, %v; want empty addresses", c, err)
+	}
+
+	want := model.NotificationChannels{UserID: u.ID, Email: "reachable@example.com", WebhookURL: "https://example.com/hook"}
+	if c, err := UpsertNotificationChannels(ctx, want, db); err != nil || *c != want {
+		t.Fatalf("UpsertNotificationChannels() = %+v, %v; want %+v", c, err, want)
+	}
+	want.WebhookURL = ""
+	if c, err := UpsertNotificationChannels(ctx, want, db); err != nil || *c != want {
+		t.Errorf("UpsertNotificationChannels() clearing the webhook = %+v, %v; want %+v", c, err, want)
+	}
+}
+
+func TestGetDebtTotalsSince(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "spender"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	rows := []struct {
+		amount    int64
+		isDebt    bool
+		createdAt string
+	}{
+		{1000, true, "2026-02-28 23:59:59"},
+		{2000, true, "2026-03-01 00:00:00"},
+		{3000, true, "2026-03-15 10:00:00"},
+		{9000, false, "2026-03-15 10:00:00"},
+	}
+	for _, r := range rows {
+		if _, err := db.Exec(`INSERT INTO transactions(amount, is_debt, created_at, user_id) VALUES (?, ?, ?, ?)`, r.amount, r.isDebt, r.createdAt, u.ID); err != nil {
+			t.Fatalf("could not insert transaction: %v", err)
+		}
+	}
+
+	totals, err := GetDebtTotalsSince(ctx, "2026-03-01 00:00:00", db)
+	if err != nil {
+		t.Fatalf("GetDebtTotalsSince() returned error: %v", err)
+	}
+	if totals[u.ID] != utils.Money(5000) {
+		t.Errorf("expected 5000 spent since March, got %d", totals[u.ID])
+	}
+}
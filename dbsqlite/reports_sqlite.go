@@ -0,0 +1,220 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// periodKeyExprs maps report granularities to the SQL expression turning a timestamp column into a
+// period key: the date for days, the date of the Monday starting the week for weeks, "YYYY-MM" for
+// months and "YYYY" for years.
+var periodKeyExprs = map[string]string{
+	model.GranularityDay:   "strftime('%%Y-%%m-%%d', %s)",
+	model.GranularityWeek:  "date(%s, 'weekday 0', '-6 days')",
+	model.GranularityMonth: "strftime('%%Y-%%m', %s)",
+	model.GranularityYear:  "strftime('%%Y', %s)",
+}
+
+// GetCashflowTotals sums, per period of the given granularity, the income and expenses of a user's
+// transactions and the balance changes of their monthly adjustments, between from (inclusive) and to
+// (exclusive), both "YYYY-MM-DD HH:MM:SS" timestamps in UTC. Periods without activity are omitted.
+func GetCashflowTotals(ctx context.Context, userID int64, granularity, from, to string, db *sql.DB) ([]model.CashflowPeriod, error) {
+	expr, ok := periodKeyExprs[granularity]
+	if !ok {
+		return nil, fmt.Errorf("unknown report granularity %q", granularity)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT period,
+			CAST(ROUND(SUM(income)) AS INTEGER),
+			CAST(ROUND(SUM(expenses)) AS INTEGER),
+			CAST(ROUND(SUM(adjustments)) AS INTEGER)
+		FROM (
+			SELECT %s AS period,
+				CASE WHEN is_debt = 0 THEN amount ELSE 0 END AS income,
+				CASE WHEN is_debt = 1 THEN amount ELSE 0 END AS expenses,
+				0 AS adjustments
+			FROM transactions WHERE user_id = ? AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT %s, 0, 0, balance_after - balance_before
+			FROM monthly_adjustment_entries WHERE user_id = ? AND applied_at >= ? AND applied_at < ?
+		)
+		GROUP BY period ORDER BY period`, fmt.Sprintf(expr, "created_at"), fmt.Sprintf(expr, "applied_at"))
+
+	rows, err := db.QueryContext(ctx, query, userID, from, to, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for cash flow totals: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []model.CashflowPeriod
+	for rows.Next() {
+		var p model.CashflowPeriod
+		if err := rows.Scan(&p.Period, &p.Income, &p.Expenses, &p.Adjustments); err != nil {
+			return nil, fmt.Errorf("could not scan cash flow totals: %w", err)
+		}
+		p.Net = p.Income - p.Expenses
+		periods = append(periods, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return periods, nil
+}
+
+// GetBalanceChangeSince returns how much a user's balance changed through transactions and monthly
+// adjustments recorded at or after since, a "YYYY-MM-DD HH:MM:SS" timestamp in UTC.
+func GetBalanceChangeSince(ctx context.Context, userID int64, since string, db *sql.DB) (utils.Money, error) {
+	const query = `
+		SELECT CAST(ROUND(COALESCE(SUM(delta), 0)) AS INTEGER) FROM (
+			SELECT CASE WHEN is_debt = 1 THEN -amount ELSE amount END AS delta
+			FROM transactions WHERE user_id = ? AND created_at >= ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT balance_after - balance_before
+			FROM monthly_adjustment_entries WHERE user_id = ? AND applied_at >= ?
+		)`
+
+	var change utils.Money
+	if err := db.QueryRowContext(ctx, query, userID, since, userID, since).Scan(&change); err != nil {
+		return 0, fmt.Errorf("could not execute the query for balance change: %w", err)
+	}
+
+	return change, nil
+}
+
+// DashboardBounds delimits the ranges of a dashboard as "YYYY-MM-DD HH:MM:SS" timestamps in UTC.
+// Each range includes its start and excludes its end.
+type DashboardBounds struct {
+	MonthStart    string
+	Now           string
+	PreviousStart string
+	PreviousEnd   string
+}
+
+// GetDashboard reads, in a single read-only transaction so all figures are consistent, the balance of a
+// user, the income and expenses of the current and previous ranges, the largest expenses of the current
+// range and the progress of every goal the user owns or participates in. The derived figures
+// (net, rates, comparison, goal totals) are left for the caller.
+func GetDashboard(ctx context.Context, userID int64, bounds DashboardBounds, largestLimit int, db *sql.DB) (*model.Dashboard, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	d := &model.Dashboard{UserID: userID}
+
+	if err := tx.QueryRowContext(ctx, `SELECT current_amount FROM users WHERE id = ?`, userID).Scan(&d.CurrentBalance); err != nil {
+		return nil, fmt.Errorf("could not get the user for the dashboard: %w", err)
+	}
+
+	const totalsQuery = `
+		SELECT
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 0 AND created_at >= ?1 AND created_at < ?2 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 1 AND created_at >= ?1 AND created_at < ?2 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 0 AND created_at >= ?3 AND created_at < ?4 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 1 AND created_at >= ?3 AND created_at < ?4 THEN amount END), 0)) AS INTEGER)
+		FROM transactions
+		WHERE user_id = ?5 AND created_at >= ?3 AND created_at < ?2 AND deleted_at IS NULL`
+
+	err = tx.QueryRowContext(ctx, totalsQuery, bounds.MonthStart, bounds.Now, bounds.PreviousStart, bounds.PreviousEnd, userID).
+		Scan(&d.MonthToDate.Income, &d.MonthToDate.Expenses, &d.PreviousMonth.Income, &d.PreviousMonth.Expenses)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for dashboard totals: %w", err)
+	}
+
+	const largestQuery = `
+		SELECT id, description, amount, is_debt, created_at, user_id FROM transactions
+		WHERE user_id = ? AND is_debt = 1 AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		ORDER BY amount DESC, id LIMIT ?`
+
+	rows, err := tx.QueryContext(ctx, largestQuery, userID, bounds.MonthStart, bounds.Now, largestLimit)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for largest expenses: %w", err)
+	}
+	defer rows.Close()
+
+	d.LargestExpenses = []model.Transaction{}
+	for rows.Next() {
+		var t model.Transaction
+		if err := rows.Scan(&t.ID, &t.Desc, &t.Amount, &t.IsDebt, &t.CreatedAt, &t.UserID); err != nil {
+			return nil, fmt.Errorf("could not send the rows data to transaction struct: %w", err)
+		}
+		d.LargestExpenses = append(d.LargestExpenses, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	const goalsQuery = `
+		SELECT g.id, g.name, CAST(ROUND(COALESCE(g.price, 0)) AS INTEGER), g.deadline, g.user_id <> ?1,
+			CAST(ROUND(COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id AND c.user_id = ?1), 0)) AS INTEGER)
+		FROM goals g
+		WHERE (g.user_id = ?1 OR g.id IN (SELECT goal_id FROM goal_participants WHERE user_id = ?1)) AND g.deleted_at IS NULL
+		ORDER BY g.deadline, g.id`
+
+	goalRows, err := tx.QueryContext(ctx, goalsQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for goals progress: %w", err)
+	}
+	defer goalRows.Close()
+
+	d.Goals.Goals = []model.GoalProgress{}
+	for goalRows.Next() {
+		var g model.GoalProgress
+		if err := goalRows.Scan(&g.GoalID, &g.Name, &g.Price, &g.Deadline, &g.Shared, &g.Contributed, &g.UserContributed); err != nil {
+			return nil, fmt.Errorf("could not scan goal progress: %w", err)
+		}
+		d.Goals.Goals = append(d.Goals.Goals, g)
+	}
+	if err := goalRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return d, nil
+}
+
+// GetDailySpend returns the total of a user's debt transactions per UTC day ("YYYY-MM-DD") between from
+// (inclusive) and to (exclusive), both "YYYY-MM-DD HH:MM:SS" timestamps in UTC. Days without spending are
+// omitted. The second return value is the first day with any transaction in the range, empty if none.
+func GetDailySpend(ctx context.Context, userID int64, from, to string, db *sql.DB) (map[string]utils.Money, string, error) {
+	const query = `
+		SELECT date(created_at), CAST(ROUND(SUM(CASE WHEN is_debt = 1 THEN amount ELSE 0 END)) AS INTEGER)
+		FROM transactions WHERE user_id = ? AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		GROUP BY date(created_at) ORDER BY date(created_at)`
+
+	rows, err := db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not execute the query for daily spend: %w", err)
+	}
+	defer rows.Close()
+
+	spend := make(map[string]utils.Money)
+	first := ""
+	for rows.Next() {
+		var day string
+		var total utils.Money
+		if err := rows.Scan(&day, &total); err != nil {
+			return nil, "", fmt.Errorf("could not scan daily spend: %w", err)
+		}
+		if first == "" {
+			first = day
+		}
+		if total > 0 {
+			spend[day] = total
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return spend, first, nil
+}
@@ -0,0 +1,230 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// insertTransactionAt inserts a transaction with an explicit creation timestamp.
+func insertTransactionAt(t *testing.T, db *sql.DB, userID int64, amount utils.Money, isDebt bool, createdAt string) {
+	t.Helper()
+
+	const stmt = `INSERT INTO transactions(description, amount, is_debt, user_id, created_at) VALUES ('fixture', ?, ?, ?, ?)`
+	if _, err := db.Exec(stmt, amount, isDebt, userID, createdAt); err != nil {
+		t.Fatalf("could not insert fixture transaction: %v", err)
+	}
+}
+
+// cashflowFixture creates two users with transactions spread over early 2030 and returns the first user's ID.
+func cashflowFixture(t *testing.T, db *sql.DB) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "reported"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, err := CreateUser(ctx, model.User{UserName: "other"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// 2029-12-31 is a Monday, so it starts the week that also holds the first days of 2030
+	insertTransactionAt(t, db, u.ID, 100000, false, "2029-12-31 09:00:00")
+	insertTransactionAt(t, db, u.ID, 2500, true, "2030-01-01 12:00:00")
+	insertTransactionAt(t, db, u.ID, 1500, true, "2030-01-01 18:30:00")
+	insertTransactionAt(t, db, u.ID, 4000, true, "2030-01-15 08:00:00")
+	insertTransactionAt(t, db, u.ID, 20000, false, "2030-02-03 23:59:59")
+	insertTransactionAt(t, db, u.ID, 999, true, "2030-03-01 00:00:00")
+	insertTransactionAt(t, db, other.ID, 77700, true, "2030-01-10 10:00:00")
+
+	const adjustment = `INSERT INTO monthly_adjustment_entries(user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after, applied_at)
+		VALUES (?, '2030-02', 5000, 3000, 0, 2000, '2030-02-01 00:00:05')`
+	if _, err := db.Exec(adjustment, u.ID); err != nil {
+		t.Fatalf("could not insert fixture adjustment: %v", err)
+	}
+
+	return u.ID
+}
+
+func TestGetCashflowTotals(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	tests := []struct {
+		name        string
+		granularity string
+		from, to    string
+		want        []model.CashflowPeriod
+	}{
+		{
+			name:        "days",
+			granularity: model.GranularityDay,
+			from:        "2030-01-01 00:00:00",
+			to:          "2030-01-16 00:00:00",
+			want: []model.CashflowPeriod{
+				{Period: "2030-01-01", Expenses: 4000, Net: -4000},
+				{Period: "2030-01-15", Expenses: 4000, Net: -4000},
+			},
+		},
+		{
+			name:        "weeks start on monday across the year boundary",
+			granularity: model.GranularityWeek,
+			from:        "2029-12-31 00:00:00",
+			to:          "2030-01-21 00:00:00",
+			want: []model.CashflowPeriod{
+				{Period: "2029-12-31", Income: 100000, Expenses: 4000, Net: 96000},
+				{Period: "2030-01-14", Expenses: 4000, Net: -4000},
+			},
+		},
+		{
+			name:        "months include adjustments",
+			granularity: model.GranularityMonth,
+			from:        "2030-01-01 00:00:00",
+			to:          "2030-03-01 00:00:00",
+			want: []model.CashflowPeriod{
+				{Period: "2030-01", Expenses: 8000, Net: -8000},
+				{Period: "2030-02", Income: 20000, Adjustments: 2000, Net: 20000},
+			},
+		},
+		{
+			name:        "years",
+			granularity: model.GranularityYear,
+			from:        "2029-01-01 00:00:00",
+			to:          "2031-01-01 00:00:00",
+			want: []model.CashflowPeriod{
+				{Period: "2029", Income: 100000, Net: 100000},
+				{Period: "2030", Income: 20000, Expenses: 8999, Adjustments: 2000, Net: 11001},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GetCashflowTotals(ctx, userID, tc.granularity, tc.from, tc.to, db)
+			if err != nil {
+				t.Fatalf("GetCashflowTotals() returned error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d periods, got %d: %+v", len(tc.want), len(got), got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("period %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := GetCashflowTotals(ctx, userID, "fortnight", "2030-01-01 00:00:00", "2030-02-01 00:00:00", db); err == nil {
+		t.Error("GetCashflowTotals() with unknown granularity expected error, got nil")
+	}
+}
+
+func TestGetBalanceChangeSince(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	tests := []struct {
+		since string
+		want  utils.Money
+	}{
+		{"2029-01-01 00:00:00", 100000 - 8000 + 20000 + 2000 - 999},
+		{"2030-02-01 00:00:00", 20000 + 2000 - 999},
+		{"2030-03-01 00:00:01", 0},
+	}
+
+	for _, tc := range tests {
+		got, err := GetBalanceChangeSince(ctx, userID, tc.since, db)
+		if err != nil {
+			t.Fatalf("GetBalanceChangeSince(%q) returned error: %v", tc.since, err)
+		}
+		if got != tc.want {
+			t.Errorf("GetBalanceChangeSince(%q) = %d, want %d", tc.since, got, tc.want)
+		}
+	}
+}
+
+func TestGetDashboard(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	bounds := DashboardBounds{
+		MonthStart:    "2030-01-01 00:00:00",
+		Now:           "2030-01-16 00:00:00",
+		PreviousStart: "2029-12-01 00:00:00",
+		PreviousEnd:   "2029-12-16 00:00:00",
+	}
+
+	d, err := GetDashboard(ctx, userID, bounds, 2, db)
+	if err != nil {
+		t.Fatalf("GetDashboard() returned error: %v", err)
+	}
+	if d.MonthToDate.Income != 0 || d.MonthToDate.Expenses != 8000 || d.PreviousMonth.Income != 0 || d.PreviousMonth.Expenses != 0 {
+		t.Errorf("unexpected totals: %+v %+v", d.MonthToDate, d.PreviousMonth)
+	}
+	if len(d.LargestExpenses) != 2 || d.LargestExpenses[0].Amount != 4000 || d.LargestExpenses[1].Amount != 2500 {
+		t.Errorf("unexpected largest expenses: %+v", d.LargestExpenses)
+	}
+	if len(d.Goals.Goals) != 0 {
+		t.Errorf("expected no goals, got %+v", d.Goals.Goals)
+	}
+
+	bounds.PreviousEnd = "2030-01-01 00:00:00"
+	d, err = GetDashboard(ctx, userID, bounds, 2, db)
+	if err != nil {
+		t.Fatalf("GetDashboard() returned error: %v", err)
+	}
+	if d.PreviousMonth.Income != 100000 {
+		t.Errorf("previous income = %d, want 100000", d.PreviousMonth.Income)
+	}
+
+	if _, err := GetDashboard(ctx, 999999, bounds, 2, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetDashboard() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestGetDailySpend(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	spend, first, err := GetDailySpend(ctx, userID, "2029-12-31 00:00:00", "2030-01-16 00:00:00", db)
+	if err != nil {
+		t.Fatalf("GetDailySpend() returned error: %v", err)
+	}
+	// The income on the first day counts for the history length but not as spending
+	if first != "2029-12-31" {
+		t.Errorf("first day = %q, want 2029-12-31", first)
+	}
+	want := map[string]utils.Money{"2030-01-01": 4000, "2030-01-15": 4000}
+	if len(spend) != len(want) {
+		t.Fatalf("got %d days of spending, want %d: %v", len(spend), len(want), spend)
+	}
+	for day, amount := range want {
+		if spend[day] != amount {
+			t.Errorf("spend on %s = %d, want %d", day, spend[day], amount)
+		}
+	}
+
+	spend, first, err = GetDailySpend(ctx, userID, "2031-01-01 00:00:00", "2031-02-01 00:00:00", db)
+	if err != nil || first != "" || len(spend) != 0 {
+		t.Errorf("GetDailySpend() without transactions = %v, %q, %v; want empty", spend, first, err)
+	}
+}
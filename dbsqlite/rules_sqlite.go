@@ -0,0 +1,207 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+const selectRuleColumns = `
+	SELECT id, user_id, name, priority, description_contains, description_regex,
+		CAST(min_amount AS INTEGER), CAST(max_amount AS INTEGER), is_debt, category, tags, enabled, created_at
+	FROM rules`
+
+// ruleScanner is implemented by both *sql.Row and *sql.Rows.
+type ruleScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRule reads a row selected with selectRuleColumns. Tags are stored as a JSON array.
+func scanRule(s ruleScanner) (*model.Rule, error) {
+	var r model.Rule
+	var minAmount, maxAmount sql.NullInt64
+	var isDebt sql.NullBool
+	var tags string
+
+	if err := s.Scan(&r.ID, &r.UserID, &r.Name, &r.Priority, &r.DescriptionContains, &r.DescriptionRegex,
+		&minAmount, &maxAmount, &isDebt, &r.Category, &tags, &r.Enabled, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if minAmount.Valid {
+		v := utils.Money(minAmount.Int64)
+		r.MinAmount = &v
+	}
+	if maxAmount.Valid {
+		v := utils.Money(maxAmount.Int64)
+		r.MaxAmount = &v
+	}
+	if isDebt.Valid {
+		r.IsDebt = &isDebt.Bool
+	}
+	if err := json.Unmarshal([]byte(tags), &r.Tags); err != nil {
+		return nil, fmt.Errorf("could not decode the tags of rule %d: %w", r.ID, err)
+	}
+
+	return &r, nil
+}
+
+// ruleArgs returns the column values of a rule in the order of the insert and update statements.
+func ruleArgs(r model.Rule) ([]any, error) {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the rule tags: %w", err)
+	}
+
+	return []any{r.Name, r.Priority, r.DescriptionContains, r.DescriptionRegex, r.MinAmount, r.MaxAmount,
+		r.IsDebt, r.Category, string(encoded), r.Enabled}, nil
+}
+
+// CreateRule inserts a new categorisation rule.
+func CreateRule(ctx context.Context, rule model.Rule, db *sql.DB) (*model.Rule, error) {
+	const insertStmt = `
+		INSERT INTO rules(name, priority, description_contains, description_regex, min_amount, max_amount,
+			is_debt, category, tags, enabled, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	args, err := ruleArgs(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.ExecContext(ctx, insertStmt, append(args, rule.UserID)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into rules table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the new rule: %w", err)
+	}
+
+	return GetRuleByID(ctx, id, db)
+}
+
+// GetRuleByID retrieves a categorisation rule by its ID.
+func GetRuleByID(ctx context.Context, id int64, db *sql.DB) (*model.Rule, error) {
+	rule, err := scanRule(db.QueryRowContext(ctx, selectRuleColumns+" WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("rule", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into rule struct: %w", err)
+	}
+
+	return rule, nil
+}
+
+// GetRulesByUserID retrieves the categorisation rules of a user in evaluation order, by ascending priority
+// and then creation. When enabledOnly is set, disabled rules are left out.
+func GetRulesByUserID(ctx context.Context, userID int64, enabledOnly bool, db *sql.DB) ([]model.Rule, error) {
+	query := selectRuleColumns + " WHERE user_id = ?"
+	if enabledOnly {
+		query += " AND enabled = 1"
+	}
+	query += " ORDER BY priority, id"
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for rules using user_id: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []model.Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan the row into rule struct: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return rules, nil
+}
+
+// UpdateRule overwrites every editable field of a categorisation rule.
+func UpdateRule(ctx context.Context, rule model.Rule, db *sql.DB) (*model.Rule, error) {
+	const updateStmt = `
+		UPDATE rules SET name = ?, priority = ?, description_contains = ?, description_regex = ?, min_amount = ?,
+			max_amount = ?, is_debt = ?, category = ?, tags = ?, enabled = ?
+		WHERE id = ?`
+
+	args, err := ruleArgs(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.ExecContext(ctx, updateStmt, append(args, rule.ID)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the update query for rule: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return nil, model.NotFoundError("rule", sql.ErrNoRows)
+	}
+
+	return GetRuleByID(ctx, rule.ID, db)
+}
+
+// DeleteRuleByID deletes a categorisation rule by its ID.
+func DeleteRuleByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM rules WHERE id = ?", id)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for rule: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ApplyRuleChanges sets the categories and adds the tags described by changes to the transactions of a
+// user, all or nothing.
+func ApplyRuleChanges(ctx context.Context, userID int64, changes []model.RuleChange, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const setCategory = `UPDATE transactions SET category = ? WHERE id = ? AND user_id = ?`
+
+	for _, c := range changes {
+		if c.Category != "" {
+			if _, err := tx.ExecContext(ctx, setCategory, c.Category, c.TransactionID, userID); err != nil {
+				return fmt.Errorf("could not set the category of transaction %d: %w", c.TransactionID, err)
+			}
+		}
+		if err := addTags(ctx, tx, transactionTagLink, c.TransactionID, userID, c.AddedTags); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit rule changes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestRules_CRUD(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "ruler"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	min, max, debt := utils.Money(1000), utils.Money(5000), true
+	full := model.Rule{UserID: u.ID, Name: "groceries", Priority: 2, DescriptionContains: "market", DescriptionRegex: `^MKT\d+`,
+		MinAmount: &min, MaxAmount: &max, IsDebt: &debt, Category: "food", Tags: []string{"home", "weekly"}, Enabled: true}
+	bare := model.Rule{UserID: u.ID, Name: "salary", Priority: 1, DescriptionContains: "salary", Category: "income"}
+
+	created, err := CreateRule(ctx, full, db)
+	if err != nil {
+		t.Fatalf("CreateRule() returned error: %v", err)
+	}
+	if created.ID == 0 || created.MinAmount == nil || *created.MinAmount != min || *created.MaxAmount != max || !*created.IsDebt ||
+		!slices.Equal(created.Tags, full.Tags) || !created.Enabled || created.CreatedAt == "" {
+		t.Errorf("CreateRule() = %+v, want the stored fields back", created)
+	}
+
+	createdBare, err := CreateRule(ctx, bare, db)
+	if err != nil {
+		t.Fatalf("CreateRule() returned error: %v", err)
+	}
+	if createdBare.MinAmount != nil || createdBare.MaxAmount != nil || createdBare.IsDebt != nil || len(createdBare.Tags) != 0 || createdBare.Enabled {
+		t.Errorf("CreateRule() without optional fields = %+v", createdBare)
+	}
+
+	all, err := GetRulesByUserID(ctx, u.ID, false, db)
+	if err != nil {
+		t.Fatalf("GetRulesByUserID() returned error: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != createdBare.ID {
+		t.Errorf("expected both rules ordered by priority, got %+v", all)
+	}
+	enabled, _ := GetRulesByUserID(ctx, u.ID, true, db)
+	if len(enabled) != 1 || enabled[0].ID != created.ID {
+		t.Errorf("expected only the enabled rule, got %+v", enabled)
+	}
+
+	created.Tags = nil
+	created.MinAmount = nil
+	created.Name = "food"
+	updated, err := UpdateRule(ctx, *created, db)
+	if err != nil {
+		t.Fatalf("UpdateRule() returned error: %v", err)
+	}
+	if updated.Name != "food" || updated.MinAmount != nil || len(updated.Tags) != 0 {
+		t.Errorf("UpdateRule() = %+v", updated)
+	}
+
+	missing := *created
+	missing.ID = 999999
+	if _, err := UpdateRule(ctx, missing, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateRule() for a missing rule error = %v, want sql.ErrNoRows", err)
+	}
+
+	rows, err := DeleteRuleByID(ctx, created.ID, db)
+	if err != nil || rows != 1 {
+		t.Fatalf("DeleteRuleByID() = %d, %v; want 1, nil", rows, err)
+	}
+	if _, err := GetRuleByID(ctx, created.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRuleByID() after delete error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestApplyRuleChanges(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "tagged"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	first, _ := CreateTransaction(ctx, model.Transaction{Desc: "market", Amount: 100, IsDebt: true, UserID: u.ID, Category: "misc"}, db)
+	second, _ := CreateTransaction(ctx, model.Transaction{Desc: "bus", Amount: 50, IsDebt: true, UserID: u.ID}, db)
+
+	if err := AddTransactionTags(ctx, first.ID, u.ID, []string{"home"}, db); err != nil {
+		t.Fatalf("AddTransactionTags() returned error: %v", err)
+	}
+
+	changes := []model.RuleChange{
+		{TransactionID: first.ID, Category: "food", AddedTags: []string{"weekly", "home"}},
+		{TransactionID: second.ID, AddedTags: []string{"commute"}},
+	}
+	if err := ApplyRuleChanges(ctx, u.ID, changes, db); err != nil {
+		t.Fatalf("ApplyRuleChanges() returned error: %v", err)
+	}
+
+	got, _ := GetTransactionByID(ctx, first.ID, db)
+	if got.Category != "food" {
+		t.Errorf("category = %q, want food", got.Category)
+	}
+	got, _ = GetTransactionByID(ctx, second.ID, db)
+	if got.Category != "" {
+		t.Errorf("category of the untouched transaction = %q, want none", got.Category)
+	}
+
+	tags, err := GetTransactionTagsByUserID(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetTransactionTagsByUserID() returned error: %v", err)
+	}
+	if !slices.Equal(tags[first.ID], []string{"home", "weekly"}) || !slices.Equal(tags[second.ID], []string{"commute"}) {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	one, err := GetTransactionTags(ctx, first.ID, db)
+	if err != nil || !slices.Equal(one, []string{"home", "weekly"}) {
+		t.Errorf("GetTransactionTags() = %v, %v", one, err)
+	}
+}
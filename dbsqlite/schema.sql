@@ -3,7 +3,8 @@ CREATE TABLE users(id INTEGER PRIMARY KEY AUTOINCREMENT,
 user_name TEXT NOT NULL,
 current_amount REAL NOT NULL,
 monthly_inputs REAL NOT NULL,
-monthly_outputs REAL NOT NULL);
+monthly_outputs REAL NOT NULL,
+deleted_at TEXT);
 CREATE TABLE transactions(
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	description TEXT,
@@ -11,6 +12,8 @@ CREATE TABLE transactions(
 	is_debt INTEGER NOT NULL,
 	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
 	user_id INTEGER NOT NULL,
+	category TEXT,
+	deleted_at TEXT,
 	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 CREATE TABLE monthly_adjustments_log(
@@ -28,6 +31,213 @@ CREATE TABLE goals(
 	user_id INTEGER NOT NULL,
 	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
 	deadline TEXT NOT NULL,
+	deleted_at TEXT,
 	FOREIGN KEY(user_id) REFERENCES users(id)
 ON DELETE CASCADE
 );
+CREATE TABLE goal_participants(
+	goal_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	target_share REAL NOT NULL DEFAULT 0,
+	joined_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(goal_id, user_id),
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE goal_contributions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	goal_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	amount REAL NOT NULL,
+	note TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE notifications(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	message TEXT NOT NULL,
+	dedup_key TEXT NOT NULL UNIQUE,
+	is_read INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE notification_deliveries(
+	notification_id INTEGER NOT NULL,
+	channel TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TEXT,
+	delivered_at TEXT,
+	PRIMARY KEY(notification_id, channel),
+	FOREIGN KEY(notification_id) REFERENCES notifications(id) ON DELETE CASCADE
+);
+CREATE TABLE user_notification_channels(
+	user_id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL DEFAULT '',
+	webhook_url TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE monthly_adjustment_entries(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	year_month TEXT NOT NULL,
+	inputs_applied REAL NOT NULL,
+	outputs_applied REAL NOT NULL,
+	balance_before REAL NOT NULL,
+	balance_after REAL NOT NULL,
+	applied_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, year_month),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE user_adjustment_settings(
+	user_id INTEGER PRIMARY KEY,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	pay_day INTEGER NOT NULL DEFAULT 1,
+	timezone TEXT NOT NULL DEFAULT '',
+	last_period TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE jobs(
+	name TEXT PRIMARY KEY,
+	schedule TEXT NOT NULL,
+	next_run_at TEXT NOT NULL
+);
+CREATE TABLE job_runs(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_name TEXT NOT NULL,
+	triggered_by TEXT NOT NULL,
+	scheduled_for TEXT,
+	started_at TEXT NOT NULL,
+	finished_at TEXT,
+	status TEXT NOT NULL,
+	error TEXT,
+	instance TEXT,
+	FOREIGN KEY(job_name) REFERENCES jobs(name) ON DELETE CASCADE
+);
+CREATE TABLE scheduler_leases(
+	name TEXT PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);
+CREATE TABLE tags(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	UNIQUE(user_id, name),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE transaction_tags(
+	transaction_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY(transaction_id, tag_id),
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+CREATE TABLE goal_tags(
+	goal_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY(goal_id, tag_id),
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+CREATE TABLE rules(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	priority INTEGER NOT NULL DEFAULT 0,
+	description_contains TEXT NOT NULL DEFAULT '',
+	description_regex TEXT NOT NULL DEFAULT '',
+	min_amount REAL,
+	max_amount REAL,
+	is_debt INTEGER,
+	category TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '[]',
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE transaction_splits(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	transaction_id INTEGER NOT NULL,
+	amount REAL NOT NULL,
+	category TEXT NOT NULL DEFAULT '',
+	note TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+CREATE TABLE attachments(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	transaction_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	sha256 TEXT NOT NULL,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_attachments_sha256 ON attachments(sha256);
+CREATE TABLE credentials(
+	user_id INTEGER PRIMARY KEY,
+	login TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE sessions(
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE api_tokens(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT,
+	last_used_at TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE households(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE household_members(
+	household_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL UNIQUE,
+	role TEXT NOT NULL DEFAULT 'member',
+	joined_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(household_id, user_id),
+	FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE household_invites(
+	household_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL DEFAULT 'member',
+	invited_by INTEGER,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(household_id, user_id),
+	FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE audit_log(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entity TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor_id INTEGER,
+	actor_role TEXT NOT NULL DEFAULT '',
+	before TEXT,
+	after TEXT,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id);
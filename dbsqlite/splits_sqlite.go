@@ -0,0 +1,128 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+// querySplits runs a query selecting split columns and groups the splits by transaction ID, in the order
+// they were stored.
+func querySplits(ctx context.Context, db *sql.DB, query string, args ...any) (map[int64][]model.Split, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for transaction splits: %w", err)
+	}
+	defer rows.Close()
+
+	splits := make(map[int64][]model.Split)
+	for rows.Next() {
+		var s model.Split
+		if err := rows.Scan(&s.ID, &s.TransactionID, &s.Amount, &s.Category, &s.Note); err != nil {
+			return nil, fmt.Errorf("could not scan the row into split struct: %w", err)
+		}
+		splits[s.TransactionID] = append(splits[s.TransactionID], s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return splits, nil
+}
+
+const selectSplitColumns = `SELECT s.id, s.transaction_id, CAST(ROUND(s.amount) AS INTEGER), s.category, s.note FROM transaction_splits s`
+
+// GetTransactionSplits retrieves the splits of a transaction in the order they were stored.
+func GetTransactionSplits(ctx context.Context, transactionID int64, db *sql.DB) ([]model.Split, error) {
+	splits, err := querySplits(ctx, db, selectSplitColumns+" WHERE s.transaction_id = ? ORDER BY s.id", transactionID)
+	return splits[transactionID], err
+}
+
+// GetAllTransactionSplits retrieves the splits of every transaction, keyed by transaction ID.
+// Transactions without splits are left out.
+func GetAllTransactionSplits(ctx context.Context, db *sql.DB) (map[int64][]model.Split, error) {
+	return querySplits(ctx, db, selectSplitColumns+" ORDER BY s.transaction_id, s.id")
+}
+
+// GetTransactionSplitsByUserID retrieves the splits of each transaction of a user, keyed by transaction ID.
+// Transactions without splits are left out.
+func GetTransactionSplitsByUserID(ctx context.Context, userID int64, db *sql.DB) (map[int64][]model.Split, error) {
+	const query = selectSplitColumns + `
+		JOIN transactions t ON t.id = s.transaction_id
+		WHERE t.user_id = ? ORDER BY s.transaction_id, s.id`
+
+	return querySplits(ctx, db, query, userID)
+}
+
+// SetTransactionSplits replaces the splits of a transaction, all or nothing. An empty list removes them.
+func SetTransactionSplits(ctx context.Context, transactionID int64, splits []model.Split, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_splits WHERE transaction_id = ?`, transactionID); err != nil {
+		return fmt.Errorf("could not clear the splits of transaction %d: %w", transactionID, err)
+	}
+
+	const insertSplit = `INSERT INTO transaction_splits(transaction_id, amount, category, note) VALUES (?, ?, ?, ?)`
+	for _, s := range splits {
+		if _, err := tx.ExecContext(ctx, insertSplit, transactionID, s.Amount, s.Category, s.Note); err != nil {
+			return fmt.Errorf("could not insert a split of transaction %d: %w", transactionID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit splits: %w", err)
+	}
+
+	return nil
+}
+
+// GetCategoryTotals sums the transactions of a user created between from (inclusive) and to (exclusive),
+// both "YYYY-MM-DD HH:MM:SS" timestamps in UTC, per category. A split transaction counts towards the
+// category of each split, falling back to its own category for splits without one; any other transaction
+// counts towards its own category. Categories are in alphabetical order, uncategorised amounts first under
+// the empty category; Net is left for the caller.
+func GetCategoryTotals(ctx context.Context, userID int64, from, to string, db *sql.DB) ([]model.CategoryTotal, error) {
+	const query = `
+		WITH lines AS (
+			SELECT t.id, COALESCE(NULLIF(s.category, ''), t.category, '') AS category, t.is_debt, s.amount
+			FROM transaction_splits s JOIN transactions t ON t.id = s.transaction_id
+			WHERE t.user_id = ? AND t.created_at >= ? AND t.created_at < ? AND t.deleted_at IS NULL
+			UNION ALL
+			SELECT t.id, COALESCE(t.category, ''), t.is_debt, t.amount
+			FROM transactions t
+			WHERE t.user_id = ? AND t.created_at >= ? AND t.created_at < ? AND t.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+		)
+		SELECT category, COUNT(DISTINCT id),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 0 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN is_debt = 1 THEN amount END), 0)) AS INTEGER)
+		FROM lines
+		GROUP BY category
+		ORDER BY category`
+
+	rows, err := db.QueryContext(ctx, query, userID, from, to, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for category totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []model.CategoryTotal{}
+	for rows.Next() {
+		var total model.CategoryTotal
+		if err := rows.Scan(&total.Category, &total.Transactions, &total.Income, &total.Expenses); err != nil {
+			return nil, fmt.Errorf("could not scan category totals: %w", err)
+		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,93 @@
+package dbsqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestTransactionSplits(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "splitter"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	tx, _ := CreateTransaction(ctx, model.Transaction{Desc: "supermarket", Amount: 10000, IsDebt: true, UserID: u.ID}, db)
+
+	splits := []model.Split{
+		{Amount: 6000, Category: "food"},
+		{Amount: 2500, Category: "cleaning", Note: "detergent"},
+		{Amount: 1500, Category: "pharmacy"},
+	}
+	if err := SetTransactionSplits(ctx, tx.ID, splits, db); err != nil {
+		t.Fatalf("SetTransactionSplits() returned error: %v", err)
+	}
+
+	got, err := GetTransactionSplits(ctx, tx.ID, db)
+	if err != nil {
+		t.Fatalf("GetTransactionSplits() returned error: %v", err)
+	}
+	if len(got) != 3 || got[1].Amount != 2500 || got[1].Note != "detergent" || got[1].TransactionID != tx.ID || got[1].ID == 0 {
+		t.Errorf("GetTransactionSplits() = %+v", got)
+	}
+
+	if err := SetTransactionSplits(ctx, tx.ID, splits[:1], db); err != nil {
+		t.Fatalf("SetTransactionSplits() returned error: %v", err)
+	}
+	byUser, err := GetTransactionSplitsByUserID(ctx, u.ID, db)
+	if err != nil || len(byUser[tx.ID]) != 1 || byUser[tx.ID][0].Category != "food" {
+		t.Errorf("GetTransactionSplitsByUserID() = %+v, %v", byUser, err)
+	}
+
+	if err := SetTransactionSplits(ctx, tx.ID, nil, db); err != nil {
+		t.Fatalf("SetTransactionSplits() returned error: %v", err)
+	}
+	if all, _ := GetAllTransactionSplits(ctx, db); len(all) != 0 {
+		t.Errorf("GetAllTransactionSplits() after removing the splits = %+v, want none", all)
+	}
+}
+
+func TestGetCategoryTotals(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "categories"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	receipt, _ := CreateTransaction(ctx, model.Transaction{Desc: "supermarket", Amount: 10000, IsDebt: true, UserID: u.ID, Category: "groceries"}, db)
+	if err := SetTransactionSplits(ctx, receipt.ID, []model.Split{{Amount: 7000, Category: "food"}, {Amount: 2000, Category: "cleaning"}, {Amount: 1000}}, db); err != nil {
+		t.Fatalf("SetTransactionSplits() returned error: %v", err)
+	}
+	if _, err := CreateTransaction(ctx, model.Transaction{Desc: "bakery", Amount: 800, IsDebt: true, UserID: u.ID, Category: "food"}, db); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if _, err := CreateTransaction(ctx, model.Transaction{Desc: "salary", Amount: 500000, UserID: u.ID}, db); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+
+	totals, err := GetCategoryTotals(ctx, u.ID, "0000-01-01 00:00:00", "9999-12-31 23:59:59", db)
+	if err != nil {
+		t.Fatalf("GetCategoryTotals() returned error: %v", err)
+	}
+	want := []model.CategoryTotal{
+		{Category: "", Transactions: 1, Income: 500000},
+		{Category: "cleaning", Transactions: 1, Expenses: 2000},
+		{Category: "food", Transactions: 2, Expenses: 7800},
+		{Category: "groceries", Transactions: 1, Expenses: 1000},
+	}
+	if !slices.Equal(totals, want) {
+		t.Errorf("GetCategoryTotals() = %+v, want %+v", totals, want)
+	}
+
+	if none, err := GetCategoryTotals(ctx, u.ID, "2000-01-01 00:00:00", "2000-02-01 00:00:00", db); err != nil || len(none) != 0 {
+		t.Errorf("GetCategoryTotals() outside the range = %+v, %v; want none", none, err)
+	}
+}
@@ -19,19 +19,316 @@ CREATE TABLE IF NOT EXISTS monthly_adjustments_log(
 	applied_at TEXT DEFAULT CURRENT_TIMESTAMP
 );`
 
+const createGoalParticipantsTableSQL = `
+CREATE TABLE IF NOT EXISTS goal_participants(
+	goal_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	target_share REAL NOT NULL DEFAULT 0,
+	joined_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(goal_id, user_id),
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createGoalContributionsTableSQL = `
+CREATE TABLE IF NOT EXISTS goal_contributions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	goal_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	amount REAL NOT NULL,
+	note TEXT,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createNotificationsTableSQL = `
+CREATE TABLE IF NOT EXISTS notifications(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	message TEXT NOT NULL,
+	dedup_key TEXT NOT NULL UNIQUE,
+	is_read INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createNotificationDeliveriesTableSQL = `
+CREATE TABLE IF NOT EXISTS notification_deliveries(
+	notification_id INTEGER NOT NULL,
+	channel TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TEXT,
+	delivered_at TEXT,
+	PRIMARY KEY(notification_id, channel),
+	FOREIGN KEY(notification_id) REFERENCES notifications(id) ON DELETE CASCADE
+);`
+
+const createUserNotificationChannelsTableSQL = `
+CREATE TABLE IF NOT EXISTS user_notification_channels(
+	user_id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL DEFAULT '',
+	webhook_url TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createMonthlyAdjustmentEntriesTableSQL = `
+CREATE TABLE IF NOT EXISTS monthly_adjustment_entries(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	year_month TEXT NOT NULL,
+	inputs_applied REAL NOT NULL,
+	outputs_applied REAL NOT NULL,
+	balance_before REAL NOT NULL,
+	balance_after REAL NOT NULL,
+	applied_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, year_month),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createUserAdjustmentSettingsTableSQL = `
+CREATE TABLE IF NOT EXISTS user_adjustment_settings(
+	user_id INTEGER PRIMARY KEY,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	pay_day INTEGER NOT NULL DEFAULT 1,
+	timezone TEXT NOT NULL DEFAULT '',
+	last_period TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createJobsTableSQL = `
+CREATE TABLE IF NOT EXISTS jobs(
+	name TEXT PRIMARY KEY,
+	schedule TEXT NOT NULL,
+	next_run_at TEXT NOT NULL
+);`
+
+const createJobRunsTableSQL = `
+CREATE TABLE IF NOT EXISTS job_runs(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job_name TEXT NOT NULL,
+	triggered_by TEXT NOT NULL,
+	scheduled_for TEXT,
+	started_at TEXT NOT NULL,
+	finished_at TEXT,
+	status TEXT NOT NULL,
+	error TEXT,
+	instance TEXT,
+	FOREIGN KEY(job_name) REFERENCES jobs(name) ON DELETE CASCADE
+);`
+
+const createSchedulerLeasesTableSQL = `
+CREATE TABLE IF NOT EXISTS scheduler_leases(
+	name TEXT PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);`
+
+const createTagsTableSQL = `
+CREATE TABLE IF NOT EXISTS tags(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	UNIQUE(user_id, name),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createTransactionTagsTableSQL = `
+CREATE TABLE IF NOT EXISTS transaction_tags(
+	transaction_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY(transaction_id, tag_id),
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);`
+
+const createGoalTagsTableSQL = `
+CREATE TABLE IF NOT EXISTS goal_tags(
+	goal_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY(goal_id, tag_id),
+	FOREIGN KEY(goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+	FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);`
+
+const createTransactionSplitsTableSQL = `
+CREATE TABLE IF NOT EXISTS transaction_splits(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	transaction_id INTEGER NOT NULL,
+	amount REAL NOT NULL,
+	category TEXT NOT NULL DEFAULT '',
+	note TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);`
+
+const createAttachmentsTableSQL = `
+CREATE TABLE IF NOT EXISTS attachments(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	transaction_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	sha256 TEXT NOT NULL,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments(sha256);`
+
+const createCredentialsTableSQL = `
+CREATE TABLE IF NOT EXISTS credentials(
+	user_id INTEGER PRIMARY KEY,
+	login TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	updated_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createSessionsTableSQL = `
+CREATE TABLE IF NOT EXISTS sessions(
+	token_hash TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createAPITokensTableSQL = `
+CREATE TABLE IF NOT EXISTS api_tokens(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT,
+	last_used_at TEXT,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createHouseholdsTableSQL = `
+CREATE TABLE IF NOT EXISTS households(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP
+);`
+
+const createHouseholdMembersTableSQL = `
+CREATE TABLE IF NOT EXISTS household_members(
+	household_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL UNIQUE,
+	role TEXT NOT NULL DEFAULT 'member',
+	joined_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(household_id, user_id),
+	FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createHouseholdInvitesTableSQL = `
+CREATE TABLE IF NOT EXISTS household_invites(
+	household_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL DEFAULT 'member',
+	invited_by INTEGER,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(household_id, user_id),
+	FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+const createAuditLogTableSQL = `
+CREATE TABLE IF NOT EXISTS audit_log(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entity TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor_id INTEGER,
+	actor_role TEXT NOT NULL DEFAULT '',
+	before TEXT,
+	after TEXT,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id);`
+
+const createRulesTableSQL = `
+CREATE TABLE IF NOT EXISTS rules(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	priority INTEGER NOT NULL DEFAULT 0,
+	description_contains TEXT NOT NULL DEFAULT '',
+	description_regex TEXT NOT NULL DEFAULT '',
+	min_amount REAL,
+	max_amount REAL,
+	is_debt INTEGER,
+	category TEXT NOT NULL DEFAULT '',
+	tags TEXT NOT NULL DEFAULT '[]',
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);`
+
+// schemaMigrations holds the statements that bring databases created by older versions
+// of fingo up to date. Every statement must be safe to run more than once.
+var schemaMigrations = []string{
+	createMonthlyAdjustmentsTableSQL,
+	createGoalParticipantsTableSQL,
+	createGoalContributionsTableSQL,
+	createNotificationsTableSQL,
+	createMonthlyAdjustmentEntriesTableSQL,
+	createUserAdjustmentSettingsTableSQL,
+	createJobsTableSQL,
+	createJobRunsTableSQL,
+	createSchedulerLeasesTableSQL,
+	createTagsTableSQL,
+	createTransactionTagsTableSQL,
+	createRulesTableSQL,
+	createGoalTagsTableSQL,
+	createTransactionSplitsTableSQL,
+	createAttachmentsTableSQL,
+	createCredentialsTableSQL,
+	createSessionsTableSQL,
+	createAPITokensTableSQL,
+	createHouseholdsTableSQL,
+	createHouseholdMembersTableSQL,
+	createAuditLogTableSQL,
+	createNotificationDeliveriesTableSQL,
+	createUserNotificationChannelsTableSQL,
+	createHouseholdInvitesTableSQL,
+}
+
+// columnMigration describes a column added to a table after the table was first released.
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// columnMigrations lists the columns EnsureSchema adds to existing tables that lack them.
+var columnMigrations = []columnMigration{
+	{"job_runs", "instance", "TEXT"},
+	{"transactions", "category", "TEXT"},
+	{"credentials", "role", "TEXT NOT NULL DEFAULT 'user'"},
+	{"users", "deleted_at", "TEXT"},
+	{"transactions", "deleted_at", "TEXT"},
+	{"goals", "deleted_at", "TEXT"},
+}
+
 // Compiler directive below
 //
 //go:embed schema.sql
 var schemaSQL string
 
 // CheckAndCreate verifies if the database file exists and creates it with the schema if not.
-// For existing databases, it ensures the monthly_adjustments_log table exists (migration).
+// For existing databases, it runs the schema migrations so newer tables are present.
 func CheckAndCreate() error {
 	if _, err := os.Stat("fingo.db"); err == nil {
 		log.Printf("fingo.db already detected! Skipping database creation...")
-		// Ensure the monthly_adjustments_log table exists for existing databases
-		if err := EnsureMonthlyAdjustmentsTable(); err != nil {
-			return fmt.Errorf("failed to ensure monthly_adjustments_log table: %w", err)
+		if err := EnsureSchema(); err != nil {
+			return fmt.Errorf("failed to migrate existing database: %w", err)
 		}
 		return nil
 	}
@@ -43,24 +340,65 @@ func CheckAndCreate() error {
 	return nil
 }
 
-// EnsureMonthlyAdjustmentsTable creates the monthly_adjustments_log table if it doesn't already exist.
-// This acts as a migration for databases that were created before the monthly adjustment feature.
-func EnsureMonthlyAdjustmentsTable() error {
+// EnsureSchema creates any table that is missing from an existing database.
+// This acts as a migration for databases that were created before newer features.
+func EnsureSchema() error {
 	db, err := GetDatabaseConnection()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	_, err = db.Exec(createMonthlyAdjustmentsTableSQL)
-	if err != nil {
-		return fmt.Errorf("failed to create monthly_adjustments_log table: %w", err)
+	for _, stmt := range schemaMigrations {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to run schema migration: %w", err)
+		}
+	}
+
+	for _, m := range columnMigrations {
+		if err := ensureColumn(db, m); err != nil {
+			return err
+		}
+	}
+
+	log.Println("Database schema ensured.")
+	return nil
+}
+
+// ensureColumn adds a column to a table unless the table already has it. Tables that do not exist are
+// left alone, since they are not fingo's to create here.
+func ensureColumn(db *sql.DB, m columnMigration) error {
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, m.table).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to look up table %s: %w", m.table, err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	exists, err := hasColumn(db, m.table, m.column)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
 	}
 
-	log.Println("monthly_adjustments_log table ensured.")
 	return nil
 }
 
+// hasColumn reports whether the table has a column with the given name.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if err := db.QueryRow(query, table, column).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	return count > 0, nil
+}
+
 // GetDatabaseConnection opens and returns a connection to the SQLite database.
 func GetDatabaseConnection() (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", "file:fingo.db")
@@ -76,3 +76,84 @@ func TestDatabaseLifecycle(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureSchema_AddsMissingColumns(t *testing.T) {
+	_ = os.Remove("fingo.db")
+	defer func() { _ = os.Remove("fingo.db") }()
+
+	db, err := GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("GetDatabaseConnection() returned error: %v", err)
+	}
+	defer db.Close()
+
+	// job_runs as it was created before the instance column existed
+	const oldJobRuns = `CREATE TABLE job_runs(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_name TEXT NOT NULL,
+		triggered_by TEXT NOT NULL,
+		scheduled_for TEXT,
+		started_at TEXT NOT NULL,
+		finished_at TEXT,
+		status TEXT NOT NULL,
+		error TEXT
+	);`
+	if _, err := db.Exec(oldJobRuns); err != nil {
+		t.Fatalf("setup: could not create old job_runs table: %v", err)
+	}
+
+	// transactions as it was created before the category column existed
+	const oldTransactions = `CREATE TABLE transactions(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		description TEXT,
+		amount REAL NOT NULL,
+		is_debt INTEGER NOT NULL,
+		created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+		user_id INTEGER NOT NULL
+	);`
+	if _, err := db.Exec(oldTransactions); err != nil {
+		t.Fatalf("setup: could not create old transactions table: %v", err)
+	}
+
+	// users and goals as they were created before soft deletion
+	const oldUsers = `CREATE TABLE users(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_name TEXT NOT NULL,
+		current_amount REAL NOT NULL,
+		monthly_inputs REAL NOT NULL,
+		monthly_outputs REAL NOT NULL
+	);`
+	const oldGoals = `CREATE TABLE goals(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT,
+		price REAL,
+		pros TEXT,
+		cons TEXT,
+		user_id INTEGER NOT NULL,
+		created_at TEXT DEFAULT CURRENT_TIMESTAMP,
+		deadline TEXT NOT NULL
+	);`
+	for _, stmt := range []string{oldUsers, oldGoals} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("setup: could not create old table: %v", err)
+		}
+	}
+
+	// Running the migrations twice must be safe
+	for i := 0; i < 2; i++ {
+		if err := EnsureSchema(); err != nil {
+			t.Fatalf("EnsureSchema() run %d returned error: %v", i+1, err)
+		}
+	}
+
+	for _, m := range columnMigrations {
+		ok, err := hasColumn(db, m.table, m.column)
+		if err != nil {
+			t.Fatalf("hasColumn() returned error: %v", err)
+		}
+		if !ok {
+			t.Errorf("expected %s.%s to be added", m.table, m.column)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// GetStatement reads, in a single read-only transaction, what a monthly statement of a user needs for the
+// range from (inclusive) to to (exclusive), both "YYYY-MM-DD HH:MM:SS" timestamps in UTC: the closing
+// balance, the income, expenses and adjustments of the range, its transactions in chronological order, the
+// adjustment entry of yearMonth and the progress, as of the end of the range, of the goals the user owns or
+// shares. The opening balance and dates are left for the caller.
+func GetStatement(ctx context.Context, userID int64, yearMonth, from, to string, db *sql.DB) (*model.Statement, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	s := &model.Statement{UserID: userID, YearMonth: yearMonth}
+
+	if err := tx.QueryRowContext(ctx, `SELECT user_name, current_amount FROM users WHERE id = ?`, userID).Scan(&s.UserName, &s.ClosingBalance); err != nil {
+		return nil, fmt.Errorf("could not get the user for the statement: %w", err)
+	}
+
+	// The closing balance is the current one minus everything recorded after the range
+	const totalsQuery = `
+		SELECT
+			CAST(ROUND(COALESCE(SUM(CASE WHEN kind = 'income' AND at >= ?2 AND at < ?3 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN kind = 'expense' AND at >= ?2 AND at < ?3 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN kind = 'adjustment' AND at >= ?2 AND at < ?3 THEN amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN at >= ?3 THEN CASE WHEN kind = 'expense' THEN -amount ELSE amount END END), 0)) AS INTEGER)
+		FROM (
+			SELECT CASE WHEN is_debt = 1 THEN 'expense' ELSE 'income' END AS kind, amount, created_at AS at
+			FROM transactions WHERE user_id = ?1 AND created_at >= ?2 AND deleted_at IS NULL
+			UNION ALL
+			SELECT 'adjustment', balance_after - balance_before, applied_at
+			FROM monthly_adjustment_entries WHERE user_id = ?1 AND applied_at >= ?2
+		)`
+
+	var later utils.Money
+	if err := tx.QueryRowContext(ctx, totalsQuery, userID, from, to).Scan(&s.Income, &s.Expenses, &s.Adjustments, &later); err != nil {
+		return nil, fmt.Errorf("could not execute the query for statement totals: %w", err)
+	}
+	s.ClosingBalance -= later
+
+	const transactionsQuery = `
+		SELECT id, COALESCE(description, ''), amount, is_debt, created_at, user_id FROM transactions
+		WHERE user_id = ? AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		ORDER BY created_at, id`
+
+	rows, err := tx.QueryContext(ctx, transactionsQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for statement transactions: %w", err)
+	}
+	defer rows.Close()
+
+	s.Transactions = []model.Transaction{}
+	for rows.Next() {
+		var t model.Transaction
+		if err := rows.Scan(&t.ID, &t.Desc, &t.Amount, &t.IsDebt, &t.CreatedAt, &t.UserID); err != nil {
+			return nil, fmt.Errorf("could not send the rows data to transaction struct: %w", err)
+		}
+		s.Transactions = append(s.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	const adjustmentQuery = `
+		SELECT id, user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after, applied_at
+		FROM monthly_adjustment_entries WHERE user_id = ? AND year_month = ?`
+
+	var entry model.AdjustmentEntry
+	err = tx.QueryRowContext(ctx, adjustmentQuery, userID, yearMonth).
+		Scan(&entry.ID, &entry.UserID, &entry.YearMonth, &entry.InputsApplied, &entry.OutputsApplied, &entry.BalanceBefore, &entry.BalanceAfter, &entry.AppliedAt)
+	switch {
+	case err == nil:
+		s.Adjustment = &entry
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("could not get the adjustment entry for the statement: %w", err)
+	}
+
+	const goalsQuery = `
+		SELECT g.id, g.name, CAST(ROUND(COALESCE(g.price, 0)) AS INTEGER), g.deadline, g.user_id <> ?1,
+			CAST(ROUND(COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id AND c.created_at < ?2), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id AND c.user_id = ?1 AND c.created_at < ?2), 0)) AS INTEGER)
+		FROM goals g
+		WHERE (g.user_id = ?1 OR g.id IN (SELECT goal_id FROM goal_participants WHERE user_id = ?1)) AND g.created_at < ?2 AND g.deleted_at IS NULL
+		ORDER BY g.deadline, g.id`
+
+	goalRows, err := tx.QueryContext(ctx, goalsQuery, userID, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for statement goals: %w", err)
+	}
+	defer goalRows.Close()
+
+	s.Goals = []model.GoalProgress{}
+	for goalRows.Next() {
+		var g model.GoalProgress
+		if err := goalRows.Scan(&g.GoalID, &g.Name, &g.Price, &g.Deadline, &g.Shared, &g.Contributed, &g.UserContributed); err != nil {
+			return nil, fmt.Errorf("could not scan goal progress: %w", err)
+		}
+		s.Goals = append(s.Goals, g)
+	}
+	if err := goalRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,73 @@
+package dbsqlite
+
+import (
+	"context"
+	"testing"
+
+	"natan/fingo/utils"
+)
+
+func TestGetStatement(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	goals := []struct {
+		name, createdAt string
+	}{
+		{"bike", "2029-06-01 00:00:00"},
+		{"future", "2030-03-01 00:00:00"},
+	}
+	for _, g := range goals {
+		if _, err := db.Exec(`INSERT INTO goals(name, price, user_id, deadline, created_at) VALUES (?, 10000, ?, '2030-12-31', ?)`, g.name, userID, g.createdAt); err != nil {
+			t.Fatalf("could not insert goal: %v", err)
+		}
+	}
+	contributions := []string{"2030-01-20 00:00:00", "2030-02-01 00:00:00"}
+	for _, at := range contributions {
+		if _, err := db.Exec(`INSERT INTO goal_contributions(goal_id, user_id, amount, created_at) VALUES ((SELECT id FROM goals WHERE name = 'bike'), ?, 2500, ?)`, userID, at); err != nil {
+			t.Fatalf("could not insert contribution: %v", err)
+		}
+	}
+
+	tests := []struct {
+		yearMonth, from, to string
+		income, expenses    utils.Money
+		adjustments         utils.Money
+		closing             utils.Money
+		transactions        int
+		hasAdjustment       bool
+		contributed         utils.Money
+	}{
+		{"2030-01", "2030-01-01 00:00:00", "2030-02-01 00:00:00", 0, 8000, 0, -(20000 + 2000 - 999), 3, false, 2500},
+		{"2030-02", "2030-02-01 00:00:00", "2030-03-01 00:00:00", 20000, 0, 2000, 999, 1, true, 5000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.yearMonth, func(t *testing.T) {
+			s, err := GetStatement(ctx, userID, tc.yearMonth, tc.from, tc.to, db)
+			if err != nil {
+				t.Fatalf("GetStatement() returned error: %v", err)
+			}
+			if s.UserName != "reported" || s.Income != tc.income || s.Expenses != tc.expenses || s.Adjustments != tc.adjustments || s.ClosingBalance != tc.closing {
+				t.Errorf("unexpected totals: %+v", s)
+			}
+			if len(s.Transactions) != tc.transactions {
+				t.Errorf("expected %d transactions, got %d", tc.transactions, len(s.Transactions))
+			}
+			for i := 1; i < len(s.Transactions); i++ {
+				if s.Transactions[i].CreatedAt < s.Transactions[i-1].CreatedAt {
+					t.Errorf("transactions are not in chronological order: %+v", s.Transactions)
+				}
+			}
+			if (s.Adjustment != nil) != tc.hasAdjustment {
+				t.Errorf("adjustment = %+v, want present %v", s.Adjustment, tc.hasAdjustment)
+			}
+			if len(s.Goals) != 1 || s.Goals[0].Name != "bike" || s.Goals[0].Contributed != tc.contributed {
+				t.Errorf("unexpected goals: %+v", s.Goals)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+// execer is implemented by both *sql.DB and *sql.Tx, so statements can run inside or outside a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// tagLink describes a table linking tags to the rows of another table.
+type tagLink struct {
+	table  string
+	column string
+}
+
+var (
+	transactionTagLink = tagLink{"transaction_tags", "transaction_id"}
+	goalTagLink        = tagLink{"goal_tags", "goal_id"}
+)
+
+// addTags links the named tags of a user to a row, creating the tags that do not exist yet.
+// Tags already linked are left alone.
+func addTags(ctx context.Context, ex execer, link tagLink, rowID, userID int64, names []string) error {
+	const insertTag = `INSERT OR IGNORE INTO tags(user_id, name) VALUES (?, ?)`
+	attachTag := fmt.Sprintf(`
+		INSERT OR IGNORE INTO %s(%s, tag_id)
+		SELECT ?, id FROM tags WHERE user_id = ? AND name = ?`, link.table, link.column)
+
+	for _, name := range names {
+		if _, err := ex.ExecContext(ctx, insertTag, userID, name); err != nil {
+			return fmt.Errorf("could not create tag %q: %w", name, err)
+		}
+		if _, err := ex.ExecContext(ctx, attachTag, rowID, userID, name); err != nil {
+			return fmt.Errorf("could not tag %s %d with %q: %w", link.column, rowID, name, err)
+		}
+	}
+
+	return nil
+}
+
+// setTags replaces the tags linked to a row with the named tags of a user, all or nothing.
+func setTags(ctx context.Context, link tagLink, rowID, userID int64, names []string, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", link.table, link.column), rowID); err != nil {
+		return fmt.Errorf("could not clear the tags of %s %d: %w", link.column, rowID, err)
+	}
+	if err := addTags(ctx, tx, link, rowID, userID, names); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit tags: %w", err)
+	}
+
+	return nil
+}
+
+// queryTagNames runs a query selecting a row ID and a tag name, and groups the names by row ID.
+func queryTagNames(ctx context.Context, db *sql.DB, query string, args ...any) (map[int64][]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("could not scan tag name: %w", err)
+		}
+		tags[id] = append(tags[id], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// getTaggedIDs retrieves the IDs of the rows linked to a tag, in the trash or not, in ascending order.
+func getTaggedIDs(ctx context.Context, link tagLink, tagID int64, db *sql.DB) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE tag_id = ? ORDER BY %[1]s", link.column, link.table), tagID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for rows tagged with %d: %w", tagID, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("could not scan tagged row ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetTaggedTransactionIDs retrieves the IDs of the transactions carrying a tag, including those in the trash.
+func GetTaggedTransactionIDs(ctx context.Context, tagID int64, db *sql.DB) ([]int64, error) {
+	return getTaggedIDs(ctx, transactionTagLink, tagID, db)
+}
+
+// GetTaggedGoalIDs retrieves the IDs of the goals carrying a tag, including those in the trash.
+func GetTaggedGoalIDs(ctx context.Context, tagID int64, db *sql.DB) ([]int64, error) {
+	return getTaggedIDs(ctx, goalTagLink, tagID, db)
+}
+
+// AddTransactionTags attaches the named tags of a user to a transaction, creating the tags that do not exist yet.
+func AddTransactionTags(ctx context.Context, transactionID, userID int64, names []string, db *sql.DB) error {
+	return addTags(ctx, db, transactionTagLink, transactionID, userID, names)
+}
+
+// SetTransactionTags replaces the tags of a transaction with the named tags of its owner.
+func SetTransactionTags(ctx context.Context, transactionID, userID int64, names []string, db *sql.DB) error {
+	return setTags(ctx, transactionTagLink, transactionID, userID, names, db)
+}
+
+// SetGoalTags replaces the tags of a goal with the named tags of its owner.
+func SetGoalTags(ctx context.Context, goalID, userID int64, names []string, db *sql.DB) error {
+	return setTags(ctx, goalTagLink, goalID, userID, names, db)
+}
+
+// GetTransactionTags retrieves the names of the tags attached to a transaction, in alphabetical order.
+func GetTransactionTags(ctx context.Context, transactionID int64, db *sql.DB) ([]string, error) {
+	const query = `
+		SELECT tt.transaction_id, g.name FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+		WHERE tt.transaction_id = ? ORDER BY g.name`
+
+	tags, err := queryTagNames(ctx, db, query, transactionID)
+	return tags[transactionID], err
+}
+
+// GetGoalTags retrieves the names of the tags attached to a goal, in alphabetical order.
+func GetGoalTags(ctx context.Context, goalID int64, db *sql.DB) ([]string, error) {
+	const query = `
+		SELECT gt.goal_id, g.name FROM goal_tags gt JOIN tags g ON g.id = gt.tag_id
+		WHERE gt.goal_id = ? ORDER BY g.name`
+
+	tags, err := queryTagNames(ctx, db, query, goalID)
+	return tags[goalID], err
+}
+
+// GetAllTransactionTags retrieves the names of the tags attached to every transaction, keyed by transaction
+// ID and in alphabetical order. Transactions without tags are left out.
+func GetAllTransactionTags(ctx context.Context, db *sql.DB) (map[int64][]string, error) {
+	const query = `
+		SELECT tt.transaction_id, g.name FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+		ORDER BY tt.transaction_id, g.name`
+
+	return queryTagNames(ctx, db, query)
+}
+
+// GetTransactionTagsByUserID retrieves the names of the tags attached to each transaction of a user, keyed
+// by transaction ID and in alphabetical order. Transactions without tags are left out.
+func GetTransactionTagsByUserID(ctx context.Context, userID int64, db *sql.DB) (map[int64][]string, error) {
+	const query = `
+		SELECT tt.transaction_id, g.name FROM transaction_tags tt
+		JOIN tags g ON g.id = tt.tag_id
+		JOIN transactions t ON t.id = tt.transaction_id
+		WHERE t.user_id = ? ORDER BY tt.transaction_id, g.name`
+
+	return queryTagNames(ctx, db, query, userID)
+}
+
+// GetAllGoalTags retrieves the names of the tags attached to every goal, keyed by goal ID and in
+// alphabetical order. Goals without tags are left out.
+func GetAllGoalTags(ctx context.Context, db *sql.DB) (map[int64][]string, error) {
+	const query = `
+		SELECT gt.goal_id, g.name FROM goal_tags gt JOIN tags g ON g.id = gt.tag_id
+		ORDER BY gt.goal_id, g.name`
+
+	return queryTagNames(ctx, db, query)
+}
+
+const selectTagColumns = `
+	SELECT t.id, t.user_id, t.name,
+		(SELECT COUNT(*) FROM transaction_tags tt WHERE tt.tag_id = t.id),
+		(SELECT COUNT(*) FROM goal_tags gt WHERE gt.tag_id = t.id)
+	FROM tags t`
+
+// GetTagsByUserID retrieves the tags of a user with their usage counts, in alphabetical order.
+func GetTagsByUserID(ctx context.Context, userID int64, db *sql.DB) ([]model.Tag, error) {
+	rows, err := db.QueryContext(ctx, selectTagColumns+" WHERE t.user_id = ? ORDER BY t.name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for tags using user_id: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []model.Tag{}
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Transactions, &tag.Goals); err != nil {
+			return nil, fmt.Errorf("could not scan the row into tag struct: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// getTag retrieves a single tag selected by the given condition.
+func getTag(ctx context.Context, db *sql.DB, where string, args ...any) (*model.Tag, error) {
+	var tag model.Tag
+	row := db.QueryRowContext(ctx, selectTagColumns+" WHERE "+where, args...)
+	if err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Transactions, &tag.Goals); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("tag", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into tag struct: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// GetTagByID retrieves a tag by its ID.
+func GetTagByID(ctx context.Context, id int64, db *sql.DB) (*model.Tag, error) {
+	return getTag(ctx, db, "t.id = ?", id)
+}
+
+// GetTagByName retrieves a tag of a user by its name.
+func GetTagByName(ctx context.Context, userID int64, name string, db *sql.DB) (*model.Tag, error) {
+	return getTag(ctx, db, "t.user_id = ? AND t.name = ?", userID, name)
+}
+
+// CreateTag inserts a new tag for a user.
+func CreateTag(ctx context.Context, userID int64, name string, db *sql.DB) (*model.Tag, error) {
+	res, err := db.ExecContext(ctx, `INSERT INTO tags(user_id, name) VALUES (?, ?)`, userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute insert into tags table: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("could not get the id of the new tag: %w", err)
+	}
+
+	return GetTagByID(ctx, id, db)
+}
+
+// RenameTag changes the name of a tag, which every transaction and goal carrying it follows.
+func RenameTag(ctx context.Context, id int64, name string, db *sql.DB) (*model.Tag, error) {
+	res, err := db.ExecContext(ctx, `UPDATE tags SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the update query for tag: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return nil, model.NotFoundError("tag", sql.ErrNoRows)
+	}
+
+	return GetTagByID(ctx, id, db)
+}
+
+// DeleteTagByID deletes a tag and detaches it from every transaction and goal, all or nothing.
+func DeleteTagByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, link := range []tagLink{transactionTagLink, goalTagLink} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE tag_id = ?", link.table), id); err != nil {
+			return 0, fmt.Errorf("could not detach tag %d: %w", id, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not execute the delete query for tag: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected for delete: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit tag deletion: %w", err)
+	}
+
+	return rows, nil
+}
+
+// GetTagTotals sums, for every tag of a user, the transactions carrying it that were created between from
+// (inclusive) and to (exclusive), both "YYYY-MM-DD HH:MM:SS" timestamps in UTC, and the goals tagged with it.
+// Tags are in alphabetical order; Net is left for the caller.
+func GetTagTotals(ctx context.Context, userID int64, from, to string, db *sql.DB) ([]model.TagTotal, error) {
+	const query = `
+		SELECT g.name, COUNT(t.id),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN t.is_debt = 0 THEN t.amount END), 0)) AS INTEGER),
+			CAST(ROUND(COALESCE(SUM(CASE WHEN t.is_debt = 1 THEN t.amount END), 0)) AS INTEGER),
+			(SELECT COUNT(*) FROM goal_tags gt JOIN goals o ON o.id = gt.goal_id WHERE gt.tag_id = g.id AND o.deleted_at IS NULL),
+			CAST(ROUND(COALESCE((SELECT SUM(o.price) FROM goal_tags gt JOIN goals o ON o.id = gt.goal_id WHERE gt.tag_id = g.id AND o.deleted_at IS NULL), 0)) AS INTEGER)
+		FROM tags g
+		LEFT JOIN transaction_tags tt ON tt.tag_id = g.id
+		LEFT JOIN transactions t ON t.id = tt.transaction_id AND t.created_at >= ? AND t.created_at < ? AND t.deleted_at IS NULL
+		WHERE g.user_id = ?
+		GROUP BY g.id
+		ORDER BY g.name`
+
+	rows, err := db.QueryContext(ctx, query, from, to, userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for tag totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []model.TagTotal{}
+	for rows.Next() {
+		var total model.TagTotal
+		if err := rows.Scan(&total.Tag, &total.Transactions, &total.Income, &total.Expenses, &total.Goals, &total.GoalsPrice); err != nil {
+			return nil, fmt.Errorf("could not scan tag totals: %w", err)
+		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return totals, nil
+}
+
+// GetGoalTagsByUserID retrieves the names of the tags attached to each goal a user owns or participates in,
+// keyed by goal ID and in alphabetical order. Goals without tags are left out.
+func GetGoalTagsByUserID(ctx context.Context, userID int64, db *sql.DB) (map[int64][]string, error) {
+	const query = `
+		SELECT gt.goal_id, g.name FROM goal_tags gt
+		JOIN tags g ON g.id = gt.tag_id
+		JOIN goals o ON o.id = gt.goal_id
+		WHERE o.user_id = ? OR o.id IN (SELECT goal_id FROM goal_participants WHERE user_id = ?)
+		ORDER BY gt.goal_id, g.name`
+
+	return queryTagNames(ctx, db, query, userID, userID)
+}
@@ -0,0 +1,138 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestTags_CRUD(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "tagger"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	tx, _ := CreateTransaction(ctx, model.Transaction{Desc: "flight", Amount: 90000, IsDebt: true, UserID: u.ID}, db)
+	goal, _ := CreateGoal(ctx, model.Goal{Name: "hotel", Price: 50000, UserID: u.ID, Deadline: "2030-01-01"}, db)
+
+	tag, err := CreateTag(ctx, u.ID, "reimbursable", db)
+	if err != nil {
+		t.Fatalf("CreateTag() returned error: %v", err)
+	}
+	if _, err := CreateTag(ctx, u.ID, "reimbursable", db); err == nil {
+		t.Errorf("CreateTag() with a duplicate name expected error, got nil")
+	}
+
+	if err := SetTransactionTags(ctx, tx.ID, u.ID, []string{"vacation", "reimbursable"}, db); err != nil {
+		t.Fatalf("SetTransactionTags() returned error: %v", err)
+	}
+	if err := SetGoalTags(ctx, goal.ID, u.ID, []string{"vacation"}, db); err != nil {
+		t.Fatalf("SetGoalTags() returned error: %v", err)
+	}
+
+	tags, err := GetTagsByUserID(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetTagsByUserID() returned error: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "reimbursable" || tags[0].Transactions != 1 || tags[0].Goals != 0 ||
+		tags[1].Name != "vacation" || tags[1].Transactions != 1 || tags[1].Goals != 1 {
+		t.Errorf("GetTagsByUserID() = %+v", tags)
+	}
+
+	// Replacing the set drops the tags left out
+	if err := SetTransactionTags(ctx, tx.ID, u.ID, []string{"vacation"}, db); err != nil {
+		t.Fatalf("SetTransactionTags() returned error: %v", err)
+	}
+	if got, _ := GetTransactionTags(ctx, tx.ID, db); !slices.Equal(got, []string{"vacation"}) {
+		t.Errorf("GetTransactionTags() = %v, want [vacation]", got)
+	}
+
+	renamed, err := RenameTag(ctx, tags[1].ID, "vacation-2026", db)
+	if err != nil || renamed.Name != "vacation-2026" {
+		t.Fatalf("RenameTag() = %+v, %v", renamed, err)
+	}
+	if got, _ := GetGoalTags(ctx, goal.ID, db); !slices.Equal(got, []string{"vacation-2026"}) {
+		t.Errorf("GetGoalTags() after rename = %v", got)
+	}
+	byUser, err := GetGoalTagsByUserID(ctx, u.ID, db)
+	if err != nil || !slices.Equal(byUser[goal.ID], []string{"vacation-2026"}) {
+		t.Errorf("GetGoalTagsByUserID() = %v, %v", byUser, err)
+	}
+	if byName, err := GetTagByName(ctx, u.ID, "vacation-2026", db); err != nil || byName.ID != renamed.ID {
+		t.Errorf("GetTagByName() = %+v, %v", byName, err)
+	}
+
+	rows, err := DeleteTagByID(ctx, renamed.ID, db)
+	if err != nil || rows != 1 {
+		t.Fatalf("DeleteTagByID() = %d, %v; want 1, nil", rows, err)
+	}
+	if got, _ := GetTransactionTags(ctx, tx.ID, db); len(got) != 0 {
+		t.Errorf("GetTransactionTags() after delete = %v, want none", got)
+	}
+	if all, _ := GetAllGoalTags(ctx, db); len(all) != 0 {
+		t.Errorf("GetAllGoalTags() after delete = %v, want none", all)
+	}
+	if _, err := GetTagByID(ctx, tag.ID, db); err != nil {
+		t.Errorf("GetTagByID() for the remaining tag returned error: %v", err)
+	}
+	if _, err := RenameTag(ctx, renamed.ID, "gone", db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RenameTag() for a deleted tag error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestGetTagTotals(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "totals"}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	record := func(amount int64, isDebt bool, createdAt string, tags ...string) {
+		t.Helper()
+		tx, err := CreateTransaction(ctx, model.Transaction{Desc: "fixture", Amount: 0, IsDebt: isDebt, UserID: u.ID}, db)
+		if err != nil {
+			t.Fatalf("CreateTransaction() returned error: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE transactions SET amount = ?, created_at = ? WHERE id = ?`, amount, createdAt, tx.ID); err != nil {
+			t.Fatalf("setup: could not backdate transaction: %v", err)
+		}
+		if err := AddTransactionTags(ctx, tx.ID, u.ID, tags, db); err != nil {
+			t.Fatalf("AddTransactionTags() returned error: %v", err)
+		}
+	}
+	record(30000, true, "2030-03-05 10:00:00", "trip")
+	record(12000, true, "2030-03-06 10:00:00", "trip", "reimbursable")
+	record(12000, false, "2030-03-20 10:00:00", "reimbursable")
+	record(9900, true, "2030-04-02 10:00:00", "trip")
+
+	goal, _ := CreateGoal(ctx, model.Goal{Name: "camera", Price: 250000, UserID: u.ID, Deadline: "2030-06-01"}, db)
+	if err := SetGoalTags(ctx, goal.ID, u.ID, []string{"trip"}, db); err != nil {
+		t.Fatalf("SetGoalTags() returned error: %v", err)
+	}
+	if _, err := CreateTag(ctx, u.ID, "unused", db); err != nil {
+		t.Fatalf("CreateTag() returned error: %v", err)
+	}
+
+	totals, err := GetTagTotals(ctx, u.ID, "2030-03-01 00:00:00", "2030-04-01 00:00:00", db)
+	if err != nil {
+		t.Fatalf("GetTagTotals() returned error: %v", err)
+	}
+	want := []model.TagTotal{
+		{Tag: "reimbursable", Transactions: 2, Income: 12000, Expenses: 12000},
+		{Tag: "trip", Transactions: 2, Expenses: 42000, Goals: 1, GoalsPrice: 250000},
+		{Tag: "unused"},
+	}
+	if !slices.Equal(totals, want) {
+		t.Errorf("GetTagTotals() = %+v, want %+v", totals, want)
+	}
+}
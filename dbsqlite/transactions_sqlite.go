@@ -11,7 +11,7 @@ import (
 
 // GetAllTransactions retrieves all transactions from the database
 func GetAllTransactions(ctx context.Context, db *sql.DB) ([]model.Transaction, error) {
-	const query = "SELECT id, description, amount, is_debt, created_at, user_id FROM transactions"
+	const query = "SELECT id, description, amount, is_debt, created_at, user_id, COALESCE(category, '') FROM transactions WHERE deleted_at IS NULL"
 	var transactionsList []model.Transaction
 
 	rows, err := db.QueryContext(ctx, query)
@@ -21,7 +21,7 @@ func GetAllTransactions(ctx context.Context, db *sql.DB) ([]model.Transaction, e
 	defer rows.Close()
 	for rows.Next() {
 		var transaction model.Transaction
-		if err := rows.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID); err != nil {
+		if err := rows.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID, &transaction.Category); err != nil {
 			return nil, fmt.Errorf("could not send the rows data to transaction struct: %w", err)
 		}
 		transactionsList = append(transactionsList, transaction)
@@ -35,7 +35,7 @@ func GetAllTransactions(ctx context.Context, db *sql.DB) ([]model.Transaction, e
 }
 
 func GetAllTransactionsByUserID(ctx context.Context, id int64, db *sql.DB) ([]model.Transaction, error) {
-	const query = "SELECT id, description, amount, is_debt, created_at, user_id FROM transactions WHERE user_id = ?"
+	const query = "SELECT id, description, amount, is_debt, created_at, user_id, COALESCE(category, '') FROM transactions WHERE user_id = ? AND deleted_at IS NULL"
 	var transactionsList []model.Transaction
 
 	rows, err := db.QueryContext(ctx, query, id)
@@ -47,7 +47,7 @@ func GetAllTransactionsByUserID(ctx context.Context, id int64, db *sql.DB) ([]mo
 
 	for rows.Next() {
 		var transaction model.Transaction
-		if err := rows.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID); err != nil {
+		if err := rows.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID, &transaction.Category); err != nil {
 			return nil, fmt.Errorf("could not send the rows data to transaction struct: %w", err)
 		}
 		transactionsList = append(transactionsList, transaction)
@@ -61,9 +61,9 @@ func GetAllTransactionsByUserID(ctx context.Context, id int64, db *sql.DB) ([]mo
 
 // CreateTransaction inserts a new transaction into the database
 func CreateTransaction(ctx context.Context, transaction model.Transaction, db *sql.DB) (*model.Transaction, error) {
-	const createStmt = "INSERT INTO transactions(description, amount, is_debt, user_id)VALUES(?,?,?,?)"
+	const createStmt = "INSERT INTO transactions(description, amount, is_debt, user_id, category)VALUES(?,?,?,?,NULLIF(?, ''))"
 
-	res, err := db.ExecContext(ctx, createStmt, transaction.Desc, transaction.Amount, transaction.IsDebt, transaction.UserID)
+	res, err := db.ExecContext(ctx, createStmt, transaction.Desc, transaction.Amount, transaction.IsDebt, transaction.UserID, transaction.Category)
 	if err != nil {
 		return nil, fmt.Errorf("could not execute insert into transaction table: %w", err)
 	}
@@ -77,13 +77,13 @@ func CreateTransaction(ctx context.Context, transaction model.Transaction, db *s
 
 // GetTransactionByID retrieves a transaction by its ID
 func GetTransactionByID(ctx context.Context, id int64, db *sql.DB) (*model.Transaction, error) {
-	const selectStmt = "SELECT id, description, amount, is_debt, created_at, user_id FROM transactions WHERE id = ?"
+	const selectStmt = "SELECT id, description, amount, is_debt, created_at, user_id, COALESCE(category, '') FROM transactions WHERE id = ? AND deleted_at IS NULL"
 
 	var transaction model.Transaction
 	row := db.QueryRowContext(ctx, selectStmt, id)
-	if err := row.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID); err != nil {
+	if err := row.Scan(&transaction.ID, &transaction.Desc, &transaction.Amount, &transaction.IsDebt, &transaction.CreatedAt, &transaction.UserID, &transaction.Category); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("transaction not found: %w", err)
+			return nil, model.NotFoundError("transaction", err)
 		}
 		return nil, fmt.Errorf("could not scan the row into transaction struct: %w", err)
 	}
@@ -112,7 +112,7 @@ func DeleteTransactionByID(ctx context.Context, id int64, db *sql.DB) (int64, er
 // Fields not provided (nil) are not updated, preserving existing values
 func UpdateTransactionPartialByID(ctx context.Context, id int64, update *model.TransactionUpdate, db *sql.DB) (*model.Transaction, error) {
 	if update == nil {
-		return nil, fmt.Errorf("update data cannot be nil")
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
 	}
 
 	// Verify that the transaction exists
@@ -140,13 +140,18 @@ func UpdateTransactionPartialByID(ctx context.Context, id int64, update *model.T
 		args = append(args, *update.IsDebt)
 	}
 
+	if update.Category != nil {
+		setParts = append(setParts, "category = NULLIF(?, '')")
+		args = append(args, *update.Category)
+	}
+
 	// If no fields are provided, return the current transaction without modifications
 	if len(setParts) == 0 {
 		return GetTransactionByID(ctx, id, db)
 	}
 
 	// Build and execute the dynamic UPDATE statement
-	updateStmt := fmt.Sprintf("UPDATE transactions SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	updateStmt := fmt.Sprintf("UPDATE transactions SET %s WHERE id = ? AND deleted_at IS NULL", strings.Join(setParts, ", "))
 	args = append(args, id)
 
 	res, err := db.ExecContext(ctx, updateStmt, args...)
@@ -160,9 +165,38 @@ func UpdateTransactionPartialByID(ctx context.Context, id int64, update *model.T
 	}
 
 	if affected == 0 {
-		return nil, sql.ErrNoRows
+		return nil, model.NotFoundError("transaction", sql.ErrNoRows)
 	}
 
 	// Fetch and return the updated transaction
 	return GetTransactionByID(ctx, id, db)
 }
+
+// GetDebtTransactionsBetween retrieves the debt transactions of a user created between from (inclusive) and
+// to (exclusive), both "YYYY-MM-DD HH:MM:SS" timestamps in UTC, in chronological order.
+func GetDebtTransactionsBetween(ctx context.Context, userID int64, from, to string, db *sql.DB) ([]model.Transaction, error) {
+	const query = `
+		SELECT id, COALESCE(description, ''), amount, is_debt, created_at, user_id FROM transactions
+		WHERE user_id = ? AND is_debt = 1 AND created_at >= ? AND created_at < ? AND deleted_at IS NULL
+		ORDER BY created_at, id`
+
+	rows, err := db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute the query for debt transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []model.Transaction
+	for rows.Next() {
+		var t model.Transaction
+		if err := rows.Scan(&t.ID, &t.Desc, &t.Amount, &t.IsDebt, &t.CreatedAt, &t.UserID); err != nil {
+			return nil, fmt.Errorf("could not send the rows data to transaction struct: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return transactions, nil
+}
@@ -91,6 +91,9 @@ func TestTransactions_TableDriven(t *testing.T) {
 				if !errors.Is(err, sql.ErrNoRows) {
 					t.Fatalf("expected sql.ErrNoRows, got: %v", err)
 				}
+				if !errors.Is(err, model.KindNotFound) {
+					t.Fatalf("expected a not found error, got: %v", err)
+				}
 			},
 		},
 		{
@@ -395,3 +398,27 @@ func TestTransactions_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDebtTransactionsBetween(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	userID := cashflowFixture(t, db)
+
+	got, err := GetDebtTransactionsBetween(ctx, userID, "2030-01-01 12:00:00", "2030-03-01 00:00:00", db)
+	if err != nil {
+		t.Fatalf("GetDebtTransactionsBetween() returned error: %v", err)
+	}
+
+	// Income, the other user's expense and the bounds' far sides are left out
+	want := []utils.Money{2500, 1500, 4000}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d transactions, got %+v", len(want), got)
+	}
+	for i, amount := range want {
+		if got[i].Amount != amount || !got[i].IsDebt || got[i].UserID != userID {
+			t.Errorf("transaction %d = %+v, want a debt of %d", i, got[i], amount)
+		}
+	}
+}
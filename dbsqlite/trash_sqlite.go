@@ -0,0 +1,327 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/model"
+)
+
+// softDelete marks the row of table with the given ID as deleted at deletedAt, unless it already is, and
+// returns the number of affected rows.
+func softDelete(ctx context.Context, table string, id int64, deletedAt string, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `UPDATE `+table+` SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, deletedAt, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not move %s %d to the trash: %w", table, id, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// restore clears the deletion of the row of table with the given ID and returns the number of affected rows,
+// 0 when it is not in the trash.
+func restore(ctx context.Context, table string, id int64, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `UPDATE `+table+` SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not restore %s %d: %w", table, id, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// SoftDeleteTransaction moves a transaction to the trash and returns the number of affected rows.
+func SoftDeleteTransaction(ctx context.Context, id int64, deletedAt string, db *sql.DB) (int64, error) {
+	return softDelete(ctx, "transactions", id, deletedAt, db)
+}
+
+// SoftDeleteGoal moves a goal to the trash and returns the number of affected rows.
+func SoftDeleteGoal(ctx context.Context, id int64, deletedAt string, db *sql.DB) (int64, error) {
+	return softDelete(ctx, "goals", id, deletedAt, db)
+}
+
+// SoftDeleteUser moves a user to the trash along with their transactions and goals that are not there yet,
+// all marked with the same deletedAt so RestoreUser brings them back together. It returns the number of
+// users affected.
+func SoftDeleteUser(ctx context.Context, id int64, deletedAt string, db *sql.DB) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, deletedAt, id)
+	if err != nil {
+		return 0, fmt.Errorf("could not move user %d to the trash: %w", id, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return 0, nil
+	}
+
+	for _, table := range []string{"transactions", "goals"} {
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET deleted_at = ? WHERE user_id = ? AND deleted_at IS NULL`, deletedAt, id); err != nil {
+			return 0, fmt.Errorf("could not move the %s of user %d to the trash: %w", table, id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit the deletion of user %d: %w", id, err)
+	}
+
+	return rows, nil
+}
+
+// RestoreTransaction takes a transaction out of the trash and returns the number of affected rows.
+func RestoreTransaction(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	return restore(ctx, "transactions", id, db)
+}
+
+// RestoreGoal takes a goal out of the trash and returns the number of affected rows.
+func RestoreGoal(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	return restore(ctx, "goals", id, db)
+}
+
+// RestoreUser takes a user out of the trash along with the transactions and goals deleted with them, and
+// returns the number of users affected. Those deleted on their own before stay in the trash.
+func RestoreUser(ctx context.Context, id int64, db *sql.DB) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt string
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at FROM users WHERE id = ? AND deleted_at IS NOT NULL`, id).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not get the deletion of user %d: %w", id, err)
+	}
+
+	for _, table := range []string{"users", "transactions", "goals"} {
+		column := "user_id"
+		if table == "users" {
+			column = "id"
+		}
+		stmt := `UPDATE ` + table + ` SET deleted_at = NULL WHERE ` + column + ` = ? AND deleted_at = ?`
+		if _, err := tx.ExecContext(ctx, stmt, id, deletedAt); err != nil {
+			return 0, fmt.Errorf("could not restore the %s of user %d: %w", table, id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("could not commit the restoration of user %d: %w", id, err)
+	}
+
+	return 1, nil
+}
+
+// GetDeletedUserByID retrieves a user in the trash.
+func GetDeletedUserByID(ctx context.Context, id int64, db *sql.DB) (*model.User, error) {
+	const selectStmt = `
+	SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs, deleted_at
+	FROM users WHERE id = ? AND deleted_at IS NOT NULL`
+
+	var u model.User
+	if err := db.QueryRowContext(ctx, selectStmt, id).Scan(&u.ID, &u.UserName, &u.CurrentAmount, &u.MonthlyInputs, &u.MonthlyOutputs, &u.DeletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("deleted user", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into user struct: %w", err)
+	}
+
+	return &u, nil
+}
+
+// GetDeletedTransactionByID retrieves a transaction in the trash.
+func GetDeletedTransactionByID(ctx context.Context, id int64, db *sql.DB) (*model.Transaction, error) {
+	const selectStmt = `
+	SELECT id, COALESCE(description, ''), amount, is_debt, created_at, user_id, COALESCE(category, ''), deleted_at
+	FROM transactions WHERE id = ? AND deleted_at IS NOT NULL`
+
+	var t model.Transaction
+	if err := db.QueryRowContext(ctx, selectStmt, id).Scan(&t.ID, &t.Desc, &t.Amount, &t.IsDebt, &t.CreatedAt, &t.UserID, &t.Category, &t.DeletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("deleted transaction", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into transaction struct: %w", err)
+	}
+
+	return &t, nil
+}
+
+// GetDeletedGoalByID retrieves a goal in the trash.
+func GetDeletedGoalByID(ctx context.Context, id int64, db *sql.DB) (*model.Goal, error) {
+	const selectStmt = `
+	SELECT id, name, COALESCE(description, ''), price, COALESCE(pros, ''), COALESCE(cons, ''), user_id, created_at, deadline, deleted_at
+	FROM goals WHERE id = ? AND deleted_at IS NOT NULL`
+
+	var g model.Goal
+	if err := db.QueryRowContext(ctx, selectStmt, id).Scan(&g.ID, &g.Name, &g.Desc, &g.Price, &g.Pros, &g.Cons, &g.UserID, &g.CreatedAt, &g.Deadline, &g.DeletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.NotFoundError("deleted goal", err)
+		}
+		return nil, fmt.Errorf("could not scan the row into goal struct: %w", err)
+	}
+
+	return &g, nil
+}
+
+// GetTrash retrieves the users, transactions and goals in the trash, newest deletions first. A userID other
+// than 0 keeps only that user and what they own.
+func GetTrash(ctx context.Context, userID int64, db *sql.DB) (*model.Trash, error) {
+	const (
+		usersQuery = `
+		SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs, deleted_at FROM users
+		WHERE deleted_at IS NOT NULL AND (?1 = 0 OR id = ?1) ORDER BY deleted_at DESC, id`
+		transactionsQuery = `
+		SELECT id, COALESCE(description, ''), amount, is_debt, created_at, user_id, COALESCE(category, ''), deleted_at FROM transactions
+		WHERE deleted_at IS NOT NULL AND (?1 = 0 OR user_id = ?1) ORDER BY deleted_at DESC, id`
+		goalsQuery = `
+		SELECT id, name, COALESCE(description, ''), price, COALESCE(pros, ''), COALESCE(cons, ''), user_id, created_at, deadline, deleted_at FROM goals
+		WHERE deleted_at IS NOT NULL AND (?1 = 0 OR user_id = ?1) ORDER BY deleted_at DESC, id`
+	)
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	trash := &model.Trash{Users: []model.User{}, Transactions: []model.Transaction{}, Goals: []model.Goal{}}
+
+	err = queryTrash(ctx, tx, usersQuery, userID, func(rows *sql.Rows) error {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UserName, &u.CurrentAmount, &u.MonthlyInputs, &u.MonthlyOutputs, &u.DeletedAt); err != nil {
+			return err
+		}
+		trash.Users = append(trash.Users, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the users in the trash: %w", err)
+	}
+
+	err = queryTrash(ctx, tx, transactionsQuery, userID, func(rows *sql.Rows) error {
+		var t model.Transaction
+		if err := rows.Scan(&t.ID, &t.Desc, &t.Amount, &t.IsDebt, &t.CreatedAt, &t.UserID, &t.Category, &t.DeletedAt); err != nil {
+			return err
+		}
+		trash.Transactions = append(trash.Transactions, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the transactions in the trash: %w", err)
+	}
+
+	err = queryTrash(ctx, tx, goalsQuery, userID, func(rows *sql.Rows) error {
+		var g model.Goal
+		if err := rows.Scan(&g.ID, &g.Name, &g.Desc, &g.Price, &g.Pros, &g.Cons, &g.UserID, &g.CreatedAt, &g.Deadline, &g.DeletedAt); err != nil {
+			return err
+		}
+		trash.Goals = append(trash.Goals, g)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the goals in the trash: %w", err)
+	}
+
+	return trash, nil
+}
+
+// queryTrash runs one of the queries of GetTrash and hands every row to scan.
+func queryTrash(ctx context.Context, tx *sql.Tx, query string, userID int64, scan func(*sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// purgeableTransactions selects the transactions a purge of everything deleted before ?1 removes, directly
+// or along with their user.
+const purgeableTransactions = `
+	SELECT id FROM transactions WHERE deleted_at < ?1
+	UNION
+	SELECT t.id FROM transactions t JOIN users u ON u.id = t.user_id WHERE u.deleted_at < ?1`
+
+// GetPurgeableAttachmentHashes returns the content hashes of the attachments of the transactions that
+// PurgeDeleted would remove for the same cutoff.
+func GetPurgeableAttachmentHashes(ctx context.Context, before string, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT sha256 FROM attachments WHERE transaction_id IN (`+purgeableTransactions+`)`, before)
+	if err != nil {
+		return nil, fmt.Errorf("could not query the attachments to purge: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("could not scan attachment hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// PurgeDeleted permanently deletes the users, transactions and goals moved to the trash before the given
+// "YYYY-MM-DD HH:MM:SS" timestamp, all or nothing. Deleting a user also deletes whatever they still own.
+func PurgeDeleted(ctx context.Context, before string, db *sql.DB) (*model.PurgeResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var result model.PurgeResult
+	for _, p := range []struct {
+		table string
+		count *int64
+	}{
+		{"goals", &result.Goals},
+		{"transactions", &result.Transactions},
+		{"users", &result.Users},
+	} {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+p.table+` WHERE deleted_at < ?`, before)
+		if err != nil {
+			return nil, fmt.Errorf("could not purge %s: %w", p.table, err)
+		}
+		if *p.count, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("could not get rows affected: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit the purge: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,129 @@
+package dbsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestTrash_SoftDeleteAndRestore(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	u, err := CreateUser(ctx, model.User{UserName: "trashed", CurrentAmount: 1000}, db)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	kept, _ := CreateTransaction(ctx, model.Transaction{Desc: "kept", Amount: 100, UserID: u.ID}, db)
+	earlier, _ := CreateTransaction(ctx, model.Transaction{Desc: "earlier", Amount: 200, UserID: u.ID}, db)
+	goal, _ := CreateGoal(ctx, model.Goal{Name: "trip", Price: 5000, UserID: u.ID, Deadline: "2031-01-01"}, db)
+
+	if rows, err := SoftDeleteTransaction(ctx, earlier.ID, "2030-01-01 10:00:00", db); err != nil || rows != 1 {
+		t.Fatalf("SoftDeleteTransaction() = %d, %v", rows, err)
+	}
+	if rows, _ := SoftDeleteTransaction(ctx, earlier.ID, "2030-01-02 10:00:00", db); rows != 0 {
+		t.Errorf("SoftDeleteTransaction() of a deleted transaction = %d, want 0", rows)
+	}
+	if _, err := GetTransactionByID(ctx, earlier.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTransactionByID() of a deleted transaction error = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := GetDeletedTransactionByID(ctx, earlier.ID, db); err != nil || got.DeletedAt != "2030-01-01 10:00:00" {
+		t.Errorf("GetDeletedTransactionByID() = %+v, %v", got, err)
+	}
+
+	// Deleting the user takes along what they still have, and restoring them brings back only that
+	if rows, err := SoftDeleteUser(ctx, u.ID, "2030-02-01 10:00:00", db); err != nil || rows != 1 {
+		t.Fatalf("SoftDeleteUser() = %d, %v", rows, err)
+	}
+	if users, _ := GetAllUsers(ctx, db); len(users) != 0 {
+		t.Errorf("GetAllUsers() after deletion = %+v, want none", users)
+	}
+	if transactions, _ := GetAllTransactions(ctx, db); len(transactions) != 0 {
+		t.Errorf("GetAllTransactions() after deleting the user = %+v, want none", transactions)
+	}
+	if goals, _ := GetAllGoals(ctx, db); len(goals) != 0 {
+		t.Errorf("GetAllGoals() after deleting the user = %+v, want none", goals)
+	}
+
+	trash, err := GetTrash(ctx, u.ID, db)
+	if err != nil {
+		t.Fatalf("GetTrash() returned error: %v", err)
+	}
+	if len(trash.Users) != 1 || len(trash.Transactions) != 2 || len(trash.Goals) != 1 || trash.Transactions[0].ID != kept.ID {
+		t.Errorf("GetTrash() = %+v, want the user, both transactions newest first and the goal", trash)
+	}
+	if other, _ := GetTrash(ctx, u.ID+1, db); len(other.Users)+len(other.Transactions)+len(other.Goals) != 0 {
+		t.Errorf("GetTrash() of another user = %+v, want nothing", other)
+	}
+
+	if rows, err := RestoreUser(ctx, u.ID, db); err != nil || rows != 1 {
+		t.Fatalf("RestoreUser() = %d, %v", rows, err)
+	}
+	if rows, _ := RestoreUser(ctx, u.ID, db); rows != 0 {
+		t.Errorf("RestoreUser() of an active user = %d, want 0", rows)
+	}
+	if _, err := GetTransactionByID(ctx, kept.ID, db); err != nil {
+		t.Errorf("GetTransactionByID() of a transaction deleted with its user returned error: %v", err)
+	}
+	if _, err := GetGoalByID(ctx, goal.ID, db); err != nil {
+		t.Errorf("GetGoalByID() of a goal deleted with its user returned error: %v", err)
+	}
+	if _, err := GetTransactionByID(ctx, earlier.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTransactionByID() of a transaction deleted before its user error = %v, want sql.ErrNoRows", err)
+	}
+
+	if rows, err := RestoreTransaction(ctx, earlier.ID, db); err != nil || rows != 1 {
+		t.Errorf("RestoreTransaction() = %d, %v", rows, err)
+	}
+	if rows, err := SoftDeleteGoal(ctx, goal.ID, "2030-03-01 10:00:00", db); err != nil || rows != 1 {
+		t.Errorf("SoftDeleteGoal() = %d, %v", rows, err)
+	}
+	if rows, err := RestoreGoal(ctx, goal.ID, db); err != nil || rows != 1 {
+		t.Errorf("RestoreGoal() = %d, %v", rows, err)
+	}
+}
+
+func TestTrash_Purge(t *testing.T) {
+	db, teardown := setupDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	gone, _ := CreateUser(ctx, model.User{UserName: "gone"}, db)
+	stays, _ := CreateUser(ctx, model.User{UserName: "stays"}, db)
+	withFile, _ := CreateTransaction(ctx, model.Transaction{Desc: "with file", Amount: 100, UserID: gone.ID}, db)
+	old, _ := CreateTransaction(ctx, model.Transaction{Desc: "old", Amount: 100, UserID: stays.ID}, db)
+	recent, _ := CreateTransaction(ctx, model.Transaction{Desc: "recent", Amount: 100, UserID: stays.ID}, db)
+	if _, err := CreateAttachment(ctx, model.Attachment{TransactionID: withFile.ID, FileName: "a.png", ContentType: "image/png", Size: 1, SHA256: "hash-gone"}, db); err != nil {
+		t.Fatalf("failed to create attachment: %v", err)
+	}
+
+	SoftDeleteUser(ctx, gone.ID, "2030-01-01 10:00:00", db)
+	SoftDeleteTransaction(ctx, old.ID, "2030-01-05 10:00:00", db)
+	SoftDeleteTransaction(ctx, recent.ID, "2030-03-01 10:00:00", db)
+
+	hashes, err := GetPurgeableAttachmentHashes(ctx, "2030-02-01 00:00:00", db)
+	if err != nil || len(hashes) != 1 || hashes[0] != "hash-gone" {
+		t.Errorf("GetPurgeableAttachmentHashes() = %v, %v", hashes, err)
+	}
+
+	result, err := PurgeDeleted(ctx, "2030-02-01 00:00:00", db)
+	if err != nil {
+		t.Fatalf("PurgeDeleted() returned error: %v", err)
+	}
+	if *result != (model.PurgeResult{Users: 1, Transactions: 2}) {
+		t.Errorf("PurgeDeleted() = %+v, want the user and both old transactions", result)
+	}
+	if _, err := GetDeletedUserByID(ctx, gone.ID, db); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetDeletedUserByID() after purge error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := GetDeletedTransactionByID(ctx, recent.ID, db); err != nil {
+		t.Errorf("GetDeletedTransactionByID() of a recent deletion returned error: %v", err)
+	}
+	if count, _ := CountAttachmentsBySHA256(ctx, "hash-gone", db); count != 0 {
+		t.Errorf("CountAttachmentsBySHA256() after purge = %d, want 0", count)
+	}
+}
@@ -13,7 +13,7 @@ import (
 // GetAllUsers retrieves all users from the database with context support
 func GetAllUsers(ctx context.Context, db *sql.DB) ([]model.User, error) {
 	const query = `
-	SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs FROM users ORDER BY id;
+	SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs FROM users WHERE deleted_at IS NULL ORDER BY id;
 	`
 
 	rows, err := db.QueryContext(ctx, query)
@@ -58,13 +58,13 @@ func CreateUser(ctx context.Context, user model.User, db *sql.DB) (*model.User,
 
 // GetUserByID retrieves a user by ID from the database with context support
 func GetUserByID(ctx context.Context, id int64, db *sql.DB) (*model.User, error) {
-	const selectStmt = `SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs FROM users WHERE id = ?`
+	const selectStmt = `SELECT id, user_name, current_amount, monthly_inputs, monthly_outputs FROM users WHERE id = ? AND deleted_at IS NULL`
 
 	row := db.QueryRowContext(ctx, selectStmt, id)
 	var user model.User
 	if err := row.Scan(&user.ID, &user.UserName, &user.CurrentAmount, &user.MonthlyInputs, &user.MonthlyOutputs); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found: %w", err)
+			return nil, model.NotFoundError("user", err)
 		}
 		return nil, fmt.Errorf("could not scan the row into user struct: %w", err)
 	}
@@ -94,7 +94,7 @@ func DeleteUserByID(ctx context.Context, id int64, db *sql.DB) (int64, error) {
 // This prevents overwriting existing values with zero/empty values.
 func UpdateUserPartialByID(ctx context.Context, id int64, update *model.UserUpdate, db *sql.DB) (*model.User, error) {
 	if update == nil {
-		return nil, fmt.Errorf("update data cannot be nil")
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
 	}
 
 	// First, fetch the current user data to verify it exists
@@ -133,7 +133,7 @@ func UpdateUserPartialByID(ctx context.Context, id int64, update *model.UserUpda
 	}
 
 	// Build and execute the dynamic UPDATE statement
-	updateStmt := fmt.Sprintf("UPDATE users SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	updateStmt := fmt.Sprintf("UPDATE users SET %s WHERE id = ? AND deleted_at IS NULL", strings.Join(setParts, ", "))
 	args = append(args, id)
 
 	res, err := db.ExecContext(ctx, updateStmt, args...)
@@ -147,7 +147,7 @@ func UpdateUserPartialByID(ctx context.Context, id int64, update *model.UserUpda
 	}
 
 	if affected == 0 {
-		return nil, sql.ErrNoRows
+		return nil, model.NotFoundError("user", sql.ErrNoRows)
 	}
 
 	// Fetch and return the updated user
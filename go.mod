@@ -3,7 +3,11 @@ module natan/fingo
 go 1.25.6
 
 require (
-	github.com/ncruces/go-sqlite3 v0.30.5 // indirect
+	github.com/ncruces/go-sqlite3 v0.30.5
+	golang.org/x/crypto v0.47.0
+)
+
+require (
 	github.com/ncruces/julianday v1.0.0 // indirect
 	github.com/tetratelabs/wazero v1.11.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
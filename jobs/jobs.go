@@ -0,0 +1,524 @@
+// Package jobs runs registered background jobs on cron-like schedules and records every run in the
+// database. The next run of each job is persisted and claimed atomically, so a schedule slot runs at
+// most once across restarts, and slots missed while the process was down collapse into a single run.
+// When several fingo processes share a database, a lease stored in the database elects the single
+// instance that runs jobs, scheduled or triggered; another instance takes over once the lease expires.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"natan/fingo/clock"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// DefaultPollInterval is how often a started scheduler looks for due jobs.
+const DefaultPollInterval = time.Minute
+
+// DefaultLeaseTTL is how long the scheduler lease lasts without being renewed. A started scheduler
+// renews it three times per TTL.
+const DefaultLeaseTTL = 2 * time.Minute
+
+// leaseName is the database lease electing the instance that runs scheduled jobs.
+const leaseName = "scheduler"
+
+// releaseTimeout bounds how long releasing the lease on shutdown may take.
+const releaseTimeout = 2 * time.Second
+
+// runHistoryLimit caps how many past runs are returned for a job.
+const runHistoryLimit = 50
+
+var (
+	// ErrUnknownJob is returned when no job is registered under the given name.
+	ErrUnknownJob = model.NewError(model.KindNotFound, "unknown job")
+	// ErrJobRunning is returned when a job is triggered while it is already running in this process.
+	ErrJobRunning = model.NewError(model.KindConflict, "job is already running")
+	// ErrNotLeader is returned when a job is triggered on an instance while another one holds the lease.
+	ErrNotLeader = model.NewError(model.KindConflict, "another instance holds the scheduler lease")
+	// ErrDuplicateJob is returned when a job name is registered twice.
+	ErrDuplicateJob = errors.New("job already registered")
+)
+
+// Func is the work done by a job. A returned error marks the run as failed.
+type Func func(ctx context.Context) error
+
+// job is a registered job definition.
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	run      Func
+}
+
+// Scheduler runs registered jobs when their schedule is due and it holds the scheduler lease.
+// It is safe for concurrent use.
+type Scheduler struct {
+	clock    clock.Clock
+	instance string
+
+	mu           sync.Mutex
+	pollInterval time.Duration
+	leaseTTL     time.Duration
+	leader       bool
+	jobs         map[string]*job
+	order        []string
+	running      map[string]bool
+}
+
+// NewScheduler returns a scheduler that reads the current time, and evaluates schedules in the
+// timezone, of the given clock. Each scheduler gets its own instance ID to compete for the lease.
+func NewScheduler(c clock.Clock) *Scheduler {
+	return &Scheduler{
+		clock:        c,
+		instance:     newInstanceID(),
+		pollInterval: DefaultPollInterval,
+		leaseTTL:     DefaultLeaseTTL,
+		jobs:         make(map[string]*job),
+		running:      make(map[string]bool),
+	}
+}
+
+// newInstanceID returns an identifier made of the host name, the process ID and random bytes.
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "fingo"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Instance returns the ID this scheduler holds the lease under.
+func (s *Scheduler) Instance() string {
+	return s.instance
+}
+
+// SetLeaseTTL sets how long the scheduler lease lasts without renewal. It should be well above the
+// poll interval. Non-positive durations are ignored.
+func (s *Scheduler) SetLeaseTTL(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaseTTL = d
+}
+
+// SetPollInterval sets how often Start looks for due jobs. Non-positive durations are ignored.
+func (s *Scheduler) SetPollInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollInterval = d
+}
+
+// Register adds a job running fn on the schedule described by spec (see ParseSchedule) and records
+// it in the database. A job seen for the first time is due immediately.
+func (s *Scheduler) Register(ctx context.Context, name, spec string, fn Func) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	now := s.clock.Now()
+	if schedule.Next(now).IsZero() {
+		return fmt.Errorf("invalid schedule %q: it never runs", spec)
+	}
+	if err := dbsqlite.EnsureJob(ctx, name, spec, now, db); err != nil {
+		return err
+	}
+
+	s.jobs[name] = &job{name: name, spec: spec, schedule: schedule, run: fn}
+	s.order = append(s.order, name)
+	return nil
+}
+
+// Jobs returns the registered jobs, in registration order, with their next and last runs.
+func (s *Scheduler) Jobs(ctx context.Context) ([]model.Job, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	s.mu.Lock()
+	names := append([]string(nil), s.order...)
+	s.mu.Unlock()
+
+	jobs := make([]model.Job, 0, len(names))
+	for _, name := range names {
+		j, err := dbsqlite.GetJob(ctx, name, db)
+		if err != nil {
+			return nil, err
+		}
+		j.Running = s.isRunning(name)
+		jobs = append(jobs, *j)
+	}
+
+	return jobs, nil
+}
+
+// Runs returns the most recent runs of a registered job, newest first.
+func (s *Scheduler) Runs(ctx context.Context, name string) ([]model.JobRun, error) {
+	if _, err := s.lookup(name); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	runs, err := dbsqlite.GetJobRuns(ctx, name, runHistoryLimit, db)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		return []model.JobRun{}, nil
+	}
+	return runs, nil
+}
+
+// Status reports whether this scheduler holds the lease, along with the lease as stored in the database.
+func (s *Scheduler) Status(ctx context.Context) (*model.SchedulerStatus, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	status := &model.SchedulerStatus{Instance: s.instance}
+
+	lease, err := dbsqlite.GetLease(ctx, leaseName, db)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lease != nil {
+		status.Lease = lease
+		status.Leader = lease.Holder == s.instance && lease.ExpiresAt > s.clock.Now().UTC().Format(dbsqlite.TimestampLayout)
+	}
+
+	return status, nil
+}
+
+// Trigger runs a registered job right away, outside its schedule, and returns the recorded run.
+// The next scheduled run is not affected. Like scheduled runs, manual runs need the lease, so they
+// never overlap a run of another instance; ErrNotLeader is returned while another instance holds it.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*model.JobRun, error) {
+	j, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	leader, err := s.acquireLease(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if !leader {
+		return nil, fmt.Errorf("%w: cannot run %s on %s", ErrNotLeader, name, s.instance)
+	}
+
+	if !s.markRunning(name) {
+		return nil, fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	defer s.clearRunning(name)
+
+	return s.execute(ctx, db, j, model.JobRun{JobName: name, Trigger: model.JobTriggerManual})
+}
+
+// RunDue runs, one after the other, every registered job whose next run is due, as long as this
+// scheduler holds the lease. Each due job is claimed before it runs, so a slot claimed by another
+// scheduler or an earlier process is skipped. Returns the number of jobs run.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	s.mu.Lock()
+	names := append([]string(nil), s.order...)
+	s.mu.Unlock()
+
+	ran := 0
+	for _, name := range names {
+		j, err := s.lookup(name)
+		if err != nil {
+			return ran, err
+		}
+
+		// Renewing the lease before every job keeps it from expiring during a long series of runs
+		leader, err := s.acquireLease(ctx, db)
+		if err != nil {
+			return ran, err
+		}
+		if !leader {
+			return ran, nil
+		}
+
+		if !s.markRunning(name) {
+			continue
+		}
+
+		now := s.clock.Now()
+		slot, claimed, err := dbsqlite.ClaimJobRun(ctx, name, now, j.schedule.Next(now), db)
+		if err != nil || !claimed {
+			s.clearRunning(name)
+			if err != nil {
+				return ran, err
+			}
+			continue
+		}
+
+		_, err = s.execute(ctx, db, j, model.JobRun{JobName: name, Trigger: model.JobTriggerSchedule, ScheduledFor: slot})
+		s.clearRunning(name)
+		if err != nil {
+			return ran, err
+		}
+		ran++
+	}
+
+	return ran, nil
+}
+
+// Start runs due jobs right away and every poll interval in a background goroutine, and keeps the
+// lease renewed in another, until ctx is canceled. The lease is released on cancellation so another
+// instance can take over immediately.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	interval := s.pollInterval
+	renewEvery := s.leaseTTL / 3
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(renewEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.releaseLease()
+				return
+			case <-ticker.C:
+				if err := s.renewLease(ctx); err != nil {
+					log.Printf("[Jobs] Could not renew the scheduler lease: %v", err)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		if _, err := s.RunDue(ctx); err != nil {
+			log.Printf("[Jobs] Error during startup processing: %v", err)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		log.Printf("[Jobs] Scheduler started. Checking every %s for due jobs.", interval)
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("[Jobs] Scheduler stopped.")
+				return
+			case <-ticker.C:
+				if _, err := s.RunDue(ctx); err != nil {
+					log.Printf("[Jobs] Error during periodic check: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// renewLease acquires or renews the lease on its own connection.
+func (s *Scheduler) renewLease(ctx context.Context) error {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = s.acquireLease(ctx, db)
+	return err
+}
+
+// acquireLease acquires the lease, or renews it if this scheduler already holds it, and reports
+// whether it does. When the lease is taken over from an instance that let it expire, the runs that
+// instance left unfinished are marked as interrupted.
+func (s *Scheduler) acquireLease(ctx context.Context, db *sql.DB) (bool, error) {
+	s.mu.Lock()
+	ttl := s.leaseTTL
+	s.mu.Unlock()
+
+	now := s.clock.Now()
+	leader, previous, err := dbsqlite.AcquireLease(ctx, leaseName, s.instance, now, ttl, db)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	wasLeader := s.leader
+	s.leader = leader
+	s.mu.Unlock()
+
+	switch {
+	case leader && !wasLeader:
+		log.Printf("[Jobs] Instance %s acquired the scheduler lease.", s.instance)
+	case !leader && wasLeader:
+		log.Printf("[Jobs] Instance %s lost the scheduler lease.", s.instance)
+	}
+
+	if previous != "" {
+		n, err := dbsqlite.MarkInterruptedJobRuns(ctx, previous, now, db)
+		if err != nil {
+			return leader, err
+		}
+		log.Printf("[Jobs] Took over the scheduler lease from %s and marked %d unfinished run(s) as interrupted.", previous, n)
+	}
+
+	return leader, nil
+}
+
+// releaseLease gives up the lease if this scheduler holds it.
+func (s *Scheduler) releaseLease() {
+	s.mu.Lock()
+	wasLeader := s.leader
+	s.leader = false
+	s.mu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		log.Printf("[Jobs] Could not release the scheduler lease: %v", err)
+		return
+	}
+	defer db.Close()
+
+	if err := dbsqlite.ReleaseLease(ctx, leaseName, s.instance, db); err != nil {
+		log.Printf("[Jobs] Could not release the scheduler lease: %v", err)
+		return
+	}
+	log.Printf("[Jobs] Instance %s released the scheduler lease.", s.instance)
+}
+
+// execute records a run of j, runs it and records its outcome. A panic in the job is recorded as a
+// failure. The returned error only reports problems recording the run; the job outcome is in the run.
+func (s *Scheduler) execute(ctx context.Context, db *sql.DB, j *job, run model.JobRun) (*model.JobRun, error) {
+	run.Instance = s.instance
+	id, err := dbsqlite.CreateJobRun(ctx, run, s.clock.Now(), db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := model.JobRunSucceeded
+	errMsg := ""
+	if err := runSafely(ctx, j.run); err != nil {
+		status = model.JobRunFailed
+		errMsg = err.Error()
+		log.Printf("[Jobs] Job %s failed: %v", j.name, err)
+	}
+
+	// The outcome is recorded even when the job stopped because ctx was canceled
+	recordCtx := context.WithoutCancel(ctx)
+	if err := dbsqlite.FinishJobRun(recordCtx, id, status, errMsg, s.clock.Now(), db); err != nil {
+		return nil, err
+	}
+
+	runs, err := dbsqlite.GetJobRuns(recordCtx, j.name, 1, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 || runs[0].ID != id {
+		return nil, fmt.Errorf("could not read back run %d of job %s", id, j.name)
+	}
+	return &runs[0], nil
+}
+
+// runSafely calls fn, turning a panic into an error.
+func runSafely(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// lookup returns the registered job with the given name.
+func (s *Scheduler) lookup(name string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return j, nil
+}
+
+// markRunning flags a job as running in this process. Returns false if it already was.
+func (s *Scheduler) markRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+// clearRunning clears the running flag of a job.
+func (s *Scheduler) clearRunning(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
+
+// isRunning reports whether a job is running in this process.
+func (s *Scheduler) isRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[name]
+}
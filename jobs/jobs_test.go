@@ -0,0 +1,470 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"natan/fingo/clock"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// TestMain creates a fresh fingo.db for the scheduler tests and removes it afterwards.
+func TestMain(m *testing.M) {
+	_ = os.Remove("fingo.db")
+	if err := dbsqlite.CheckAndCreate(); err != nil {
+		log.Fatalf("could not create test database: %v", err)
+	}
+
+	code := m.Run()
+
+	_ = os.Remove("fingo.db")
+	os.Exit(code)
+}
+
+// counter returns a job function counting its calls.
+func counter(calls *int32) Func {
+	return func(context.Context) error {
+		atomic.AddInt32(calls, 1)
+		return nil
+	}
+}
+
+// newTestScheduler returns a scheduler on the fake clock after clearing the lease left by earlier tests.
+func newTestScheduler(t *testing.T, c clock.Clock) *Scheduler {
+	t.Helper()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`DELETE FROM scheduler_leases`); err != nil {
+		t.Fatalf("could not clear leases: %v", err)
+	}
+	return NewScheduler(c)
+}
+
+func runDue(t *testing.T, s *Scheduler) int {
+	t.Helper()
+	n, err := s.RunDue(context.Background())
+	if err != nil {
+		t.Fatalf("RunDue() unexpected error: %v", err)
+	}
+	return n
+}
+
+func TestScheduler_RunsEachSlotOnceAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 5, 1, 10, 0, 0, 0, time.UTC))
+	var calls int32
+
+	s := newTestScheduler(t, fake)
+	if err := s.Register(ctx, "restart-safe", "@hourly", counter(&calls)); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	// A new job is due right away, then only once its next slot is reached
+	if n := runDue(t, s); n != 1 {
+		t.Fatalf("first RunDue() ran %d job(s), want 1", n)
+	}
+	fake.Advance(30 * time.Minute)
+	if n := runDue(t, s); n != 0 {
+		t.Fatalf("RunDue() before the next slot ran %d job(s), want 0", n)
+	}
+
+	// A restarted process takes over the expired lease, sees the persisted next run and does not repeat the slot
+	restarted := NewScheduler(fake)
+	if err := restarted.Register(ctx, "restart-safe", "@hourly", counter(&calls)); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	if n := runDue(t, restarted); n != 0 {
+		t.Fatalf("RunDue() after restart ran %d job(s), want 0", n)
+	}
+
+	fake.Advance(30 * time.Minute)
+	if n := runDue(t, restarted); n != 1 {
+		t.Fatalf("RunDue() at the next slot ran %d job(s), want 1", n)
+	}
+
+	// Slots missed during a long downtime collapse into a single run
+	fake.Advance(72 * time.Hour)
+	if n := runDue(t, restarted); n != 1 {
+		t.Fatalf("RunDue() after downtime ran %d job(s), want 1", n)
+	}
+	if n := runDue(t, restarted); n != 0 {
+		t.Fatalf("RunDue() right after catching up ran %d job(s), want 0", n)
+	}
+
+	if calls != 3 {
+		t.Errorf("job ran %d time(s), want 3", calls)
+	}
+
+	list, err := restarted.Jobs(ctx)
+	if err != nil {
+		t.Fatalf("Jobs() unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].NextRunAt != "2030-05-04 12:00:00" {
+		t.Fatalf("unexpected jobs: %+v", list)
+	}
+	if last := list[0].LastRun; last == nil || last.Status != model.JobRunSucceeded || last.ScheduledFor != "2030-05-01 12:00:00" {
+		t.Errorf("unexpected last run: %+v", last)
+	}
+}
+
+func TestScheduler_RecordsFailuresAndPanics(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC))
+	s := newTestScheduler(t, fake)
+
+	if err := s.Register(ctx, "failing", "@daily", func(context.Context) error { return errors.New("disk full") }); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	if err := s.Register(ctx, "panicking", "@daily", func(context.Context) error { panic("nil map") }); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if n := runDue(t, s); n != 2 {
+		t.Fatalf("RunDue() ran %d job(s), want 2", n)
+	}
+
+	for name, want := range map[string]string{"failing": "disk full", "panicking": "job panicked: nil map"} {
+		runs, err := s.Runs(ctx, name)
+		if err != nil {
+			t.Fatalf("Runs(%q) unexpected error: %v", name, err)
+		}
+		if len(runs) != 1 || runs[0].Status != model.JobRunFailed || runs[0].Error != want || runs[0].FinishedAt == "" {
+			t.Errorf("unexpected runs of %s: %+v", name, runs)
+		}
+	}
+
+	// A failed slot is not retried before the next one
+	if n := runDue(t, s); n != 0 {
+		t.Errorf("RunDue() after failures ran %d job(s), want 0", n)
+	}
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC))
+	s := newTestScheduler(t, fake)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if err := s.Register(ctx, "manual", "@monthly", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if _, err := s.Trigger(ctx, "missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Fatalf("Trigger() of unknown job error = %v, want ErrUnknownJob", err)
+	}
+
+	done := make(chan *model.JobRun)
+	go func() {
+		run, err := s.Trigger(ctx, "manual")
+		if err != nil {
+			t.Errorf("Trigger() unexpected error: %v", err)
+		}
+		done <- run
+	}()
+	<-started
+
+	if _, err := s.Trigger(ctx, "manual"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("Trigger() while running error = %v, want ErrJobRunning", err)
+	}
+	if n := runDue(t, s); n != 0 {
+		t.Errorf("RunDue() while running ran %d job(s), want 0", n)
+	}
+
+	close(release)
+	run := <-done
+	if run == nil || run.Trigger != model.JobTriggerManual || run.Status != model.JobRunSucceeded || run.ScheduledFor != "" {
+		t.Fatalf("unexpected manual run: %+v", run)
+	}
+
+	// The manual run leaves the schedule untouched, so the first slot is still due
+	list, err := s.Jobs(ctx)
+	if err != nil {
+		t.Fatalf("Jobs() unexpected error: %v", err)
+	}
+	if list[0].NextRunAt != "2030-07-01 00:00:00" {
+		t.Errorf("next run = %q, want the untouched first slot", list[0].NextRunAt)
+	}
+}
+
+func TestScheduler_TriggerNeedsLease(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 8, 1, 0, 0, 0, 0, time.UTC))
+	var calls int32
+
+	leader := newTestScheduler(t, fake)
+	follower := NewScheduler(fake)
+	for _, s := range []*Scheduler{leader, follower} {
+		s.SetLeaseTTL(5 * time.Minute)
+		if err := s.Register(ctx, "contended", "@monthly", counter(&calls)); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	if _, err := leader.Trigger(ctx, "contended"); err != nil {
+		t.Fatalf("leader Trigger() unexpected error: %v", err)
+	}
+	if _, err := follower.Trigger(ctx, "contended"); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Trigger() while another instance holds the lease error = %v, want ErrNotLeader", err)
+	}
+
+	// Once the leader lets the lease expire, the other instance may run the job
+	fake.Advance(6 * time.Minute)
+	if run, err := follower.Trigger(ctx, "contended"); err != nil || run.Instance != follower.Instance() {
+		t.Fatalf("Trigger() after the lease expired = %+v, %v", run, err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("job ran %d time(s), want 2", got)
+	}
+}
+
+func TestScheduler_RegisterValidation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestScheduler(t, clock.NewFake(time.Date(2030, 8, 1, 0, 0, 0, 0, time.UTC)))
+
+	if err := s.Register(ctx, "bad-spec", "every day", counter(new(int32))); err == nil {
+		t.Error("Register() with invalid spec expected error, got nil")
+	}
+	if err := s.Register(ctx, "never", "0 0 30 2 *", counter(new(int32))); err == nil {
+		t.Error("Register() with a schedule that never runs expected error, got nil")
+	}
+	if err := s.Register(ctx, "twice", "@daily", counter(new(int32))); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	if err := s.Register(ctx, "twice", "@daily", counter(new(int32))); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Register() twice error = %v, want ErrDuplicateJob", err)
+	}
+}
+
+// concurrencyProbe is a job function recording how many runs overlap.
+type concurrencyProbe struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	calls     int
+}
+
+func (p *concurrencyProbe) run(context.Context) error {
+	p.mu.Lock()
+	p.active++
+	p.calls++
+	if p.active > p.maxActive {
+		p.maxActive = p.active
+	}
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *concurrencyProbe) snapshot() (calls, maxActive int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls, p.maxActive
+}
+
+func TestScheduler_ConcurrentSchedulersRunEachSlotOnce(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 9, 1, 0, 0, 0, 0, time.UTC))
+	probe := &concurrencyProbe{}
+
+	a := newTestScheduler(t, fake)
+	b := NewScheduler(fake)
+	for _, s := range []*Scheduler{a, b} {
+		s.SetLeaseTTL(time.Hour)
+		if err := s.Register(ctx, "contended", "@every 1m", probe.run); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	const rounds = 10
+	leader := 0
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		counts := make([]int, 2)
+		errs := make([]error, 2)
+		for k, s := range []*Scheduler{a, b} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				counts[k], errs[k] = s.RunDue(ctx)
+			}()
+		}
+		wg.Wait()
+
+		for k, err := range errs {
+			if err != nil {
+				t.Fatalf("round %d: scheduler %d RunDue() unexpected error: %v", i, k, err)
+			}
+		}
+		if counts[0]+counts[1] != 1 {
+			t.Fatalf("round %d: schedulers ran %d and %d job(s), want 1 in total", i, counts[0], counts[1])
+		}
+		winner := 0
+		if counts[1] == 1 {
+			winner = 1
+		}
+		if i == 0 {
+			leader = winner
+		} else if winner != leader {
+			t.Fatalf("round %d: scheduler %d ran a job while scheduler %d held the lease", i, winner, leader)
+		}
+		fake.Advance(time.Minute)
+	}
+
+	if calls, maxActive := probe.snapshot(); calls != rounds || maxActive != 1 {
+		t.Errorf("job ran %d time(s) with up to %d concurrent run(s), want %d and 1", calls, maxActive, rounds)
+	}
+}
+
+func TestScheduler_TakesOverExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2030, 10, 1, 0, 0, 0, 0, time.UTC))
+	var calls int32
+
+	crashed := newTestScheduler(t, fake)
+	survivor := NewScheduler(fake)
+	for _, s := range []*Scheduler{crashed, survivor} {
+		s.SetLeaseTTL(5 * time.Minute)
+		if err := s.Register(ctx, "takeover", "@every 1m", counter(&calls)); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	if n := runDue(t, crashed); n != 1 {
+		t.Fatalf("leader RunDue() ran %d job(s), want 1", n)
+	}
+
+	// The leader stops in the middle of a run and never renews its lease
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	orphan, err := dbsqlite.CreateJobRun(ctx, model.JobRun{JobName: "takeover", Trigger: model.JobTriggerSchedule, Instance: crashed.Instance()}, fake.Now(), db)
+	if err != nil {
+		t.Fatalf("CreateJobRun() unexpected error: %v", err)
+	}
+
+	fake.Advance(2 * time.Minute)
+	if n := runDue(t, survivor); n != 0 {
+		t.Fatalf("RunDue() before the lease expired ran %d job(s), want 0", n)
+	}
+
+	fake.Advance(4 * time.Minute)
+	if n := runDue(t, survivor); n != 1 {
+		t.Fatalf("RunDue() after the lease expired ran %d job(s), want 1", n)
+	}
+
+	status, err := survivor.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() unexpected error: %v", err)
+	}
+	if !status.Leader || status.Lease == nil || status.Lease.Holder != survivor.Instance() {
+		t.Errorf("unexpected status after takeover: %+v", status)
+	}
+
+	runs, err := survivor.Runs(ctx, "takeover")
+	if err != nil {
+		t.Fatalf("Runs() unexpected error: %v", err)
+	}
+	for _, run := range runs {
+		if run.ID == orphan && run.Status != model.JobRunInterrupted {
+			t.Errorf("orphaned run status = %q, want %q", run.Status, model.JobRunInterrupted)
+		}
+	}
+	if runs[0].Instance != survivor.Instance() {
+		t.Errorf("latest run by %q, want %q", runs[0].Instance, survivor.Instance())
+	}
+}
+
+// waitFor polls cond until it holds or the deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestScheduler_StartedSchedulersHandOverOnShutdown(t *testing.T) {
+	fake := clock.NewFake(time.Date(2030, 11, 1, 0, 0, 0, 0, time.UTC))
+	probe := &concurrencyProbe{}
+
+	a := newTestScheduler(t, fake)
+	b := NewScheduler(fake)
+	for _, s := range []*Scheduler{a, b} {
+		s.SetPollInterval(5 * time.Millisecond)
+		s.SetLeaseTTL(time.Hour)
+		if err := s.Register(context.Background(), "handover", "@every 1m", probe.run); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	isLeader := func(s *Scheduler) bool {
+		status, err := s.Status(context.Background())
+		return err == nil && status.Leader
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	defer stopA()
+	a.Start(ctxA)
+	waitFor(t, "the first scheduler to lead", func() bool { return isLeader(a) })
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	b.Start(ctxB)
+
+	for i := 0; i < 5; i++ {
+		want, _ := probe.snapshot()
+		fake.Advance(time.Minute)
+		waitFor(t, "a scheduled run", func() bool { calls, _ := probe.snapshot(); return calls > want })
+	}
+	if isLeader(b) {
+		t.Fatal("second scheduler took the lease while the first one held it")
+	}
+
+	stopA()
+	waitFor(t, "the second scheduler to take over", func() bool { return isLeader(b) })
+
+	want, _ := probe.snapshot()
+	fake.Advance(time.Minute)
+	waitFor(t, "a run by the new leader", func() bool { calls, _ := probe.snapshot(); return calls > want })
+
+	if _, maxActive := probe.snapshot(); maxActive != 1 {
+		t.Errorf("up to %d runs overlapped, want 1", maxActive)
+	}
+
+	runs, err := b.Runs(context.Background(), "handover")
+	if err != nil {
+		t.Fatalf("Runs() unexpected error: %v", err)
+	}
+	if runs[0].Instance != b.Instance() {
+		t.Errorf("latest run by %q, want %q", runs[0].Instance, b.Instance())
+	}
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatalf("could not load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"every_minute", "* * * * *", time.Date(2030, 1, 1, 10, 0, 30, 0, time.UTC), time.Date(2030, 1, 1, 10, 1, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"daily_at_three", "0 3 * * *", time.Date(2030, 1, 1, 3, 0, 0, 0, time.UTC), time.Date(2030, 1, 2, 3, 0, 0, 0, time.UTC)},
+		{"monthly_crosses_year", "@monthly", time.Date(2030, 12, 15, 0, 0, 0, 0, time.UTC), time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"step_minutes", "*/15 * * * *", time.Date(2030, 1, 1, 10, 16, 0, 0, time.UTC), time.Date(2030, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"list_and_range", "0 9-17/4 * * 1,3", time.Date(2030, 1, 1, 18, 0, 0, 0, time.UTC), time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"sunday_as_seven", "0 0 * * 7", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"day_31_skips_short_months", "0 0 31 * *", time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"dom_or_dow", "0 0 15 * 1", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"leap_day", "0 0 29 2 *", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2032, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"application_timezone", "0 0 * * *", time.Date(2030, 1, 1, 12, 0, 0, 0, saoPaulo), time.Date(2030, 1, 2, 0, 0, 0, 0, saoPaulo)},
+		{"every_interval", "@every 90m", time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 11, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) unexpected error: %v", tc.spec, err)
+			}
+			if got := s.Next(tc.from); !got.Equal(tc.want) {
+				t.Errorf("Next(%v) = %v, want %v", tc.from, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every",
+		"@every soon",
+		"@every 10ms",
+		"@sometimes",
+	}
+
+	for _, spec := range specs {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) expected error, got nil", spec)
+		}
+	}
+}
+
+func TestCronSchedule_NeverMatches(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseSchedule() unexpected error: %v", err)
+	}
+	if got := s.Next(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %v, want zero time", got)
+	}
+}
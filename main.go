@@ -3,13 +3,18 @@ package main
 import (
 	"context"
 	"log"
+	"natan/fingo/clock"
+	"natan/fingo/controller"
 	"natan/fingo/dbsqlite"
+	"natan/fingo/notify"
 	"natan/fingo/service"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 )
 
 func main() {
@@ -17,19 +22,46 @@ func main() {
 		log.Println(err)
 	}
 
+	configureClock()
+
+	// FINGO_ATTACHMENTS_DIR sets where attachment content is stored, "attachments" when unset
+	service.SetAttachmentsDir(os.Getenv("FINGO_ATTACHMENTS_DIR"))
+
+	// FINGO_TRASH_RETENTION_DAYS sets how many days deleted records can be restored, 30 when unset
+	if v := os.Getenv("FINGO_TRASH_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			log.Printf("Invalid FINGO_TRASH_RETENTION_DAYS %q, keeping the default", v)
+		} else {
+			service.SetTrashRetention(time.Duration(days) * 24 * time.Hour)
+		}
+	}
+
+	// FINGO_INSECURE_COOKIES=true lets the session cookie travel over plain HTTP, for trusted networks only
+	if os.Getenv("FINGO_INSECURE_COOKIES") == "true" {
+		controller.SetSecureCookies(false)
+		log.Println("Session cookies will also be sent over plain HTTP")
+	}
+
 	// Create a context that is canceled on OS signals for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Start the monthly adjustment scheduler in the background
-	service.StartMonthlyAdjustmentScheduler(ctx)
+	// Register the configured notification delivery channels
+	for _, sender := range notificationSendersFromEnv() {
+		service.RegisterNotificationSender(sender)
+	}
 
-	log.Printf("Server listening on PORT 8000...")
-	mux := RouterMux()
+	// Register the background jobs and start running them
+	if err := service.RegisterDefaultJobs(ctx); err != nil {
+		log.Fatalf("could not register background jobs: %v", err)
+	}
+	service.StartJobScheduler(ctx)
 
+	log.Printf("Server listening on PORT 8000...")
 	server := &http.Server{
 		Addr:    ":8000",
-		Handler: mux,
+		Handler: Router(),
 	}
 
 	// Run the server in a goroutine so we can listen for shutdown signals
@@ -52,3 +84,51 @@ func main() {
 
 	log.Println("Server exited gracefully.")
 }
+
+// notificationSendersFromEnv builds the notification delivery channels configured through the environment.
+// Each channel delivers to the address every user sets for it. FINGO_SMTP_ADDR and FINGO_SMTP_FROM enable
+// e-mail delivery; FINGO_USER_WEBHOOKS=true enables webhook delivery, which is opt-in because the server
+// posts to whatever URL users set.
+func notificationSendersFromEnv() []notify.Sender {
+	var senders []notify.Sender
+
+	if addr := os.Getenv("FINGO_SMTP_ADDR"); addr != "" {
+		senders = append(senders, &notify.SMTPSender{
+			Addr: addr,
+			From: os.Getenv("FINGO_SMTP_FROM"),
+		})
+		log.Printf("Notifications will be e-mailed through %s", addr)
+	}
+
+	if enabled, _ := strconv.ParseBool(os.Getenv("FINGO_USER_WEBHOOKS")); enabled {
+		senders = append(senders, &notify.WebhookSender{})
+		log.Println("Notifications will be posted to the webhooks of users")
+	}
+
+	return senders
+}
+
+// configureClock sets the application timezone from FINGO_TZ (an IANA name, the server timezone when unset)
+// and the interval of the built-in jobs from FINGO_SCHEDULER_INTERVAL (a Go duration such as "15m", one hour when unset).
+func configureClock() {
+	loc := time.Local
+	if tz := os.Getenv("FINGO_TZ"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("Invalid FINGO_TZ %q, using the server timezone: %v", tz, err)
+		} else {
+			loc = l
+		}
+	}
+	service.SetClock(clock.System{Location: loc})
+	log.Printf("Application timezone: %s", loc)
+
+	if v := os.Getenv("FINGO_SCHEDULER_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid FINGO_SCHEDULER_INTERVAL %q, keeping the default", v)
+			return
+		}
+		service.SetSchedulerInterval(d)
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import "natan/fingo/utils"
+
+// AdjustmentEntry records what a monthly adjustment applied to a single user
+type AdjustmentEntry struct {
+	ID             int64       `json:"id"`
+	UserID         int64       `json:"user_id"`
+	YearMonth      string      `json:"year_month"`
+	InputsApplied  utils.Money `json:"inputs_applied"`
+	OutputsApplied utils.Money `json:"outputs_applied"`
+	BalanceBefore  utils.Money `json:"balance_before"`
+	BalanceAfter   utils.Money `json:"balance_after"`
+	AppliedAt      string      `json:"applied_at,omitempty"`
+}
+
+// AdjustmentSettings controls when, and whether, monthly adjustments are applied to a user.
+// PayDay is the day of month the adjustment becomes due, clamped to the length of short months.
+// An empty Timezone means the application timezone.
+type AdjustmentSettings struct {
+	UserID     int64  `json:"user_id"`
+	Enabled    bool   `json:"enabled"`
+	PayDay     int    `json:"pay_day"`
+	Timezone   string `json:"timezone"`
+	LastPeriod string `json:"last_period,omitempty"`
+}
+
+// AdjustmentSettingsUpdate is used for partial updates of AdjustmentSettings, where all fields are optional
+type AdjustmentSettingsUpdate struct {
+	Enabled  *bool   `json:"enabled,omitempty"`
+	PayDay   *int    `json:"pay_day,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+}
@@ -0,0 +1,37 @@
+package model
+
+// Scopes an API token is created with. Full tokens may do whatever their user may; read-only tokens may
+// only read.
+const (
+	ScopeFull     = "full"
+	ScopeReadOnly = "read-only"
+)
+
+// APIToken is a personal token scripts sign their requests with instead of a session. Only a hash of the
+// token is kept.
+type APIToken struct {
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"user_id"`
+	Name       string `json:"name"`
+	TokenHash  string `json:"-"`
+	Scope      string `json:"scope"`
+	CreatedAt  string `json:"created_at"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+	// Role is the current role of the user, filled in when the token signs a request.
+	Role string `json:"-"`
+}
+
+// APITokenRequest is the body used to create an API token. Without expires_in_days the token never expires.
+type APITokenRequest struct {
+	Name          string `json:"name"`
+	Scope         string `json:"scope,omitempty"`
+	ExpiresInDays int    `json:"expires_in_days,omitempty"`
+}
+
+// CreatedAPIToken is an API token that was just created, along with the token itself, which is only
+// ever shown this once.
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
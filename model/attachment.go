@@ -0,0 +1,13 @@
+package model
+
+// Attachment is a receipt or document stored alongside a transaction.
+// The content lives in the attachments directory under its SHA-256, so identical files are stored once.
+type Attachment struct {
+	ID            int64  `json:"id"`
+	TransactionID int64  `json:"transaction_id"`
+	FileName      string `json:"file_name"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	CreatedAt     string `json:"created_at,omitempty"`
+}
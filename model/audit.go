@@ -0,0 +1,49 @@
+package model
+
+import "encoding/json"
+
+// Entities whose changes are written to the audit log.
+const (
+	AuditEntityUser        = "user"
+	AuditEntityTransaction = "transaction"
+	AuditEntityGoal        = "goal"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+
+	// Actions on a goal that change who takes part in it or what was put towards it rather than the goal itself.
+	AuditActionSetParticipant    = "set_participant"
+	AuditActionRemoveParticipant = "remove_participant"
+	AuditActionContribute        = "contribute"
+)
+
+// AuditActorSystem is the actor role of changes made by background jobs, such as the monthly adjustments
+// of the scheduler, rather than on behalf of a principal.
+const AuditActorSystem = "system"
+
+// AuditEntry records a change to a user, transaction or goal: who made it, when, and the record as it was
+// before and after. Creations have no before, and deletions and purges no after. The participant and
+// contribution actions on a goal hold the participant or contribution instead of the goal.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	Action    string          `json:"action"`
+	ActorID   int64           `json:"actor_id,omitempty"`
+	ActorRole string          `json:"actor_role"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log query. Empty fields match every entry.
+type AuditFilter struct {
+	Entity   string
+	EntityID int64
+}
@@ -0,0 +1,48 @@
+package model
+
+// Roles a user signs in with. Admins may see and change everything; users only what is theirs.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Principal is who a request is made on behalf of.
+type Principal struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// Credentials are the login and password a user signs in with. Only a hash of the password is kept.
+type Credentials struct {
+	UserID       int64  `json:"user_id"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+}
+
+// RoleUpdate is the body used to change the role of a user.
+type RoleUpdate struct {
+	Role string `json:"role"`
+}
+
+// CredentialsUpdate is the body used to set the login and password of a user.
+type CredentialsUpdate struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// LoginRequest is the body of a login attempt.
+type LoginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// Session is a signed-in browser. The token given to the browser is never stored, only its hash.
+type Session struct {
+	TokenHash string `json:"-"`
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
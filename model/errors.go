@@ -0,0 +1,64 @@
+package model
+
+// ErrorKind classifies a domain error by what went wrong, which decides the HTTP status of the response. A
+// kind is itself an error, so errors.Is(err, KindNotFound) tells whether err is a not found error.
+type ErrorKind string
+
+// Kinds of domain errors, also sent to clients as the code of the error.
+const (
+	KindValidation   ErrorKind = "validation_failed"
+	KindNotFound     ErrorKind = "not_found"
+	KindConflict     ErrorKind = "conflict"
+	KindForbidden    ErrorKind = "forbidden"
+	KindUnauthorized ErrorKind = "unauthorized"
+	KindTooLarge     ErrorKind = "too_large"
+)
+
+func (k ErrorKind) Error() string {
+	return string(k)
+}
+
+// FieldError describes what is wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error: a failure of a known kind with a message meant for the client, the fields at
+// fault when the request did not validate, and the error that caused it, if any.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// NewError returns an error of the given kind. Sentinel errors are declared with it and wrapped with
+// fmt.Errorf to add details.
+func NewError(kind ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// NotFoundError returns a not found error for the record described by what, caused by err.
+func NotFoundError(what string, err error) *Error {
+	return &Error{Kind: KindNotFound, Message: what + " not found", Err: err}
+}
+
+// ValidationError returns a validation error listing every field at fault.
+func ValidationError(fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: "invalid request", Fields: fields}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error is of the kind given as target.
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == e.Kind
+}
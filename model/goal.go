@@ -15,6 +15,8 @@ type Goal struct {
 	UserID    int64       `json:"user_id"`
 	CreatedAt string      `json:"created_at,omitempty"`
 	Deadline  string      `json:"deadline"`
+	Tags      []string    `json:"tags,omitempty"`
+	DeletedAt string      `json:"deleted_at,omitempty"`
 }
 
 // GoalUpdate is used for partial updates of Goal, where all fields are optional
@@ -25,4 +27,24 @@ type GoalUpdate struct {
 	Pros     *string      `json:"pros,omitempty"`
 	Cons     *string      `json:"cons,omitempty"`
 	Deadline *string      `json:"deadline,omitempty"`
+	Tags     *[]string    `json:"tags,omitempty"`
+}
+
+// GoalParticipant links a user to a shared goal with the part of the price they intend to cover
+type GoalParticipant struct {
+	GoalID      int64       `json:"goal_id"`
+	UserID      int64       `json:"user_id"`
+	TargetShare utils.Money `json:"target_share"`
+	Contributed utils.Money `json:"contributed"`
+	JoinedAt    string      `json:"joined_at,omitempty"`
+}
+
+// GoalContribution is an amount a user has put towards a goal
+type GoalContribution struct {
+	ID        int64       `json:"id"`
+	GoalID    int64       `json:"goal_id"`
+	UserID    int64       `json:"user_id"`
+	Amount    utils.Money `json:"amount"`
+	Note      string      `json:"note,omitempty"`
+	CreatedAt string      `json:"created_at,omitempty"`
 }
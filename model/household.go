@@ -0,0 +1,62 @@
+package model
+
+import "natan/fingo/utils"
+
+// Roles a user has in a household. Owners manage the household and its members; members see it.
+const (
+	HouseholdRoleOwner  = "owner"
+	HouseholdRoleMember = "member"
+)
+
+// Household groups the users of a family. A user belongs to at most one household.
+type Household struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	CreatedAt string            `json:"created_at,omitempty"`
+	Members   []HouseholdMember `json:"members,omitempty"`
+}
+
+// HouseholdUpdate is the body used to rename a household.
+type HouseholdUpdate struct {
+	Name *string `json:"name,omitempty"`
+}
+
+// HouseholdMember links a user to the household they belong to with their role in it
+type HouseholdMember struct {
+	HouseholdID int64  `json:"household_id"`
+	UserID      int64  `json:"user_id"`
+	UserName    string `json:"user_name,omitempty"`
+	Role        string `json:"role"`
+	JoinedAt    string `json:"joined_at,omitempty"`
+}
+
+// HouseholdInvite is an invitation for a user to join a household with a role. The user becomes a member
+// only once they accept it.
+type HouseholdInvite struct {
+	HouseholdID   int64  `json:"household_id"`
+	HouseholdName string `json:"household_name,omitempty"`
+	UserID        int64  `json:"user_id"`
+	Role          string `json:"role"`
+	InvitedBy     int64  `json:"invited_by,omitempty"`
+	CreatedAt     string `json:"created_at,omitempty"`
+}
+
+// HouseholdBalance adds up the balances and monthly figures of the members of a household
+type HouseholdBalance struct {
+	HouseholdID    int64       `json:"household_id"`
+	Name           string      `json:"name"`
+	CurrentAmount  utils.Money `json:"current_amount"`
+	MonthlyInputs  utils.Money `json:"monthly_inputs"`
+	MonthlyOutputs utils.Money `json:"monthly_outputs"`
+	Members        []User      `json:"members"`
+}
+
+// HouseholdCashflowReport adds up the cash flow of the members of a household per period between From
+// and To, both inclusive
+type HouseholdCashflowReport struct {
+	HouseholdID int64            `json:"household_id"`
+	Granularity string           `json:"granularity"`
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Periods     []CashflowPeriod `json:"periods"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "natan/fingo/utils"
+
+// Insight kinds
+const (
+	InsightUnusualAmount = "unusual_amount"
+	InsightDuplicate     = "possible_duplicate"
+	InsightNewMerchant   = "new_merchant"
+)
+
+// Insight flags a transaction that looks unusual for the user. Merchant is the normalised description the
+// transaction was compared by. Typical is the usual amount spent there, set for unusual amounts, and
+// RelatedTransactionID is the earlier charge a possible duplicate repeats.
+type Insight struct {
+	Kind                 string      `json:"kind"`
+	TransactionID        int64       `json:"transaction_id"`
+	RelatedTransactionID int64       `json:"related_transaction_id,omitempty"`
+	Merchant             string      `json:"merchant"`
+	Amount               utils.Money `json:"amount"`
+	Typical              utils.Money `json:"typical,omitempty"`
+	CreatedAt            string      `json:"created_at"`
+	Message              string      `json:"message"`
+}
+
+// Insights lists the flags raised for the expenses a user recorded between From and To, newest first
+type Insights struct {
+	UserID   int64     `json:"user_id"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Insights []Insight `json:"insights"`
+}
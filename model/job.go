@@ -0,0 +1,53 @@
+package model
+
+// Job run statuses
+const (
+	JobRunRunning     = "running"
+	JobRunSucceeded   = "succeeded"
+	JobRunFailed      = "failed"
+	JobRunInterrupted = "interrupted"
+)
+
+// Job run triggers
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// Job is a registered background job with its schedule and most recent run
+type Job struct {
+	Name      string  `json:"name"`
+	Schedule  string  `json:"schedule"`
+	NextRunAt string  `json:"next_run_at"`
+	Running   bool    `json:"running"`
+	LastRun   *JobRun `json:"last_run,omitempty"`
+}
+
+// JobRun records a single execution of a job and its outcome.
+// ScheduledFor is the schedule slot the run was claimed for, empty for manual runs.
+// Instance identifies the fingo process that ran the job.
+type JobRun struct {
+	ID           int64  `json:"id"`
+	JobName      string `json:"job_name"`
+	Trigger      string `json:"trigger"`
+	ScheduledFor string `json:"scheduled_for,omitempty"`
+	StartedAt    string `json:"started_at"`
+	FinishedAt   string `json:"finished_at,omitempty"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	Instance     string `json:"instance,omitempty"`
+}
+
+// Lease grants the scheduled work to a single fingo instance until it expires
+type Lease struct {
+	Name      string `json:"name"`
+	Holder    string `json:"holder"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// SchedulerStatus tells whether this instance currently runs the scheduled jobs
+type SchedulerStatus struct {
+	Instance string `json:"instance"`
+	Leader   bool   `json:"leader"`
+	Lease    *Lease `json:"lease,omitempty"`
+}
@@ -0,0 +1,49 @@
+package model
+
+// Notification kinds produced by the notification rules
+const (
+	NotificationGoalDeadline    = "goal_deadline"
+	NotificationBudgetExceeded  = "budget_exceeded"
+	NotificationNegativeBalance = "negative_balance"
+)
+
+// Notification is a message addressed to a user, kept in an outbox until delivered
+type Notification struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Kind        string `json:"kind"`
+	Message     string `json:"message"`
+	DedupKey    string `json:"-"`
+	Read        bool   `json:"read"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	DeliveredAt string `json:"delivered_at,omitempty"`
+}
+
+// PendingDelivery is a notification due to be sent through a channel, with the attempts already made.
+type PendingDelivery struct {
+	Notification Notification
+	Channel      string
+	Attempts     int
+}
+
+// NotificationReadUpdate marks notifications of a user as read or unread.
+// An empty IDs list applies the change to all notifications of the user.
+type NotificationReadUpdate struct {
+	IDs  []int64 `json:"ids,omitempty"`
+	Read *bool   `json:"read"`
+}
+
+// NotificationChannels holds the addresses notifications of a user are delivered to. A channel whose address
+// is empty does not deliver to the user.
+type NotificationChannels struct {
+	UserID     int64  `json:"user_id"`
+	Email      string `json:"email"`
+	WebhookURL string `json:"webhook_url"`
+}
+
+// NotificationChannelsUpdate is used for partial updates of NotificationChannels, where all fields are optional.
+// An empty string clears the address.
+type NotificationChannelsUpdate struct {
+	Email      *string `json:"email,omitempty"`
+	WebhookURL *string `json:"webhook_url,omitempty"`
+}
@@ -0,0 +1,150 @@
+package model
+
+import "natan/fingo/utils"
+
+// Report granularities
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+	GranularityYear  = "year"
+)
+
+// CashflowPeriod summarises a user's money movements over one report period.
+// Income and Expenses come from transactions, Adjustments from monthly adjustments;
+// Net is Income minus Expenses and EndingBalance the balance at the end of the period.
+type CashflowPeriod struct {
+	Period        string      `json:"period"`
+	Start         string      `json:"start"`
+	End           string      `json:"end"`
+	Income        utils.Money `json:"income"`
+	Expenses      utils.Money `json:"expenses"`
+	Adjustments   utils.Money `json:"adjustments"`
+	Net           utils.Money `json:"net"`
+	EndingBalance utils.Money `json:"ending_balance"`
+}
+
+// CashflowReport lists the cash flow of a user per period between From and To, both inclusive
+type CashflowReport struct {
+	UserID      int64            `json:"user_id"`
+	Granularity string           `json:"granularity"`
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Periods     []CashflowPeriod `json:"periods"`
+}
+
+// MonthSummary totals the transactions of a user between From and To.
+// SavingsRate is Net as a percentage of Income, null when there is no income.
+type MonthSummary struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Income      utils.Money `json:"income"`
+	Expenses    utils.Money `json:"expenses"`
+	Net         utils.Money `json:"net"`
+	SavingsRate *float64    `json:"savings_rate"`
+}
+
+// MonthComparison compares month-to-date figures with the same point of the previous month.
+// Percentages are relative to the previous month and null when it had nothing to compare with.
+type MonthComparison struct {
+	IncomeChange          utils.Money `json:"income_change"`
+	ExpensesChange        utils.Money `json:"expenses_change"`
+	NetChange             utils.Money `json:"net_change"`
+	IncomeChangePercent   *float64    `json:"income_change_percent"`
+	ExpensesChangePercent *float64    `json:"expenses_change_percent"`
+}
+
+// GoalProgress tells how far a goal the user owns or shares is from being funded
+type GoalProgress struct {
+	GoalID          int64       `json:"goal_id"`
+	Name            string      `json:"name"`
+	Price           utils.Money `json:"price"`
+	Contributed     utils.Money `json:"contributed"`
+	UserContributed utils.Money `json:"user_contributed"`
+	Progress        *float64    `json:"progress"`
+	Deadline        string      `json:"deadline"`
+	Shared          bool        `json:"shared"`
+}
+
+// GoalsSummary totals the goals a user owns or shares
+type GoalsSummary struct {
+	Count            int            `json:"count"`
+	TotalPrice       utils.Money    `json:"total_price"`
+	TotalContributed utils.Money    `json:"total_contributed"`
+	Progress         *float64       `json:"progress"`
+	Overdue          int            `json:"overdue"`
+	Goals            []GoalProgress `json:"goals"`
+}
+
+// Dashboard gathers the figures shown on a user's home screen
+type Dashboard struct {
+	UserID          int64           `json:"user_id"`
+	AsOf            string          `json:"as_of"`
+	CurrentBalance  utils.Money     `json:"current_balance"`
+	MonthToDate     MonthSummary    `json:"month_to_date"`
+	PreviousMonth   MonthSummary    `json:"previous_month"`
+	Comparison      MonthComparison `json:"comparison"`
+	LargestExpenses []Transaction   `json:"largest_expenses"`
+	Goals           GoalsSummary    `json:"goals"`
+}
+
+// Projection scenarios
+const (
+	ScenarioExpected    = "expected"
+	ScenarioOptimistic  = "optimistic"
+	ScenarioPessimistic = "pessimistic"
+)
+
+// ProjectionMonth is the forecast balance of a user at the end of a month under each scenario.
+// Adjustments is the net of the monthly adjustments expected during the month.
+type ProjectionMonth struct {
+	YearMonth   string      `json:"year_month"`
+	EndDate     string      `json:"end_date"`
+	Adjustments utils.Money `json:"adjustments"`
+	Expected    utils.Money `json:"expected"`
+	Optimistic  utils.Money `json:"optimistic"`
+	Pessimistic utils.Money `json:"pessimistic"`
+}
+
+// ProjectionWarning reports that the balance of a scenario is forecast to go negative before the
+// next monthly adjustment, or before the end of the projection when no adjustment is expected
+type ProjectionWarning struct {
+	Scenario       string `json:"scenario"`
+	NegativeOn     string `json:"negative_on"`
+	NextAdjustment string `json:"next_adjustment,omitempty"`
+	Message        string `json:"message"`
+}
+
+// Projection forecasts a user's balance per month from the current balance, the scheduled monthly
+// adjustments and the average daily spend of the last LookbackDays days
+type Projection struct {
+	UserID                int64               `json:"user_id"`
+	AsOf                  string              `json:"as_of"`
+	CurrentBalance        utils.Money         `json:"current_balance"`
+	LookbackDays          int                 `json:"lookback_days"`
+	AverageDailySpend     utils.Money         `json:"average_daily_spend"`
+	OptimisticDailySpend  utils.Money         `json:"optimistic_daily_spend"`
+	PessimisticDailySpend utils.Money         `json:"pessimistic_daily_spend"`
+	NextAdjustment        string              `json:"next_adjustment,omitempty"`
+	Months                []ProjectionMonth   `json:"months"`
+	Warnings              []ProjectionWarning `json:"warnings"`
+}
+
+// CategoryTotal sums the income and expenses of a category. Split transactions count towards the category
+// of each split; Transactions counts the transactions with some amount in the category.
+type CategoryTotal struct {
+	Category     string      `json:"category"`
+	Transactions int         `json:"transactions"`
+	Income       utils.Money `json:"income"`
+	Expenses     utils.Money `json:"expenses"`
+	Net          utils.Money `json:"net"`
+}
+
+// CategoryReport lists the totals of every category a user recorded transactions in. From and To, when
+// set, bound the transactions counted. Uncategorised amounts are under the empty category.
+type CategoryReport struct {
+	UserID     int64           `json:"user_id"`
+	From       string          `json:"from,omitempty"`
+	To         string          `json:"to,omitempty"`
+	Categories []CategoryTotal `json:"categories"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import "natan/fingo/utils"
+
+// Rule assigns a category and tags to the transactions of a user that match all of its conditions.
+// DescriptionContains matches case-insensitively; DescriptionRegex uses Go regular expression syntax.
+// MinAmount and MaxAmount are inclusive. Rules are evaluated by ascending Priority: the first matching rule
+// with a category decides it, while the tags of every matching rule are added.
+type Rule struct {
+	ID                  int64        `json:"id"`
+	UserID              int64        `json:"user_id"`
+	Name                string       `json:"name"`
+	Priority            int          `json:"priority"`
+	DescriptionContains string       `json:"description_contains,omitempty"`
+	DescriptionRegex    string       `json:"description_regex,omitempty"`
+	MinAmount           *utils.Money `json:"min_amount,omitempty"`
+	MaxAmount           *utils.Money `json:"max_amount,omitempty"`
+	IsDebt              *bool        `json:"is_debt,omitempty"`
+	Category            string       `json:"category,omitempty"`
+	Tags                []string     `json:"tags,omitempty"`
+	Enabled             bool         `json:"enabled"`
+	CreatedAt           string       `json:"created_at,omitempty"`
+}
+
+// RuleUpdate is used for partial updates of Rule, where all fields are optional
+type RuleUpdate struct {
+	Name                *string      `json:"name,omitempty"`
+	Priority            *int         `json:"priority,omitempty"`
+	DescriptionContains *string      `json:"description_contains,omitempty"`
+	DescriptionRegex    *string      `json:"description_regex,omitempty"`
+	MinAmount           *utils.Money `json:"min_amount,omitempty"`
+	MaxAmount           *utils.Money `json:"max_amount,omitempty"`
+	IsDebt              *bool        `json:"is_debt,omitempty"`
+	Category            *string      `json:"category,omitempty"`
+	Tags                *[]string    `json:"tags,omitempty"`
+	Enabled             *bool        `json:"enabled,omitempty"`
+}
+
+// RuleChange is what applying the rules does to one transaction. Category is empty when it stays as it is.
+type RuleChange struct {
+	TransactionID    int64    `json:"transaction_id"`
+	Description      string   `json:"description"`
+	PreviousCategory string   `json:"previous_category,omitempty"`
+	Category         string   `json:"category,omitempty"`
+	AddedTags        []string `json:"added_tags,omitempty"`
+	RuleIDs          []int64  `json:"rule_ids"`
+}
+
+// RuleApplyResult reports the transactions a rules run changed, or would change when DryRun is set
+type RuleApplyResult struct {
+	UserID   int64        `json:"user_id"`
+	DryRun   bool         `json:"dry_run"`
+	Examined int          `json:"examined"`
+	Changed  int          `json:"changed"`
+	Changes  []RuleChange `json:"changes"`
+}
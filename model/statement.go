@@ -0,0 +1,23 @@
+package model
+
+import "natan/fingo/utils"
+
+// Statement is the printable summary of a user's month: the balances it opened and closed with,
+// every transaction recorded in it, the monthly adjustment for it and the goals progress at its end.
+// Adjustments is the net of the monthly adjustments applied during the month.
+type Statement struct {
+	UserID         int64            `json:"user_id"`
+	UserName       string           `json:"user_name"`
+	YearMonth      string           `json:"year_month"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	GeneratedAt    string           `json:"generated_at"`
+	OpeningBalance utils.Money      `json:"opening_balance"`
+	Income         utils.Money      `json:"income"`
+	Expenses       utils.Money      `json:"expenses"`
+	Adjustments    utils.Money      `json:"adjustments"`
+	ClosingBalance utils.Money      `json:"closing_balance"`
+	Transactions   []Transaction    `json:"transactions"`
+	Adjustment     *AdjustmentEntry `json:"adjustment,omitempty"`
+	Goals          []GoalProgress   `json:"goals"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "natan/fingo/utils"
+
+// Tag is a free-form label a user attaches to transactions and goals.
+// Transactions and Goals count what carries the tag.
+type Tag struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	Name         string `json:"name"`
+	Transactions int    `json:"transactions"`
+	Goals        int    `json:"goals"`
+}
+
+// TagTotal sums the transactions carrying a tag and the goals tagged with it
+type TagTotal struct {
+	Tag          string      `json:"tag"`
+	Transactions int         `json:"transactions"`
+	Income       utils.Money `json:"income"`
+	Expenses     utils.Money `json:"expenses"`
+	Net          utils.Money `json:"net"`
+	Goals        int         `json:"goals"`
+	GoalsPrice   utils.Money `json:"goals_price"`
+}
+
+// TagReport lists the totals of every tag of a user. From and To, when set, bound the transactions counted.
+type TagReport struct {
+	UserID int64      `json:"user_id"`
+	From   string     `json:"from,omitempty"`
+	To     string     `json:"to,omitempty"`
+	Tags   []TagTotal `json:"tags"`
+}
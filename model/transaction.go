@@ -12,11 +12,29 @@ type Transaction struct {
 	IsDebt    bool        `json:"is_debt"`
 	CreatedAt string      `json:"created_at,omitempty"`
 	UserID    int64       `json:"user_id"`
+	Category  string      `json:"category,omitempty"`
+	Tags      []string    `json:"tags,omitempty"`
+	Splits    []Split     `json:"splits,omitempty"`
+	DeletedAt string      `json:"deleted_at,omitempty"`
 }
 
 // TransactionUpdate is used for partial updates of Transaction, where all fields are optional
 type TransactionUpdate struct {
-	Desc   *string      `json:"description,omitempty"`
-	Amount *utils.Money `json:"amount,omitempty"`
-	IsDebt *bool        `json:"is_debt,omitempty"`
+	Desc     *string      `json:"description,omitempty"`
+	Amount   *utils.Money `json:"amount,omitempty"`
+	IsDebt   *bool        `json:"is_debt,omitempty"`
+	Category *string      `json:"category,omitempty"`
+	Tags     *[]string    `json:"tags,omitempty"`
+	Splits   *[]Split     `json:"splits,omitempty"`
+}
+
+// Split is a part of a transaction with its own category, such as the cleaning products on a supermarket
+// receipt. The splits of a transaction add up to its amount; a split without a category falls under the
+// category of the transaction.
+type Split struct {
+	ID            int64       `json:"id"`
+	TransactionID int64       `json:"transaction_id"`
+	Amount        utils.Money `json:"amount"`
+	Category      string      `json:"category,omitempty"`
+	Note          string      `json:"note,omitempty"`
 }
@@ -0,0 +1,15 @@
+package model
+
+// Trash holds the deleted users, transactions and goals that can still be restored, newest deletions first.
+type Trash struct {
+	Users        []User        `json:"users"`
+	Transactions []Transaction `json:"transactions"`
+	Goals        []Goal        `json:"goals"`
+}
+
+// PurgeResult counts the records a purge of the trash deleted for good.
+type PurgeResult struct {
+	Users        int64 `json:"users"`
+	Transactions int64 `json:"transactions"`
+	Goals        int64 `json:"goals"`
+}
@@ -9,6 +9,7 @@ type User struct {
 	CurrentAmount  utils.Money `json:"current_amount"`
 	MonthlyInputs  utils.Money `json:"monthly_inputs"`
 	MonthlyOutputs utils.Money `json:"monthly_outputs"`
+	DeletedAt      string      `json:"deleted_at,omitempty"`
 }
 
 // UserUpdate is used for partial updates of User, where all fields are optional
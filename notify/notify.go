@@ -0,0 +1,32 @@
+// Package notify delivers user notifications through pluggable channels such as e-mail and webhooks.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoAddress is returned by a Sender when the user a message is addressed to has no address on its channel.
+var ErrNoAddress = errors.New("the user has no address on this channel")
+
+// Message is the channel-independent content of a notification being delivered, with the addresses of
+// the user it is addressed to. The addresses are never part of the content.
+type Message struct {
+	NotificationID int64  `json:"notification_id"`
+	UserID         int64  `json:"user_id"`
+	UserName       string `json:"user_name"`
+	Kind           string `json:"kind"`
+	Subject        string `json:"subject"`
+	Body           string `json:"body"`
+	Email          string `json:"-"`
+	WebhookURL     string `json:"-"`
+}
+
+// Sender delivers a message through a single channel.
+type Sender interface {
+	// Name identifies the channel in logs.
+	Name() string
+	// Send delivers the message to the user it is addressed to, returning ErrNoAddress if the user has no
+	// address on the channel, or another error if the channel rejected it.
+	Send(ctx context.Context, msg Message) error
+}
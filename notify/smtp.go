@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPSender delivers messages by e-mail through any SMTP-compatible server, to the e-mail address of the
+// user each message is addressed to.
+type SMTPSender struct {
+	Addr string // host:port of the SMTP server
+	From string
+	Auth smtp.Auth // optional; nil sends without authentication
+}
+
+// Name identifies the channel in logs.
+func (s *SMTPSender) Name() string {
+	return "smtp"
+}
+
+// Send writes the message as a plain-text e-mail to the user's e-mail address.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.Email == "" {
+		return ErrNoAddress
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	b.WriteString("\r\n")
+
+	if err := smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.Email}, []byte(b.String())); err != nil {
+		return fmt.Errorf("could not send e-mail through %s: %w", s.Addr, err)
+	}
+
+	return nil
+}
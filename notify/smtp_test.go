@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single SMTP session on a local port and sends the DATA section to the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"), strings.HasPrefix(cmd, "NOOP"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 end with <CRLF>.<CRLF>")
+				var body strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					body.WriteString(l)
+				}
+				data <- body.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), data
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+
+	sender := &SMTPSender{Addr: addr, From: "fingo@localhost"}
+	msg := Message{Subject: "Goal deadline approaching", Body: "Your goal Trip is due in 3 days.", Email: "alice@localhost"}
+
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+
+	got := <-data
+	for _, want := range []string{"Subject: Goal deadline approaching", "To: alice@localhost", msg.Body} {
+		if !strings.Contains(got, want) {
+			t.Errorf("e-mail does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSMTPSender_NoAddress(t *testing.T) {
+	sender := &SMTPSender{Addr: "127.0.0.1:1", From: "fingo@localhost"}
+	if err := sender.Send(context.Background(), Message{}); !errors.Is(err, ErrNoAddress) {
+		t.Fatalf("Send() to a user without an e-mail address error = %v, want ErrNoAddress", err)
+	}
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookSender delivers messages by POSTing them as JSON to the webhook URL of the user each message is
+// addressed to.
+type WebhookSender struct {
+	Client *http.Client // optional; defaults to a client with a 10 second timeout
+}
+
+// Name identifies the channel in logs.
+func (s *WebhookSender) Name() string {
+	return "webhook"
+}
+
+// Send posts the message as JSON to the user's webhook URL and treats any non-2xx response as a failure.
+func (s *WebhookSender) Send(ctx context.Context, msg Message) error {
+	if msg.WebhookURL == "" {
+		return ErrNoAddress
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not post webhook to %s: %w", msg.WebhookURL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", msg.WebhookURL, res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSender_Send(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusNoContent, false},
+		{"rejected", http.StatusInternalServerError, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Message
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST, got %s", r.Method)
+				}
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected JSON content type, got %q", ct)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("could not decode webhook body: %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			sender := &WebhookSender{}
+			msg := Message{NotificationID: 7, UserID: 3, Kind: "goal_deadline", Subject: "Deadline", Body: "soon", Email: "alice@localhost", WebhookURL: server.URL}
+
+			err := sender.Send(context.Background(), msg)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Send() error = %v, wantErr = %v", err, tc.wantErr)
+			}
+			want := msg
+			want.Email, want.WebhookURL = "", ""
+			if got != want {
+				t.Errorf("payload mismatch: got %+v want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestWebhookSender_NoAddress(t *testing.T) {
+	sender := &WebhookSender{}
+	if err := sender.Send(context.Background(), Message{}); !errors.Is(err, ErrNoAddress) {
+		t.Fatalf("Send() to a user without a webhook URL error = %v, want ErrNoAddress", err)
+	}
+}
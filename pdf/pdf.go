@@ -0,0 +1,202 @@
+// Package pdf writes simple PDF documents made of text and lines, using the standard Type 1 fonts every
+// PDF reader ships with, so no font files have to be embedded.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page sizes in points (1/72 inch), the unit of every coordinate. The origin is the bottom-left corner.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font is one of the standard fonts available to every page.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+	Courier
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Courier"}
+
+// MonoWidth returns the width of s written in Courier at the given size. Courier is monospaced, which makes
+// it the font to use for right-aligned columns such as amounts.
+func MonoWidth(s string, size float64) float64 {
+	return float64(len(encode(s))) * 0.6 * size
+}
+
+// Document is a PDF document being built, page by page.
+type Document struct {
+	title string
+	pages []*Page
+}
+
+// Page is a single page of a Document.
+type Page struct {
+	content bytes.Buffer
+}
+
+// New returns an empty document with the given title.
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage appends a new A4 portrait page to the document and returns it.
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text writes s with its baseline starting at x, y. Characters outside the Windows-1252 set are written as "?".
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, num(size), num(x), num(y), escape(encode(s)))
+}
+
+// TextRight writes s in Courier so that it ends at x.
+func (p *Page) TextRight(x, y, size float64, s string) {
+	p.Text(x-MonoWidth(s, size), y, Courier, size, s)
+}
+
+// Line draws a line of the given width from x1, y1 to x2, y2.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// WriteTo writes the document to w. A document without pages gets a single blank page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int64
+
+	// Objects are numbered from 1: the catalog, the page tree, the info dictionary, the fonts, then each
+	// page followed by its content stream.
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.n)
+		fmt.Fprintf(out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			fmt.Fprintf(out, "stream\n%s\nendstream\n", stream)
+		}
+		fmt.Fprint(out, "endobj\n")
+	}
+
+	const firstFont = 4
+	firstPage := firstFont + len(fontNames)
+
+	fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+
+	object(fmt.Sprintf("<< /Title (%s) /Producer (fingo) >>", escape(encode(d.title))), nil)
+
+	fonts := make([]string, len(fontNames))
+	for i, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name), nil)
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i+1, firstFont+i)
+	}
+
+	for i, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			num(A4Width), num(A4Height), strings.Join(fonts, " "), firstPage+2*i+1), nil)
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(p.content.Bytes())
+		zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", compressed.Len()), compressed.Bytes())
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	return out.n, out.w.Flush()
+}
+
+// countingWriter tracks how many bytes were written, which the cross-reference table needs,
+// and remembers the first error so the writes above need not be checked one by one.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// num formats a coordinate or size without needless decimals.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// cp1252 maps the characters Windows-1252 places in 0x80-0x9F, the range where it departs from Latin-1.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89,
+	'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encode converts s to Windows-1252 (WinAnsiEncoding), the encoding of the standard fonts.
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			b = append(b, ' ')
+		case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			b = append(b, byte(r))
+		default:
+			if c, ok := cp1252[r]; ok {
+				b = append(b, c)
+			} else {
+				b = append(b, '?')
+			}
+		}
+	}
+	return b
+}
+
+// escape escapes the characters with a meaning inside a PDF literal string.
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '\\' || c == '(' || c == ')' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo_CrossReferenceTable(t *testing.T) {
+	doc := New("Statement")
+	doc.AddPage().Text(50, 800, Helvetica, 12, "first page")
+	doc.AddPage().Line(50, 50, 545, 50, 0.5)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() returned error: %v", err)
+	}
+	out := buf.Bytes()
+	if n != int64(len(out)) {
+		t.Errorf("WriteTo() reported %d bytes, wrote %d", n, len(out))
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Errorf("expected a page tree with 2 pages")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	// Every in-use entry must point at the start of its object
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 3+len(fontNames)+2*2 {
+		t.Fatalf("expected %d objects, got %d", 3+len(fontNames)+4, len(entries))
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(out[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, out[off:off+10], want)
+		}
+	}
+}
+
+func TestText_EncodesAndEscapes(t *testing.T) {
+	doc := New("t")
+	doc.AddPage().Text(10, 20, HelveticaBold, 9.5, `Café (50%) \ 10€ 漢`)
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() returned error: %v", err)
+	}
+
+	content := pageContent(t, buf.Bytes())
+	want := "BT /F2 9.5 Tf 10 20 Td (Caf\xe9 \\(50%\\) \\\\ 10\x80 ?) Tj ET\n"
+	if content != want {
+		t.Errorf("page content = %q, want %q", content, want)
+	}
+}
+
+func TestTextRight(t *testing.T) {
+	if got := MonoWidth("1,234.56", 10); got != 48 {
+		t.Errorf("MonoWidth() = %v, want 48", got)
+	}
+
+	doc := New("t")
+	doc.AddPage().TextRight(100, 20, 10, "12.00")
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() returned error: %v", err)
+	}
+	if content := pageContent(t, buf.Bytes()); !strings.HasPrefix(content, "BT /F3 10 Tf 70 20 Td (12.00)") {
+		t.Errorf("page content = %q, want Courier text starting at x=70", content)
+	}
+}
+
+// pageContent inflates the content stream of the first page.
+func pageContent(t *testing.T, out []byte) string {
+	t.Helper()
+
+	start := bytes.Index(out, []byte("stream\n"))
+	end := bytes.Index(out, []byte("\nendstream"))
+	if start < 0 || end < 0 {
+		t.Fatalf("missing content stream")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(out[start+len("stream\n") : end]))
+	if err != nil {
+		t.Fatalf("could not inflate content stream: %v", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not inflate content stream: %v", err)
+	}
+	return string(b)
+}
@@ -15,11 +15,43 @@ type Route struct {
 var UserRoutes = []Route{
 	{"GET", "/users/{id}", controller.GetUserByIDHandler},
 	{"GET", "/users", controller.GetAllUsersHandler},
-	{"GET", "/users/transactions/{id}", controller.GetAllTransactionsByUserIDHandler},
-	{"GET", "/users/goals/{id}", controller.GetAllGoalsByUserIDHandler},
+	{"GET", "/users/{id}/transactions", controller.GetAllTransactionsByUserIDHandler},
+	{"GET", "/users/{id}/goals", controller.GetAllGoalsByUserIDHandler},
+	{"GET", "/users/{collection}/{id}", controller.LegacyUserListingHandler},
 	{"POST", "/users", controller.CreateUserHandler},
 	{"PATCH", "/users/{id}", controller.UpdateUserByIDHandler},
 	{"DELETE", "/users/{id}", controller.DeleteUserByIDHandler},
+	{"POST", "/users/{id}/restore", controller.RestoreUserHandler},
+	{"GET", "/users/{id}/notifications", controller.GetNotificationsByUserIDHandler},
+	{"PATCH", "/users/{id}/notifications", controller.UpdateNotificationsByUserIDHandler},
+	{"GET", "/users/{id}/notification-channels", controller.GetNotificationChannelsHandler},
+	{"PATCH", "/users/{id}/notification-channels", controller.UpdateNotificationChannelsHandler},
+	{"GET", "/users/{id}/adjustments", controller.GetAdjustmentsByUserIDHandler},
+	{"GET", "/users/{id}/adjustment-settings", controller.GetAdjustmentSettingsHandler},
+	{"PATCH", "/users/{id}/adjustment-settings", controller.UpdateAdjustmentSettingsHandler},
+	{"GET", "/users/{id}/reports/cashflow", controller.GetCashflowReportHandler},
+	{"GET", "/users/{id}/reports/categories", controller.GetCategoryReportHandler},
+	{"GET", "/users/{id}/dashboard", controller.GetDashboardHandler},
+	{"GET", "/users/{id}/projection", controller.GetProjectionHandler},
+	{"GET", "/users/{id}/statements/{yearMonth}", controller.GetStatementHandler},
+	{"GET", "/users/{id}/insights", controller.GetInsightsHandler},
+	{"GET", "/users/{id}/rules", controller.GetRulesHandler},
+	{"POST", "/users/{id}/rules", controller.CreateRuleHandler},
+	{"PATCH", "/users/{id}/rules/{ruleID}", controller.UpdateRuleHandler},
+	{"DELETE", "/users/{id}/rules/{ruleID}", controller.DeleteRuleHandler},
+	{"POST", "/users/{id}/rules/apply", controller.ApplyRulesHandler},
+	{"GET", "/users/{id}/tags", controller.GetTagsHandler},
+	{"POST", "/users/{id}/tags", controller.CreateTagHandler},
+	{"PATCH", "/users/{id}/tags/{tagID}", controller.RenameTagHandler},
+	{"DELETE", "/users/{id}/tags/{tagID}", controller.DeleteTagHandler},
+	{"GET", "/users/{id}/reports/tags", controller.GetTagReportHandler},
+	{"GET", "/users/{id}/credentials", controller.GetCredentialsHandler},
+	{"PUT", "/users/{id}/credentials", controller.SetCredentialsHandler},
+	{"PUT", "/users/{id}/role", controller.SetRoleHandler},
+	{"GET", "/users/{id}/tokens", controller.GetAPITokensHandler},
+	{"POST", "/users/{id}/tokens", controller.CreateAPITokenHandler},
+	{"DELETE", "/users/{id}/tokens/{tokenID}", controller.RevokeAPITokenHandler},
+	{"GET", "/users/{id}/household-invites", controller.GetHouseholdInvitesHandler},
 }
 
 var TransactionRoutes = []Route{
@@ -28,6 +60,11 @@ var TransactionRoutes = []Route{
 	{"POST", "/transactions", controller.CreateTransactionHandler},
 	{"PATCH", "/transactions/{id}", controller.UpdateTransactionByIDHandler},
 	{"DELETE", "/transactions/{id}", controller.DeleteTransactionByIDHandler},
+	{"POST", "/transactions/{id}/restore", controller.RestoreTransactionHandler},
+	{"GET", "/transactions/{id}/attachments", controller.GetAttachmentsHandler},
+	{"POST", "/transactions/{id}/attachments", controller.CreateAttachmentHandler},
+	{"GET", "/transactions/{id}/attachments/{attachmentID}", controller.DownloadAttachmentHandler},
+	{"DELETE", "/transactions/{id}/attachments/{attachmentID}", controller.DeleteAttachmentHandler},
 }
 
 var GoalRoutes = []Route{
@@ -36,6 +73,51 @@ var GoalRoutes = []Route{
 	{"POST", "/goals", controller.CreateGoalHandler},
 	{"PATCH", "/goals/{id}", controller.UpdateGoalByIDHandler},
 	{"DELETE", "/goals/{id}", controller.DeleteGoalByIDHandler},
+	{"POST", "/goals/{id}/restore", controller.RestoreGoalHandler},
+	{"GET", "/goals/{id}/participants", controller.GetGoalParticipantsHandler},
+	{"PUT", "/goals/{id}/participants/{userID}", controller.SetGoalParticipantHandler},
+	{"DELETE", "/goals/{id}/participants/{userID}", controller.DeleteGoalParticipantHandler},
+	{"GET", "/goals/{id}/contributions", controller.GetGoalContributionsHandler},
+	{"POST", "/goals/{id}/contributions", controller.CreateGoalContributionHandler},
+}
+
+var HouseholdRoutes = []Route{
+	{"GET", "/households", controller.GetHouseholdsHandler},
+	{"POST", "/households", controller.CreateHouseholdHandler},
+	{"GET", "/households/{id}", controller.GetHouseholdByIDHandler},
+	{"PATCH", "/households/{id}", controller.UpdateHouseholdByIDHandler},
+	{"DELETE", "/households/{id}", controller.DeleteHouseholdByIDHandler},
+	{"PUT", "/households/{id}/members/{userID}", controller.SetHouseholdMemberHandler},
+	{"DELETE", "/households/{id}/members/{userID}", controller.DeleteHouseholdMemberHandler},
+	{"POST", "/households/{id}/invites", controller.InviteHouseholdMemberHandler},
+	{"POST", "/households/{id}/invites/{userID}/accept", controller.AcceptHouseholdInviteHandler},
+	{"DELETE", "/households/{id}/invites/{userID}", controller.DeleteHouseholdInviteHandler},
+	{"GET", "/households/{id}/balance", controller.GetHouseholdBalanceHandler},
+	{"GET", "/households/{id}/reports/cashflow", controller.GetHouseholdCashflowReportHandler},
+}
+
+var AdminRoutes = []Route{
+	{"GET", "/admin/adjustments/preview", controller.PreviewAdjustmentsHandler},
+	{"POST", "/admin/adjustments/{yearMonth}", controller.TriggerAdjustmentHandler},
+	{"POST", "/admin/adjustments/{yearMonth}/rollback", controller.RollbackAdjustmentHandler},
+	{"GET", "/admin/jobs", controller.GetJobsHandler},
+	{"GET", "/admin/jobs/{name}/runs", controller.GetJobRunsHandler},
+	{"POST", "/admin/jobs/{name}/run", controller.TriggerJobHandler},
+	{"GET", "/admin/scheduler", controller.GetSchedulerStatusHandler},
+}
+
+var AuditRoutes = []Route{
+	{"GET", "/audit", controller.GetAuditLogHandler},
+}
+
+var TrashRoutes = []Route{
+	{"GET", "/trash", controller.GetTrashHandler},
+}
+
+var AuthRoutes = []Route{
+	{"POST", "/auth/login", controller.LoginHandler},
+	{"POST", "/auth/logout", controller.LogoutHandler},
+	{"GET", "/auth/session", controller.GetSessionHandler},
 }
 
 // registerRoutes registers a slice of routes on the given ServeMux.
@@ -56,8 +138,22 @@ func RouterMux() *http.ServeMux {
 		http.ServeFile(w, r, "./web/index.html")
 	})
 
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./web/login.html")
+	})
+
 	registerRoutes(mux, UserRoutes)
 	registerRoutes(mux, TransactionRoutes)
 	registerRoutes(mux, GoalRoutes)
+	registerRoutes(mux, HouseholdRoutes)
+	registerRoutes(mux, AdminRoutes)
+	registerRoutes(mux, AuditRoutes)
+	registerRoutes(mux, TrashRoutes)
+	registerRoutes(mux, AuthRoutes)
 	return mux
 }
+
+// Router returns the application handler: the routes of RouterMux, reachable only after signing in.
+func Router() http.Handler {
+	return controller.RequireSession(RouterMux())
+}
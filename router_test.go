@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRouterMux_RoutesDoNotConflict guards against patterns that net/http rejects at registration,
+// which would stop the server from starting.
+func TestRouterMux_RoutesDoNotConflict(t *testing.T) {
+	mux := RouterMux()
+
+	for _, routes := range [][]Route{UserRoutes, TransactionRoutes, GoalRoutes, HouseholdRoutes, AdminRoutes, AuditRoutes, TrashRoutes, AuthRoutes} {
+		for _, route := range routes {
+			req := httptest.NewRequest(route.Method, route.Path, nil)
+			if _, pattern := mux.Handler(req); pattern != route.Method+" "+route.Path {
+				t.Errorf("%s %s is served by %q", route.Method, route.Path, pattern)
+			}
+		}
+	}
+
+	if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/users/7/transactions", nil)); pattern != "GET /users/{id}/transactions" {
+		t.Errorf("GET /users/7/transactions is served by %q", pattern)
+	}
+	for _, path := range []string{"/users/transactions/7", "/users/goals/7"} {
+		if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, path, nil)); pattern != "GET /users/{collection}/{id}" {
+			t.Errorf("GET %s is served by %q", path, pattern)
+		}
+	}
+}
+
+// TestRouter_RequiresSession checks that without a session only the login page and its assets are served,
+// the application page redirects to the login page and the API is refused.
+func TestRouter_RequiresSession(t *testing.T) {
+	router := Router()
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/login", http.StatusOK},
+		{http.MethodGet, "/static/style.css", http.StatusOK},
+		{http.MethodGet, "/", http.StatusSeeOther},
+		{http.MethodGet, "/users", http.StatusUnauthorized},
+		{http.MethodGet, "/transactions/1", http.StatusUnauthorized},
+		{http.MethodDelete, "/users/1", http.StatusUnauthorized},
+		{http.MethodGet, "/auth/session", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.wantStatus {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// GetAdjustmentsByUserID returns the monthly adjustment entries recorded for the user with the given ID.
+func GetAdjustmentsByUserID(ctx context.Context, userID int64) ([]model.AdjustmentEntry, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetAdjustmentEntriesByUserID(ctx, userID, db)
+}
+
+// ErrInvalidAdjustmentSettings is returned when a pay day is out of range or a timezone is unknown.
+var ErrInvalidAdjustmentSettings = model.NewError(model.KindValidation, "invalid adjustment settings")
+
+// GetAdjustmentSettings returns the monthly adjustment settings of the user with the given ID.
+func GetAdjustmentSettings(ctx context.Context, userID int64) (*model.AdjustmentSettings, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetAdjustmentSettings(ctx, userID, db)
+}
+
+// UpdateAdjustmentSettings applies a partial update to the monthly adjustment settings of a user.
+// The pay day must be between 1 and 31 and the timezone must be empty or a valid IANA name.
+func UpdateAdjustmentSettings(ctx context.Context, userID int64, update *model.AdjustmentSettingsUpdate) (*model.AdjustmentSettings, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if update == nil {
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	settings, err := dbsqlite.GetAdjustmentSettings(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Enabled != nil {
+		settings.Enabled = *update.Enabled
+	}
+	if update.PayDay != nil {
+		settings.PayDay = *update.PayDay
+	}
+	if update.Timezone != nil {
+		settings.Timezone = *update.Timezone
+	}
+
+	if settings.PayDay < 1 || settings.PayDay > 31 {
+		return nil, fmt.Errorf("%w: pay_day must be between 1 and 31", ErrInvalidAdjustmentSettings)
+	}
+	if _, err := loadLocation(settings.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidAdjustmentSettings, settings.Timezone)
+	}
+
+	return dbsqlite.UpsertAdjustmentSettings(ctx, *settings, db)
+}
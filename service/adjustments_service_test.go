@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestGetAdjustmentsByUserID(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "adjustments-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	entries, err := GetAdjustmentsByUserID(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetAdjustmentsByUserID() unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries for a new user, got %d", len(entries))
+	}
+
+	if _, err := GetAdjustmentsByUserID(ctxTest, 999999999); err == nil {
+		t.Errorf("GetAdjustmentsByUserID() expected error for missing user, got nil")
+	}
+}
+
+func TestUpdateAdjustmentSettings(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "payday-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	defaults, err := GetAdjustmentSettings(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetAdjustmentSettings() unexpected error: %v", err)
+	}
+	if !defaults.Enabled || defaults.PayDay != 1 || defaults.Timezone != "" {
+		t.Fatalf("unexpected default settings: %+v", defaults)
+	}
+
+	intPtr := func(i int) *int { return &i }
+	boolPtr := func(b bool) *bool { return &b }
+
+	tests := []struct {
+		name    string
+		update  model.AdjustmentSettingsUpdate
+		wantErr bool
+	}{
+		{"pay_day_zero", model.AdjustmentSettingsUpdate{PayDay: intPtr(0)}, true},
+		{"pay_day_too_large", model.AdjustmentSettingsUpdate{PayDay: intPtr(32)}, true},
+		{"unknown_timezone", model.AdjustmentSettingsUpdate{Timezone: strPtr("Mars/Olympus")}, true},
+		{"valid_update", model.AdjustmentSettingsUpdate{PayDay: intPtr(20), Timezone: strPtr("America/Sao_Paulo"), Enabled: boolPtr(false)}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := UpdateAdjustmentSettings(ctxTest, user.ID, &tc.update)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidAdjustmentSettings) {
+					t.Fatalf("UpdateAdjustmentSettings() error = %v, want ErrInvalidAdjustmentSettings", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateAdjustmentSettings() unexpected error: %v", err)
+			}
+			if got.PayDay != 20 || got.Timezone != "America/Sao_Paulo" || got.Enabled {
+				t.Errorf("settings not updated: %+v", got)
+			}
+		})
+	}
+}
+
+func TestTriggerAndRollbackMonthlyAdjustment(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "manual-adjustment", CurrentAmount: 1000, MonthlyInputs: 500, MonthlyOutputs: 200})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	disabled, err := CreateUser(ctxTest, model.User{UserName: "manual-adjustment-disabled", CurrentAmount: 1000, MonthlyInputs: 500})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	off := false
+	if _, err := UpdateAdjustmentSettings(ctxTest, disabled.ID, &model.AdjustmentSettingsUpdate{Enabled: &off}); err != nil {
+		t.Fatalf("failed to disable adjustments: %v", err)
+	}
+
+	balance := func(id int64) int64 {
+		u, err := GetUserByID(ctxTest, id)
+		if err != nil {
+			t.Fatalf("GetUserByID() unexpected error: %v", err)
+		}
+		return int64(u.CurrentAmount)
+	}
+
+	if _, err := TriggerMonthlyAdjustment(ctxTest, "2024-13"); !errors.Is(err, ErrInvalidYearMonth) {
+		t.Fatalf("TriggerMonthlyAdjustment(invalid) error = %v, want ErrInvalidYearMonth", err)
+	}
+	if _, err := TriggerMonthlyAdjustment(ctxTest, "2999-01"); !errors.Is(err, ErrInvalidYearMonth) {
+		t.Fatalf("TriggerMonthlyAdjustment(future) error = %v, want ErrInvalidYearMonth", err)
+	}
+
+	entries, err := TriggerMonthlyAdjustment(ctxTest, "2024-01")
+	if err != nil {
+		t.Fatalf("TriggerMonthlyAdjustment() unexpected error: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.UserID == disabled.ID {
+			t.Errorf("disabled user must not be adjusted")
+		}
+		if e.UserID == user.ID {
+			found = true
+			if e.BalanceBefore != 1000 || e.BalanceAfter != 1300 {
+				t.Errorf("unexpected entry: %+v", e)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected an entry for user %d", user.ID)
+	}
+	if got := balance(user.ID); got != 1300 {
+		t.Fatalf("expected balance 1300 after trigger, got %d", got)
+	}
+
+	// Triggering the same month again must not apply it twice
+	again, err := TriggerMonthlyAdjustment(ctxTest, "2024-01")
+	if err != nil {
+		t.Fatalf("TriggerMonthlyAdjustment() second call unexpected error: %v", err)
+	}
+	for _, e := range again {
+		if e.UserID == user.ID {
+			t.Errorf("month applied twice for user %d", user.ID)
+		}
+	}
+
+	if _, err := PreviewPendingAdjustments(ctxTest); err != nil {
+		t.Fatalf("PreviewPendingAdjustments() unexpected error: %v", err)
+	}
+	if got := balance(user.ID); got != 1300 {
+		t.Fatalf("preview must not change balances, got %d", got)
+	}
+
+	reversed, err := RollbackMonthlyAdjustment(ctxTest, "2024-01")
+	if err != nil {
+		t.Fatalf("RollbackMonthlyAdjustment() unexpected error: %v", err)
+	}
+	if len(reversed) == 0 {
+		t.Fatalf("expected reversed entries")
+	}
+	if got := balance(user.ID); got != 1000 {
+		t.Errorf("expected balance 1000 after rollback, got %d", got)
+	}
+	if got := balance(disabled.ID); got != 1000 {
+		t.Errorf("disabled user balance changed: %d", got)
+	}
+
+	history, err := GetAdjustmentsByUserID(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetAdjustmentsByUserID() unexpected error: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected entries removed by rollback, got %d", len(history))
+	}
+
+	if _, err := RollbackMonthlyAdjustment(ctxTest, "2024-01"); err == nil {
+		t.Errorf("expected error when rolling back a month that is not recorded")
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// apiTokenPrefix starts every API token, so a leaked one is easy to recognise.
+const apiTokenPrefix = "fingo_"
+
+const (
+	maxAPITokenNameLength = 64
+	// maxAPITokenDays caps how far in the future an API token may expire.
+	maxAPITokenDays = 3650
+)
+
+// ErrInvalidAPIToken is returned when the name, scope or expiry of a new API token is not valid.
+var ErrInvalidAPIToken = model.NewError(model.KindValidation, "invalid api token")
+
+// GetAPITokens returns the API tokens of a user. The tokens themselves are never returned.
+func GetAPITokens(ctx context.Context, userID int64) ([]model.APIToken, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetAPITokensByUserID(ctx, userID, db)
+}
+
+// CreateAPIToken creates a personal API token for a user. Only its hash is stored, so the returned token
+// is the only time it can be read. Without a scope the token gets the full access of its user.
+func CreateAPIToken(ctx context.Context, userID int64, req model.APITokenRequest) (*model.CreatedAPIToken, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPITokenNameLength {
+		return nil, fmt.Errorf("%w: the name must have between 1 and %d characters", ErrInvalidAPIToken, maxAPITokenNameLength)
+	}
+	scope := req.Scope
+	if scope == "" {
+		scope = model.ScopeFull
+	}
+	if scope != model.ScopeFull && scope != model.ScopeReadOnly {
+		return nil, fmt.Errorf("%w: scope %q, want %q or %q", ErrInvalidAPIToken, req.Scope, model.ScopeFull, model.ScopeReadOnly)
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenDays {
+		return nil, fmt.Errorf("%w: expires_in_days must be between 0 and %d", ErrInvalidAPIToken, maxAPITokenDays)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	token = apiTokenPrefix + token
+
+	now := currentTime().UTC()
+	t := model.APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		Scope:     scope,
+		CreatedAt: now.Format(dbsqlite.TimestampLayout),
+	}
+	if req.ExpiresInDays > 0 {
+		t.ExpiresAt = now.AddDate(0, 0, req.ExpiresInDays).Format(dbsqlite.TimestampLayout)
+	}
+
+	created, err := dbsqlite.CreateAPIToken(ctx, t, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedAPIToken{APIToken: *created, Token: token}, nil
+}
+
+// RevokeAPIToken deletes an API token of a user, so it stops working right away.
+func RevokeAPIToken(ctx context.Context, userID, tokenID int64) (int64, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	rows, err := dbsqlite.DeleteAPIToken(ctx, userID, tokenID, db)
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, model.NotFoundError(fmt.Sprintf("api token %d of user %d", tokenID, userID), sql.ErrNoRows)
+	}
+
+	return rows, nil
+}
+
+// AuthenticateAPIToken returns the API token a request was signed with, or sql.ErrNoRows when it does not
+// exist or has expired. Its last use is recorded.
+func AuthenticateAPIToken(ctx context.Context, token string) (*model.APIToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, fmt.Errorf("not an api token: %w", sql.ErrNoRows)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	now := currentTime()
+	t, err := dbsqlite.GetActiveAPIToken(ctx, hashToken(token), now, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbsqlite.TouchAPIToken(ctx, t.ID, now, db); err != nil {
+		// Failing to record the use should not lock scripts out
+		log.Println(err)
+	}
+
+	return t, nil
+}
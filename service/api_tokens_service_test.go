@@ -0,0 +1,94 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestAPITokens_Lifecycle(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2031, 6, 1, 9, 0, 0, 0, time.UTC))
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "tokens-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, _ := CreateUser(ctxTest, model.User{UserName: "tokens-other"})
+	asUserCtx := asUser(user.ID)
+
+	invalid := []model.APITokenRequest{
+		{Name: "  "},
+		{Name: strings.Repeat("x", maxAPITokenNameLength+1)},
+		{Name: "backup", Scope: "admin"},
+		{Name: "backup", ExpiresInDays: -1},
+	}
+	for _, req := range invalid {
+		if _, err := CreateAPIToken(asUserCtx, user.ID, req); !errors.Is(err, ErrInvalidAPIToken) {
+			t.Errorf("CreateAPIToken(%+v) error = %v, want ErrInvalidAPIToken", req, err)
+		}
+	}
+	if _, err := CreateAPIToken(asUserCtx, other.ID, model.APITokenRequest{Name: "theirs"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateAPIToken() for someone else error = %v, want ErrForbidden", err)
+	}
+
+	full, err := CreateAPIToken(asUserCtx, user.ID, model.APITokenRequest{Name: " import "})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() returned error: %v", err)
+	}
+	if !strings.HasPrefix(full.Token, apiTokenPrefix) || full.Name != "import" || full.Scope != model.ScopeFull || full.ExpiresAt != "" {
+		t.Errorf("CreateAPIToken() = %+v", full)
+	}
+	if full.TokenHash == full.Token {
+		t.Error("CreateAPIToken() stored the token itself instead of its hash")
+	}
+	readOnly, err := CreateAPIToken(asUserCtx, user.ID, model.APITokenRequest{Name: "backup", Scope: model.ScopeReadOnly, ExpiresInDays: 30})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() returned error: %v", err)
+	}
+	if readOnly.ExpiresAt != "2031-07-01 09:00:00" {
+		t.Errorf("CreateAPIToken() expires at %q, want 2031-07-01 09:00:00", readOnly.ExpiresAt)
+	}
+
+	got, err := AuthenticateAPIToken(ctxTest, readOnly.Token)
+	if err != nil || got.ID != readOnly.ID || got.UserID != user.ID || got.Scope != model.ScopeReadOnly {
+		t.Errorf("AuthenticateAPIToken() = %+v, %v", got, err)
+	}
+	for _, token := range []string{"", "not-a-token", apiTokenPrefix + "unknown"} {
+		if _, err := AuthenticateAPIToken(ctxTest, token); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("AuthenticateAPIToken(%q) error = %v, want sql.ErrNoRows", token, err)
+		}
+	}
+
+	fake.Advance(31 * 24 * time.Hour)
+	if _, err := AuthenticateAPIToken(ctxTest, readOnly.Token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("AuthenticateAPIToken() after expiry error = %v, want sql.ErrNoRows", err)
+	}
+
+	tokens, err := GetAPITokens(asUserCtx, user.ID)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("GetAPITokens() = %+v, %v", tokens, err)
+	}
+	if tokens[1].LastUsedAt != "2031-06-01 09:00:00" {
+		t.Errorf("GetAPITokens() last use = %q, want 2031-06-01 09:00:00", tokens[1].LastUsedAt)
+	}
+	if _, err := GetAPITokens(asUser(other.ID), user.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetAPITokens() of someone else error = %v, want ErrForbidden", err)
+	}
+
+	if _, err := RevokeAPIToken(asUser(other.ID), user.ID, full.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("RevokeAPIToken() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := RevokeAPIToken(ctxTest, other.ID, full.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RevokeAPIToken() under the wrong user error = %v, want sql.ErrNoRows", err)
+	}
+	if rows, err := RevokeAPIToken(asUserCtx, user.ID, full.ID); err != nil || rows != 1 {
+		t.Errorf("RevokeAPIToken() = %d, %v", rows, err)
+	}
+	if _, err := AuthenticateAPIToken(ctxTest, full.Token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("AuthenticateAPIToken() after revoking error = %v, want sql.ErrNoRows", err)
+	}
+}
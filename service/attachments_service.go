@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// MaxAttachmentSize caps the size of an attachment, in bytes.
+const MaxAttachmentSize = 10 << 20
+
+// maxAttachmentNameLength caps how long the stored file name of an attachment may be.
+const maxAttachmentNameLength = 255
+
+// attachmentTypes lists the content types an attachment may have, as sniffed from its first bytes.
+var attachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+var (
+	// ErrInvalidAttachment is returned when an attachment is empty or is not an image or a PDF.
+	ErrInvalidAttachment = model.NewError(model.KindValidation, "invalid attachment")
+	// ErrAttachmentTooLarge is returned when an attachment is larger than MaxAttachmentSize.
+	ErrAttachmentTooLarge = model.NewError(model.KindTooLarge, "attachment too large")
+)
+
+var (
+	attachmentsMu  sync.RWMutex
+	attachmentsDir = "attachments"
+
+	// contentMu keeps content from being removed between an upload finding it already stored and the
+	// upload's metadata referring to it.
+	contentMu sync.Mutex
+)
+
+// SetAttachmentsDir sets the local directory the content of attachments is stored in.
+// Empty paths are ignored.
+func SetAttachmentsDir(dir string) {
+	if dir == "" {
+		return
+	}
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	attachmentsDir = dir
+}
+
+// currentAttachmentsDir returns the directory the content of attachments is stored in.
+func currentAttachmentsDir() string {
+	attachmentsMu.RLock()
+	defer attachmentsMu.RUnlock()
+	return attachmentsDir
+}
+
+// attachmentPath returns where the content with the given hash is stored, fanned out by its first two
+// characters so no single directory grows too large.
+func attachmentPath(hash string) string {
+	return filepath.Join(currentAttachmentsDir(), hash[:2], hash)
+}
+
+// attachmentName reduces a client supplied file name to a safe base name.
+func attachmentName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if len(name) > maxAttachmentNameLength {
+		name = name[len(name)-maxAttachmentNameLength:]
+	}
+	return name
+}
+
+// stageAttachment copies content into a temporary file in the attachments directory and returns its path,
+// the content's SHA-256, size and sniffed content type. The caller removes the file once it is placed.
+func stageAttachment(content io.Reader) (string, string, int64, string, error) {
+	dir := currentAttachmentsDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", 0, "", fmt.Errorf("could not create the attachments directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return "", "", 0, "", fmt.Errorf("could not create a temporary attachment file: %w", err)
+	}
+	defer tmp.Close()
+
+	fail := func(err error) (string, string, int64, string, error) {
+		os.Remove(tmp.Name())
+		return "", "", 0, "", err
+	}
+
+	// One byte more than the limit tells an oversized upload apart from one exactly at the limit
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(content, MaxAttachmentSize+1))
+	if err != nil {
+		return fail(fmt.Errorf("could not store attachment: %w", err))
+	}
+	if size > MaxAttachmentSize {
+		return fail(fmt.Errorf("%w: the limit is %d MiB", ErrAttachmentTooLarge, MaxAttachmentSize>>20))
+	}
+	if size == 0 {
+		return fail(fmt.Errorf("%w: the file is empty", ErrInvalidAttachment))
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fail(fmt.Errorf("could not read stored attachment: %w", err))
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !attachmentTypes[contentType] {
+		return fail(fmt.Errorf("%w: %s files are not accepted, only images and PDFs", ErrInvalidAttachment, contentType))
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fail(fmt.Errorf("could not store attachment: %w", err))
+	}
+
+	return tmp.Name(), hex.EncodeToString(hasher.Sum(nil)), size, contentType, nil
+}
+
+// placeAttachment moves a staged file to where the content with the given hash is stored, unless that
+// content is already stored.
+func placeAttachment(staged, hash string) error {
+	path := attachmentPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("could not create the attachment directory: %w", err)
+	}
+	if err := os.Rename(staged, path); err != nil {
+		return fmt.Errorf("could not store attachment: %w", err)
+	}
+	return nil
+}
+
+// releaseAttachmentContent removes the stored content with the given hash once no attachment refers to it.
+// Failures are logged: a leftover file does no harm beyond the space it takes.
+func releaseAttachmentContent(ctx context.Context, hash string, db *sql.DB) {
+	contentMu.Lock()
+	defer contentMu.Unlock()
+
+	count, err := dbsqlite.CountAttachmentsBySHA256(ctx, hash, db)
+	if err != nil {
+		log.Printf("could not check the references to attachment %s: %v", hash, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if err := os.Remove(attachmentPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("could not remove attachment %s: %v", hash, err)
+	}
+}
+
+// getTransactionAttachment returns the attachment with the given ID, or sql.ErrNoRows when it does not
+// belong to the transaction. Callers authorize the principal on the transaction first.
+func getTransactionAttachment(ctx context.Context, transactionID, attachmentID int64, db *sql.DB) (*model.Attachment, error) {
+	a, err := dbsqlite.GetAttachmentByID(ctx, attachmentID, db)
+	if err != nil {
+		return nil, err
+	}
+	if a.TransactionID != transactionID {
+		return nil, model.NotFoundError(fmt.Sprintf("attachment %d of transaction %d", attachmentID, transactionID), sql.ErrNoRows)
+	}
+	return a, nil
+}
+
+// CreateAttachment stores content as an attachment of a transaction. The content type is sniffed from the
+// content itself; only images and PDFs up to MaxAttachmentSize are accepted.
+func CreateAttachment(ctx context.Context, transactionID int64, fileName string, content io.Reader) (*model.Attachment, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeTransaction(ctx, transactionID, db); err != nil {
+		return nil, err
+	}
+
+	staged, hash, size, contentType, err := stageAttachment(content)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(staged)
+
+	contentMu.Lock()
+	if err := placeAttachment(staged, hash); err != nil {
+		contentMu.Unlock()
+		return nil, err
+	}
+
+	a, err := dbsqlite.CreateAttachment(ctx, model.Attachment{
+		TransactionID: transactionID,
+		FileName:      attachmentName(fileName),
+		ContentType:   contentType,
+		Size:          size,
+		SHA256:        hash,
+	}, db)
+	contentMu.Unlock()
+	if err != nil {
+		releaseAttachmentContent(ctx, hash, db)
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetAttachments returns the attachments of a transaction, oldest first. Like the transaction itself, they
+// may be read by the members of its owner's household.
+func GetAttachments(ctx context.Context, transactionID int64) ([]model.Attachment, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeTransactionRead(ctx, transactionID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetAttachmentsByTransactionID(ctx, transactionID, db)
+}
+
+// OpenAttachment returns an attachment of a transaction with its content, which the caller must close.
+// Members of the household of the transaction's owner may open it too.
+func OpenAttachment(ctx context.Context, transactionID, attachmentID int64) (*model.Attachment, *os.File, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeTransactionRead(ctx, transactionID, db); err != nil {
+		return nil, nil, err
+	}
+
+	a, err := getTransactionAttachment(ctx, transactionID, attachmentID, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(attachmentPath(a.SHA256))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open the content of attachment %d: %w", a.ID, err)
+	}
+
+	return a, f, nil
+}
+
+// DeleteAttachment removes an attachment of a transaction, and its content once nothing else refers to it.
+func DeleteAttachment(ctx context.Context, transactionID, attachmentID int64) (int64, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeTransaction(ctx, transactionID, db); err != nil {
+		return 0, err
+	}
+
+	a, err := getTransactionAttachment(ctx, transactionID, attachmentID, db)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := dbsqlite.DeleteAttachmentByID(ctx, attachmentID, db)
+	if err != nil {
+		return 0, err
+	}
+	releaseAttachmentContent(ctx, a.SHA256, db)
+
+	return rows, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+// useAttachmentsDir stores attachments in a temporary directory for the duration of the test.
+func useAttachmentsDir(t *testing.T) string {
+	t.Helper()
+
+	previous := currentAttachmentsDir()
+	dir := t.TempDir()
+	SetAttachmentsDir(dir)
+	t.Cleanup(func() { SetAttachmentsDir(previous) })
+	return dir
+}
+
+func TestAttachmentName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"receipt.pdf", "receipt.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\scan.png`, "scan.png"},
+		{"  ", "attachment"},
+		{"/", "attachment"},
+	}
+
+	for _, tc := range tests {
+		if got := attachmentName(tc.in); got != tc.want {
+			t.Errorf("attachmentName(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestAttachments_Lifecycle(t *testing.T) {
+	useAttachmentsDir(t)
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "attachments-user", CurrentAmount: 100000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	dinner, _ := CreateTransaction(ctxTest, model.Transaction{Desc: "dinner", Amount: 8000, IsDebt: true, UserID: user.ID})
+	lunch, _ := CreateTransaction(ctxTest, model.Transaction{Desc: "lunch", Amount: 3000, IsDebt: true, UserID: user.ID})
+
+	pdf := []byte("%PDF-1.4\n1 0 obj << >> endobj\n%%EOF\n")
+	receipt, err := CreateAttachment(ctxTest, dinner.ID, "../receipt.pdf", bytes.NewReader(pdf))
+	if err != nil {
+		t.Fatalf("CreateAttachment() returned error: %v", err)
+	}
+	if receipt.FileName != "receipt.pdf" || receipt.ContentType != "application/pdf" || receipt.Size != int64(len(pdf)) || len(receipt.SHA256) != 64 {
+		t.Errorf("CreateAttachment() = %+v", receipt)
+	}
+
+	// The same content on another transaction is stored once
+	copied, err := CreateAttachment(ctxTest, lunch.ID, "copy.pdf", bytes.NewReader(pdf))
+	if err != nil || copied.SHA256 != receipt.SHA256 {
+		t.Fatalf("CreateAttachment() of the same content = %+v, %v", copied, err)
+	}
+
+	if _, err := CreateAttachment(ctxTest, dinner.ID, "notes.txt", strings.NewReader("just text")); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("CreateAttachment() with a text file error = %v, want ErrInvalidAttachment", err)
+	}
+	if _, err := CreateAttachment(ctxTest, dinner.ID, "empty.pdf", strings.NewReader("")); !errors.Is(err, ErrInvalidAttachment) {
+		t.Errorf("CreateAttachment() with an empty file error = %v, want ErrInvalidAttachment", err)
+	}
+	huge := io.MultiReader(bytes.NewReader(pdf), io.LimitReader(zeroReader{}, MaxAttachmentSize))
+	if _, err := CreateAttachment(ctxTest, dinner.ID, "huge.pdf", huge); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("CreateAttachment() above the size limit error = %v, want ErrAttachmentTooLarge", err)
+	}
+	if _, err := CreateAttachment(ctxTest, 999999999, "receipt.pdf", bytes.NewReader(pdf)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("CreateAttachment() for a missing transaction error = %v, want sql.ErrNoRows", err)
+	}
+
+	list, err := GetAttachments(ctxTest, dinner.ID)
+	if err != nil || len(list) != 1 || list[0].ID != receipt.ID {
+		t.Errorf("GetAttachments() = %+v, %v", list, err)
+	}
+
+	a, content, err := OpenAttachment(ctxTest, dinner.ID, receipt.ID)
+	if err != nil {
+		t.Fatalf("OpenAttachment() returned error: %v", err)
+	}
+	got, _ := io.ReadAll(content)
+	content.Close()
+	if a.ID != receipt.ID || !bytes.Equal(got, pdf) {
+		t.Errorf("OpenAttachment() = %+v with %q", a, got)
+	}
+	if _, _, err := OpenAttachment(ctxTest, lunch.ID, receipt.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("OpenAttachment() through another transaction error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Deleting one reference keeps the shared content
+	if rows, err := DeleteAttachment(ctxTest, lunch.ID, copied.ID); err != nil || rows != 1 {
+		t.Fatalf("DeleteAttachment() = %d, %v", rows, err)
+	}
+	if _, err := os.Stat(attachmentPath(receipt.SHA256)); err != nil {
+		t.Errorf("shared content was removed with one of its attachments: %v", err)
+	}
+
+	// Deleting the transaction keeps its attachments in the trash, out of reach
+	fake := useFakeClock(t, time.Now().UTC())
+	if _, err := DeleteTransactionByID(ctxTest, dinner.ID); err != nil {
+		t.Fatalf("DeleteTransactionByID() returned error: %v", err)
+	}
+	if _, _, err := OpenAttachment(ctxTest, dinner.ID, receipt.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("OpenAttachment() after deleting the transaction error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := os.Stat(attachmentPath(receipt.SHA256)); err != nil {
+		t.Errorf("content of a transaction in the trash was removed: %v", err)
+	}
+
+	// Purging the transaction removes the content nothing refers to anymore
+	fake.Advance(currentTrashRetention() + time.Hour)
+	if err := PurgeTrash(context.Background()); err != nil {
+		t.Fatalf("PurgeTrash() returned error: %v", err)
+	}
+	if _, err := os.Stat(attachmentPath(receipt.SHA256)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("content of a purged transaction still stored: %v", err)
+	}
+}
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
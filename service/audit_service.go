@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// ErrInvalidAuditQuery is returned when the audit log is queried for an unknown entity, or for an ID without
+// its entity.
+var ErrInvalidAuditQuery = model.NewError(model.KindValidation, "invalid audit query")
+
+// auditEntities lists the entities whose changes are written to the audit log.
+var auditEntities = []string{model.AuditEntityUser, model.AuditEntityTransaction, model.AuditEntityGoal}
+
+// recordAudit writes a change made on behalf of the principal of ctx to the audit log, or by the system when
+// ctx has none. before and after are the record as it was and as it is now; pass nil for the one a creation
+// or deletion does not have.
+func recordAudit(ctx context.Context, entity string, entityID int64, action string, before, after any, db *sql.DB) error {
+	entry := model.AuditEntry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		ActorRole: model.AuditActorSystem,
+		CreatedAt: currentTime().UTC().Format(dbsqlite.TimestampLayout),
+	}
+	if p, ok := PrincipalFromContext(ctx); ok {
+		entry.ActorID, entry.ActorRole = p.UserID, p.Role
+	}
+
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("could not encode %s %d for the audit log: %w", entity, entityID, err)
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("could not encode %s %d for the audit log: %w", entity, entityID, err)
+		}
+	}
+
+	_, err = dbsqlite.CreateAuditEntry(ctx, entry, db)
+	return err
+}
+
+// recordBalanceAudit writes a user update to the audit log for a balance moved by delta outside the user
+// service, such as by a monthly adjustment. The user, in the trash or not, is read as it is after the change.
+func recordBalanceAudit(ctx context.Context, userID int64, delta utils.Money, db *sql.DB) error {
+	after, err := dbsqlite.GetUserByID(ctx, userID, db)
+	if errors.Is(err, model.KindNotFound) {
+		after, err = dbsqlite.GetDeletedUserByID(ctx, userID, db)
+	}
+	if err != nil {
+		return err
+	}
+
+	before := *after
+	before.CurrentAmount -= delta
+	return recordAudit(ctx, model.AuditEntityUser, userID, model.AuditActionUpdate, &before, after, db)
+}
+
+// GetAuditLog returns the audit log entries of an entity, of one record of it when id is not 0, or of
+// everything when entity is empty, oldest first. Only admins may read the audit log.
+func GetAuditLog(ctx context.Context, entity string, id int64) ([]model.AuditEntry, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if entity != "" && !slices.Contains(auditEntities, entity) {
+		return nil, fmt.Errorf("%w: unknown entity %q, want one of %v", ErrInvalidAuditQuery, entity, auditEntities)
+	}
+	if id != 0 && entity == "" {
+		return nil, fmt.Errorf("%w: an id needs an entity", ErrInvalidAuditQuery)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return dbsqlite.GetAuditEntries(ctx, model.AuditFilter{Entity: entity, EntityID: id}, db)
+}
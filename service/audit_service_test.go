@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestAuditLog_RecordsMutations(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "audited", CurrentAmount: 10000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	asAudited := asUser(user.ID)
+
+	tx, err := CreateTransaction(asAudited, model.Transaction{Desc: "groceries", Amount: 2500, IsDebt: true, UserID: user.ID, Tags: []string{"food"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	credit := false
+	if _, err := UpdateTransactionByID(asAudited, tx.ID, &model.TransactionUpdate{IsDebt: &credit}); err != nil {
+		t.Fatalf("UpdateTransactionByID() returned error: %v", err)
+	}
+	if _, err := DeleteTransactionByID(asAudited, tx.ID); err != nil {
+		t.Fatalf("DeleteTransactionByID() returned error: %v", err)
+	}
+
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityTransaction, tx.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog() returned error: %v", err)
+	}
+	wantActions := []string{model.AuditActionCreate, model.AuditActionUpdate, model.AuditActionDelete}
+	if len(entries) != len(wantActions) {
+		t.Fatalf("GetAuditLog() returned %d entries, want %d: %+v", len(entries), len(wantActions), entries)
+	}
+	for i, e := range entries {
+		if e.Action != wantActions[i] || e.ActorID != user.ID || e.ActorRole != model.RoleUser || e.CreatedAt == "" {
+			t.Errorf("entry %d = %+v, want a %s by user %d", i, e, wantActions[i], user.ID)
+		}
+	}
+
+	var before, after model.Transaction
+	if err := json.Unmarshal(entries[1].Before, &before); err != nil {
+		t.Fatalf("could not decode before snapshot: %v", err)
+	}
+	if err := json.Unmarshal(entries[1].After, &after); err != nil {
+		t.Fatalf("could not decode after snapshot: %v", err)
+	}
+	if !before.IsDebt || after.IsDebt || len(before.Tags) != 1 || before.Tags[0] != "food" {
+		t.Errorf("update snapshots before = %+v, after = %+v", before, after)
+	}
+	if entries[0].Before != nil || entries[2].After != nil {
+		t.Errorf("creation before = %s, deletion after = %s, want neither", entries[0].Before, entries[2].After)
+	}
+
+	// Every balance change the transaction caused is on the user's own log
+	userEntries, err := GetAuditLog(ctxTest, model.AuditEntityUser, user.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog() returned error: %v", err)
+	}
+	var balances []model.User
+	for _, e := range userEntries {
+		var u model.User
+		if err := json.Unmarshal(e.After, &u); err != nil {
+			t.Fatalf("could not decode after snapshot: %v", err)
+		}
+		balances = append(balances, u)
+	}
+	if len(balances) != 4 || balances[0].CurrentAmount != 10000 || balances[1].CurrentAmount != 7500 || balances[2].CurrentAmount != 12500 || balances[3].CurrentAmount != 10000 {
+		t.Errorf("user audit balances = %+v, want 10000, 7500, 12500, 10000", balances)
+	}
+	if userEntries[0].ActorRole != model.RoleAdmin || userEntries[0].Action != model.AuditActionCreate {
+		t.Errorf("user creation entry = %+v, want an admin create", userEntries[0])
+	}
+}
+
+func TestAuditLog_Goals(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "audited-goals"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "boat", Price: 90000, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateGoal() returned error: %v", err)
+	}
+	if _, err := UpdateGoalByID(ctxTest, goal.ID, &model.GoalUpdate{Name: strPtr("yacht")}); err != nil {
+		t.Fatalf("UpdateGoalByID() returned error: %v", err)
+	}
+	if _, err := DeleteGoalByID(ctxTest, goal.ID); err != nil {
+		t.Fatalf("DeleteGoalByID() returned error: %v", err)
+	}
+	if rows, err := DeleteGoalByID(ctxTest, goal.ID); !errors.Is(err, model.KindNotFound) {
+		t.Fatalf("DeleteGoalByID() of a deleted goal = %d, %v, want a not found error", rows, err)
+	}
+
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityGoal, goal.ID)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("GetAuditLog() = %+v, %v, want three entries", entries, err)
+	}
+	var renamed model.Goal
+	if err := json.Unmarshal(entries[1].After, &renamed); err != nil || renamed.Name != "yacht" {
+		t.Errorf("goal update after = %s, %v", entries[1].After, err)
+	}
+}
+
+func TestAuditLog_Tags(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "audited-tags", CurrentAmount: 10000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	asAudited := asUser(user.ID)
+
+	tx, err := CreateTransaction(asAudited, model.Transaction{Desc: "lunch", Amount: 1500, IsDebt: true, UserID: user.ID, Tags: []string{"food"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	trashed, err := CreateTransaction(asAudited, model.Transaction{Desc: "snack", Amount: 300, IsDebt: true, UserID: user.ID, Tags: []string{"food"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if _, err := DeleteTransactionByID(asAudited, trashed.ID); err != nil {
+		t.Fatalf("DeleteTransactionByID() returned error: %v", err)
+	}
+	goal, err := CreateGoal(asAudited, model.Goal{Name: "feast", Price: 5000, UserID: user.ID, Tags: []string{"food"}})
+	if err != nil {
+		t.Fatalf("CreateGoal() returned error: %v", err)
+	}
+	tags, err := GetTags(asAudited, user.ID)
+	if err != nil || len(tags) != 1 {
+		t.Fatalf("GetTags() = %+v, %v", tags, err)
+	}
+
+	if _, err := RenameTag(asAudited, user.ID, tags[0].ID, "meals"); err != nil {
+		t.Fatalf("RenameTag() returned error: %v", err)
+	}
+	if _, err := DeleteTag(asAudited, user.ID, tags[0].ID); err != nil {
+		t.Fatalf("DeleteTag() returned error: %v", err)
+	}
+
+	// Every record carrying the tag, in the trash or not, logs the rename and then the removal
+	logs := []struct {
+		entity string
+		id     int64
+	}{
+		{model.AuditEntityTransaction, tx.ID},
+		{model.AuditEntityTransaction, trashed.ID},
+		{model.AuditEntityGoal, goal.ID},
+	}
+	for _, l := range logs {
+		entries, err := GetAuditLog(ctxTest, l.entity, l.id)
+		if err != nil || len(entries) < 2 {
+			t.Fatalf("GetAuditLog(%s %d) = %+v, %v", l.entity, l.id, entries, err)
+		}
+		var renamedBefore, renamedAfter, deletedAfter struct {
+			Tags []string `json:"tags"`
+		}
+		renamed, deleted := entries[len(entries)-2], entries[len(entries)-1]
+		for _, snapshot := range []struct {
+			raw  json.RawMessage
+			into any
+		}{{renamed.Before, &renamedBefore}, {renamed.After, &renamedAfter}, {deleted.After, &deletedAfter}} {
+			if err := json.Unmarshal(snapshot.raw, snapshot.into); err != nil {
+				t.Fatalf("could not decode snapshot of %s %d: %v", l.entity, l.id, err)
+			}
+		}
+		if renamed.Action != model.AuditActionUpdate || deleted.Action != model.AuditActionUpdate || deleted.ActorID != user.ID {
+			t.Errorf("%s %d entries = %+v, %+v; want two updates by user %d", l.entity, l.id, renamed, deleted, user.ID)
+		}
+		if !slices.Equal(renamedBefore.Tags, []string{"food"}) || !slices.Equal(renamedAfter.Tags, []string{"meals"}) || len(deletedAfter.Tags) != 0 {
+			t.Errorf("%s %d tags went %v -> %v -> %v, want [food] -> [meals] -> []", l.entity, l.id, renamedBefore.Tags, renamedAfter.Tags, deletedAfter.Tags)
+		}
+	}
+}
+
+func TestAuditLog_GoalParticipants(t *testing.T) {
+	owner, err := CreateUser(ctxTest, model.User{UserName: "audited-shared-owner"})
+	if err != nil {
+		t.Fatalf("failed to create owner: %v", err)
+	}
+	partner, err := CreateUser(ctxTest, model.User{UserName: "audited-shared-partner"})
+	if err != nil {
+		t.Fatalf("failed to create partner: %v", err)
+	}
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "sofa", Price: 80000, UserID: owner.ID})
+	if err != nil {
+		t.Fatalf("CreateGoal() returned error: %v", err)
+	}
+
+	for _, share := range []utils.Money{30000, 40000} {
+		if _, err := SetGoalParticipant(asUser(owner.ID), model.GoalParticipant{GoalID: goal.ID, UserID: partner.ID, TargetShare: share}); err != nil {
+			t.Fatalf("SetGoalParticipant() returned error: %v", err)
+		}
+	}
+	if _, err := CreateGoalContribution(asUser(partner.ID), model.GoalContribution{GoalID: goal.ID, UserID: partner.ID, Amount: 2500}); err != nil {
+		t.Fatalf("CreateGoalContribution() returned error: %v", err)
+	}
+	if _, err := RemoveGoalParticipant(asUser(partner.ID), goal.ID, partner.ID); err != nil {
+		t.Fatalf("RemoveGoalParticipant() returned error: %v", err)
+	}
+
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityGoal, goal.ID)
+	if err != nil {
+		t.Fatalf("GetAuditLog() returned error: %v", err)
+	}
+	want := []struct {
+		action    string
+		actor     int64
+		hasBefore bool
+		hasAfter  bool
+	}{
+		{model.AuditActionCreate, 0, false, true},
+		{model.AuditActionSetParticipant, owner.ID, false, true},
+		{model.AuditActionSetParticipant, owner.ID, true, true},
+		{model.AuditActionContribute, partner.ID, false, true},
+		{model.AuditActionRemoveParticipant, partner.ID, true, false},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("GetAuditLog() returned %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Action != w.action || (w.actor != 0 && e.ActorID != w.actor) || (e.Before != nil) != w.hasBefore || (e.After != nil) != w.hasAfter {
+			t.Errorf("entry %d = %+v, want a %s by user %d", i, e, w.action, w.actor)
+		}
+	}
+
+	var updated model.GoalParticipant
+	if err := json.Unmarshal(entries[2].After, &updated); err != nil || updated.UserID != partner.ID || updated.TargetShare != 40000 {
+		t.Errorf("participant update after = %s, %v", entries[2].After, err)
+	}
+	var contribution model.GoalContribution
+	if err := json.Unmarshal(entries[3].After, &contribution); err != nil || contribution.Amount != 2500 {
+		t.Errorf("contribution after = %s, %v", entries[3].After, err)
+	}
+}
+
+func TestAuditLog_MonthlyAdjustments(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "audited-payday", MonthlyInputs: 1000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	isolateAdjustmentUsers(t, user.ID)
+	setLastPeriod(t, user.ID, "2032-02")
+	useFakeClock(t, time.Date(2032, 3, 20, 12, 0, 0, 0, time.UTC))
+
+	if err := ProcessPendingAdjustments(context.Background()); err != nil {
+		t.Fatalf("ProcessPendingAdjustments() unexpected error: %v", err)
+	}
+	if _, err := RollbackMonthlyAdjustment(ctxTest, "2032-03"); err != nil {
+		t.Fatalf("RollbackMonthlyAdjustment() unexpected error: %v", err)
+	}
+	if _, err := TriggerMonthlyAdjustment(ctxTest, "2032-03"); err != nil {
+		t.Fatalf("TriggerMonthlyAdjustment() unexpected error: %v", err)
+	}
+
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityUser, user.ID)
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("GetAuditLog() = %+v, %v, want a creation and three balance changes", entries, err)
+	}
+
+	wantActors := []string{model.AuditActorSystem, model.RoleAdmin, model.RoleAdmin}
+	wantBalances := [][2]utils.Money{{0, 1000}, {1000, 0}, {0, 1000}}
+	for i, e := range entries[1:] {
+		var before, after model.User
+		if err := json.Unmarshal(e.Before, &before); err != nil {
+			t.Fatalf("entry %d before = %s: %v", i, e.Before, err)
+		}
+		if err := json.Unmarshal(e.After, &after); err != nil {
+			t.Fatalf("entry %d after = %s: %v", i, e.After, err)
+		}
+		if e.Action != model.AuditActionUpdate || e.ActorRole != wantActors[i] || before.CurrentAmount != wantBalances[i][0] || after.CurrentAmount != wantBalances[i][1] {
+			t.Errorf("entry %d = %s by %s, %d -> %d, want update by %s, %d -> %d", i, e.Action, e.ActorRole, before.CurrentAmount, after.CurrentAmount, wantActors[i], wantBalances[i][0], wantBalances[i][1])
+		}
+	}
+}
+
+func TestGetAuditLog_Validation(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "audit-reader"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if _, err := GetAuditLog(asUser(user.ID), model.AuditEntityUser, user.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetAuditLog() as a regular user error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetAuditLog(ctxTest, "budget", 0); !errors.Is(err, ErrInvalidAuditQuery) {
+		t.Errorf("GetAuditLog() for an unknown entity error = %v, want ErrInvalidAuditQuery", err)
+	}
+	if _, err := GetAuditLog(ctxTest, "", user.ID); !errors.Is(err, ErrInvalidAuditQuery) {
+		t.Errorf("GetAuditLog() with an id but no entity error = %v, want ErrInvalidAuditQuery", err)
+	}
+	if entries, err := GetAuditLog(ctxTest, "", 0); err != nil || len(entries) == 0 {
+		t.Errorf("GetAuditLog() of everything = %d entries, %v", len(entries), err)
+	}
+}
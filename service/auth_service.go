@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// SessionLifetime is how long a session stays valid after signing in.
+const SessionLifetime = 7 * 24 * time.Hour
+
+// JobSessionCleanup is the name of the background job that deletes expired sessions.
+const JobSessionCleanup = "session-cleanup"
+
+const (
+	maxLoginLength    = 64
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt takes into account; longer passwords are refused rather than cut.
+	maxPasswordLength = 72
+)
+
+var (
+	// ErrInvalidCredentials is returned when a login and password do not match any account.
+	ErrInvalidCredentials = model.NewError(model.KindUnauthorized, "invalid login or password")
+	// ErrInvalidLogin is returned when a login or password does not meet the requirements.
+	ErrInvalidLogin = model.NewError(model.KindValidation, "invalid credentials")
+	// ErrDuplicateLogin is returned when another user already signs in with the requested login.
+	ErrDuplicateLogin = model.NewError(model.KindConflict, "login already in use")
+)
+
+// dummyPasswordHash is compared against when a login does not exist, so a failed sign in takes as long
+// whether or not the login exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("fingo-dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("could not hash the dummy password: %v", err)
+	}
+	return hash
+})
+
+// normalizeLogin trims a login and lowercases it, so logins are matched regardless of case.
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// validateCredentials checks a normalised login and a password.
+func validateCredentials(login, password string) error {
+	if login == "" || len(login) > maxLoginLength {
+		return fmt.Errorf("%w: the login must have between 1 and %d characters", ErrInvalidLogin, maxLoginLength)
+	}
+	if strings.IndexFunc(login, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("%w: the login cannot contain spaces", ErrInvalidLogin)
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: the password must have between %d and %d bytes", ErrInvalidLogin, minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// newToken returns a random token to hand to a client.
+func newToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("could not generate a token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken returns the hash a session or API token is stored under.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasAccounts reports whether any user is able to sign in yet. Until one is, the first user and their
+// credentials may be created without signing in.
+func HasAccounts(ctx context.Context) (bool, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	count, err := dbsqlite.CountCredentials(ctx, db)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetCredentials returns the login and role of a user.
+func GetCredentials(ctx context.Context, userID int64) (*model.Credentials, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return dbsqlite.GetCredentialsByUserID(ctx, userID, db)
+}
+
+// SetCredentials sets the login and password a user signs in with. The password is stored as a bcrypt
+// hash, and every session of the user is ended so only the new password grants access. The first user to
+// get credentials becomes an admin; later ones start as regular users.
+func SetCredentials(ctx context.Context, userID int64, update model.CredentialsUpdate) (*model.Credentials, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	login := normalizeLogin(update.Login)
+	if err := validateCredentials(login, update.Password); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	existing, err := dbsqlite.GetCredentialsByLogin(ctx, login, db)
+	if err == nil && existing.UserID != userID {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateLogin, login)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	accounts, err := dbsqlite.CountCredentials(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	role := model.RoleUser
+	if accounts == 0 {
+		role = model.RoleAdmin
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(update.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash the password: %w", err)
+	}
+
+	c, err := dbsqlite.SetCredentials(ctx, model.Credentials{UserID: userID, Login: login, PasswordHash: string(hash), Role: role}, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dbsqlite.DeleteSessionsByUserID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Login checks a login and password and starts a session for the user they belong to. It returns the
+// token identifying the session, which is only ever handed to the client, and the session itself.
+func Login(ctx context.Context, req model.LoginRequest) (string, *model.Session, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return "", nil, err
+	}
+	defer db.Close()
+
+	c, err := dbsqlite.GetCredentialsByLogin(ctx, normalizeLogin(req.Login), db)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", nil, err
+		}
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		return "", nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(c.PasswordHash), []byte(req.Password)); err != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := currentTime().UTC()
+	session := model.Session{
+		TokenHash: hashToken(token),
+		UserID:    c.UserID,
+		CreatedAt: now.Format(dbsqlite.TimestampLayout),
+		ExpiresAt: now.Add(SessionLifetime).Format(dbsqlite.TimestampLayout),
+	}
+	if err := dbsqlite.CreateSession(ctx, session, db); err != nil {
+		return "", nil, err
+	}
+
+	return token, &session, nil
+}
+
+// Authenticate returns the active session identified by token, or sql.ErrNoRows when there is none.
+func Authenticate(ctx context.Context, token string) (*model.Session, error) {
+	if token == "" {
+		return nil, fmt.Errorf("empty session token: %w", sql.ErrNoRows)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return dbsqlite.GetActiveSession(ctx, hashToken(token), currentTime(), db)
+}
+
+// Logout ends the session identified by token. Ending a session that does not exist is not an error.
+func Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = dbsqlite.DeleteSession(ctx, hashToken(token), db)
+	return err
+}
+
+// PurgeExpiredSessions deletes the sessions that have expired. It stops when ctx is canceled.
+func PurgeExpiredSessions(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	deleted, err := dbsqlite.DeleteExpiredSessions(ctx, currentTime(), db)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("[Sessions] Deleted %d expired session(s).", deleted)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  bool
+	}{
+		{"valid", "ana", "correct horse", false},
+		{"empty login", "", "correct horse", true},
+		{"login with spaces", "ana maria", "correct horse", true},
+		{"short password", "ana", "short", true},
+		{"password too long for bcrypt", "ana", string(make([]byte, 73)), true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCredentials(tc.login, tc.password)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateCredentials() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidLogin) {
+				t.Errorf("validateCredentials() error = %v, want ErrInvalidLogin", err)
+			}
+		})
+	}
+}
+
+func TestAuth_LoginSessionsAndLogout(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2031, 3, 1, 9, 0, 0, 0, time.UTC))
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "auth-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, _ := CreateUser(ctxTest, model.User{UserName: "auth-other"})
+
+	c, err := SetCredentials(ctxTest, user.ID, model.CredentialsUpdate{Login: " Auth-User ", Password: "s3cret-pass"})
+	if err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+	if c.Login != "auth-user" || c.PasswordHash == "s3cret-pass" || c.PasswordHash == "" {
+		t.Errorf("SetCredentials() = %+v, want a normalised login and a hashed password", c)
+	}
+	if hasAccounts, err := HasAccounts(ctxTest); err != nil || !hasAccounts {
+		t.Errorf("HasAccounts() = %v, %v; want true", hasAccounts, err)
+	}
+	if _, err := SetCredentials(ctxTest, other.ID, model.CredentialsUpdate{Login: "auth-user", Password: "another-pass"}); !errors.Is(err, ErrDuplicateLogin) {
+		t.Errorf("SetCredentials() with a login in use error = %v, want ErrDuplicateLogin", err)
+	}
+	if _, err := SetCredentials(ctxTest, 999999999, model.CredentialsUpdate{Login: "ghost", Password: "another-pass"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("SetCredentials() for a missing user error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, _, err := Login(ctxTest, model.LoginRequest{Login: "auth-user", Password: "wrong-pass"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with a wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := Login(ctxTest, model.LoginRequest{Login: "nobody", Password: "s3cret-pass"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with an unknown login error = %v, want ErrInvalidCredentials", err)
+	}
+
+	token, session, err := Login(ctxTest, model.LoginRequest{Login: "AUTH-USER", Password: "s3cret-pass"})
+	if err != nil {
+		t.Fatalf("Login() returned error: %v", err)
+	}
+	if token == "" || session.UserID != user.ID || session.ExpiresAt != "2031-03-08 09:00:00" {
+		t.Errorf("Login() = %q, %+v", token, session)
+	}
+	if session.TokenHash == token {
+		t.Error("Login() stored the session token itself instead of its hash")
+	}
+
+	got, err := Authenticate(ctxTest, token)
+	if err != nil || got.UserID != user.ID {
+		t.Errorf("Authenticate() = %+v, %v", got, err)
+	}
+	if _, err := Authenticate(ctxTest, "forged"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Authenticate() with an unknown token error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := Logout(ctxTest, token); err != nil {
+		t.Fatalf("Logout() returned error: %v", err)
+	}
+	if _, err := Authenticate(ctxTest, token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Authenticate() after Logout() error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Sessions expire after SessionLifetime and are purged by the cleanup job
+	token, _, _ = Login(ctxTest, model.LoginRequest{Login: "auth-user", Password: "s3cret-pass"})
+	fake.Advance(SessionLifetime)
+	if _, err := Authenticate(ctxTest, token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Authenticate() after the session expired error = %v, want sql.ErrNoRows", err)
+	}
+	if err := PurgeExpiredSessions(context.Background()); err != nil {
+		t.Errorf("PurgeExpiredSessions() returned error: %v", err)
+	}
+
+	// Changing the password ends the sessions started with the old one
+	token, _, _ = Login(ctxTest, model.LoginRequest{Login: "auth-user", Password: "s3cret-pass"})
+	if _, err := SetCredentials(ctxTest, user.ID, model.CredentialsUpdate{Login: "auth-user", Password: "n3w-secret"}); err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+	if _, err := Authenticate(ctxTest, token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Authenticate() after a password change error = %v, want sql.ErrNoRows", err)
+	}
+	if _, _, err := Login(ctxTest, model.LoginRequest{Login: "auth-user", Password: "s3cret-pass"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with the old password error = %v, want ErrInvalidCredentials", err)
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// ErrForbidden is returned when the principal of a request may not see or change what it asks for.
+var ErrForbidden = model.NewError(model.KindForbidden, "not allowed")
+
+// ErrInvalidRole is returned when a role is unknown or the change would leave nobody able to administer fingo.
+var ErrInvalidRole = model.NewError(model.KindValidation, "invalid role")
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying who the calls made with it are made on behalf of. Every
+// service call that reads or changes users, transactions or goals checks it; calls without one are refused.
+func WithPrincipal(ctx context.Context, p model.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any.
+func PrincipalFromContext(ctx context.Context) (model.Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(model.Principal)
+	return p, ok
+}
+
+// principal returns the principal carried by ctx, or ErrForbidden when there is none.
+func principal(ctx context.Context) (model.Principal, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return model.Principal{}, fmt.Errorf("%w: no principal", ErrForbidden)
+	}
+	return p, nil
+}
+
+// requireAdmin fails with ErrForbidden unless the principal is an admin.
+func requireAdmin(ctx context.Context) error {
+	p, err := principal(ctx)
+	if err != nil {
+		return err
+	}
+	if p.Role != model.RoleAdmin {
+		return fmt.Errorf("%w: only admins may do this", ErrForbidden)
+	}
+	return nil
+}
+
+// authorizeUser fails with ErrForbidden unless the principal is the given user or an admin.
+func authorizeUser(ctx context.Context, userID int64) error {
+	p, err := principal(ctx)
+	if err != nil {
+		return err
+	}
+	if p.Role != model.RoleAdmin && p.UserID != userID {
+		return fmt.Errorf("%w: user %d belongs to someone else", ErrForbidden, userID)
+	}
+	return nil
+}
+
+// householdUserIDs returns the users in the same household as the given user, the user included.
+func householdUserIDs(ctx context.Context, userID int64, db *sql.DB) ([]int64, error) {
+	return dbsqlite.GetHouseholdUserIDs(ctx, userID, db)
+}
+
+// listingScope returns the users whose records a listing is limited to: the members of the principal's
+// household, or only the principal when they have none. It returns false when the principal may list
+// everybody's.
+func listingScope(ctx context.Context, db *sql.DB) ([]int64, bool, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if p.Role == model.RoleAdmin {
+		return nil, false, nil
+	}
+
+	ids, err := householdUserIDs(ctx, p.UserID, db)
+	if err != nil {
+		return nil, false, err
+	}
+	return ids, true, nil
+}
+
+// authorizeHouseholdUser fails with ErrForbidden unless the principal is the given user, shares a household
+// with them or is an admin. It only guards reading; changes stay with the user and admins.
+func authorizeHouseholdUser(ctx context.Context, userID int64, db *sql.DB) error {
+	p, err := principal(ctx)
+	if err != nil {
+		return err
+	}
+	if p.Role == model.RoleAdmin || p.UserID == userID {
+		return nil
+	}
+
+	ids, err := householdUserIDs(ctx, p.UserID, db)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(ids, userID) {
+		return fmt.Errorf("%w: user %d belongs to someone else", ErrForbidden, userID)
+	}
+	return nil
+}
+
+// authorizeTransaction looks up a transaction and fails with ErrForbidden unless the principal owns it or
+// is an admin.
+func authorizeTransaction(ctx context.Context, transactionID int64, db *sql.DB) (*model.Transaction, error) {
+	if _, err := principal(ctx); err != nil {
+		return nil, err
+	}
+
+	transaction, err := dbsqlite.GetTransactionByID(ctx, transactionID, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeUser(ctx, transaction.UserID); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// authorizeTransactionRead looks up a transaction and fails with ErrForbidden unless the principal may read
+// the records of its owner.
+func authorizeTransactionRead(ctx context.Context, transactionID int64, db *sql.DB) (*model.Transaction, error) {
+	if _, err := principal(ctx); err != nil {
+		return nil, err
+	}
+
+	transaction, err := dbsqlite.GetTransactionByID(ctx, transactionID, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeHouseholdUser(ctx, transaction.UserID, db); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// authorizeGoalOwner looks up a goal and fails with ErrForbidden unless the principal owns it or is an admin.
+func authorizeGoalOwner(ctx context.Context, goalID int64, db *sql.DB) (*model.Goal, error) {
+	if _, err := principal(ctx); err != nil {
+		return nil, err
+	}
+
+	goal, err := dbsqlite.GetGoalByID(ctx, goalID, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeUser(ctx, goal.UserID); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// authorizeGoalMember looks up a goal and fails with ErrForbidden unless the principal, or someone in their
+// household, owns it or participates in it, or the principal is an admin.
+func authorizeGoalMember(ctx context.Context, goalID int64, db *sql.DB) (*model.Goal, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := dbsqlite.GetGoalByID(ctx, goalID, db)
+	if err != nil {
+		return nil, err
+	}
+	if p.Role == model.RoleAdmin {
+		return goal, nil
+	}
+
+	ids, err := householdUserIDs(ctx, p.UserID, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		member, err := isGoalMember(ctx, goal, id, db)
+		if err != nil {
+			return nil, err
+		}
+		if member {
+			return goal, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: goal %d belongs to someone else", ErrForbidden, goalID)
+}
+
+// SetRole changes the role of a user who has credentials. Only admins may change roles, and the last
+// admin may not give up the role.
+func SetRole(ctx context.Context, userID int64, update model.RoleUpdate) (*model.Credentials, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if update.Role != model.RoleAdmin && update.Role != model.RoleUser {
+		return nil, fmt.Errorf("%w: %q, want %q or %q", ErrInvalidRole, update.Role, model.RoleAdmin, model.RoleUser)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	current, err := dbsqlite.GetCredentialsByUserID(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Role == model.RoleAdmin && update.Role != model.RoleAdmin {
+		admins, err := dbsqlite.CountCredentialsByRole(ctx, model.RoleAdmin, db)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, fmt.Errorf("%w: user %d is the last admin", ErrInvalidRole, userID)
+		}
+	}
+
+	return dbsqlite.SetCredentialsRole(ctx, userID, update.Role, db)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+// asUser returns a context acting as the given regular user.
+func asUser(userID int64) context.Context {
+	return WithPrincipal(context.Background(), model.Principal{UserID: userID, Role: model.RoleUser})
+}
+
+func TestAuthorization_WithoutPrincipal(t *testing.T) {
+	if _, err := GetAllUsers(context.Background()); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetAllUsers() without a principal error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetTransactionByID(context.Background(), 1); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetTransactionByID() without a principal error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthorization_UsersAndTransactions(t *testing.T) {
+	alice, err := CreateUser(ctxTest, model.User{UserName: "authz-alice", CurrentAmount: 10000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	bob, _ := CreateUser(ctxTest, model.User{UserName: "authz-bob", CurrentAmount: 10000})
+	asAlice, asBob := asUser(alice.ID), asUser(bob.ID)
+
+	if _, err := CreateUser(asAlice, model.User{UserName: "authz-mallory"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateUser() as a regular user error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetUserByID(asAlice, alice.ID); err != nil {
+		t.Errorf("GetUserByID() of their own user returned error: %v", err)
+	}
+	if _, err := GetUserByID(asAlice, bob.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetUserByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := UpdateUserByID(asAlice, bob.ID, &model.UserUpdate{UserName: strPtr("hijacked")}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateUserByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := DeleteUserByID(asAlice, bob.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteUserByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if users, err := GetAllUsers(asAlice); err != nil || len(users) != 1 || users[0].ID != alice.ID {
+		t.Errorf("GetAllUsers() as a regular user = %+v, %v; want only their own user", users, err)
+	}
+
+	if _, err := CreateTransaction(asAlice, model.Transaction{Desc: "sneaky", Amount: 100, IsDebt: true, UserID: bob.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateTransaction() for someone else error = %v, want ErrForbidden", err)
+	}
+	bobs, err := CreateTransaction(asBob, model.Transaction{Desc: "groceries", Amount: 2500, IsDebt: true, UserID: bob.ID})
+	if err != nil {
+		t.Fatalf("CreateTransaction() for their own user returned error: %v", err)
+	}
+	if _, err := GetTransactionByID(asAlice, bobs.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetTransactionByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := UpdateTransactionByID(asAlice, bobs.ID, &model.TransactionUpdate{Desc: strPtr("mine now")}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateTransactionByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := DeleteTransactionByID(asAlice, bobs.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteTransactionByID() of someone else error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetAttachments(asAlice, bobs.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetAttachments() of someone else's transaction error = %v, want ErrForbidden", err)
+	}
+	if _, err := CreateAttachment(asAlice, bobs.ID, "x.png", strings.NewReader("x")); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateAttachment() on someone else's transaction error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetAllTransactionsByUserID(asAlice, bob.ID, nil); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetAllTransactionsByUserID() of someone else error = %v, want ErrForbidden", err)
+	}
+
+	listed, err := GetAllTransactions(asBob, nil)
+	if err != nil {
+		t.Fatalf("GetAllTransactions() returned error: %v", err)
+	}
+	for _, tx := range listed {
+		if tx.UserID != bob.ID {
+			t.Errorf("GetAllTransactions() as a regular user listed %+v of someone else", tx)
+		}
+	}
+	if len(listed) != 1 {
+		t.Errorf("GetAllTransactions() as a regular user = %d transactions, want 1", len(listed))
+	}
+
+	// Admins see and change everything
+	if _, err := GetTransactionByID(ctxTest, bobs.ID); err != nil {
+		t.Errorf("GetTransactionByID() as an admin returned error: %v", err)
+	}
+	if rows, err := DeleteTransactionByID(asBob, bobs.ID); err != nil || rows != 1 {
+		t.Errorf("DeleteTransactionByID() of their own transaction = %d, %v", rows, err)
+	}
+}
+
+func TestAuthorization_UserScopedResources(t *testing.T) {
+	alice, err := CreateUser(ctxTest, model.User{UserName: "scoped-alice"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	bob, _ := CreateUser(ctxTest, model.User{UserName: "scoped-bob"})
+	asAlice := asUser(alice.ID)
+
+	calls := map[string]func() error{
+		"GetAdjustmentsByUserID": func() error { _, err := GetAdjustmentsByUserID(asAlice, bob.ID); return err },
+		"GetAdjustmentSettings":  func() error { _, err := GetAdjustmentSettings(asAlice, bob.ID); return err },
+		"GetNotificationsByUserID": func() error {
+			_, err := GetNotificationsByUserID(asAlice, bob.ID, false)
+			return err
+		},
+		"GetCashflowReport": func() error { _, err := GetCashflowReport(asAlice, bob.ID, "", "", ""); return err },
+		"GetDashboard":      func() error { _, err := GetDashboard(asAlice, bob.ID); return err },
+		"GetProjection":     func() error { _, err := GetProjection(asAlice, bob.ID, 0); return err },
+		"GetInsights":       func() error { _, err := GetInsights(asAlice, bob.ID, 0); return err },
+		"GetStatement":      func() error { _, err := GetStatement(asAlice, bob.ID, "2030-01"); return err },
+		"GetRules":          func() error { _, err := GetRules(asAlice, bob.ID); return err },
+		"ApplyRules":        func() error { _, err := ApplyRules(asAlice, bob.ID, true); return err },
+		"GetTags":           func() error { _, err := GetTags(asAlice, bob.ID); return err },
+		"CreateTag":         func() error { _, err := CreateTag(asAlice, bob.ID, "groceries"); return err },
+		"GetTagReport":      func() error { _, err := GetTagReport(asAlice, bob.ID, "", ""); return err },
+		"GetCategoryReport": func() error { _, err := GetCategoryReport(asAlice, bob.ID, "", ""); return err },
+		"GetCredentials":    func() error { _, err := GetCredentials(asAlice, bob.ID); return err },
+		"GetAllGoalsByUserID": func() error {
+			_, err := GetAllGoalsByUserID(asAlice, bob.ID, nil)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s() of someone else error = %v, want ErrForbidden", name, err)
+		}
+	}
+
+	if _, err := GetTags(asAlice, alice.ID); err != nil {
+		t.Errorf("GetTags() of their own user returned error: %v", err)
+	}
+	if _, err := GetJobs(asAlice); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetJobs() as a regular user error = %v, want ErrForbidden", err)
+	}
+	if _, err := PreviewPendingAdjustments(asAlice); !errors.Is(err, ErrForbidden) {
+		t.Errorf("PreviewPendingAdjustments() as a regular user error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestAuthorization_Goals(t *testing.T) {
+	owner, err := CreateUser(ctxTest, model.User{UserName: "goal-owner"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	member, _ := CreateUser(ctxTest, model.User{UserName: "goal-member"})
+	stranger, _ := CreateUser(ctxTest, model.User{UserName: "goal-stranger"})
+	asOwner, asMember, asStranger := asUser(owner.ID), asUser(member.ID), asUser(stranger.ID)
+
+	if _, err := CreateGoal(asStranger, model.Goal{Name: "not theirs", UserID: owner.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateGoal() for someone else error = %v, want ErrForbidden", err)
+	}
+	goal, err := CreateGoal(asOwner, model.Goal{Name: "trip", Price: 100000, UserID: owner.ID})
+	if err != nil {
+		t.Fatalf("CreateGoal() returned error: %v", err)
+	}
+	if _, err := SetGoalParticipant(asMember, model.GoalParticipant{GoalID: goal.ID, UserID: member.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetGoalParticipant() by a non-owner error = %v, want ErrForbidden", err)
+	}
+	if _, err := SetGoalParticipant(asOwner, model.GoalParticipant{GoalID: goal.ID, UserID: member.ID, TargetShare: 50000}); err != nil {
+		t.Fatalf("SetGoalParticipant() returned error: %v", err)
+	}
+
+	// Participants see the goal and contribute in their own name, but do not manage it
+	if _, err := GetGoalByID(asMember, goal.ID); err != nil {
+		t.Errorf("GetGoalByID() as a participant returned error: %v", err)
+	}
+	if _, err := GetGoalParticipants(asMember, goal.ID); err != nil {
+		t.Errorf("GetGoalParticipants() as a participant returned error: %v", err)
+	}
+	if _, err := CreateGoalContribution(asMember, model.GoalContribution{GoalID: goal.ID, UserID: member.ID, Amount: 1000}); err != nil {
+		t.Errorf("CreateGoalContribution() as a participant returned error: %v", err)
+	}
+	if _, err := CreateGoalContribution(asMember, model.GoalContribution{GoalID: goal.ID, UserID: owner.ID, Amount: 1000}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CreateGoalContribution() in someone else's name error = %v, want ErrForbidden", err)
+	}
+	if _, err := UpdateGoalByID(asMember, goal.ID, &model.GoalUpdate{Name: strPtr("mine")}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateGoalByID() as a participant error = %v, want ErrForbidden", err)
+	}
+	if _, err := DeleteGoalByID(asMember, goal.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteGoalByID() as a participant error = %v, want ErrForbidden", err)
+	}
+
+	// Strangers see nothing of it
+	if _, err := GetGoalByID(asStranger, goal.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetGoalByID() as a stranger error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetGoalContributions(asStranger, goal.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetGoalContributions() as a stranger error = %v, want ErrForbidden", err)
+	}
+	if goals, err := GetAllGoals(asStranger, nil); err != nil || len(goals) != 0 {
+		t.Errorf("GetAllGoals() as a stranger = %+v, %v; want none", goals, err)
+	}
+	if goals, err := GetAllGoals(asMember, nil); err != nil || len(goals) != 1 || goals[0].ID != goal.ID {
+		t.Errorf("GetAllGoals() as a participant = %+v, %v; want the shared goal", goals, err)
+	}
+
+	// Participants may leave on their own
+	if _, err := RemoveGoalParticipant(asStranger, goal.ID, member.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("RemoveGoalParticipant() by a stranger error = %v, want ErrForbidden", err)
+	}
+	if rows, err := RemoveGoalParticipant(asMember, goal.ID, member.ID); err != nil || rows != 1 {
+		t.Errorf("RemoveGoalParticipant() of themselves = %d, %v", rows, err)
+	}
+	if rows, err := DeleteGoalByID(asOwner, goal.ID); err != nil || rows != 1 {
+		t.Errorf("DeleteGoalByID() by the owner = %d, %v", rows, err)
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "role-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := SetCredentials(ctxTest, user.ID, model.CredentialsUpdate{Login: "role-user", Password: "role-password"}); err != nil {
+		t.Fatalf("SetCredentials() returned error: %v", err)
+	}
+
+	if _, err := SetRole(asUser(user.ID), user.ID, model.RoleUpdate{Role: model.RoleAdmin}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetRole() as a regular user error = %v, want ErrForbidden", err)
+	}
+	if _, err := SetRole(ctxTest, user.ID, model.RoleUpdate{Role: "owner"}); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("SetRole() with an unknown role error = %v, want ErrInvalidRole", err)
+	}
+
+	c, err := SetRole(ctxTest, user.ID, model.RoleUpdate{Role: model.RoleAdmin})
+	if err != nil || c.Role != model.RoleAdmin {
+		t.Fatalf("SetRole() = %+v, %v", c, err)
+	}
+	if c, err := SetRole(ctxTest, user.ID, model.RoleUpdate{Role: model.RoleUser}); err != nil || c.Role != model.RoleUser {
+		t.Errorf("SetRole() demoting one of several admins = %+v, %v", c, err)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"natan/fingo/clock"
+)
+
+var (
+	clockMu           sync.RWMutex
+	appClock          clock.Clock = clock.System{}
+	schedulerInterval             = time.Hour
+)
+
+// SetClock replaces the clock used by the time-based service logic. The application timezone is
+// the location of the times reported by the clock.
+func SetClock(c clock.Clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	appClock = c
+}
+
+// SetSchedulerInterval sets how often the built-in background jobs run.
+// Non-positive durations are ignored.
+func SetSchedulerInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	schedulerInterval = d
+}
+
+// currentTime returns the current time of the application clock, in the application timezone.
+func currentTime() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return appClock.Now()
+}
+
+// currentSchedulerInterval returns how often the built-in background jobs run.
+func currentSchedulerInterval() time.Duration {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return schedulerInterval
+}
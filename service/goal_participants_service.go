@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+var (
+	// ErrInvalidShare is returned when a target share is negative or the shares exceed the goal price.
+	ErrInvalidShare = model.NewError(model.KindValidation, "invalid target share")
+	// ErrInvalidContribution is returned when a contribution amount is not positive.
+	ErrInvalidContribution = model.NewError(model.KindValidation, "contribution amount must be positive")
+	// ErrNotGoalMember is returned when a user is neither the owner nor a participant of a goal.
+	ErrNotGoalMember = model.NewError(model.KindValidation, "user is not the owner or a participant of the goal")
+)
+
+// isGoalMember reports whether the user owns the goal or participates in it.
+func isGoalMember(ctx context.Context, goal *model.Goal, userID int64, db *sql.DB) (bool, error) {
+	if goal.UserID == userID {
+		return true, nil
+	}
+
+	if _, err := dbsqlite.GetGoalParticipant(ctx, goal.ID, userID, db); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetGoalParticipants returns the participants of the goal with the given ID.
+func GetGoalParticipants(ctx context.Context, goalID int64) ([]model.GoalParticipant, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeGoalMember(ctx, goalID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetGoalParticipants(ctx, goalID, db)
+}
+
+// SetGoalParticipant adds a user to a goal, or updates their target share if they already participate.
+// The sum of all target shares may not exceed the goal price when the price is set. Only the owner of the
+// goal or an admin may do so.
+func SetGoalParticipant(ctx context.Context, participant model.GoalParticipant) (*model.GoalParticipant, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if participant.TargetShare < 0 {
+		return nil, fmt.Errorf("%w: share cannot be negative", ErrInvalidShare)
+	}
+
+	goal, err := authorizeGoalOwner(ctx, participant.GoalID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dbsqlite.GetUserByID(ctx, participant.UserID, db); err != nil {
+		return nil, err
+	}
+
+	others, err := dbsqlite.SumGoalTargetShares(ctx, goal.ID, participant.UserID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if goal.Price > 0 && others+participant.TargetShare > goal.Price {
+		return nil, fmt.Errorf("%w: shares would total %d but the goal price is %d", ErrInvalidShare, others+participant.TargetShare, goal.Price)
+	}
+
+	var before any
+	if existing, err := dbsqlite.GetGoalParticipant(ctx, goal.ID, participant.UserID, db); err == nil {
+		before = existing
+	} else if !errors.Is(err, model.KindNotFound) {
+		return nil, err
+	}
+
+	updated, err := dbsqlite.UpsertGoalParticipant(ctx, participant, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, goal.ID, model.AuditActionSetParticipant, before, updated, db); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// RemoveGoalParticipant removes a user from a goal and returns the number of affected rows, or a not found
+// error when the goal or the participant does not exist. Participants may leave a goal on their own;
+// removing anyone else takes the owner of the goal or an admin.
+func RemoveGoalParticipant(ctx context.Context, goalID, userID int64) (int64, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	goal, err := dbsqlite.GetGoalByID(ctx, goalID, db)
+	if err != nil {
+		return 0, err
+	}
+	if err := authorizeUser(ctx, userID); err != nil {
+		if err := authorizeUser(ctx, goal.UserID); err != nil {
+			return 0, err
+		}
+	}
+
+	before, err := dbsqlite.GetGoalParticipant(ctx, goalID, userID, db)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := dbsqlite.DeleteGoalParticipant(ctx, goalID, userID, db)
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, model.NotFoundError("goal participant", sql.ErrNoRows)
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, goalID, model.AuditActionRemoveParticipant, before, nil, db); err != nil {
+		return rows, err
+	}
+
+	return rows, nil
+}
+
+// GetGoalContributions returns the contributions made towards the goal with the given ID.
+func GetGoalContributions(ctx context.Context, goalID int64) ([]model.GoalContribution, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeGoalMember(ctx, goalID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetGoalContributions(ctx, goalID, db)
+}
+
+// CreateGoalContribution records an amount put towards a goal by its owner or one of its participants.
+// Contributions are made in the name of the principal, unless they are an admin.
+func CreateGoalContribution(ctx context.Context, contribution model.GoalContribution) (*model.GoalContribution, error) {
+	if err := authorizeUser(ctx, contribution.UserID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if contribution.Amount <= 0 {
+		return nil, ErrInvalidContribution
+	}
+
+	goal, err := dbsqlite.GetGoalByID(ctx, contribution.GoalID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := isGoalMember(ctx, goal, contribution.UserID, db)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrNotGoalMember
+	}
+
+	created, err := dbsqlite.CreateGoalContribution(ctx, contribution, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, goal.ID, model.AuditActionContribute, nil, created, db); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
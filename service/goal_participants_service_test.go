@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestSharedGoal_ParticipantsAndContributions(t *testing.T) {
+	owner, err := CreateUser(ctxTest, model.User{UserName: "shared-goal-owner"})
+	if err != nil {
+		t.Fatalf("failed to create owner: %v", err)
+	}
+	partner, err := CreateUser(ctxTest, model.User{UserName: "shared-goal-partner"})
+	if err != nil {
+		t.Fatalf("failed to create partner: %v", err)
+	}
+	outsider, err := CreateUser(ctxTest, model.User{UserName: "shared-goal-outsider"})
+	if err != nil {
+		t.Fatalf("failed to create outsider: %v", err)
+	}
+
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "Trip for two", Price: 100000, UserID: owner.ID})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+
+	if _, err := SetGoalParticipant(ctxTest, model.GoalParticipant{GoalID: goal.ID, UserID: owner.ID, TargetShare: 60000}); err != nil {
+		t.Fatalf("SetGoalParticipant(owner) unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		share     int64
+		wantErr   error
+		wantShare int64
+	}{
+		{"negative_share", -1, ErrInvalidShare, 0},
+		{"exceeds_price", 50000, ErrInvalidShare, 0},
+		{"fits_remaining", 40000, nil, 40000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SetGoalParticipant(ctxTest, model.GoalParticipant{GoalID: goal.ID, UserID: partner.ID, TargetShare: utils.Money(tc.share)})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("SetGoalParticipant() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetGoalParticipant() unexpected error: %v", err)
+			}
+			if int64(got.TargetShare) != tc.wantShare {
+				t.Errorf("share mismatch: got %d want %d", got.TargetShare, tc.wantShare)
+			}
+		})
+	}
+
+	if _, err := CreateGoalContribution(ctxTest, model.GoalContribution{GoalID: goal.ID, UserID: partner.ID, Amount: 5000}); err != nil {
+		t.Fatalf("CreateGoalContribution(partner) unexpected error: %v", err)
+	}
+	if _, err := CreateGoalContribution(ctxTest, model.GoalContribution{GoalID: goal.ID, UserID: outsider.ID, Amount: 5000}); !errors.Is(err, ErrNotGoalMember) {
+		t.Fatalf("CreateGoalContribution(outsider) error = %v, want ErrNotGoalMember", err)
+	}
+	if _, err := CreateGoalContribution(ctxTest, model.GoalContribution{GoalID: goal.ID, UserID: owner.ID, Amount: 0}); !errors.Is(err, ErrInvalidContribution) {
+		t.Fatalf("CreateGoalContribution(zero) error = %v, want ErrInvalidContribution", err)
+	}
+
+	participants, err := GetGoalParticipants(ctxTest, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalParticipants() unexpected error: %v", err)
+	}
+	if len(participants) != 2 {
+		t.Fatalf("expected 2 participants, got %d", len(participants))
+	}
+	for _, p := range participants {
+		if p.UserID == partner.ID && p.Contributed != 5000 {
+			t.Errorf("partner contributed = %d, want 5000", p.Contributed)
+		}
+	}
+
+	goals, err := GetAllGoalsByUserID(ctxTest, partner.ID, nil)
+	if err != nil {
+		t.Fatalf("GetAllGoalsByUserID() unexpected error: %v", err)
+	}
+	if len(goals) != 1 || goals[0].ID != goal.ID {
+		t.Fatalf("expected partner listing to include shared goal %d, got %+v", goal.ID, goals)
+	}
+}
+
+func TestRemoveGoalParticipant_NotFound(t *testing.T) {
+	owner, err := CreateUser(ctxTest, model.User{UserName: "leaving-goal-owner"})
+	if err != nil {
+		t.Fatalf("failed to create owner: %v", err)
+	}
+	partner, err := CreateUser(ctxTest, model.User{UserName: "leaving-goal-partner"})
+	if err != nil {
+		t.Fatalf("failed to create partner: %v", err)
+	}
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "Bike", UserID: owner.ID})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := SetGoalParticipant(ctxTest, model.GoalParticipant{GoalID: goal.ID, UserID: partner.ID}); err != nil {
+		t.Fatalf("SetGoalParticipant() unexpected error: %v", err)
+	}
+
+	if rows, err := RemoveGoalParticipant(ctxTest, goal.ID, partner.ID); err != nil || rows != 1 {
+		t.Fatalf("RemoveGoalParticipant() = %d, %v; want 1 row", rows, err)
+	}
+	if _, err := RemoveGoalParticipant(ctxTest, goal.ID, partner.ID); !errors.Is(err, model.KindNotFound) {
+		t.Errorf("RemoveGoalParticipant() of a user that left error = %v, want not found", err)
+	}
+	if _, err := RemoveGoalParticipant(ctxTest, 1<<40, partner.ID); !errors.Is(err, model.KindNotFound) {
+		t.Errorf("RemoveGoalParticipant() of a missing goal error = %v, want not found", err)
+	}
+}
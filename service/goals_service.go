@@ -1,12 +1,14 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
+	"slices"
 )
 
-// GetGoalByID returns the goal with the given ID.
+// GetGoalByID returns the goal with the given ID to its owner, its participants and admins.
 func GetGoalByID(ctx context.Context, id int64) (*model.Goal, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -14,32 +16,119 @@ func GetGoalByID(ctx context.Context, id int64) (*model.Goal, error) {
 	}
 	defer db.Close()
 
-	return dbsqlite.GetGoalByID(ctx, id, db)
+	goal, err := authorizeGoalMember(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	goal.Tags, err = dbsqlite.GetGoalTags(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return goal, nil
 }
 
-// GetAllGoals returns all goals in the database.
-func GetAllGoals(ctx context.Context) ([]model.Goal, error) {
+// GetAllGoals returns all goals in the database with their tags to admins, and the goals owned or shared by
+// the members of their household to anyone else. When tags is not empty, only the goals carrying every one
+// of them are returned.
+func GetAllGoals(ctx context.Context, tags []string) ([]model.Goal, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	return dbsqlite.GetAllGoals(ctx, db)
+	ids, scoped, err := listingScope(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if scoped {
+		// Goals shared between members of the household are listed once
+		seen := make(map[int64]bool)
+		goals := []model.Goal{}
+		for _, id := range ids {
+			memberGoals, err := userGoals(ctx, id, tags, db)
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range memberGoals {
+				if !seen[g.ID] {
+					seen[g.ID] = true
+					goals = append(goals, g)
+				}
+			}
+		}
+		slices.SortFunc(goals, func(a, b model.Goal) int { return cmp.Compare(a.ID, b.ID) })
+		return goals, nil
+	}
+
+	goals, err := dbsqlite.GetAllGoals(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	goalTags, err := dbsqlite.GetAllGoalTags(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return withGoalTags(goals, goalTags, tags), nil
+}
+
+// withGoalTags fills the tags of goals and keeps the ones carrying every tag of filter.
+func withGoalTags(goals []model.Goal, goalTags map[int64][]string, filter []string) []model.Goal {
+	filtered := make([]model.Goal, 0, len(goals))
+	for _, g := range goals {
+		g.Tags = goalTags[g.ID]
+		if hasAllTags(g.Tags, filter) {
+			filtered = append(filtered, g)
+		}
+	}
+	return filtered
 }
 
-// CreateGoal persists a new goal and returns the created record.
+// CreateGoal persists a new goal with its tags and returns the created record.
 func CreateGoal(ctx context.Context, goal model.Goal) (*model.Goal, error) {
+	if err := authorizeUser(ctx, goal.UserID); err != nil {
+		return nil, err
+	}
+
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	return dbsqlite.CreateGoal(ctx, goal, db)
+	if err := validateGoal(ctx, &goal, db); err != nil {
+		return nil, err
+	}
+	tags := goal.Tags
+
+	created, err := dbsqlite.CreateGoal(ctx, goal, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) > 0 {
+		if err := dbsqlite.SetGoalTags(ctx, created.ID, created.UserID, tags, db); err != nil {
+			return nil, err
+		}
+	}
+	created.Tags, err = dbsqlite.GetGoalTags(ctx, created.ID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, created.ID, model.AuditActionCreate, nil, created, db); err != nil {
+		return nil, err
+	}
+
+	return created, nil
 }
 
 // UpdateGoalByID applies a partial update to the goal with the given ID and returns the updated record.
+// Tags, when given, replace the tags of the goal.
 func UpdateGoalByID(ctx context.Context, id int64, goal *model.GoalUpdate) (*model.Goal, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -47,10 +136,41 @@ func UpdateGoalByID(ctx context.Context, id int64, goal *model.GoalUpdate) (*mod
 	}
 	defer db.Close()
 
-	return dbsqlite.UpdateGoalPartialByID(ctx, id, goal, db)
+	original, err := authorizeGoalOwner(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := validateGoalUpdate(goal)
+	if err != nil {
+		return nil, err
+	}
+	if original.Tags, err = dbsqlite.GetGoalTags(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	updated, err := dbsqlite.UpdateGoalPartialByID(ctx, id, goal, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if goal.Tags != nil {
+		if err := dbsqlite.SetGoalTags(ctx, id, updated.UserID, tags, db); err != nil {
+			return nil, err
+		}
+	}
+	updated.Tags, err = dbsqlite.GetGoalTags(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, id, model.AuditActionUpdate, original, updated, db); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
 }
 
-// DeleteGoalByID removes the goal with the given ID and returns the number of affected rows.
+// DeleteGoalByID moves the goal with the given ID to the trash and returns the number of affected rows.
 func DeleteGoalByID(ctx context.Context, id int64) (int64, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -58,5 +178,24 @@ func DeleteGoalByID(ctx context.Context, id int64) (int64, error) {
 	}
 	defer db.Close()
 
-	return dbsqlite.DeleteGoalByID(ctx, id, db)
+	goal, err := authorizeGoalOwner(ctx, id, db)
+	if err != nil {
+		return 0, err
+	}
+	if goal.Tags, err = dbsqlite.GetGoalTags(ctx, id, db); err != nil {
+		return 0, err
+	}
+
+	rows, err := dbsqlite.SoftDeleteGoal(ctx, id, deletionTime(), db)
+	if err != nil {
+		return rows, err
+	}
+
+	if rows > 0 {
+		if err := recordAudit(ctx, model.AuditEntityGoal, id, model.AuditActionDelete, goal, nil, db); err != nil {
+			return rows, err
+		}
+	}
+
+	return rows, nil
 }
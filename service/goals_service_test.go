@@ -102,7 +102,7 @@ func TestGoalsService_GetAllGoals(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			goals, err := GetAllGoals(ctxTest)
+			goals, err := GetAllGoals(ctxTest, nil)
 			if err != nil {
 				t.Fatalf("GetAllGoals() unexpected error: %v", err)
 			}
@@ -219,16 +219,14 @@ func TestGoalsService_DeleteGoalByID(t *testing.T) {
 			wantRows: 1,
 		},
 		{
-			name:     "delete_non_existing_goal",
-			id:       base.ID + 999999,
-			wantErr:  false,
-			wantRows: 0,
+			name:    "delete_non_existing_goal",
+			id:      base.ID + 999999,
+			wantErr: true,
 		},
 		{
-			name:     "delete_zero_id",
-			id:       0,
-			wantErr:  false,
-			wantRows: 0,
+			name:    "delete_zero_id",
+			id:      0,
+			wantErr: true,
 		},
 	}
 
@@ -0,0 +1,491 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// maxHouseholdNameLength caps how long the name of a household may be.
+const maxHouseholdNameLength = 64
+
+var (
+	// ErrInvalidHousehold is returned when a household or membership change is not valid, such as leaving
+	// a household without an owner.
+	ErrInvalidHousehold = model.NewError(model.KindValidation, "invalid household")
+	// ErrAlreadyInHousehold is returned when a user who already belongs to a household is added to another.
+	ErrAlreadyInHousehold = model.NewError(model.KindConflict, "user already belongs to a household")
+)
+
+// validateHouseholdName trims a household name and checks its length.
+func validateHouseholdName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxHouseholdNameLength {
+		return "", fmt.Errorf("%w: the name must have between 1 and %d characters", ErrInvalidHousehold, maxHouseholdNameLength)
+	}
+	return name, nil
+}
+
+// authorizeHousehold looks up a household and fails with ErrForbidden unless the principal belongs to it
+// or is an admin. When manage is set, the principal must also be one of its owners.
+func authorizeHousehold(ctx context.Context, householdID int64, manage bool, db *sql.DB) (*model.Household, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	household, err := dbsqlite.GetHouseholdByID(ctx, householdID, db)
+	if err != nil {
+		return nil, err
+	}
+	if p.Role == model.RoleAdmin {
+		return household, nil
+	}
+
+	member, err := dbsqlite.GetHouseholdMember(ctx, householdID, p.UserID, db)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: household %d is someone else's", ErrForbidden, householdID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if manage && member.Role != model.HouseholdRoleOwner {
+		return nil, fmt.Errorf("%w: only owners manage household %d", ErrForbidden, householdID)
+	}
+	return household, nil
+}
+
+// householdOwners counts the owners among members.
+func householdOwners(members []model.HouseholdMember) int {
+	owners := 0
+	for _, m := range members {
+		if m.Role == model.HouseholdRoleOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+// withMembers fills the members of a household.
+func withMembers(ctx context.Context, household *model.Household, db *sql.DB) (*model.Household, error) {
+	members, err := dbsqlite.GetHouseholdMembers(ctx, household.ID, db)
+	if err != nil {
+		return nil, err
+	}
+	household.Members = members
+	return household, nil
+}
+
+// CreateHousehold creates a household owned by the principal, who must not belong to one yet.
+func CreateHousehold(ctx context.Context, household model.Household) (*model.Household, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID == 0 {
+		return nil, fmt.Errorf("%w: only a signed-in user can own a household", ErrInvalidHousehold)
+	}
+	name, err := validateHouseholdName(household.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetHouseholdByUserID(ctx, p.UserID, db); err == nil {
+		return nil, fmt.Errorf("%w: user %d", ErrAlreadyInHousehold, p.UserID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	created, err := dbsqlite.CreateHousehold(ctx, name, p.UserID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return withMembers(ctx, created, db)
+}
+
+// GetHouseholds returns every household to admins, and the household they belong to, if any, to anyone else.
+func GetHouseholds(ctx context.Context) ([]model.Household, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if p.Role == model.RoleAdmin {
+		return dbsqlite.GetAllHouseholds(ctx, db)
+	}
+
+	household, err := dbsqlite.GetHouseholdByUserID(ctx, p.UserID, db)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []model.Household{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []model.Household{*household}, nil
+}
+
+// GetHouseholdByID returns a household with its members to its members and admins.
+func GetHouseholdByID(ctx context.Context, id int64) (*model.Household, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	household, err := authorizeHousehold(ctx, id, false, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return withMembers(ctx, household, db)
+}
+
+// UpdateHouseholdByID renames a household. Only its owners and admins may.
+func UpdateHouseholdByID(ctx context.Context, id int64, update *model.HouseholdUpdate) (*model.Household, error) {
+	if update == nil {
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	household, err := authorizeHousehold(ctx, id, true, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		name, err := validateHouseholdName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		household, err = dbsqlite.RenameHousehold(ctx, id, name, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return withMembers(ctx, household, db)
+}
+
+// DeleteHouseholdByID deletes a household, leaving its users without one. Only its owners and admins may.
+func DeleteHouseholdByID(ctx context.Context, id int64) (int64, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeHousehold(ctx, id, true, db); err != nil {
+		return 0, err
+	}
+
+	return dbsqlite.DeleteHouseholdByID(ctx, id, db)
+}
+
+// validateHouseholdRole defaults an empty household role to member and checks it is a known one.
+func validateHouseholdRole(role string) (string, error) {
+	if role == "" {
+		role = model.HouseholdRoleMember
+	}
+	if role != model.HouseholdRoleOwner && role != model.HouseholdRoleMember {
+		return "", fmt.Errorf("%w: role %q, want %q or %q", ErrInvalidHousehold, role, model.HouseholdRoleOwner, model.HouseholdRoleMember)
+	}
+	return role, nil
+}
+
+// SetHouseholdMember changes the role of a member of a household. Only its owners and admins may, and the
+// last owner may not step down. Admins may also add users directly; anyone else joins a household only by
+// accepting an invite, since members see each other's records.
+func SetHouseholdMember(ctx context.Context, member model.HouseholdMember) (*model.HouseholdMember, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role, err = validateHouseholdRole(member.Role); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeHousehold(ctx, member.HouseholdID, true, db); err != nil {
+		return nil, err
+	}
+	if _, err := dbsqlite.GetUserByID(ctx, member.UserID, db); err != nil {
+		return nil, err
+	}
+
+	current, err := dbsqlite.GetHouseholdByUserID(ctx, member.UserID, db)
+	if err == nil && current.ID != member.HouseholdID {
+		return nil, fmt.Errorf("%w: user %d", ErrAlreadyInHousehold, member.UserID)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil && p.Role != model.RoleAdmin {
+		return nil, fmt.Errorf("%w: user %d joins household %d by accepting an invite", ErrForbidden, member.UserID, member.HouseholdID)
+	}
+
+	if member.Role != model.HouseholdRoleOwner {
+		members, err := dbsqlite.GetHouseholdMembers(ctx, member.HouseholdID, db)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if m.UserID == member.UserID && m.Role == model.HouseholdRoleOwner && householdOwners(members) == 1 {
+				return nil, fmt.Errorf("%w: user %d is the last owner", ErrInvalidHousehold, member.UserID)
+			}
+		}
+	}
+
+	return dbsqlite.UpsertHouseholdMember(ctx, member, db)
+}
+
+// RemoveHouseholdMember removes a user from a household. Owners and admins may remove anyone, and members
+// may leave on their own. The last owner may only leave once nobody else is left, which deletes the household.
+func RemoveHouseholdMember(ctx context.Context, householdID, userID int64) (int64, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeHousehold(ctx, householdID, p.UserID != userID, db); err != nil {
+		return 0, err
+	}
+
+	members, err := dbsqlite.GetHouseholdMembers(ctx, householdID, db)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range members {
+		if m.UserID != userID || m.Role != model.HouseholdRoleOwner || householdOwners(members) > 1 {
+			continue
+		}
+		if len(members) > 1 {
+			return 0, fmt.Errorf("%w: user %d is the last owner, make someone else an owner first", ErrInvalidHousehold, userID)
+		}
+		return dbsqlite.DeleteHouseholdByID(ctx, householdID, db)
+	}
+
+	return dbsqlite.DeleteHouseholdMember(ctx, householdID, userID, db)
+}
+
+// InviteHouseholdMember invites a user who belongs to no household to join one with a role. Only its owners
+// and admins may invite.
+func InviteHouseholdMember(ctx context.Context, invite model.HouseholdInvite) (*model.HouseholdInvite, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if invite.Role, err = validateHouseholdRole(invite.Role); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeHousehold(ctx, invite.HouseholdID, true, db); err != nil {
+		return nil, err
+	}
+	if _, err := dbsqlite.GetUserByID(ctx, invite.UserID, db); err != nil {
+		return nil, err
+	}
+	if _, err := dbsqlite.GetHouseholdByUserID(ctx, invite.UserID, db); err == nil {
+		return nil, fmt.Errorf("%w: user %d", ErrAlreadyInHousehold, invite.UserID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	invite.InvitedBy = p.UserID
+	return dbsqlite.UpsertHouseholdInvite(ctx, invite, db)
+}
+
+// GetHouseholdInvites returns the pending household invites of the user with the given ID, oldest first.
+func GetHouseholdInvites(ctx context.Context, userID int64) ([]model.HouseholdInvite, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetHouseholdInvitesByUserID(ctx, userID, db)
+}
+
+// AcceptHouseholdInvite makes a user a member of the household that invited them and drops their other
+// invites. Only the invited user and admins may accept.
+func AcceptHouseholdInvite(ctx context.Context, householdID, userID int64) (*model.HouseholdMember, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetHouseholdInvite(ctx, householdID, userID, db); err != nil {
+		return nil, err
+	}
+	if _, err := dbsqlite.GetHouseholdByUserID(ctx, userID, db); err == nil {
+		return nil, fmt.Errorf("%w: user %d", ErrAlreadyInHousehold, userID)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return dbsqlite.AcceptHouseholdInvite(ctx, householdID, userID, db)
+}
+
+// DeleteHouseholdInvite declines or withdraws the invite of a user to a household. The invited user, the
+// owners of the household and admins may.
+func DeleteHouseholdInvite(ctx context.Context, householdID, userID int64) (int64, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if p.UserID != userID {
+		if _, err := authorizeHousehold(ctx, householdID, true, db); err != nil {
+			return 0, err
+		}
+	}
+
+	rows, err := dbsqlite.DeleteHouseholdInvite(ctx, householdID, userID, db)
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, model.NotFoundError("household invite", sql.ErrNoRows)
+	}
+	return rows, nil
+}
+
+// GetHouseholdBalance adds up the balances and monthly figures of the members of a household.
+func GetHouseholdBalance(ctx context.Context, id int64) (*model.HouseholdBalance, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	household, err := authorizeHousehold(ctx, id, false, db)
+	if err != nil {
+		return nil, err
+	}
+	members, err := dbsqlite.GetHouseholdMembers(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := model.HouseholdBalance{HouseholdID: household.ID, Name: household.Name, Members: []model.User{}}
+	for _, m := range members {
+		user, err := dbsqlite.GetUserByID(ctx, m.UserID, db)
+		if err != nil {
+			return nil, err
+		}
+		balance.CurrentAmount += user.CurrentAmount
+		balance.MonthlyInputs += user.MonthlyInputs
+		balance.MonthlyOutputs += user.MonthlyOutputs
+		balance.Members = append(balance.Members, *user)
+	}
+
+	return &balance, nil
+}
+
+// GetHouseholdCashflowReport adds up the cash flow reports of the members of a household, period by period.
+// The parameters are those of GetCashflowReport.
+func GetHouseholdCashflowReport(ctx context.Context, id int64, from, to, granularity string) (*model.HouseholdCashflowReport, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := authorizeHousehold(ctx, id, false, db); err != nil {
+		return nil, err
+	}
+	members, err := dbsqlite.GetHouseholdMembers(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *model.HouseholdCashflowReport
+	for _, m := range members {
+		memberReport, err := cashflowReport(ctx, m.UserID, from, to, granularity)
+		if err != nil {
+			return nil, err
+		}
+		if report == nil {
+			report = &model.HouseholdCashflowReport{
+				HouseholdID: id,
+				Granularity: memberReport.Granularity,
+				From:        memberReport.From,
+				To:          memberReport.To,
+				Periods:     memberReport.Periods,
+			}
+			continue
+		}
+		// Every member's report covers the same periods
+		for i, p := range memberReport.Periods {
+			total := &report.Periods[i]
+			total.Income += p.Income
+			total.Expenses += p.Expenses
+			total.Adjustments += p.Adjustments
+			total.Net += p.Net
+			total.EndingBalance += p.EndingBalance
+		}
+	}
+	if report == nil {
+		return nil, fmt.Errorf("%w: household %d has no members", ErrInvalidHousehold, id)
+	}
+
+	return report, nil
+}
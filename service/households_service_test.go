@@ -0,0 +1,239 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestHouseholds_MembersAndRoles(t *testing.T) {
+	parent, err := CreateUser(ctxTest, model.User{UserName: "household-parent"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	child, _ := CreateUser(ctxTest, model.User{UserName: "household-child"})
+	neighbour, _ := CreateUser(ctxTest, model.User{UserName: "household-neighbour"})
+	asParent, asChild, asNeighbour := asUser(parent.ID), asUser(child.ID), asUser(neighbour.ID)
+
+	if _, err := CreateHousehold(asParent, model.Household{Name: "  "}); !errors.Is(err, ErrInvalidHousehold) {
+		t.Errorf("CreateHousehold() without a name error = %v, want ErrInvalidHousehold", err)
+	}
+	home, err := CreateHousehold(asParent, model.Household{Name: " Home "})
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+	if home.Name != "Home" || len(home.Members) != 1 || home.Members[0].UserID != parent.ID || home.Members[0].Role != model.HouseholdRoleOwner {
+		t.Errorf("CreateHousehold() = %+v", home)
+	}
+	if _, err := CreateHousehold(asParent, model.Household{Name: "Second home"}); !errors.Is(err, ErrAlreadyInHousehold) {
+		t.Errorf("CreateHousehold() while in a household error = %v, want ErrAlreadyInHousehold", err)
+	}
+	next, err := CreateHousehold(asNeighbour, model.Household{Name: "Next door"})
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+
+	if _, err := SetHouseholdMember(asChild, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetHouseholdMember() by an outsider error = %v, want ErrForbidden", err)
+	}
+	if _, err := SetHouseholdMember(asParent, model.HouseholdMember{HouseholdID: home.ID, UserID: neighbour.ID}); !errors.Is(err, ErrAlreadyInHousehold) {
+		t.Errorf("SetHouseholdMember() of a user in another household error = %v, want ErrAlreadyInHousehold", err)
+	}
+	if _, err := SetHouseholdMember(asParent, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID, Role: "guest"}); !errors.Is(err, ErrInvalidHousehold) {
+		t.Errorf("SetHouseholdMember() with an unknown role error = %v, want ErrInvalidHousehold", err)
+	}
+
+	// Owners only invite: the child joins, and sees the household, once they accept
+	if _, err := SetHouseholdMember(asParent, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetHouseholdMember() of a new member by an owner error = %v, want ErrForbidden", err)
+	}
+	if _, err := InviteHouseholdMember(asChild, model.HouseholdInvite{HouseholdID: home.ID, UserID: child.ID}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("InviteHouseholdMember() by an outsider error = %v, want ErrForbidden", err)
+	}
+	if _, err := InviteHouseholdMember(asParent, model.HouseholdInvite{HouseholdID: home.ID, UserID: neighbour.ID}); !errors.Is(err, ErrAlreadyInHousehold) {
+		t.Errorf("InviteHouseholdMember() of a user in another household error = %v, want ErrAlreadyInHousehold", err)
+	}
+	invite, err := InviteHouseholdMember(asParent, model.HouseholdInvite{HouseholdID: home.ID, UserID: child.ID})
+	if err != nil || invite.Role != model.HouseholdRoleMember || invite.InvitedBy != parent.ID {
+		t.Fatalf("InviteHouseholdMember() = %+v, %v", invite, err)
+	}
+	if _, err := GetHouseholdByID(asChild, home.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetHouseholdByID() by an invited user error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetHouseholdInvites(asNeighbour, child.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetHouseholdInvites() of someone else error = %v, want ErrForbidden", err)
+	}
+	if invites, err := GetHouseholdInvites(asChild, child.ID); err != nil || len(invites) != 1 || invites[0].HouseholdID != home.ID {
+		t.Errorf("GetHouseholdInvites() = %+v, %v", invites, err)
+	}
+	if _, err := AcceptHouseholdInvite(asParent, home.ID, child.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AcceptHouseholdInvite() on someone else's behalf error = %v, want ErrForbidden", err)
+	}
+	member, err := AcceptHouseholdInvite(asChild, home.ID, child.ID)
+	if err != nil || member.Role != model.HouseholdRoleMember {
+		t.Fatalf("AcceptHouseholdInvite() = %+v, %v", member, err)
+	}
+	if _, err := AcceptHouseholdInvite(asChild, home.ID, child.ID); !errors.Is(err, model.KindNotFound) {
+		t.Errorf("AcceptHouseholdInvite() twice error = %v, want not found", err)
+	}
+
+	// Members see the household but do not manage it
+	if got, err := GetHouseholdByID(asChild, home.ID); err != nil || len(got.Members) != 2 {
+		t.Errorf("GetHouseholdByID() as a member = %+v, %v", got, err)
+	}
+	if _, err := GetHouseholdByID(asNeighbour, home.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetHouseholdByID() as an outsider error = %v, want ErrForbidden", err)
+	}
+	if _, err := UpdateHouseholdByID(asChild, home.ID, &model.HouseholdUpdate{Name: strPtr("Mine")}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateHouseholdByID() as a member error = %v, want ErrForbidden", err)
+	}
+	if got, err := UpdateHouseholdByID(asParent, home.ID, &model.HouseholdUpdate{Name: strPtr("The house")}); err != nil || got.Name != "The house" {
+		t.Errorf("UpdateHouseholdByID() = %+v, %v", got, err)
+	}
+	if households, err := GetHouseholds(asChild); err != nil || len(households) != 1 || households[0].ID != home.ID {
+		t.Errorf("GetHouseholds() as a member = %+v, %v", households, err)
+	}
+
+	// The last owner can not step down or leave while others remain
+	if _, err := SetHouseholdMember(asParent, model.HouseholdMember{HouseholdID: home.ID, UserID: parent.ID, Role: model.HouseholdRoleMember}); !errors.Is(err, ErrInvalidHousehold) {
+		t.Errorf("SetHouseholdMember() demoting the last owner error = %v, want ErrInvalidHousehold", err)
+	}
+	if _, err := RemoveHouseholdMember(asParent, home.ID, parent.ID); !errors.Is(err, ErrInvalidHousehold) {
+		t.Errorf("RemoveHouseholdMember() of the last owner error = %v, want ErrInvalidHousehold", err)
+	}
+	if _, err := RemoveHouseholdMember(asNeighbour, home.ID, child.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("RemoveHouseholdMember() by an outsider error = %v, want ErrForbidden", err)
+	}
+	if rows, err := RemoveHouseholdMember(asChild, home.ID, child.ID); err != nil || rows != 1 {
+		t.Errorf("RemoveHouseholdMember() of themselves = %d, %v", rows, err)
+	}
+
+	// Once alone, the last owner leaving deletes the household
+	if rows, err := RemoveHouseholdMember(asNeighbour, next.ID, neighbour.ID); err != nil || rows != 1 {
+		t.Errorf("RemoveHouseholdMember() of the only member = %d, %v", rows, err)
+	}
+	if _, err := GetHouseholdByID(ctxTest, next.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetHouseholdByID() after its last member left error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := DeleteHouseholdByID(asChild, home.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteHouseholdByID() as an outsider error = %v, want ErrForbidden", err)
+	}
+	if rows, err := DeleteHouseholdByID(asParent, home.ID); err != nil || rows != 1 {
+		t.Errorf("DeleteHouseholdByID() = %d, %v", rows, err)
+	}
+}
+
+func TestHouseholds_ScopedListingsAndTotals(t *testing.T) {
+	parent, err := CreateUser(ctxTest, model.User{UserName: "scope-parent", CurrentAmount: 100000, MonthlyInputs: 500000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	child, _ := CreateUser(ctxTest, model.User{UserName: "scope-child", CurrentAmount: 20000, MonthlyOutputs: 1000})
+	outsider, _ := CreateUser(ctxTest, model.User{UserName: "scope-outsider", CurrentAmount: 70000})
+	asParent, asChild, asOutsider := asUser(parent.ID), asUser(child.ID), asUser(outsider.ID)
+
+	home, err := CreateHousehold(asParent, model.Household{Name: "Scope home"})
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+	if _, err := InviteHouseholdMember(asParent, model.HouseholdInvite{HouseholdID: home.ID, UserID: child.ID}); err != nil {
+		t.Fatalf("InviteHouseholdMember() returned error: %v", err)
+	}
+
+	parentTx, _ := CreateTransaction(asParent, model.Transaction{Desc: "salary", Amount: 300000, UserID: parent.ID})
+	childTx, _ := CreateTransaction(asChild, model.Transaction{Desc: "toys", Amount: 5000, IsDebt: true, UserID: child.ID})
+	outsiderTx, _ := CreateTransaction(asOutsider, model.Transaction{Desc: "rent", Amount: 80000, IsDebt: true, UserID: outsider.ID})
+	goal, _ := CreateGoal(asChild, model.Goal{Name: "bike", Price: 30000, UserID: child.ID})
+
+	// A pending invite grants no access to the inviting household's records
+	if _, err := GetTransactionByID(asParent, childTx.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetTransactionByID() of an invited user error = %v, want ErrForbidden", err)
+	}
+	if _, err := AcceptHouseholdInvite(asChild, home.ID, child.ID); err != nil {
+		t.Fatalf("AcceptHouseholdInvite() returned error: %v", err)
+	}
+
+	users, err := GetAllUsers(asChild)
+	if err != nil || len(users) != 2 || users[0].ID != parent.ID || users[1].ID != child.ID {
+		t.Errorf("GetAllUsers() as a household member = %+v, %v", users, err)
+	}
+	transactions, err := GetAllTransactions(asChild, nil)
+	if err != nil || len(transactions) != 2 || transactions[0].ID != parentTx.ID || transactions[1].ID != childTx.ID {
+		t.Errorf("GetAllTransactions() as a household member = %+v, %v", transactions, err)
+	}
+	if goals, err := GetAllGoals(asParent, nil); err != nil || len(goals) != 1 || goals[0].ID != goal.ID {
+		t.Errorf("GetAllGoals() as a household member = %+v, %v", goals, err)
+	}
+	if outsiderUsers, err := GetAllUsers(asOutsider); err != nil || len(outsiderUsers) != 1 {
+		t.Errorf("GetAllUsers() without a household = %+v, %v", outsiderUsers, err)
+	}
+
+	// Members read each other's records, but only change their own
+	if _, err := GetTransactionByID(asParent, childTx.ID); err != nil {
+		t.Errorf("GetTransactionByID() of a household member returned error: %v", err)
+	}
+	if _, err := GetGoalByID(asParent, goal.ID); err != nil {
+		t.Errorf("GetGoalByID() of a household member returned error: %v", err)
+	}
+	if _, err := UpdateTransactionByID(asParent, childTx.ID, &model.TransactionUpdate{Desc: strPtr("mine")}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateTransactionByID() of a household member error = %v, want ErrForbidden", err)
+	}
+	if _, err := GetTransactionByID(asChild, outsiderTx.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetTransactionByID() of an outsider error = %v, want ErrForbidden", err)
+	}
+
+	balance, err := GetHouseholdBalance(asChild, home.ID)
+	if err != nil {
+		t.Fatalf("GetHouseholdBalance() returned error: %v", err)
+	}
+	if balance.CurrentAmount != 400000+15000 || balance.MonthlyInputs != 500000 || balance.MonthlyOutputs != 1000 || len(balance.Members) != 2 {
+		t.Errorf("GetHouseholdBalance() = %+v", balance)
+	}
+	if _, err := GetHouseholdBalance(asOutsider, home.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetHouseholdBalance() as an outsider error = %v, want ErrForbidden", err)
+	}
+
+	// Dated transactions only feed the report; the balances above already include them
+	insertTransactionAt(t, parent.ID, 2000, false, "2031-02-10 10:00:00")
+	insertTransactionAt(t, child.ID, 500, true, "2031-02-20 10:00:00")
+
+	report, err := GetHouseholdCashflowReport(asParent, home.ID, "2031-02-01", "2031-02-28", model.GranularityMonth)
+	if err != nil {
+		t.Fatalf("GetHouseholdCashflowReport() returned error: %v", err)
+	}
+	if len(report.Periods) != 1 {
+		t.Fatalf("GetHouseholdCashflowReport() = %+v, want one period", report)
+	}
+	if p := report.Periods[0]; p.Income != 2000 || p.Expenses != 500 || p.Net != 1500 {
+		t.Errorf("GetHouseholdCashflowReport() period = %+v", p)
+	}
+	if _, err := GetHouseholdCashflowReport(asParent, home.ID, "", "", "hourly"); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetHouseholdCashflowReport() with a bad granularity error = %v, want ErrInvalidReport", err)
+	}
+}
+
+func TestHouseholds_TrashedMember(t *testing.T) {
+	parent, err := CreateUser(ctxTest, model.User{UserName: "trashed-household-parent"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	child, _ := CreateUser(ctxTest, model.User{UserName: "trashed-household-child"})
+	home, err := CreateHousehold(asUser(parent.ID), model.Household{Name: "Trash home"})
+	if err != nil {
+		t.Fatalf("CreateHousehold() returned error: %v", err)
+	}
+	if _, err := SetHouseholdMember(ctxTest, model.HouseholdMember{HouseholdID: home.ID, UserID: child.ID}); err != nil {
+		t.Fatalf("SetHouseholdMember() returned error: %v", err)
+	}
+	if _, err := DeleteUserByID(ctxTest, child.ID); err != nil {
+		t.Fatalf("DeleteUserByID() returned error: %v", err)
+	}
+
+	// The remaining members keep listing the household without the user in the trash
+	if users, err := GetAllUsers(asUser(parent.ID)); err != nil || len(users) != 1 || users[0].ID != parent.ID {
+		t.Errorf("GetAllUsers() with a member in the trash = %+v, %v; want only user %d", users, err, parent.ID)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+const (
+	// defaultInsightDays is how many days back the expenses are analysed when no range is requested.
+	defaultInsightDays = 30
+	// maxInsightDays caps how many days back the expenses may be analysed.
+	maxInsightDays = 365
+	// insightBaselineDays is how much history before the analysed range the typical amounts are learnt from.
+	insightBaselineDays = 365
+	// unusualMinSamples is how many earlier expenses at a merchant are needed before an amount can be unusual.
+	unusualMinSamples = 3
+	// unusualFactor is how many times the typical amount an expense must exceed to be unusual.
+	unusualFactor = 3
+	// duplicateWindow is how close two identical charges must be to look like a duplicate.
+	duplicateWindow = 3 * time.Hour
+)
+
+// merchantKey normalises a description into the merchant it names: letters only, lower case, single spaced,
+// so "UBER *TRIP 4421" and "Uber trip 9310" are the same merchant.
+func merchantKey(description string) string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// median returns the median of amounts, which must not be empty.
+func median(amounts []utils.Money) utils.Money {
+	sorted := slices.Clone(amounts)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// describeGap renders the time between two charges for a message.
+func describeGap(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%d hours", int(d.Hours()))
+}
+
+// analyzeExpenses flags the expenses of history created at or after from, a "YYYY-MM-DD HH:MM:SS"
+// timestamp. history holds debt transactions in chronological order; the ones before from only serve as
+// the baseline. An expense is flagged when it is far above the median of the earlier expenses at the same
+// merchant, when it repeats the amount of a charge at the same merchant shortly before, or when it is the
+// first at its merchant, which is only reported when the history starts before from so a new user is not
+// flooded with new merchants. Expenses without a description are not compared. Insights are newest first.
+func analyzeExpenses(history []model.Transaction, from string) []model.Insight {
+	type charge struct {
+		merchant string
+		amount   utils.Money
+	}
+
+	reportNew := len(history) > 0 && history[0].CreatedAt < from
+	amounts := make(map[string][]utils.Money)
+	last := make(map[charge]model.Transaction)
+
+	insights := []model.Insight{}
+	for _, t := range history {
+		key := merchantKey(t.Desc)
+		if key == "" {
+			continue
+		}
+		earlier := amounts[key]
+		previous, repeated := last[charge{key, t.Amount}]
+		amounts[key] = append(earlier, t.Amount)
+		last[charge{key, t.Amount}] = t
+
+		if t.CreatedAt < from {
+			continue
+		}
+		flag := model.Insight{TransactionID: t.ID, Merchant: key, Amount: t.Amount, CreatedAt: t.CreatedAt}
+
+		if len(earlier) >= unusualMinSamples {
+			if typical := median(earlier); typical > 0 && t.Amount > unusualFactor*typical {
+				f := flag
+				f.Kind = model.InsightUnusualAmount
+				f.Typical = typical
+				f.Message = fmt.Sprintf("%s spent at %q is %.1f times the typical %s.", formatMoney(t.Amount), key, float64(t.Amount)/float64(typical), formatMoney(typical))
+				insights = append(insights, f)
+			}
+		}
+
+		if repeated {
+			at, err1 := time.Parse(dbsqlite.TimestampLayout, t.CreatedAt)
+			before, err2 := time.Parse(dbsqlite.TimestampLayout, previous.CreatedAt)
+			if gap := at.Sub(before); err1 == nil && err2 == nil && gap < duplicateWindow {
+				f := flag
+				f.Kind = model.InsightDuplicate
+				f.RelatedTransactionID = previous.ID
+				f.Message = fmt.Sprintf("%s at %q repeats a charge of the same amount made %s before.", formatMoney(t.Amount), key, describeGap(gap))
+				insights = append(insights, f)
+			}
+		}
+
+		if reportNew && len(earlier) == 0 {
+			f := flag
+			f.Kind = model.InsightNewMerchant
+			f.Message = fmt.Sprintf("First expense at %q: %s.", key, formatMoney(t.Amount))
+			insights = append(insights, f)
+		}
+	}
+
+	// Newest first, keeping the flags of a single expense together
+	slices.Reverse(insights)
+	return insights
+}
+
+// GetInsights analyses the expenses a user recorded in the last days days (30 when zero) and flags unusual
+// amounts, possible duplicate charges and new merchants. Typical amounts are learnt from up to a year of
+// earlier history.
+func GetInsights(ctx context.Context, userID int64, days int) (*model.Insights, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if days == 0 {
+		days = defaultInsightDays
+	}
+	if days < 0 || days > maxInsightDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidReport, maxInsightDays)
+	}
+
+	now := currentTime().UTC()
+	from := now.AddDate(0, 0, -days)
+	baselineFrom := from.AddDate(0, 0, -insightBaselineDays)
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	to := now.Add(time.Second)
+	history, err := dbsqlite.GetDebtTransactionsBetween(ctx, userID, baselineFrom.Format(dbsqlite.TimestampLayout), to.Format(dbsqlite.TimestampLayout), db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Insights{
+		UserID:   userID,
+		From:     from.Format(time.RFC3339),
+		To:       now.Format(time.RFC3339),
+		Insights: analyzeExpenses(history, from.Format(dbsqlite.TimestampLayout)),
+	}, nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestMerchantKey(t *testing.T) {
+	tests := map[string]string{
+		"UBER *TRIP 4421":  "uber trip",
+		"  Uber trip 9310": "uber trip",
+		"Padaria São João": "padaria são joão",
+		"12345":            "",
+	}
+	for in, want := range tests {
+		if got := merchantKey(in); got != want {
+			t.Errorf("merchantKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// expense builds a synthetic debt transaction created the given number of hours after 2032-01-01.
+func expense(id int64, desc string, amount utils.Money, hours int) model.Transaction {
+	at := time.Date(2032, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(hours) * time.Hour)
+	return model.Transaction{ID: id, Desc: desc, Amount: amount, IsDebt: true, CreatedAt: at.Format("2006-01-02 15:04:05")}
+}
+
+func TestAnalyzeExpenses(t *testing.T) {
+	// The analysed range starts 10 days in
+	const from = "2032-01-11 00:00:00"
+	const day = 24
+
+	type flag struct {
+		kind    string
+		id      int64
+		related int64
+		typical utils.Money
+	}
+
+	tests := []struct {
+		name    string
+		history []model.Transaction
+		want    []flag
+	}{
+		{
+			name: "amount far above the median is unusual",
+			history: []model.Transaction{
+				expense(1, "Market", 1000, 0), expense(2, "market", 1200, day), expense(3, "MARKET", 80000, 2*day),
+				expense(4, "Market", 1100, 11*day), expense(5, "Market", 5000, 12*day),
+			},
+			// The median stays at 1150 despite the earlier outlier
+			want: []flag{{model.InsightUnusualAmount, 5, 0, 1150}},
+		},
+		{
+			name: "too few earlier expenses to know the typical amount",
+			history: []model.Transaction{
+				expense(1, "Cinema", 1000, 0), expense(2, "Cinema", 1000, 2*day), expense(3, "Cinema", 9000, 11*day),
+			},
+		},
+		{
+			name: "same amount at the same merchant within hours is a possible duplicate",
+			history: []model.Transaction{
+				expense(1, "Coffee", 500, 0),
+				expense(2, "Streaming 01/12", 3990, 11*day), expense(3, "Streaming 02/12", 3990, 11*day+1),
+				expense(4, "Coffee", 500, 13*day), expense(5, "Coffee", 500, 14*day),
+			},
+			want: []flag{{model.InsightDuplicate, 3, 2, 0}, {model.InsightNewMerchant, 2, 0, 0}},
+		},
+		{
+			name: "first expense at a merchant is new once there is older history",
+			history: []model.Transaction{
+				expense(1, "Bakery", 300, 0), expense(2, "Bookstore", 4500, 11*day), expense(3, "Bookstore", 2000, 12*day), expense(4, "Bakery", 300, 13*day),
+			},
+			want: []flag{{model.InsightNewMerchant, 2, 0, 0}},
+		},
+		{
+			name: "no new merchants for a user without older history",
+			history: []model.Transaction{
+				expense(1, "Bookstore", 4500, 11*day), expense(2, "Bakery", 300, 12*day),
+			},
+		},
+		{
+			name: "expenses without a description are not compared",
+			history: []model.Transaction{
+				expense(1, "Bakery", 300, 0), expense(2, "", 300, 11*day), expense(3, "", 300, 11*day+1),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := analyzeExpenses(tc.history, from)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d insights, want %d: %+v", len(got), len(tc.want), got)
+			}
+			for i, w := range tc.want {
+				g := got[i]
+				if g.Kind != w.kind || g.TransactionID != w.id || g.RelatedTransactionID != w.related || g.Typical != w.typical || g.Message == "" {
+					t.Errorf("insight %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestGetInsights(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "insights-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2032, 11, 30, 12, 0, 0, 0, time.UTC))
+
+	for i := 1; i <= 5; i++ {
+		insertTransactionAt(t, user.ID, 1000, true, fmt.Sprintf("2032-10-%02d 09:00:00", i))
+	}
+	// Too old to be analysed with the default 30 days, yet part of the baseline
+	insertTransactionAt(t, user.ID, 9000, true, "2032-10-20 09:00:00")
+	insertTransactionAt(t, user.ID, 9000, true, "2032-11-25 09:00:00")
+	// Income is never flagged
+	insertTransactionAt(t, user.ID, 900000, false, "2032-11-26 09:00:00")
+
+	insights, err := GetInsights(ctxTest, user.ID, 0)
+	if err != nil {
+		t.Fatalf("GetInsights() unexpected error: %v", err)
+	}
+	if insights.From != "2032-10-31T12:00:00Z" || insights.To != "2032-11-30T12:00:00Z" {
+		t.Errorf("range = %s to %s", insights.From, insights.To)
+	}
+	if len(insights.Insights) != 1 || insights.Insights[0].Kind != model.InsightUnusualAmount || insights.Insights[0].Typical != 1000 {
+		t.Errorf("unexpected insights: %+v", insights.Insights)
+	}
+
+	// Over 60 days the October outlier is analysed too
+	insights, err = GetInsights(ctxTest, user.ID, 60)
+	if err != nil {
+		t.Fatalf("GetInsights() unexpected error: %v", err)
+	}
+	if len(insights.Insights) != 2 {
+		t.Errorf("expected 2 insights over 60 days, got %+v", insights.Insights)
+	}
+
+	if _, err := GetInsights(ctxTest, user.ID, maxInsightDays+1); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetInsights() with too many days error = %v, want ErrInvalidReport", err)
+	}
+	if _, err := GetInsights(ctxTest, 999999999, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetInsights() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"natan/fingo/jobs"
+	"natan/fingo/model"
+)
+
+// Names of the built-in background jobs
+const (
+	JobMonthlyAdjustments = "monthly-adjustments"
+	JobNotifications      = "notifications"
+)
+
+// applicationClock adapts the application clock to the jobs scheduler, so SetClock also applies to schedules.
+type applicationClock struct{}
+
+// Now returns the current time of the application clock.
+func (applicationClock) Now() time.Time {
+	return currentTime()
+}
+
+var jobScheduler = jobs.NewScheduler(applicationClock{})
+
+// RegisterJob adds a background job running fn on the schedule described by spec, a cron expression,
+// a descriptor such as "@daily" or an interval such as "@every 15m".
+func RegisterJob(ctx context.Context, name, spec string, fn jobs.Func) error {
+	return jobScheduler.Register(ctx, name, spec, fn)
+}
+
+// RegisterDefaultJobs registers the monthly adjustment, notification, session cleanup and trash purge jobs,
+// all running every scheduler interval.
+func RegisterDefaultJobs(ctx context.Context) error {
+	every := "@every " + currentSchedulerInterval().String()
+
+	if err := RegisterJob(ctx, JobMonthlyAdjustments, every, ProcessPendingAdjustments); err != nil {
+		return err
+	}
+	if err := RegisterJob(ctx, JobNotifications, every, ProcessNotifications); err != nil {
+		return err
+	}
+	if err := RegisterJob(ctx, JobSessionCleanup, every, PurgeExpiredSessions); err != nil {
+		return err
+	}
+	return RegisterJob(ctx, JobTrashPurge, every, PurgeTrash)
+}
+
+// StartJobScheduler runs the registered jobs in the background whenever they are due, until ctx is canceled.
+func StartJobScheduler(ctx context.Context) {
+	jobScheduler.Start(ctx)
+}
+
+// GetJobs returns the registered background jobs with their next and last runs.
+func GetJobs(ctx context.Context) ([]model.Job, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return jobScheduler.Jobs(ctx)
+}
+
+// GetJobRuns returns the most recent runs of the named job, newest first.
+func GetJobRuns(ctx context.Context, name string) ([]model.JobRun, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return jobScheduler.Runs(ctx, name)
+}
+
+// TriggerJob runs the named job right away and returns the recorded run. It fails with jobs.ErrNotLeader
+// while another instance holds the scheduler lease.
+func TriggerJob(ctx context.Context, name string) (*model.JobRun, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return jobScheduler.Trigger(ctx, name)
+}
+
+// GetSchedulerStatus tells whether this instance holds the lease to run the scheduled jobs.
+func GetSchedulerStatus(ctx context.Context) (*model.SchedulerStatus, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	return jobScheduler.Status(ctx)
+}
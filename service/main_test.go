@@ -0,0 +1,22 @@
+package service
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"natan/fingo/dbsqlite"
+)
+
+// TestMain creates a fresh fingo.db for the service tests and removes it afterwards.
+func TestMain(m *testing.M) {
+	_ = os.Remove("fingo.db")
+	if err := dbsqlite.CheckAndCreate(); err != nil {
+		log.Fatalf("could not create test database: %v", err)
+	}
+
+	code := m.Run()
+
+	_ = os.Remove("fingo.db")
+	os.Exit(code)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"natan/fingo/clock"
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// useFakeClock installs a fake clock for the duration of the test.
+func useFakeClock(t *testing.T, start time.Time) *clock.Fake {
+	t.Helper()
+
+	fake := clock.NewFake(start)
+	SetClock(fake)
+	t.Cleanup(func() { SetClock(clock.System{}) })
+	return fake
+}
+
+// isolateAdjustmentUsers disables automatic adjustments for every user except the given ones,
+// so scheduler runs in a test only touch the users it created.
+func isolateAdjustmentUsers(t *testing.T, keep ...int64) {
+	t.Helper()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		INSERT INTO user_adjustment_settings(user_id, enabled) SELECT id, 0 FROM users WHERE true
+		ON CONFLICT(user_id) DO UPDATE SET enabled = 0`); err != nil {
+		t.Fatalf("could not disable adjustments: %v", err)
+	}
+	for _, id := range keep {
+		if _, err := db.Exec(`UPDATE user_adjustment_settings SET enabled = 1 WHERE user_id = ?`, id); err != nil {
+			t.Fatalf("could not enable adjustments for user %d: %v", id, err)
+		}
+	}
+}
+
+// setLastPeriod records the last processed period of a user and makes sure the log is not empty.
+func setLastPeriod(t *testing.T, userID int64, yearMonth string) {
+	t.Helper()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := dbsqlite.RecordMonthWithoutAdjustment(ctxTest, db, yearMonth); err != nil {
+		t.Fatalf("could not record month: %v", err)
+	}
+	if err := dbsqlite.SetAdjustmentLastPeriod(ctxTest, db, userID, yearMonth); err != nil {
+		t.Fatalf("could not set last period: %v", err)
+	}
+}
+
+func TestProcessPendingAdjustments_MonthAndYearBoundaries(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "clock-payday-5", MonthlyInputs: 1000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	payDay, utc := 5, "UTC"
+	if _, err := UpdateAdjustmentSettings(ctxTest, user.ID, &model.AdjustmentSettingsUpdate{PayDay: &payDay, Timezone: &utc}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	isolateAdjustmentUsers(t, user.ID)
+	setLastPeriod(t, user.ID, "2029-11")
+
+	fake := useFakeClock(t, time.Date(2029, 12, 4, 23, 59, 0, 0, time.UTC))
+
+	steps := []struct {
+		name        string
+		at          time.Time
+		wantBalance int64
+	}{
+		{"day_before_pay_day", time.Date(2029, 12, 4, 23, 59, 0, 0, time.UTC), 0},
+		{"pay_day_reached", time.Date(2029, 12, 5, 0, 0, 0, 0, time.UTC), 1000},
+		{"same_month_again", time.Date(2029, 12, 20, 0, 0, 0, 0, time.UTC), 1000},
+		{"new_year_before_pay_day", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 1000},
+		{"new_year_pay_day", time.Date(2030, 1, 5, 9, 0, 0, 0, time.UTC), 2000},
+		{"long_downtime_catches_up", time.Date(2030, 7, 20, 0, 0, 0, 0, time.UTC), 8000},
+	}
+
+	for _, step := range steps {
+		fake.Set(step.at)
+		if err := ProcessPendingAdjustments(context.Background()); err != nil {
+			t.Fatalf("%s: ProcessPendingAdjustments() unexpected error: %v", step.name, err)
+		}
+		got, err := GetUserByID(ctxTest, user.ID)
+		if err != nil {
+			t.Fatalf("%s: GetUserByID() unexpected error: %v", step.name, err)
+		}
+		if int64(got.CurrentAmount) != step.wantBalance {
+			t.Fatalf("%s: balance = %d, want %d", step.name, got.CurrentAmount, step.wantBalance)
+		}
+	}
+
+	entries, err := GetAdjustmentsByUserID(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetAdjustmentsByUserID() unexpected error: %v", err)
+	}
+	if len(entries) != 8 || entries[0].YearMonth != "2030-07" || entries[7].YearMonth != "2029-12" {
+		t.Errorf("unexpected adjustment history: %+v", entries)
+	}
+}
+
+func TestProcessPendingAdjustments_UserTimezones(t *testing.T) {
+	tokyo, err := CreateUser(ctxTest, model.User{UserName: "clock-tokyo", MonthlyInputs: 100})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	saoPaulo, err := CreateUser(ctxTest, model.User{UserName: "clock-sao-paulo", MonthlyInputs: 100})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for id, tz := range map[int64]string{tokyo.ID: "Asia/Tokyo", saoPaulo.ID: "America/Sao_Paulo"} {
+		tz := tz
+		if _, err := UpdateAdjustmentSettings(ctxTest, id, &model.AdjustmentSettingsUpdate{Timezone: &tz}); err != nil {
+			t.Fatalf("failed to update settings: %v", err)
+		}
+	}
+	isolateAdjustmentUsers(t, tokyo.ID, saoPaulo.ID)
+	setLastPeriod(t, tokyo.ID, "2031-06")
+	setLastPeriod(t, saoPaulo.ID, "2031-06")
+
+	// 16:00 UTC on June 30th is already July 1st in Tokyo but still June 30th in Sao Paulo
+	useFakeClock(t, time.Date(2031, 6, 30, 16, 0, 0, 0, time.UTC))
+
+	if err := ProcessPendingAdjustments(context.Background()); err != nil {
+		t.Fatalf("ProcessPendingAdjustments() unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id   int64
+		want int64
+	}{{tokyo.ID, 100}, {saoPaulo.ID, 0}} {
+		got, err := GetUserByID(ctxTest, tc.id)
+		if err != nil {
+			t.Fatalf("GetUserByID() unexpected error: %v", err)
+		}
+		if int64(got.CurrentAmount) != tc.want {
+			t.Errorf("user %d balance = %d, want %d", tc.id, got.CurrentAmount, tc.want)
+		}
+	}
+}
+
+func TestCurrentYearMonth_UsesApplicationTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("could not load timezone: %v", err)
+	}
+
+	// 2025-12-31 20:00 UTC is already January 2026 in Tokyo
+	useFakeClock(t, time.Date(2025, 12, 31, 20, 0, 0, 0, time.UTC).In(tokyo))
+
+	if got := currentYearMonth(); got != "2026-01" {
+		t.Errorf("currentYearMonth() = %q, want %q", got, "2026-01")
+	}
+}
+
+func TestProcessPendingAdjustments_MonthIsAllOrNothing(t *testing.T) {
+	first, err := CreateUser(ctxTest, model.User{UserName: "clock-atomic-first", MonthlyInputs: 100})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	second, err := CreateUser(ctxTest, model.User{UserName: "clock-atomic-second", MonthlyInputs: 100})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	isolateAdjustmentUsers(t, first.ID, second.ID)
+	setLastPeriod(t, first.ID, "2032-05")
+	setLastPeriod(t, second.ID, "2032-05")
+
+	// An entry left behind for the second user makes June fail for them
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`
+		INSERT INTO monthly_adjustment_entries(user_id, year_month, inputs_applied, outputs_applied, balance_before, balance_after)
+		VALUES (?, '2032-06', 0, 0, 0, 0)`, second.ID); err != nil {
+		t.Fatalf("could not insert entry: %v", err)
+	}
+
+	useFakeClock(t, time.Date(2032, 6, 15, 12, 0, 0, 0, time.UTC))
+	if err := ProcessPendingAdjustments(context.Background()); err == nil {
+		t.Fatalf("ProcessPendingAdjustments() with a clashing entry returned no error")
+	}
+
+	// June is rolled back for the first user too
+	got, err := GetUserByID(ctxTest, first.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() unexpected error: %v", err)
+	}
+	if got.CurrentAmount != 0 {
+		t.Errorf("first user balance = %d, want 0", got.CurrentAmount)
+	}
+	if entries, err := GetAdjustmentsByUserID(ctxTest, first.ID); err != nil || len(entries) != 0 {
+		t.Errorf("first user adjustments = %+v, %v; want none", entries, err)
+	}
+}
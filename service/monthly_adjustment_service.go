@@ -2,13 +2,20 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"time"
 
 	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
 )
 
+// ErrInvalidYearMonth is returned when a year-month is malformed or not allowed for the operation.
+var ErrInvalidYearMonth = model.NewError(model.KindValidation, "invalid year-month")
+
 // monthsBetween returns all year-month strings (format "YYYY-MM") from the month
 // after `from` up to and including `to`. If `from` is empty, returns only `to`.
 func monthsBetween(from, to string) ([]string, error) {
@@ -37,22 +44,129 @@ func monthsBetween(from, to string) ([]string, error) {
 	return months, nil
 }
 
-// currentYearMonth returns the current year-month string in "YYYY-MM" format.
+// currentYearMonth returns the current year-month string in "YYYY-MM" format, in the application timezone.
 func currentYearMonth() string {
-	return time.Now().Format("2006-01")
+	return currentTime().Format("2006-01")
+}
+
+// daysIn returns the number of days of the given month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dueYearMonth returns the latest year-month ("YYYY-MM") whose pay day has been reached at the local time now.
+// Pay days past the end of a short month fall on its last day.
+func dueYearMonth(now time.Time, payDay int) string {
+	day := payDay
+	if last := daysIn(now.Year(), now.Month()); day > last {
+		day = last
+	}
+
+	if now.Day() >= day {
+		return now.Format("2006-01")
+	}
+
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0).Format("2006-01")
+}
+
+// loadLocation resolves a timezone name, where an empty name means the application timezone.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return currentTime().Location(), nil
+	}
+	return time.LoadLocation(name)
+}
+
+// adjustmentPlan is the outcome of evaluating pending adjustments without applying them.
+type adjustmentPlan struct {
+	// entries lists the adjustments to apply in order, with balances chained per user.
+	entries []model.AdjustmentEntry
+	// baselines maps users to the last processed period to record without applying anything.
+	baselines map[int64]string
+}
+
+// planPendingAdjustments works out, for every user, which months have reached the user's pay day in
+// the user's timezone and were not applied yet. Users seen for the first time start from the earlier
+// of lastProcessed and their current due month, so no month is applied twice; disabled users skip
+// their periods so re-enabling does not apply a backlog.
+func planPendingAdjustments(ctx context.Context, db *sql.DB, now time.Time, lastProcessed string) (*adjustmentPlan, error) {
+	users, err := dbsqlite.GetAllUsers(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("could not load users: %w", err)
+	}
+
+	settings, err := dbsqlite.GetAllAdjustmentSettings(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("could not load adjustment settings: %w", err)
+	}
+
+	plan := &adjustmentPlan{baselines: make(map[int64]string)}
+
+	for _, user := range users {
+		s, ok := settings[user.ID]
+		if !ok {
+			s = dbsqlite.DefaultAdjustmentSettings(user.ID)
+		}
+
+		loc, err := loadLocation(s.Timezone)
+		if err != nil {
+			log.Printf("[MonthlyAdjustment] Invalid timezone %q for user %d, using application timezone: %v", s.Timezone, user.ID, err)
+			loc = now.Location()
+		}
+		due := dueYearMonth(now.In(loc), s.PayDay)
+
+		if !s.Enabled {
+			if s.LastPeriod != due {
+				plan.baselines[user.ID] = due
+			}
+			continue
+		}
+
+		last := s.LastPeriod
+		if last == "" {
+			last = due
+			if lastProcessed < due {
+				last = lastProcessed
+			}
+			plan.baselines[user.ID] = last
+		}
+
+		pendingMonths, err := monthsBetween(last, due)
+		if err != nil {
+			return nil, fmt.Errorf("could not calculate pending months for user %d: %w", user.ID, err)
+		}
+
+		balance := user.CurrentAmount
+		for _, month := range pendingMonths {
+			entry := model.AdjustmentEntry{
+				UserID:         user.ID,
+				YearMonth:      month,
+				InputsApplied:  user.MonthlyInputs,
+				OutputsApplied: user.MonthlyOutputs,
+				BalanceBefore:  balance,
+			}
+			entry.BalanceAfter = entry.BalanceBefore + entry.InputsApplied - entry.OutputsApplied
+			balance = entry.BalanceAfter
+			plan.entries = append(plan.entries, entry)
+		}
+	}
+
+	return plan, nil
 }
 
-// ProcessPendingAdjustments checks for any unprocessed months and applies
-// the monthly adjustment for each one. On first run (no log entries), it
-// records the current month without applying adjustments to establish a baseline.
-func ProcessPendingAdjustments() error {
+// ProcessPendingAdjustments applies every pending monthly adjustment, as computed by
+// planPendingAdjustments, one month at a time from the oldest: each month is applied to all the users due
+// for it in a single transaction. On first run (no log entries), it records the current month without
+// applying adjustments to establish a baseline. It stops when ctx is canceled.
+func ProcessPendingAdjustments(ctx context.Context) error {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return fmt.Errorf("could not get database connection: %w", err)
 	}
 	defer db.Close()
 
-	ctx, cancel := dbsqlite.NewDBContext()
+	jobCtx := ctx
+	ctx, cancel := dbsqlite.WithDBTimeout(jobCtx)
 	defer cancel()
 
 	lastProcessed, err := dbsqlite.GetLastProcessedMonth(ctx, db)
@@ -60,11 +174,10 @@ func ProcessPendingAdjustments() error {
 		return fmt.Errorf("could not get last processed month: %w", err)
 	}
 
-	now := currentYearMonth()
-
 	// First run: no records exist yet. Record the current month as baseline
 	// without applying any adjustment to avoid double-counting.
 	if lastProcessed == "" {
+		now := currentYearMonth()
 		log.Printf("[MonthlyAdjustment] First run detected. Recording %s as baseline (no adjustment applied).", now)
 		if err := dbsqlite.RecordMonthWithoutAdjustment(ctx, db, now); err != nil {
 			return fmt.Errorf("could not record baseline month: %w", err)
@@ -72,67 +185,189 @@ func ProcessPendingAdjustments() error {
 		return nil
 	}
 
-	// Already up to date
-	if lastProcessed == now {
-		log.Printf("[MonthlyAdjustment] Already up to date (last processed: %s).", lastProcessed)
-		return nil
+	plan, err := planPendingAdjustments(ctx, db, currentTime(), lastProcessed)
+	if err != nil {
+		return err
 	}
 
-	// Calculate all months that need processing
-	pendingMonths, err := monthsBetween(lastProcessed, now)
-	if err != nil {
-		return fmt.Errorf("could not calculate pending months: %w", err)
+	for userID, period := range plan.baselines {
+		if err := dbsqlite.SetAdjustmentLastPeriod(ctx, db, userID, period); err != nil {
+			return err
+		}
 	}
 
-	if len(pendingMonths) == 0 {
-		log.Printf("[MonthlyAdjustment] No pending months to process.")
+	if len(plan.entries) == 0 {
+		log.Printf("[MonthlyAdjustment] Already up to date.")
 		return nil
 	}
 
-	log.Printf("[MonthlyAdjustment] Processing %d pending month(s): %v", len(pendingMonths), pendingMonths)
-
-	for _, month := range pendingMonths {
-		// Each adjustment gets its own context to avoid timeout issues with many months
-		adjCtx, adjCancel := dbsqlite.NewDBContext()
+	usersByMonth := make(map[string][]int64)
+	for _, entry := range plan.entries {
+		usersByMonth[entry.YearMonth] = append(usersByMonth[entry.YearMonth], entry.UserID)
+	}
+	months := slices.Sorted(maps.Keys(usersByMonth))
 
-		if err := dbsqlite.ApplyMonthlyAdjustment(adjCtx, db, month); err != nil {
-			adjCancel()
-			return fmt.Errorf("could not apply adjustment for %s: %w", month, err)
+	for _, month := range months {
+		// Each month gets its own context to avoid timeout issues with many months
+		if err := applyPendingMonth(jobCtx, db, month, usersByMonth[month]); err != nil {
+			return err
 		}
+	}
+
+	log.Printf("[MonthlyAdjustment] Applied %d pending adjustment(s).", len(plan.entries))
+	return nil
+}
+
+// applyPendingMonth applies yearMonth to the given users in a single transaction, then writes their balance
+// changes to the audit log.
+func applyPendingMonth(ctx context.Context, db *sql.DB, yearMonth string, userIDs []int64) error {
+	ctx, cancel := dbsqlite.WithDBTimeout(ctx)
+	defer cancel()
 
-		log.Printf("[MonthlyAdjustment] Successfully applied adjustment for %s.", month)
-		adjCancel()
+	entries, err := dbsqlite.ApplyMonthlyAdjustmentToUsers(ctx, db, yearMonth, userIDs)
+	if err != nil {
+		return fmt.Errorf("could not apply adjustment for %s: %w", yearMonth, err)
+	}
+	for _, entry := range entries {
+		if err := recordBalanceAudit(ctx, entry.UserID, entry.BalanceAfter-entry.BalanceBefore, db); err != nil {
+			return fmt.Errorf("could not audit adjustment for user %d in %s: %w", entry.UserID, yearMonth, err)
+		}
 	}
 
-	log.Printf("[MonthlyAdjustment] All pending adjustments applied successfully.")
+	log.Printf("[MonthlyAdjustment] Successfully applied %s to %d user(s).", yearMonth, len(entries))
 	return nil
 }
 
-// StartMonthlyAdjustmentScheduler starts a background goroutine that periodically
-// checks whether a new month has started and applies the monthly adjustment.
-// It checks every hour. The goroutine stops when the provided context is canceled.
-func StartMonthlyAdjustmentScheduler(ctx context.Context) {
-	go func() {
-		// Run immediately on startup
-		if err := ProcessPendingAdjustments(); err != nil {
-			log.Printf("[MonthlyAdjustment] Error during startup processing: %v", err)
+// PreviewPendingAdjustments returns the adjustments ProcessPendingAdjustments would apply right now,
+// with the resulting balance changes per user, without writing anything.
+func PreviewPendingAdjustments(ctx context.Context) ([]model.AdjustmentEntry, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	lastProcessed, err := dbsqlite.GetLastProcessedMonth(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first run only records a baseline
+	if lastProcessed == "" {
+		return []model.AdjustmentEntry{}, nil
+	}
+
+	plan, err := planPendingAdjustments(ctx, db, currentTime(), lastProcessed)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.entries == nil {
+		return []model.AdjustmentEntry{}, nil
+	}
+	return plan.entries, nil
+}
+
+// TriggerMonthlyAdjustment applies the adjustment for yearMonth ("YYYY-MM") right away to every user with
+// automatic adjustments enabled who has not received it yet, regardless of pay day. All users are adjusted
+// in a single transaction. Future months are rejected.
+func TriggerMonthlyAdjustment(ctx context.Context, yearMonth string) ([]model.AdjustmentEntry, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	month, err := time.Parse("2006-01", yearMonth)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not in YYYY-MM format", ErrInvalidYearMonth, yearMonth)
+	}
+	if month.Format("2006-01") > currentYearMonth() {
+		return nil, fmt.Errorf("%w: %s is in the future", ErrInvalidYearMonth, yearMonth)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	users, err := dbsqlite.GetAllUsers(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := dbsqlite.GetAllAdjustmentSettings(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	adjusted, err := dbsqlite.GetAdjustedUserIDs(ctx, db, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []int64
+	for _, user := range users {
+		s, ok := settings[user.ID]
+		if ok && !s.Enabled {
+			continue
+		}
+		if !adjusted[user.ID] {
+			userIDs = append(userIDs, user.ID)
 		}
+	}
 
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
+	if len(userIDs) == 0 {
+		return []model.AdjustmentEntry{}, nil
+	}
 
-		log.Println("[MonthlyAdjustment] Scheduler started. Checking every hour for pending adjustments.")
+	entries, err := dbsqlite.ApplyMonthlyAdjustmentToUsers(ctx, db, yearMonth, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := recordBalanceAudit(ctx, entry.UserID, entry.BalanceAfter-entry.BalanceBefore, db); err != nil {
+			return nil, err
+		}
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				log.Println("[MonthlyAdjustment] Scheduler stopped.")
-				return
-			case <-ticker.C:
-				if err := ProcessPendingAdjustments(); err != nil {
-					log.Printf("[MonthlyAdjustment] Error during periodic check: %v", err)
-				}
-			}
+	log.Printf("[MonthlyAdjustment] Manually applied %s to %d user(s).", yearMonth, len(entries))
+	return entries, nil
+}
+
+// RollbackMonthlyAdjustment reverses the balance changes recorded for yearMonth ("YYYY-MM") and removes
+// the month from the log. Returns the reversed entries.
+func RollbackMonthlyAdjustment(ctx context.Context, yearMonth string) ([]model.AdjustmentEntry, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := time.Parse("2006-01", yearMonth); err != nil {
+		return nil, fmt.Errorf("%w: %q is not in YYYY-MM format", ErrInvalidYearMonth, yearMonth)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	entries, err := dbsqlite.RollbackMonthlyAdjustment(ctx, db, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := recordBalanceAudit(ctx, entry.UserID, entry.BalanceBefore-entry.BalanceAfter, db); err != nil {
+			return nil, err
 		}
-	}()
+	}
+
+	log.Printf("[MonthlyAdjustment] Rolled back %s for %d user(s).", yearMonth, len(entries))
+	if entries == nil {
+		return []model.AdjustmentEntry{}, nil
+	}
+	return entries, nil
 }
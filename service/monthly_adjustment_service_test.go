@@ -2,6 +2,7 @@ package service
 
 import (
 	"testing"
+	"time"
 )
 
 func TestMonthsBetween_EmptyFrom(t *testing.T) {
@@ -169,3 +170,33 @@ func TestCurrentYearMonth_Format(t *testing.T) {
 		t.Errorf("expected dash at position 4, got %q", string(ym[4]))
 	}
 }
+
+func TestDueYearMonth(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Fatalf("could not load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		payDay int
+		want   string
+	}{
+		{"first_day_default", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), 1, "2025-07"},
+		{"before_pay_day", time.Date(2025, 7, 4, 23, 59, 0, 0, time.UTC), 5, "2025-06"},
+		{"on_pay_day", time.Date(2025, 7, 5, 0, 0, 0, 0, time.UTC), 5, "2025-07"},
+		{"before_pay_day_in_january", time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), 20, "2025-12"},
+		{"pay_day_clamped_to_february_end", time.Date(2026, 2, 28, 8, 0, 0, 0, time.UTC), 31, "2026-02"},
+		{"pay_day_clamped_leap_year", time.Date(2028, 2, 28, 8, 0, 0, 0, time.UTC), 30, "2028-01"},
+		{"local_timezone_still_previous_day", time.Date(2025, 8, 1, 1, 0, 0, 0, time.UTC).In(saoPaulo), 1, "2025-07"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := dueYearMonth(tc.now, tc.payDay); got != tc.want {
+				t.Errorf("dueYearMonth(%v, %d) = %q, want %q", tc.now, tc.payDay, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"sync"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/notify"
+	"natan/fingo/utils"
+)
+
+// deadlineReminderDays is how many days before a goal deadline its members start being reminded.
+const deadlineReminderDays = 7
+
+// deliveryBatchSize caps how many outbox entries are delivered through each channel in a single run.
+const deliveryBatchSize = 100
+
+// deliveryMaxAttempts is how many times a notification is tried through a channel before giving up on it.
+const deliveryMaxAttempts = 8
+
+// deliveryRetryDelay is the wait after the first failed attempt through a channel; it doubles with every
+// further failure.
+const deliveryRetryDelay = time.Minute
+
+// ErrInvalidNotificationUpdate is returned when a read-state update does not say whether to mark read or unread.
+var ErrInvalidNotificationUpdate = model.NewError(model.KindValidation, "read must be provided")
+
+var (
+	sendersMu           sync.RWMutex
+	notificationSenders []notify.Sender
+)
+
+// notificationSubjects maps notification kinds to the subject used by delivery channels.
+var notificationSubjects = map[string]string{
+	model.NotificationGoalDeadline:    "Goal deadline approaching",
+	model.NotificationBudgetExceeded:  "Monthly budget exceeded",
+	model.NotificationNegativeBalance: "Negative balance",
+}
+
+// RegisterNotificationSender adds a delivery channel used for every notification in the outbox.
+func RegisterNotificationSender(sender notify.Sender) {
+	sendersMu.Lock()
+	defer sendersMu.Unlock()
+	notificationSenders = append(notificationSenders, sender)
+}
+
+// registeredSenders returns a snapshot of the registered delivery channels.
+func registeredSenders() []notify.Sender {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	return append([]notify.Sender(nil), notificationSenders...)
+}
+
+// GetNotificationsByUserID returns the notifications of the user with the given ID, newest first.
+func GetNotificationsByUserID(ctx context.Context, userID int64, unreadOnly bool) ([]model.Notification, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetNotificationsByUserID(ctx, userID, unreadOnly, db)
+}
+
+// SetNotificationsRead marks notifications of the user as read or unread and returns the number of affected rows.
+func SetNotificationsRead(ctx context.Context, userID int64, update *model.NotificationReadUpdate) (int64, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	if update == nil || update.Read == nil {
+		return 0, ErrInvalidNotificationUpdate
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return 0, err
+	}
+
+	return dbsqlite.SetNotificationsRead(ctx, userID, update.IDs, *update.Read, db)
+}
+
+// GetNotificationChannels returns the addresses notifications of the user with the given ID are delivered to.
+func GetNotificationChannels(ctx context.Context, userID int64) (*model.NotificationChannels, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetNotificationChannels(ctx, userID, db)
+}
+
+// UpdateNotificationChannels applies a partial update to the addresses notifications of a user are delivered
+// to. The e-mail must be a bare address and the webhook an http or https URL; either may be emptied.
+func UpdateNotificationChannels(ctx context.Context, userID int64, update *model.NotificationChannelsUpdate) (*model.NotificationChannels, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if update == nil {
+		return nil, model.NewError(model.KindValidation, "update data cannot be nil")
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	channels, err := dbsqlite.GetNotificationChannels(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs fieldErrors
+	if update.Email != nil {
+		channels.Email = *update.Email
+		if addr, err := mail.ParseAddress(channels.Email); channels.Email != "" && (err != nil || addr.Address != channels.Email) {
+			errs.add("email", "must be an e-mail address")
+		}
+	}
+	if update.WebhookURL != nil {
+		channels.WebhookURL = *update.WebhookURL
+		if u, err := url.Parse(channels.WebhookURL); channels.WebhookURL != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+			errs.add("webhook_url", "must be an http or https URL")
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.UpsertNotificationChannels(ctx, *channels, db)
+}
+
+// parseDeadline accepts goal deadlines written either as a date or as an RFC 3339 timestamp.
+func parseDeadline(deadline string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", deadline); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, deadline); err == nil {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+	}
+	return time.Time{}, false
+}
+
+// formatMoney renders a Money value in currency units with two decimals.
+func formatMoney(m utils.Money) string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// EvaluateNotificationRules checks goal deadlines, monthly budgets and balances as of now and stores
+// a notification for every rule that fires. Each rule fires at most once per goal deadline or month.
+// Returns the number of notifications created.
+func EvaluateNotificationRules(ctx context.Context, now time.Time) (int, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var pending []model.Notification
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yearMonth := now.Format("2006-01")
+
+	goals, err := dbsqlite.GetAllGoals(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, goal := range goals {
+		deadline, ok := parseDeadline(goal.Deadline)
+		if !ok {
+			continue
+		}
+		daysLeft := int(deadline.Sub(today).Hours() / 24)
+		if daysLeft < 0 || daysLeft > deadlineReminderDays {
+			continue
+		}
+
+		members := []int64{goal.UserID}
+		participants, err := dbsqlite.GetGoalParticipants(ctx, goal.ID, db)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range participants {
+			if p.UserID != goal.UserID {
+				members = append(members, p.UserID)
+			}
+		}
+
+		for _, userID := range members {
+			pending = append(pending, model.Notification{
+				UserID:   userID,
+				Kind:     model.NotificationGoalDeadline,
+				Message:  fmt.Sprintf("Goal %q is due on %s (%d day(s) left).", goal.Name, goal.Deadline, daysLeft),
+				DedupKey: fmt.Sprintf("%s:%d:%d:%s", model.NotificationGoalDeadline, goal.ID, userID, goal.Deadline),
+			})
+		}
+	}
+
+	users, err := dbsqlite.GetAllUsers(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	// created_at is stored in UTC, so the start of the month in the clock's timezone is converted to UTC
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).UTC().Format("2006-01-02 15:04:05")
+	spent, err := dbsqlite.GetDebtTotalsSince(ctx, monthStart, db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, user := range users {
+		if user.MonthlyOutputs > 0 && spent[user.ID] > user.MonthlyOutputs {
+			pending = append(pending, model.Notification{
+				UserID:   user.ID,
+				Kind:     model.NotificationBudgetExceeded,
+				Message:  fmt.Sprintf("You spent %s in %s, above your monthly budget of %s.", formatMoney(spent[user.ID]), yearMonth, formatMoney(user.MonthlyOutputs)),
+				DedupKey: fmt.Sprintf("%s:%d:%s", model.NotificationBudgetExceeded, user.ID, yearMonth),
+			})
+		}
+
+		if user.CurrentAmount < 0 {
+			pending = append(pending, model.Notification{
+				UserID:   user.ID,
+				Kind:     model.NotificationNegativeBalance,
+				Message:  fmt.Sprintf("Your balance is negative: %s.", formatMoney(user.CurrentAmount)),
+				DedupKey: fmt.Sprintf("%s:%d:%s", model.NotificationNegativeBalance, user.ID, yearMonth),
+			})
+		}
+	}
+
+	created := 0
+	for _, n := range pending {
+		ok, err := dbsqlite.CreateNotification(ctx, n, db)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// DeliverPendingNotifications sends undelivered notifications through every registered channel, each to the
+// address its user set for the channel. Delivery is tracked per channel: a notification a channel accepted,
+// or whose user has no address on it, is never sent through it again, and one it rejected is retried after
+// a delay that doubles with every failure, until deliveryMaxAttempts is reached and the channel gives up on
+// it. A notification is marked as delivered once every channel is done with it. When no channel is
+// registered the outbox is settled without sending anything, so a channel added later does not send the
+// whole backlog. Returns the number of notifications that left the outbox.
+func DeliverPendingNotifications(ctx context.Context) (int, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	senders := registeredSenders()
+	if len(senders) == 0 {
+		settled, err := dbsqlite.SettleNotifications(ctx, currentTime().UTC().Format(dbsqlite.TimestampLayout), db)
+		return int(settled), err
+	}
+
+	channels := make([]string, len(senders))
+	for i, sender := range senders {
+		channels[i] = sender.Name()
+	}
+
+	now := currentTime().UTC()
+	nowStr := now.Format(dbsqlite.TimestampLayout)
+	recipients := make(map[int64]notify.Message)
+
+	delivered := 0
+	for _, sender := range senders {
+		pending, err := dbsqlite.GetPendingDeliveries(ctx, sender.Name(), nowStr, deliveryMaxAttempts, deliveryBatchSize, db)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, p := range pending {
+			n := p.Notification
+			msg, ok := recipients[n.UserID]
+			if !ok {
+				msg.UserID = n.UserID
+				if user, err := dbsqlite.GetUserByID(ctx, n.UserID, db); err == nil {
+					msg.UserName = user.UserName
+				}
+				addresses, err := dbsqlite.GetNotificationChannels(ctx, n.UserID, db)
+				if err != nil {
+					return delivered, err
+				}
+				msg.Email, msg.WebhookURL = addresses.Email, addresses.WebhookURL
+				recipients[n.UserID] = msg
+			}
+			msg.NotificationID = n.ID
+			msg.Kind = n.Kind
+			msg.Subject = notificationSubjects[n.Kind]
+			msg.Body = n.Message
+
+			if err := sender.Send(ctx, msg); err != nil && !errors.Is(err, notify.ErrNoAddress) {
+				attempts := p.Attempts + 1
+				log.Printf("[Notifications] Could not deliver notification %d via %s (attempt %d of %d): %v", n.ID, sender.Name(), attempts, deliveryMaxAttempts, err)
+				next := now.Add(deliveryRetryDelay << (attempts - 1)).Format(dbsqlite.TimestampLayout)
+				if err := dbsqlite.RecordDeliveryFailure(ctx, n.ID, sender.Name(), err.Error(), next, db); err != nil {
+					return delivered, err
+				}
+				if attempts < deliveryMaxAttempts {
+					continue
+				}
+			} else if err := dbsqlite.RecordDeliverySuccess(ctx, n.ID, sender.Name(), nowStr, db); err != nil {
+				return delivered, err
+			}
+
+			done, err := dbsqlite.MarkNotificationDelivered(ctx, n.ID, channels, deliveryMaxAttempts, nowStr, db)
+			if err != nil {
+				return delivered, err
+			}
+			if done {
+				delivered++
+			}
+		}
+	}
+
+	return delivered, nil
+}
+
+// ProcessNotifications evaluates the notification rules and delivers the outbox. It stops when ctx is canceled.
+func ProcessNotifications(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	created, err := EvaluateNotificationRules(ctx, currentTime())
+	if err != nil {
+		return fmt.Errorf("could not evaluate notification rules: %w", err)
+	}
+	if created > 0 {
+		log.Printf("[Notifications] Created %d notification(s).", created)
+	}
+
+	delivered, err := DeliverPendingNotifications(ctx)
+	if err != nil {
+		return fmt.Errorf("could not deliver notifications: %w", err)
+	}
+	if delivered > 0 {
+		log.Printf("[Notifications] Delivered %d notification(s).", delivered)
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+	"natan/fingo/notify"
+	"natan/fingo/utils"
+)
+
+// recordingSender is a notify.Sender stand-in that records messages and can be told to fail, or to deliver
+// only to users with an e-mail address.
+type recordingSender struct {
+	name      string
+	mu        sync.Mutex
+	fail      bool
+	needsMail bool
+	sent      []notify.Message
+}
+
+func (s *recordingSender) Name() string {
+	if s.name == "" {
+		return "recording"
+	}
+	return s.name
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg notify.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("channel down")
+	}
+	if s.needsMail && msg.Email == "" {
+		return notify.ErrNoAddress
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestEvaluateNotificationRules(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "notified-user", CurrentAmount: utils.Money(-500)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	partner, err := CreateUser(ctxTest, model.User{UserName: "notified-partner"})
+	if err != nil {
+		t.Fatalf("failed to create partner: %v", err)
+	}
+
+	soon, err := CreateGoal(ctxTest, model.Goal{Name: "Due soon", UserID: user.ID, Deadline: "2026-03-14"})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := CreateGoal(ctxTest, model.Goal{Name: "Far away", UserID: user.ID, Deadline: "2026-09-01"}); err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := SetGoalParticipant(ctxTest, model.GoalParticipant{GoalID: soon.ID, UserID: partner.ID}); err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+
+	if _, err := EvaluateNotificationRules(ctxTest, now); err != nil {
+		t.Fatalf("EvaluateNotificationRules() unexpected error: %v", err)
+	}
+
+	kinds := func(userID int64) map[string]int {
+		list, err := GetNotificationsByUserID(ctxTest, userID, false)
+		if err != nil {
+			t.Fatalf("GetNotificationsByUserID() unexpected error: %v", err)
+		}
+		got := map[string]int{}
+		for _, n := range list {
+			got[n.Kind]++
+		}
+		return got
+	}
+
+	got := kinds(user.ID)
+	if got[model.NotificationGoalDeadline] != 1 {
+		t.Errorf("expected 1 deadline reminder for owner, got %d", got[model.NotificationGoalDeadline])
+	}
+	if got[model.NotificationNegativeBalance] != 1 {
+		t.Errorf("expected 1 negative balance notification, got %d", got[model.NotificationNegativeBalance])
+	}
+	if kinds(partner.ID)[model.NotificationGoalDeadline] != 1 {
+		t.Errorf("expected shared goal participant to be reminded")
+	}
+
+	// Evaluating again must not duplicate notifications
+	if _, err := EvaluateNotificationRules(ctxTest, now.Add(time.Hour)); err != nil {
+		t.Fatalf("EvaluateNotificationRules() second run unexpected error: %v", err)
+	}
+	if again := kinds(user.ID); again[model.NotificationGoalDeadline] != 1 || again[model.NotificationNegativeBalance] != 1 {
+		t.Errorf("rules fired twice: %v", again)
+	}
+
+	read := true
+	rows, err := SetNotificationsRead(ctxTest, user.ID, &model.NotificationReadUpdate{Read: &read})
+	if err != nil {
+		t.Fatalf("SetNotificationsRead() unexpected error: %v", err)
+	}
+	if rows != 2 {
+		t.Errorf("expected 2 notifications marked read, got %d", rows)
+	}
+	unread, err := GetNotificationsByUserID(ctxTest, user.ID, true)
+	if err != nil {
+		t.Fatalf("GetNotificationsByUserID() unexpected error: %v", err)
+	}
+	if len(unread) != 0 {
+		t.Errorf("expected no unread notifications, got %d", len(unread))
+	}
+
+	if _, err := SetNotificationsRead(ctxTest, user.ID, &model.NotificationReadUpdate{}); !errors.Is(err, ErrInvalidNotificationUpdate) {
+		t.Errorf("SetNotificationsRead() without read flag error = %v, want ErrInvalidNotificationUpdate", err)
+	}
+}
+
+func TestDeliverPendingNotifications(t *testing.T) {
+	mail := &recordingSender{name: "mail"}
+	hook := &recordingSender{name: "hook", fail: true}
+	RegisterNotificationSender(mail)
+	RegisterNotificationSender(hook)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		notificationSenders = nil
+		sendersMu.Unlock()
+	})
+	fake := useFakeClock(t, time.Now().UTC())
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "delivery-user", CurrentAmount: utils.Money(-1)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := EvaluateNotificationRules(ctxTest, time.Now()); err != nil {
+		t.Fatalf("EvaluateNotificationRules() unexpected error: %v", err)
+	}
+
+	sentTo := func(s *recordingSender) int {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		count := 0
+		for _, msg := range s.sent {
+			if msg.UserID == user.ID && msg.UserName == "delivery-user" && msg.Subject != "" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The webhook failing keeps the notification in the outbox without sending the e-mail again
+	for range 2 {
+		if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+			t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+		}
+		fake.Advance(deliveryRetryDelay)
+	}
+	list, _ := GetNotificationsByUserID(ctxTest, user.ID, false)
+	if len(list) == 0 || list[0].DeliveredAt != "" {
+		t.Fatalf("failed delivery must keep the notification in the outbox: %+v", list)
+	}
+	if got := sentTo(mail); got != 1 {
+		t.Errorf("e-mails sent = %d, want 1", got)
+	}
+
+	hook.mu.Lock()
+	hook.fail = false
+	hook.mu.Unlock()
+
+	// The second failure delays the next attempt by twice the retry delay
+	if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+		t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+	}
+	if got := sentTo(hook); got != 0 {
+		t.Fatalf("webhook retried before its delay: %d message(s)", got)
+	}
+	fake.Advance(deliveryRetryDelay)
+	if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+		t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+	}
+	list, _ = GetNotificationsByUserID(ctxTest, user.ID, false)
+	if list[0].DeliveredAt == "" {
+		t.Fatalf("expected notification to be marked as delivered")
+	}
+	if mails, hooks := sentTo(mail), sentTo(hook); mails != 1 || hooks != 1 {
+		t.Errorf("messages sent = %d e-mail(s) and %d webhook call(s), want one of each", mails, hooks)
+	}
+}
+
+func TestDeliverPendingNotifications_GivesUpOnChannel(t *testing.T) {
+	hook := &recordingSender{name: "hook", fail: true}
+	RegisterNotificationSender(hook)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		notificationSenders = nil
+		sendersMu.Unlock()
+	})
+	fake := useFakeClock(t, time.Now().UTC())
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "exhausted-user", CurrentAmount: utils.Money(-1)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := EvaluateNotificationRules(ctxTest, time.Now()); err != nil {
+		t.Fatalf("EvaluateNotificationRules() unexpected error: %v", err)
+	}
+
+	// Once the only channel used up its attempts the notification leaves the outbox
+	for attempt := 1; attempt <= deliveryMaxAttempts; attempt++ {
+		list, _ := GetNotificationsByUserID(ctxTest, user.ID, false)
+		if len(list) == 0 || list[0].DeliveredAt != "" {
+			t.Fatalf("notifications before attempt %d = %+v, want them in the outbox", attempt, list)
+		}
+		if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+			t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+		}
+		fake.Advance(deliveryRetryDelay << attempt)
+	}
+	list, _ := GetNotificationsByUserID(ctxTest, user.ID, false)
+	if len(list) == 0 || list[0].DeliveredAt == "" {
+		t.Errorf("notifications after the last attempt = %+v, want them out of the outbox", list)
+	}
+}
+
+func TestDeliverPendingNotifications_NoChannel(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "unsent-user", CurrentAmount: utils.Money(-1)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := EvaluateNotificationRules(ctxTest, time.Now()); err != nil {
+		t.Fatalf("EvaluateNotificationRules() unexpected error: %v", err)
+	}
+	if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+		t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+	}
+	list, _ := GetNotificationsByUserID(ctxTest, user.ID, false)
+	if len(list) == 0 || list[0].DeliveredAt == "" {
+		t.Fatalf("notifications without a channel = %+v, want them settled", list)
+	}
+
+	// A channel registered later does not send what was settled before it existed
+	mail := &recordingSender{name: "mail"}
+	RegisterNotificationSender(mail)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		notificationSenders = nil
+		sendersMu.Unlock()
+	})
+	if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+		t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+	}
+	mail.mu.Lock()
+	defer mail.mu.Unlock()
+	for _, msg := range mail.sent {
+		if msg.UserID == user.ID {
+			t.Errorf("settled notification %d sent once a channel was added", msg.NotificationID)
+		}
+	}
+}
+
+func TestDeliverPendingNotifications_RoutesByUser(t *testing.T) {
+	mail := &recordingSender{name: "mail", needsMail: true}
+	RegisterNotificationSender(mail)
+	t.Cleanup(func() {
+		sendersMu.Lock()
+		notificationSenders = nil
+		sendersMu.Unlock()
+	})
+
+	reachable, err := CreateUser(ctxTest, model.User{UserName: "routed-reachable", CurrentAmount: utils.Money(-1)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	unreachable, err := CreateUser(ctxTest, model.User{UserName: "routed-unreachable", CurrentAmount: utils.Money(-1)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := UpdateNotificationChannels(asUser(reachable.ID), reachable.ID, &model.NotificationChannelsUpdate{Email: strPtr("reachable@example.com")}); err != nil {
+		t.Fatalf("UpdateNotificationChannels() unexpected error: %v", err)
+	}
+	if _, err := EvaluateNotificationRules(ctxTest, time.Now()); err != nil {
+		t.Fatalf("EvaluateNotificationRules() unexpected error: %v", err)
+	}
+	if _, err := DeliverPendingNotifications(ctxTest); err != nil {
+		t.Fatalf("DeliverPendingNotifications() unexpected error: %v", err)
+	}
+
+	// Each message goes to its own user's address, and users without one are settled without sending
+	mail.mu.Lock()
+	for _, msg := range mail.sent {
+		if msg.UserID == unreachable.ID || (msg.UserID == reachable.ID && msg.Email != "reachable@example.com") {
+			t.Errorf("message for user %d sent to %q", msg.UserID, msg.Email)
+		}
+	}
+	mail.mu.Unlock()
+	for _, user := range []*model.User{reachable, unreachable} {
+		list, _ := GetNotificationsByUserID(ctxTest, user.ID, false)
+		if len(list) == 0 || list[0].DeliveredAt == "" {
+			t.Errorf("notifications of %s = %+v, want them delivered", user.UserName, list)
+		}
+	}
+}
+
+func TestUpdateNotificationChannels(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "channels-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, err := CreateUser(ctxTest, model.User{UserName: "channels-other"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	update := &model.NotificationChannelsUpdate{Email: strPtr("user@example.com"), WebhookURL: strPtr("https://example.com/hook")}
+	if got, err := UpdateNotificationChannels(asUser(user.ID), user.ID, update); err != nil || got.Email != *update.Email || got.WebhookURL != *update.WebhookURL {
+		t.Fatalf("UpdateNotificationChannels() = %+v, %v", got, err)
+	}
+	if _, err := UpdateNotificationChannels(asUser(other.ID), user.ID, update); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UpdateNotificationChannels() of someone else error = %v, want ErrForbidden", err)
+	}
+
+	invalid := &model.NotificationChannelsUpdate{Email: strPtr("User <user@example.com>"), WebhookURL: strPtr("file:///etc/passwd")}
+	var domainErr *model.Error
+	if _, err := UpdateNotificationChannels(asUser(user.ID), user.ID, invalid); !errors.As(err, &domainErr) || len(domainErr.Fields) != 2 {
+		t.Errorf("UpdateNotificationChannels() with invalid addresses error = %v, want two field errors", err)
+	}
+
+	// Clearing the webhook keeps the e-mail address
+	got, err := UpdateNotificationChannels(asUser(user.ID), user.ID, &model.NotificationChannelsUpdate{WebhookURL: strPtr("")})
+	if err != nil || got.Email != "user@example.com" || got.WebhookURL != "" {
+		t.Errorf("UpdateNotificationChannels() clearing the webhook = %+v, %v", got, err)
+	}
+	if got, err := GetNotificationChannels(asUser(user.ID), user.ID); err != nil || got.Email != "user@example.com" {
+		t.Errorf("GetNotificationChannels() = %+v, %v", got, err)
+	}
+}
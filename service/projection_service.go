@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+const (
+	// projectionLookbackDays is how many days of past spending the average daily spend is computed from.
+	projectionLookbackDays = 90
+	// defaultProjectionMonths is how many months are projected when none is requested.
+	defaultProjectionMonths = 3
+	// maxProjectionMonths caps how far ahead a projection may go.
+	maxProjectionMonths = 24
+)
+
+// spendStats summarises daily spending: the mean and the band one standard deviation around it.
+type spendStats struct {
+	days            int
+	mean, low, high float64
+}
+
+// dailySpendStats computes the spending statistics of the days days starting at start, where spend maps
+// "YYYY-MM-DD" days to their spending and days missing from it spent nothing.
+func dailySpendStats(spend map[string]utils.Money, start time.Time, days int) spendStats {
+	if days <= 0 {
+		return spendStats{}
+	}
+
+	var total float64
+	for _, amount := range spend {
+		total += float64(amount)
+	}
+	mean := total / float64(days)
+
+	var squares float64
+	for i := 0; i < days; i++ {
+		d := float64(spend[start.AddDate(0, 0, i).Format("2006-01-02")]) - mean
+		squares += d * d
+	}
+	sd := math.Sqrt(squares / float64(days))
+
+	return spendStats{days: days, mean: mean, low: math.Max(0, mean-sd), high: mean + sd}
+}
+
+// adjustment is a monthly adjustment expected at a point in time.
+type adjustment struct {
+	at  time.Time
+	net utils.Money
+}
+
+// upcomingAdjustments lists the monthly adjustments expected for the user from now until the given time,
+// on the pay day of each month in the timezone of now. Months that are due but not processed yet are
+// expected right away.
+func upcomingAdjustments(settings *model.AdjustmentSettings, user *model.User, now, until time.Time) []adjustment {
+	if !settings.Enabled {
+		return nil
+	}
+
+	// Users without a recorded period get the current due month as baseline, like the scheduler does
+	last := dueYearMonth(now, settings.PayDay)
+	if settings.LastPeriod != "" && settings.LastPeriod < last {
+		last = settings.LastPeriod
+	}
+	month, err := time.ParseInLocation("2006-01", last, now.Location())
+	if err != nil {
+		return nil
+	}
+
+	var list []adjustment
+	for month = month.AddDate(0, 1, 0); ; month = month.AddDate(0, 1, 0) {
+		day := settings.PayDay
+		if last := daysIn(month.Year(), month.Month()); day > last {
+			day = last
+		}
+		at := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, now.Location())
+		if at.Before(now) {
+			at = now
+		}
+		if !at.Before(until) {
+			return list
+		}
+		list = append(list, adjustment{at: at, net: user.MonthlyInputs - user.MonthlyOutputs})
+	}
+}
+
+// spendBetween returns the spending expected at the given daily rate between two times.
+func spendBetween(rate float64, from, to time.Time) utils.Money {
+	return utils.Money(math.Round(rate * to.Sub(from).Hours() / 24))
+}
+
+// GetProjection forecasts the balance of a user at the end of the current month and the following ones,
+// months in total (3 when zero). The forecast applies the expected monthly adjustments on the user's pay
+// day and subtracts the average daily spend of the last 90 days; the optimistic and pessimistic scenarios
+// use the average minus and plus one standard deviation. Warnings are raised when a scenario goes negative
+// before the next monthly adjustment.
+func GetProjection(ctx context.Context, userID int64, months int) (*model.Projection, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if months == 0 {
+		months = defaultProjectionMonths
+	}
+	if months < 1 || months > maxProjectionMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", ErrInvalidReport, maxProjectionMonths)
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	user, err := dbsqlite.GetUserByID(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := dbsqlite.GetAdjustmentSettings(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	now := currentTime()
+	if loc, err := loadLocation(settings.Timezone); err == nil {
+		now = now.In(loc)
+	}
+
+	// Spending is looked up per UTC day, up to and including today
+	windowEnd := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day()+1, 0, 0, 0, 0, time.UTC)
+	windowStart := windowEnd.AddDate(0, 0, -projectionLookbackDays)
+	spend, first, err := dbsqlite.GetDailySpend(ctx, userID, windowStart.Format(dbsqlite.TimestampLayout), windowEnd.Format(dbsqlite.TimestampLayout), db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users with a shorter history are averaged over the days since their first transaction
+	days := 0
+	if first != "" {
+		if t, err := time.Parse("2006-01-02", first); err == nil && t.After(windowStart) {
+			windowStart = t
+		}
+		days = int(windowEnd.Sub(windowStart).Hours() / 24)
+	}
+	stats := dailySpendStats(spend, windowStart, days)
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	horizon := monthStart.AddDate(0, months, 0)
+	adjustments := upcomingAdjustments(settings, user, now, horizon)
+
+	p := &model.Projection{
+		UserID:                userID,
+		AsOf:                  now.Format(time.RFC3339),
+		CurrentBalance:        user.CurrentAmount,
+		LookbackDays:          stats.days,
+		AverageDailySpend:     utils.Money(math.Round(stats.mean)),
+		OptimisticDailySpend:  utils.Money(math.Round(stats.low)),
+		PessimisticDailySpend: utils.Money(math.Round(stats.high)),
+		Months:                make([]model.ProjectionMonth, 0, months),
+		Warnings:              []model.ProjectionWarning{},
+	}
+
+	var applied utils.Money
+	next := 0
+	for i := 0; i < months; i++ {
+		start := monthStart.AddDate(0, i, 0)
+		end := start.AddDate(0, 1, 0)
+
+		m := model.ProjectionMonth{
+			YearMonth: start.Format("2006-01"),
+			EndDate:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		}
+		for ; next < len(adjustments) && adjustments[next].at.Before(end); next++ {
+			m.Adjustments += adjustments[next].net
+		}
+		applied += m.Adjustments
+
+		base := user.CurrentAmount + applied
+		m.Expected = base - spendBetween(stats.mean, now, end)
+		m.Optimistic = base - spendBetween(stats.low, now, end)
+		m.Pessimistic = base - spendBetween(stats.high, now, end)
+		p.Months = append(p.Months, m)
+	}
+
+	// Adjustments already due are applied right away; the warning looks at the balance until the next one
+	balance := user.CurrentAmount
+	limit := horizon
+	for _, a := range adjustments {
+		if a.at.After(now) {
+			limit = a.at
+			p.NextAdjustment = a.at.Format("2006-01-02")
+			break
+		}
+		balance += a.net
+	}
+
+	for _, scenario := range []struct {
+		name string
+		rate float64
+	}{{model.ScenarioExpected, stats.mean}, {model.ScenarioPessimistic, stats.high}} {
+		negativeOn := now
+		if balance >= 0 {
+			if scenario.rate <= 0 {
+				continue
+			}
+			// The balance is negative once it dropped by more than it holds
+			negativeOn = now.Add(time.Duration((float64(balance) + 1) / scenario.rate * float64(24*time.Hour)))
+		}
+		if !negativeOn.Before(limit) {
+			continue
+		}
+
+		w := model.ProjectionWarning{
+			Scenario:       scenario.name,
+			NegativeOn:     negativeOn.Format("2006-01-02"),
+			NextAdjustment: p.NextAdjustment,
+		}
+		if p.NextAdjustment != "" {
+			w.Message = fmt.Sprintf("In the %s scenario the balance goes negative on %s, before the next monthly adjustment on %s.", w.Scenario, w.NegativeOn, w.NextAdjustment)
+		} else {
+			w.Message = fmt.Sprintf("In the %s scenario the balance goes negative on %s, and no monthly adjustment is expected before %s.", w.Scenario, w.NegativeOn, p.Months[len(p.Months)-1].EndDate)
+		}
+		p.Warnings = append(p.Warnings, w)
+	}
+
+	return p, nil
+}
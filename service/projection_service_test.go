@@ -0,0 +1,141 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestDailySpendStats(t *testing.T) {
+	start := time.Date(2032, 1, 1, 0, 0, 0, 0, time.UTC)
+	spend := map[string]utils.Money{"2032-01-01": 100, "2032-01-03": 300}
+
+	got := dailySpendStats(spend, start, 4)
+	if got.days != 4 || got.mean != 100 || got.low != 0 || math.Abs(got.high-(100+math.Sqrt(15000))) > 1e-9 {
+		t.Errorf("dailySpendStats() = %+v", got)
+	}
+
+	if got := dailySpendStats(nil, start, 0); got != (spendStats{}) {
+		t.Errorf("dailySpendStats() without history = %+v, want zero", got)
+	}
+}
+
+func TestUpcomingAdjustments(t *testing.T) {
+	user := &model.User{MonthlyInputs: 3000, MonthlyOutputs: 1000}
+	now := time.Date(2032, 4, 3, 12, 0, 0, 0, time.UTC)
+	until := time.Date(2032, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		settings model.AdjustmentSettings
+		want     []string
+	}{
+		{"up_to_date", model.AdjustmentSettings{Enabled: true, PayDay: 5, LastPeriod: "2032-03"}, []string{"2032-04-05", "2032-05-05", "2032-06-05"}},
+		{"never_processed_uses_due_month", model.AdjustmentSettings{Enabled: true, PayDay: 5}, []string{"2032-04-05", "2032-05-05", "2032-06-05"}},
+		{"backlog_is_due_now", model.AdjustmentSettings{Enabled: true, PayDay: 5, LastPeriod: "2032-01"}, []string{"2032-04-03", "2032-04-03", "2032-04-05", "2032-05-05", "2032-06-05"}},
+		{"pay_day_clamped", model.AdjustmentSettings{Enabled: true, PayDay: 31, LastPeriod: "2032-03"}, []string{"2032-04-30", "2032-05-31", "2032-06-30"}},
+		{"disabled", model.AdjustmentSettings{Enabled: false, PayDay: 1, LastPeriod: "2032-03"}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := upcomingAdjustments(&tc.settings, user, now, until)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d adjustments, want %d: %+v", len(got), len(tc.want), got)
+			}
+			for i, a := range got {
+				if a.at.Format("2006-01-02") != tc.want[i] || a.net != 2000 {
+					t.Errorf("adjustment %d = %s %d, want %s 2000", i, a.at.Format("2006-01-02"), a.net, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetProjection(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "projection-user", CurrentAmount: 1000, MonthlyInputs: 3000, MonthlyOutputs: 1000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	payDay, utc, enabled := 1, "UTC", true
+	if _, err := UpdateAdjustmentSettings(ctxTest, user.ID, &model.AdjustmentSettingsUpdate{PayDay: &payDay, Timezone: &utc, Enabled: &enabled}); err != nil {
+		t.Fatalf("failed to update settings: %v", err)
+	}
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	if err := dbsqlite.SetAdjustmentLastPeriod(ctxTest, db, user.ID, "2032-04"); err != nil {
+		t.Fatalf("could not set last period: %v", err)
+	}
+
+	useFakeClock(t, time.Date(2032, 4, 10, 12, 0, 0, 0, time.UTC))
+
+	// Ten days of steady spending, plus an income that does not count as spending
+	for day := 1; day <= 10; day++ {
+		insertTransactionAt(t, user.ID, 100, true, fmt.Sprintf("2032-04-%02d 08:00:00", day))
+	}
+	insertTransactionAt(t, user.ID, 5000, false, "2032-04-02 08:00:00")
+
+	p, err := GetProjection(ctxTest, user.ID, 2)
+	if err != nil {
+		t.Fatalf("GetProjection() unexpected error: %v", err)
+	}
+
+	if p.LookbackDays != 10 || p.AverageDailySpend != 100 || p.OptimisticDailySpend != 100 || p.PessimisticDailySpend != 100 {
+		t.Errorf("unexpected spending figures: %+v", p)
+	}
+	if p.NextAdjustment != "2032-05-01" {
+		t.Errorf("next adjustment = %q, want 2032-05-01", p.NextAdjustment)
+	}
+
+	// April: 20.5 days of spending, May: the adjustment plus 51.5 days of spending
+	want := []model.ProjectionMonth{
+		{YearMonth: "2032-04", EndDate: "2032-04-30", Expected: -1050, Optimistic: -1050, Pessimistic: -1050},
+		{YearMonth: "2032-05", EndDate: "2032-05-31", Adjustments: 2000, Expected: -2150, Optimistic: -2150, Pessimistic: -2150},
+	}
+	if len(p.Months) != len(want) {
+		t.Fatalf("expected %d months, got %d", len(want), len(p.Months))
+	}
+	for i := range want {
+		if p.Months[i] != want[i] {
+			t.Errorf("month %d = %+v, want %+v", i, p.Months[i], want[i])
+		}
+	}
+
+	if len(p.Warnings) != 2 || p.Warnings[0].Scenario != model.ScenarioExpected || p.Warnings[0].NegativeOn != "2032-04-20" || p.Warnings[1].Scenario != model.ScenarioPessimistic {
+		t.Errorf("unexpected warnings: %+v", p.Warnings)
+	}
+}
+
+func TestGetProjection_NoWarningWhenBalanceLasts(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "projection-rich", CurrentAmount: 900000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2032, 6, 15, 0, 0, 0, 0, time.UTC))
+	insertTransactionAt(t, user.ID, 1000, true, "2032-06-14 08:00:00")
+
+	p, err := GetProjection(ctxTest, user.ID, 0)
+	if err != nil {
+		t.Fatalf("GetProjection() unexpected error: %v", err)
+	}
+	if len(p.Months) != defaultProjectionMonths || len(p.Warnings) != 0 {
+		t.Errorf("got %d months and warnings %+v, want %d months and none", len(p.Months), p.Warnings, defaultProjectionMonths)
+	}
+
+	if _, err := GetProjection(ctxTest, user.ID, maxProjectionMonths+1); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetProjection() with too many months error = %v, want ErrInvalidReport", err)
+	}
+	if _, err := GetProjection(ctxTest, 999999999, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetProjection() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// ErrInvalidReport is returned when report parameters are malformed or describe too large a range.
+var ErrInvalidReport = model.NewError(model.KindValidation, "invalid report parameters")
+
+// maxReportPeriods caps how many periods a single report may return.
+const maxReportPeriods = 1000
+
+// defaultReportPeriods is how many periods, ending with the current one, a report covers when no
+// start date is given.
+var defaultReportPeriods = map[string]int{
+	model.GranularityDay:   30,
+	model.GranularityWeek:  12,
+	model.GranularityMonth: 12,
+	model.GranularityYear:  5,
+}
+
+// periodStart returns the start of the period of granularity g containing the date t.
+// Weeks start on Monday.
+func periodStart(t time.Time, g string) time.Time {
+	switch g {
+	case model.GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	case model.GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case model.GranularityYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// addPeriods moves the period start t by n periods of granularity g.
+func addPeriods(t time.Time, g string, n int) time.Time {
+	switch g {
+	case model.GranularityWeek:
+		return t.AddDate(0, 0, 7*n)
+	case model.GranularityMonth:
+		return t.AddDate(0, n, 0)
+	case model.GranularityYear:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// periodKey returns the key identifying the period starting at t, matching the keys computed in SQL.
+func periodKey(t time.Time, g string) string {
+	switch g {
+	case model.GranularityMonth:
+		return t.Format("2006-01")
+	case model.GranularityYear:
+		return t.Format("2006")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// today returns the current date of the application clock, as midnight UTC.
+func today() time.Time {
+	now := currentTime()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dateRange turns optional "YYYY-MM-DD" dates, both inclusive, into the timestamps bounding a query: from
+// inclusive and to exclusive. A missing date leaves that side of the range open.
+func dateRange(from, to string) (string, string, error) {
+	start, end := "0000-01-01 00:00:00", "9999-12-31 23:59:59"
+	var fromDate, toDate time.Time
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidReport)
+		}
+		fromDate = t
+		start = t.Format(dbsqlite.TimestampLayout)
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidReport)
+		}
+		toDate = t
+		end = t.AddDate(0, 0, 1).Format(dbsqlite.TimestampLayout)
+	}
+	if from != "" && to != "" && fromDate.After(toDate) {
+		return "", "", fmt.Errorf("%w: from must not be after to", ErrInvalidReport)
+	}
+	return start, end, nil
+}
+
+// GetCashflowReport returns the income, expenses, adjustments, net and ending balance of a user per
+// period of the given granularity (day, week, month or year; month when empty). from and to are
+// "YYYY-MM-DD" dates, widened to whole periods; to defaults to today and from to a granularity
+// dependent number of periods before it. Periods are delimited in UTC, like the stored timestamps.
+func GetCashflowReport(ctx context.Context, userID int64, from, to, granularity string) (*model.CashflowReport, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return cashflowReport(ctx, userID, from, to, granularity)
+}
+
+// cashflowReport builds the cash flow report of a user described by GetCashflowReport.
+func cashflowReport(ctx context.Context, userID int64, from, to, granularity string) (*model.CashflowReport, error) {
+	if granularity == "" {
+		granularity = model.GranularityMonth
+	}
+	defaultPeriods, ok := defaultReportPeriods[granularity]
+	if !ok {
+		return nil, fmt.Errorf("%w: granularity must be day, week, month or year", ErrInvalidReport)
+	}
+
+	end := today()
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidReport)
+		}
+		end = t
+	}
+	end = periodStart(end, granularity)
+
+	start := addPeriods(end, granularity, -(defaultPeriods - 1))
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidReport)
+		}
+		start = periodStart(t, granularity)
+	}
+
+	if start.After(end) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidReport)
+	}
+
+	var periods []model.CashflowPeriod
+	for p := start; !p.After(end); p = addPeriods(p, granularity, 1) {
+		if len(periods) == maxReportPeriods {
+			return nil, fmt.Errorf("%w: the range spans more than %d periods", ErrInvalidReport, maxReportPeriods)
+		}
+		periods = append(periods, model.CashflowPeriod{
+			Period: periodKey(p, granularity),
+			Start:  p.Format("2006-01-02"),
+			End:    addPeriods(p, granularity, 1).AddDate(0, 0, -1).Format("2006-01-02"),
+		})
+	}
+	rangeEnd := addPeriods(end, granularity, 1)
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	user, err := dbsqlite.GetUserByID(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := dbsqlite.GetCashflowTotals(ctx, userID, granularity, start.Format(dbsqlite.TimestampLayout), rangeEnd.Format(dbsqlite.TimestampLayout), db)
+	if err != nil {
+		return nil, err
+	}
+
+	byPeriod := make(map[string]model.CashflowPeriod, len(totals))
+	for _, t := range totals {
+		byPeriod[t.Period] = t
+	}
+
+	// Balances are rebuilt backwards from the current balance, undoing everything recorded after the range
+	later, err := dbsqlite.GetBalanceChangeSince(ctx, userID, rangeEnd.Format(dbsqlite.TimestampLayout), db)
+	if err != nil {
+		return nil, err
+	}
+	balance := user.CurrentAmount - later
+
+	for i := len(periods) - 1; i >= 0; i-- {
+		p := &periods[i]
+		if t, ok := byPeriod[p.Period]; ok {
+			p.Income, p.Expenses, p.Adjustments, p.Net = t.Income, t.Expenses, t.Adjustments, t.Net
+		}
+		p.EndingBalance = balance
+		balance -= p.Net + p.Adjustments
+	}
+
+	return &model.CashflowReport{
+		UserID:      userID,
+		Granularity: granularity,
+		From:        periods[0].Start,
+		To:          periods[len(periods)-1].End,
+		Periods:     periods,
+	}, nil
+}
+
+// dashboardLargestExpenses is how many of the month's largest expenses the dashboard lists.
+const dashboardLargestExpenses = 5
+
+// percent returns part as a percentage of whole, rounded to two decimals, or nil when whole is zero.
+func percent(part, whole utils.Money) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	v := math.Round(float64(part)/float64(whole)*10000) / 100
+	return &v
+}
+
+// summarizeMonth fills the derived figures of a month summary.
+func summarizeMonth(m *model.MonthSummary, from, to time.Time) {
+	m.From = from.Format("2006-01-02")
+	m.To = to.Format("2006-01-02")
+	m.Net = m.Income - m.Expenses
+	m.SavingsRate = percent(m.Net, m.Income)
+}
+
+// GetDashboard returns the current balance of a user, the month-to-date income, expenses and savings
+// rate compared with the same point of the previous month, the month's largest expenses and the
+// progress of the goals the user owns or shares. Months follow the application timezone.
+func GetDashboard(ctx context.Context, userID int64) (*model.Dashboard, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := currentTime()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	previousStart := monthStart.AddDate(0, -1, 0)
+
+	// The previous month is compared up to the same day and time, clamped to its last day
+	previousEnd := time.Date(previousStart.Year(), previousStart.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+	if previousEnd.After(monthStart) {
+		previousEnd = monthStart
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	bounds := dbsqlite.DashboardBounds{
+		MonthStart:    monthStart.UTC().Format(dbsqlite.TimestampLayout),
+		Now:           now.UTC().Add(time.Second).Format(dbsqlite.TimestampLayout),
+		PreviousStart: previousStart.UTC().Format(dbsqlite.TimestampLayout),
+		PreviousEnd:   previousEnd.UTC().Format(dbsqlite.TimestampLayout),
+	}
+
+	d, err := dbsqlite.GetDashboard(ctx, userID, bounds, dashboardLargestExpenses, db)
+	if err != nil {
+		return nil, err
+	}
+
+	d.AsOf = now.Format(time.RFC3339)
+	summarizeMonth(&d.MonthToDate, monthStart, now)
+	summarizeMonth(&d.PreviousMonth, previousStart, previousEnd.Add(-time.Second))
+
+	d.Comparison = model.MonthComparison{
+		IncomeChange:          d.MonthToDate.Income - d.PreviousMonth.Income,
+		ExpensesChange:        d.MonthToDate.Expenses - d.PreviousMonth.Expenses,
+		NetChange:             d.MonthToDate.Net - d.PreviousMonth.Net,
+		IncomeChangePercent:   percent(d.MonthToDate.Income-d.PreviousMonth.Income, d.PreviousMonth.Income),
+		ExpensesChangePercent: percent(d.MonthToDate.Expenses-d.PreviousMonth.Expenses, d.PreviousMonth.Expenses),
+	}
+
+	today := now.Format("2006-01-02")
+	goals := &d.Goals
+	for i := range goals.Goals {
+		g := &goals.Goals[i]
+		g.Progress = percent(g.Contributed, g.Price)
+		goals.TotalPrice += g.Price
+		goals.TotalContributed += g.Contributed
+
+		if deadline, ok := parseDeadline(g.Deadline); ok && deadline.Format("2006-01-02") < today && g.Contributed < g.Price {
+			goals.Overdue++
+		}
+	}
+	goals.Count = len(goals.Goals)
+	goals.Progress = percent(goals.TotalContributed, goals.TotalPrice)
+
+	return d, nil
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// insertTransactionAt inserts a transaction with an explicit creation timestamp, leaving the balance untouched.
+func insertTransactionAt(t *testing.T, userID int64, amount utils.Money, isDebt bool, createdAt string) {
+	t.Helper()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+
+	const stmt = `INSERT INTO transactions(description, amount, is_debt, user_id, created_at) VALUES ('fixture', ?, ?, ?, ?)`
+	if _, err := db.Exec(stmt, amount, isDebt, userID, createdAt); err != nil {
+		t.Fatalf("could not insert fixture transaction: %v", err)
+	}
+}
+
+func TestGetCashflowReport_EndingBalances(t *testing.T) {
+	// The balance already includes every fixture transaction: 1000 + 500 - 200 - 50 + 300
+	user, err := CreateUser(ctxTest, model.User{UserName: "cashflow-user", CurrentAmount: 1550})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	insertTransactionAt(t, user.ID, 500, false, "2031-01-10 10:00:00")
+	insertTransactionAt(t, user.ID, 200, true, "2031-01-20 10:00:00")
+	insertTransactionAt(t, user.ID, 50, true, "2031-03-05 10:00:00")
+	insertTransactionAt(t, user.ID, 300, false, "2031-05-01 10:00:00")
+
+	report, err := GetCashflowReport(ctxTest, user.ID, "2031-01-15", "2031-03-31", "")
+	if err != nil {
+		t.Fatalf("GetCashflowReport() unexpected error: %v", err)
+	}
+
+	if report.Granularity != model.GranularityMonth || report.From != "2031-01-01" || report.To != "2031-03-31" {
+		t.Fatalf("unexpected report range: %+v", report)
+	}
+
+	want := []model.CashflowPeriod{
+		{Period: "2031-01", Start: "2031-01-01", End: "2031-01-31", Income: 500, Expenses: 200, Net: 300, EndingBalance: 1300},
+		{Period: "2031-02", Start: "2031-02-01", End: "2031-02-28", EndingBalance: 1300},
+		{Period: "2031-03", Start: "2031-03-01", End: "2031-03-31", Expenses: 50, Net: -50, EndingBalance: 1250},
+	}
+	if len(report.Periods) != len(want) {
+		t.Fatalf("expected %d periods, got %d: %+v", len(want), len(report.Periods), report.Periods)
+	}
+	for i := range want {
+		if report.Periods[i] != want[i] {
+			t.Errorf("period %d = %+v, want %+v", i, report.Periods[i], want[i])
+		}
+	}
+}
+
+func TestGetCashflowReport_Defaults(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "cashflow-defaults"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2031, 8, 14, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		granularity string
+		wantCount   int
+		wantFrom    string
+		wantTo      string
+	}{
+		{model.GranularityDay, 30, "2031-07-16", "2031-08-14"},
+		{model.GranularityWeek, 12, "2031-05-26", "2031-08-17"},
+		{model.GranularityMonth, 12, "2030-09-01", "2031-08-31"},
+		{model.GranularityYear, 5, "2027-01-01", "2031-12-31"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.granularity, func(t *testing.T) {
+			report, err := GetCashflowReport(ctxTest, user.ID, "", "", tc.granularity)
+			if err != nil {
+				t.Fatalf("GetCashflowReport() unexpected error: %v", err)
+			}
+			if len(report.Periods) != tc.wantCount || report.From != tc.wantFrom || report.To != tc.wantTo {
+				t.Errorf("got %d periods from %s to %s, want %d from %s to %s",
+					len(report.Periods), report.From, report.To, tc.wantCount, tc.wantFrom, tc.wantTo)
+			}
+		})
+	}
+}
+
+func TestGetCashflowReport_Validation(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "cashflow-validation"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		from, to    string
+		granularity string
+	}{
+		{"unknown_granularity", "", "", "fortnight"},
+		{"malformed_from", "2031/01/01", "", "month"},
+		{"malformed_to", "", "yesterday", "month"},
+		{"from_after_to", "2031-05-01", "2031-01-01", "month"},
+		{"too_many_periods", "2000-01-01", "2031-01-01", "day"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := GetCashflowReport(ctxTest, user.ID, tc.from, tc.to, tc.granularity); !errors.Is(err, ErrInvalidReport) {
+				t.Errorf("GetCashflowReport() error = %v, want ErrInvalidReport", err)
+			}
+		})
+	}
+
+	if _, err := GetCashflowReport(ctxTest, 999999999, "", "", ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetCashflowReport() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestGetDashboard(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "dashboard-user", CurrentAmount: 5000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	friend, err := CreateUser(ctxTest, model.User{UserName: "dashboard-friend"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2032, 3, 10, 12, 0, 0, 0, time.UTC))
+
+	// Current month up to now
+	insertTransactionAt(t, user.ID, 1000, false, "2032-03-02 09:00:00")
+	insertTransactionAt(t, user.ID, 300, true, "2032-03-05 09:00:00")
+	insertTransactionAt(t, user.ID, 700, true, "2032-03-09 09:00:00")
+	for i := 0; i < 5; i++ {
+		insertTransactionAt(t, user.ID, utils.Money(10+i), true, "2032-03-10 11:00:00")
+	}
+	insertTransactionAt(t, user.ID, 9999, true, "2032-03-11 09:00:00")
+	// Previous month, the last expense falls after the comparison point
+	insertTransactionAt(t, user.ID, 800, false, "2032-02-03 09:00:00")
+	insertTransactionAt(t, user.ID, 400, true, "2032-02-08 09:00:00")
+	insertTransactionAt(t, user.ID, 999, true, "2032-02-20 09:00:00")
+	// Another user's spending is ignored
+	insertTransactionAt(t, friend.ID, 5555, true, "2032-03-04 09:00:00")
+
+	owned, err := CreateGoal(ctxTest, model.Goal{Name: "Laptop", Price: 1000, UserID: user.ID, Deadline: "2032-01-31"})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	shared, err := CreateGoal(ctxTest, model.Goal{Name: "Trip", Price: 3000, UserID: friend.ID, Deadline: "2032-12-01"})
+	if err != nil {
+		t.Fatalf("failed to create goal: %v", err)
+	}
+	if _, err := SetGoalParticipant(ctxTest, model.GoalParticipant{GoalID: shared.ID, UserID: user.ID, TargetShare: 1500}); err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	for _, c := range []model.GoalContribution{
+		{GoalID: owned.ID, UserID: user.ID, Amount: 250},
+		{GoalID: shared.ID, UserID: user.ID, Amount: 600},
+		{GoalID: shared.ID, UserID: friend.ID, Amount: 900},
+	} {
+		if _, err := CreateGoalContribution(ctxTest, c); err != nil {
+			t.Fatalf("failed to create contribution: %v", err)
+		}
+	}
+
+	d, err := GetDashboard(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetDashboard() unexpected error: %v", err)
+	}
+
+	if d.CurrentBalance != 5000 || d.AsOf != "2032-03-10T12:00:00Z" {
+		t.Errorf("unexpected balance or date: %d %s", d.CurrentBalance, d.AsOf)
+	}
+
+	mtd := d.MonthToDate
+	if mtd.From != "2032-03-01" || mtd.To != "2032-03-10" || mtd.Income != 1000 || mtd.Expenses != 1060 || mtd.Net != -60 {
+		t.Errorf("unexpected month to date: %+v", mtd)
+	}
+	if mtd.SavingsRate == nil || *mtd.SavingsRate != -6 {
+		t.Errorf("month to date savings rate = %v, want -6", mtd.SavingsRate)
+	}
+
+	prev := d.PreviousMonth
+	if prev.From != "2032-02-01" || prev.To != "2032-02-10" || prev.Income != 800 || prev.Expenses != 400 || prev.Net != 400 {
+		t.Errorf("unexpected previous month: %+v", prev)
+	}
+	if prev.SavingsRate == nil || *prev.SavingsRate != 50 {
+		t.Errorf("previous month savings rate = %v, want 50", prev.SavingsRate)
+	}
+
+	c := d.Comparison
+	if c.IncomeChange != 200 || c.ExpensesChange != 660 || c.NetChange != -460 {
+		t.Errorf("unexpected comparison: %+v", c)
+	}
+	if c.IncomeChangePercent == nil || *c.IncomeChangePercent != 25 || c.ExpensesChangePercent == nil || *c.ExpensesChangePercent != 165 {
+		t.Errorf("unexpected comparison percentages: %v %v", c.IncomeChangePercent, c.ExpensesChangePercent)
+	}
+
+	if len(d.LargestExpenses) != dashboardLargestExpenses {
+		t.Fatalf("expected %d largest expenses, got %d", dashboardLargestExpenses, len(d.LargestExpenses))
+	}
+	wantLargest := []utils.Money{700, 300, 14, 13, 12}
+	for i, want := range wantLargest {
+		if d.LargestExpenses[i].Amount != want {
+			t.Errorf("largest expense %d = %d, want %d", i, d.LargestExpenses[i].Amount, want)
+		}
+	}
+
+	g := d.Goals
+	if g.Count != 2 || g.TotalPrice != 4000 || g.TotalContributed != 1750 || g.Overdue != 1 {
+		t.Errorf("unexpected goals summary: %+v", g)
+	}
+	if g.Progress == nil || *g.Progress != 43.75 {
+		t.Errorf("goals progress = %v, want 43.75", g.Progress)
+	}
+	if g.Goals[0].GoalID != owned.ID || g.Goals[0].Shared || g.Goals[0].UserContributed != 250 {
+		t.Errorf("unexpected owned goal progress: %+v", g.Goals[0])
+	}
+	if g.Goals[1].GoalID != shared.ID || !g.Goals[1].Shared || g.Goals[1].Contributed != 1500 || g.Goals[1].UserContributed != 600 || *g.Goals[1].Progress != 50 {
+		t.Errorf("unexpected shared goal progress: %+v", g.Goals[1])
+	}
+
+	if _, err := GetDashboard(ctxTest, 999999999); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetDashboard() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestGetDashboard_PreviousMonthClampedToItsLastDay(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "dashboard-clamp"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2032, 3, 31, 8, 0, 0, 0, time.UTC))
+
+	d, err := GetDashboard(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("GetDashboard() unexpected error: %v", err)
+	}
+	if d.PreviousMonth.From != "2032-02-01" || d.PreviousMonth.To != "2032-02-29" {
+		t.Errorf("previous month = %s..%s, want the whole of February", d.PreviousMonth.From, d.PreviousMonth.To)
+	}
+	if d.MonthToDate.SavingsRate != nil || d.Goals.Progress != nil || len(d.LargestExpenses) != 0 {
+		t.Errorf("expected empty figures for a user without activity: %+v", d)
+	}
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// ErrInvalidRule is returned when a categorisation rule has no condition, no action or a malformed condition.
+var ErrInvalidRule = model.NewError(model.KindValidation, "invalid rule")
+
+// compiledRule is a rule ready to be matched against transactions.
+type compiledRule struct {
+	model.Rule
+	contains string
+	regex    *regexp.Regexp
+}
+
+// compileRule prepares a rule for matching, failing with ErrInvalidRule when its regular expression is malformed.
+func compileRule(r model.Rule) (compiledRule, error) {
+	c := compiledRule{Rule: r, contains: strings.ToLower(r.DescriptionContains)}
+	if r.DescriptionRegex != "" {
+		re, err := regexp.Compile(r.DescriptionRegex)
+		if err != nil {
+			return c, fmt.Errorf("%w: description_regex: %v", ErrInvalidRule, err)
+		}
+		c.regex = re
+	}
+	return c, nil
+}
+
+// matches reports whether the transaction meets every condition of the rule.
+func (c compiledRule) matches(t model.Transaction) bool {
+	if c.contains != "" && !strings.Contains(strings.ToLower(t.Desc), c.contains) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(t.Desc) {
+		return false
+	}
+	if c.MinAmount != nil && t.Amount < *c.MinAmount {
+		return false
+	}
+	if c.MaxAmount != nil && t.Amount > *c.MaxAmount {
+		return false
+	}
+	if c.IsDebt != nil && t.IsDebt != *c.IsDebt {
+		return false
+	}
+	return true
+}
+
+// classify returns the category and tags the rules, in evaluation order, assign to a transaction, and the
+// IDs of the rules that matched. The first matching rule with a category decides it; tags accumulate.
+func classify(rules []compiledRule, t model.Transaction) (string, []string, []int64) {
+	var category string
+	var tags []string
+	var matched []int64
+
+	for _, r := range rules {
+		if !r.matches(t) {
+			continue
+		}
+		matched = append(matched, r.ID)
+		if category == "" {
+			category = r.Category
+		}
+		for _, tag := range r.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return category, tags, matched
+}
+
+// normalizeTags trims and lowercases tag names, dropping empty names and duplicates.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// validateRule normalises a rule and checks that it has at least one condition and one action.
+func validateRule(r *model.Rule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Category = strings.TrimSpace(r.Category)
+
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if r.DescriptionContains == "" && r.DescriptionRegex == "" && r.MinAmount == nil && r.MaxAmount == nil && r.IsDebt == nil {
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+	if (r.MinAmount != nil && *r.MinAmount < 0) || (r.MaxAmount != nil && *r.MaxAmount < 0) {
+		return fmt.Errorf("%w: amounts can not be negative", ErrInvalidRule)
+	}
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return fmt.Errorf("%w: min_amount is above max_amount", ErrInvalidRule)
+	}
+
+	tags, err := validateTags(r.Tags)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	r.Tags = tags
+	if r.Category == "" && len(r.Tags) == 0 {
+		return fmt.Errorf("%w: a category or tags to assign are required", ErrInvalidRule)
+	}
+
+	_, err = compileRule(*r)
+	return err
+}
+
+// loadRules returns the enabled rules of a user, compiled in evaluation order.
+func loadRules(ctx context.Context, userID int64, db *sql.DB) ([]compiledRule, error) {
+	rules, err := dbsqlite.GetRulesByUserID(ctx, userID, true, db)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", r.ID, err)
+		}
+		compiled = append(compiled, c)
+	}
+
+	return compiled, nil
+}
+
+// getUserRule returns the rule with the given ID, or sql.ErrNoRows when it does not belong to the user.
+func getUserRule(ctx context.Context, userID, ruleID int64, db *sql.DB) (*model.Rule, error) {
+	rule, err := dbsqlite.GetRuleByID(ctx, ruleID, db)
+	if err != nil {
+		return nil, err
+	}
+	if rule.UserID != userID {
+		return nil, model.NotFoundError(fmt.Sprintf("rule %d of user %d", ruleID, userID), sql.ErrNoRows)
+	}
+	return rule, nil
+}
+
+// GetRules returns the categorisation rules of a user in evaluation order.
+func GetRules(ctx context.Context, userID int64) ([]model.Rule, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetRulesByUserID(ctx, userID, false, db)
+}
+
+// CreateRule validates and stores a new categorisation rule for a user.
+func CreateRule(ctx context.Context, userID int64, rule model.Rule) (*model.Rule, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rule.UserID = userID
+	if err := validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.CreateRule(ctx, rule, db)
+}
+
+// UpdateRule applies a partial update to a rule of a user. The updated rule must still be valid.
+func UpdateRule(ctx context.Context, userID, ruleID int64, update *model.RuleUpdate) (*model.Rule, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rule, err := getUserRule(ctx, userID, ruleID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		rule.Name = *update.Name
+	}
+	if update.Priority != nil {
+		rule.Priority = *update.Priority
+	}
+	if update.DescriptionContains != nil {
+		rule.DescriptionContains = *update.DescriptionContains
+	}
+	if update.DescriptionRegex != nil {
+		rule.DescriptionRegex = *update.DescriptionRegex
+	}
+	if update.MinAmount != nil {
+		rule.MinAmount = update.MinAmount
+	}
+	if update.MaxAmount != nil {
+		rule.MaxAmount = update.MaxAmount
+	}
+	if update.IsDebt != nil {
+		rule.IsDebt = update.IsDebt
+	}
+	if update.Category != nil {
+		rule.Category = *update.Category
+	}
+	if update.Tags != nil {
+		rule.Tags = *update.Tags
+	}
+	if update.Enabled != nil {
+		rule.Enabled = *update.Enabled
+	}
+
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.UpdateRule(ctx, *rule, db)
+}
+
+// DeleteRule removes a rule of a user. Transactions it already classified keep their category and tags.
+func DeleteRule(ctx context.Context, userID, ruleID int64) (int64, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := getUserRule(ctx, userID, ruleID, db); err != nil {
+		return 0, err
+	}
+
+	return dbsqlite.DeleteRuleByID(ctx, ruleID, db)
+}
+
+// categorize fills the category of a new transaction, unless it already has one, and adds the tags from the
+// enabled rules of its owner.
+func categorize(ctx context.Context, t *model.Transaction, db *sql.DB) error {
+	rules, err := loadRules(ctx, t.UserID, db)
+	if err != nil {
+		return err
+	}
+
+	category, tags, _ := classify(rules, *t)
+	if t.Category == "" {
+		t.Category = category
+	}
+	t.Tags = normalizeTags(t.Tags)
+	for _, tag := range tags {
+		if !slices.Contains(t.Tags, tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+
+	return nil
+}
+
+// ApplyRules runs the enabled rules of a user over all of the user's transactions: a matching category
+// replaces the current one and matching tags are added. With dryRun nothing is stored and the result
+// previews what would change.
+func ApplyRules(ctx context.Context, userID int64, dryRun bool) (*model.RuleApplyResult, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	rules, err := loadRules(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := dbsqlite.GetAllTransactionsByUserID(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	currentTags, err := dbsqlite.GetTransactionTagsByUserID(ctx, userID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.RuleApplyResult{UserID: userID, DryRun: dryRun, Examined: len(transactions), Changes: []model.RuleChange{}}
+	for _, t := range transactions {
+		category, tags, matched := classify(rules, t)
+		if len(matched) == 0 {
+			continue
+		}
+
+		change := model.RuleChange{TransactionID: t.ID, Description: t.Desc, PreviousCategory: t.Category, RuleIDs: matched}
+		if category != "" && category != t.Category {
+			change.Category = category
+		}
+		for _, tag := range tags {
+			if !slices.Contains(currentTags[t.ID], tag) {
+				change.AddedTags = append(change.AddedTags, tag)
+			}
+		}
+
+		if change.Category != "" || len(change.AddedTags) > 0 {
+			result.Changes = append(result.Changes, change)
+		}
+	}
+	result.Changed = len(result.Changes)
+
+	if !dryRun && result.Changed > 0 {
+		if err := dbsqlite.ApplyRuleChanges(ctx, userID, result.Changes, db); err != nil {
+			return nil, err
+		}
+		if err := auditRuleChanges(ctx, transactions, currentTags, result.Changes, db); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// auditRuleChanges writes a transaction update to the audit log for every transaction a rules run changed,
+// from the transactions and their tags as they were before the run.
+func auditRuleChanges(ctx context.Context, transactions []model.Transaction, tags map[int64][]string, changes []model.RuleChange, db *sql.DB) error {
+	before := make(map[int64]model.Transaction, len(transactions))
+	for _, t := range transactions {
+		t.Tags = tags[t.ID]
+		before[t.ID] = t
+	}
+
+	for _, c := range changes {
+		after, err := dbsqlite.GetTransactionByID(ctx, c.TransactionID, db)
+		if err != nil {
+			return err
+		}
+		if after.Tags, err = dbsqlite.GetTransactionTags(ctx, c.TransactionID, db); err != nil {
+			return err
+		}
+
+		original := before[c.TransactionID]
+		if err := recordAudit(ctx, model.AuditEntityTransaction, c.TransactionID, model.AuditActionUpdate, &original, after, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
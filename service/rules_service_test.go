@@ -0,0 +1,224 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestClassify(t *testing.T) {
+	debt, credit := true, false
+	min, max := utils.Money(10000), utils.Money(50000)
+
+	rules := []model.Rule{
+		{ID: 1, DescriptionContains: "Market", Category: "food", Tags: []string{"home"}},
+		{ID: 2, DescriptionRegex: `^UBER\b`, IsDebt: &debt, Category: "transport"},
+		{ID: 3, MinAmount: &min, MaxAmount: &max, IsDebt: &debt, Tags: []string{"big", "home"}},
+		{ID: 4, DescriptionContains: "salary", IsDebt: &credit, Category: "income"},
+		{ID: 5, DescriptionContains: "market", Category: "groceries", Tags: []string{"weekly"}},
+	}
+	var compiled []compiledRule
+	for _, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			t.Fatalf("compileRule() returned error: %v", err)
+		}
+		compiled = append(compiled, c)
+	}
+
+	tests := []struct {
+		name     string
+		t        model.Transaction
+		category string
+		tags     []string
+		matched  []int64
+	}{
+		{"contains is case-insensitive and the first category wins", model.Transaction{Desc: "SUPERMARKET 42", Amount: 3000, IsDebt: true}, "food", []string{"home", "weekly"}, []int64{1, 5}},
+		{"regex and debt", model.Transaction{Desc: "UBER TRIP", Amount: 2000, IsDebt: true}, "transport", nil, []int64{2}},
+		{"regex is case-sensitive", model.Transaction{Desc: "uber trip", Amount: 2000, IsDebt: true}, "", nil, nil},
+		{"amount range is inclusive", model.Transaction{Desc: "UBER LUX", Amount: 50000, IsDebt: true}, "transport", []string{"big", "home"}, []int64{2, 3}},
+		{"tags without category", model.Transaction{Desc: "furniture", Amount: 10000, IsDebt: true}, "", []string{"big", "home"}, []int64{3}},
+		{"is_debt mismatch", model.Transaction{Desc: "salary refund", Amount: 500, IsDebt: true}, "", nil, nil},
+		{"credit", model.Transaction{Desc: "Salary", Amount: 500000}, "income", nil, []int64{4}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			category, tags, matched := classify(compiled, tc.t)
+			if category != tc.category || !slices.Equal(tags, tc.tags) || !slices.Equal(matched, tc.matched) {
+				t.Errorf("classify() = %q, %v, %v; want %q, %v, %v", category, tags, matched, tc.category, tc.tags, tc.matched)
+			}
+		})
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	min, max := utils.Money(500), utils.Money(100)
+
+	tests := []struct {
+		name    string
+		rule    model.Rule
+		wantErr bool
+	}{
+		{"valid", model.Rule{Name: " coffee ", DescriptionContains: "coffee", Tags: []string{" Coffee", "coffee", ""}}, false},
+		{"missing name", model.Rule{DescriptionContains: "coffee", Category: "food"}, true},
+		{"no condition", model.Rule{Name: "all", Category: "misc"}, true},
+		{"no action", model.Rule{Name: "coffee", DescriptionContains: "coffee", Tags: []string{" "}}, true},
+		{"bad regex", model.Rule{Name: "coffee", DescriptionRegex: "(", Category: "food"}, true},
+		{"inverted range", model.Rule{Name: "range", MinAmount: &min, MaxAmount: &max, Category: "misc"}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRule(&tc.rule)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateRule() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("validateRule() error = %v, want ErrInvalidRule", err)
+			}
+			if !tc.wantErr && (tc.rule.Name != "coffee" || !slices.Equal(tc.rule.Tags, []string{"coffee"})) {
+				t.Errorf("rule was not normalised: %+v", tc.rule)
+			}
+		})
+	}
+}
+
+func TestRules_AppliedOnCreateAndToHistory(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "rules-user", CurrentAmount: 100000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// Recorded before any rule exists
+	old, err := CreateTransaction(ctxTest, model.Transaction{Desc: "Padaria Central", Amount: 1200, IsDebt: true, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if old.Category != "" || len(old.Tags) != 0 {
+		t.Fatalf("expected no classification without rules, got %+v", old)
+	}
+
+	debt := true
+	rule, err := CreateRule(ctxTest, user.ID, model.Rule{Name: "bakery", DescriptionContains: "padaria", IsDebt: &debt, Category: "food", Tags: []string{"Daily"}, Enabled: true})
+	if err != nil {
+		t.Fatalf("CreateRule() returned error: %v", err)
+	}
+	if _, err := CreateRule(ctxTest, user.ID, model.Rule{Name: "disabled", DescriptionContains: "padaria", Category: "ignored"}); err != nil {
+		t.Fatalf("CreateRule() returned error: %v", err)
+	}
+	if _, err := CreateRule(ctxTest, user.ID, model.Rule{Name: "bad"}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("CreateRule() with an invalid rule error = %v, want ErrInvalidRule", err)
+	}
+
+	created, err := CreateTransaction(ctxTest, model.Transaction{Desc: "PADARIA DO ZE", Amount: 800, IsDebt: true, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if created.Category != "food" || !slices.Equal(created.Tags, []string{"daily"}) {
+		t.Errorf("CreateTransaction() = %+v, want category food and tag daily", created)
+	}
+
+	// An explicit category is kept, the rule's tags are still added
+	explicit, err := CreateTransaction(ctxTest, model.Transaction{Desc: "padaria gift", Amount: 5000, IsDebt: true, UserID: user.ID, Category: "gifts", Tags: []string{"Birthday"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	stored, err := GetTransactionByID(ctxTest, explicit.ID)
+	if err != nil {
+		t.Fatalf("GetTransactionByID() returned error: %v", err)
+	}
+	if stored.Category != "gifts" || !slices.Equal(stored.Tags, []string{"birthday", "daily"}) {
+		t.Errorf("stored transaction = %+v, want category gifts and tags birthday, daily", stored)
+	}
+
+	preview, err := ApplyRules(ctxTest, user.ID, true)
+	if err != nil {
+		t.Fatalf("ApplyRules() dry run returned error: %v", err)
+	}
+	// Only the transaction recorded before the rule changes; the explicit category is reclassified too
+	if !preview.DryRun || preview.Examined != 3 || preview.Changed != 2 {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if c := preview.Changes[0]; c.TransactionID != old.ID || c.Category != "food" || !slices.Equal(c.AddedTags, []string{"daily"}) || !slices.Equal(c.RuleIDs, []int64{rule.ID}) {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if c := preview.Changes[1]; c.TransactionID != explicit.ID || c.PreviousCategory != "gifts" || c.Category != "food" || len(c.AddedTags) != 0 {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if unchanged, _ := GetTransactionByID(ctxTest, old.ID); unchanged.Category != "" {
+		t.Errorf("dry run changed the transaction: %+v", unchanged)
+	}
+
+	applied, err := ApplyRules(ctxTest, user.ID, false)
+	if err != nil || applied.Changed != 2 {
+		t.Fatalf("ApplyRules() = %+v, %v", applied, err)
+	}
+	if reclassified, _ := GetTransactionByID(ctxTest, old.ID); reclassified.Category != "food" || !slices.Equal(reclassified.Tags, []string{"daily"}) {
+		t.Errorf("reclassified transaction = %+v", reclassified)
+	}
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityTransaction, old.ID)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("GetAuditLog() = %+v, %v", entries, err)
+	}
+	var before, after model.Transaction
+	last := entries[len(entries)-1]
+	if err := json.Unmarshal(last.Before, &before); err != nil {
+		t.Fatalf("audit before = %s: %v", last.Before, err)
+	}
+	if err := json.Unmarshal(last.After, &after); err != nil {
+		t.Fatalf("audit after = %s: %v", last.After, err)
+	}
+	if last.Action != model.AuditActionUpdate || before.Category != "" || after.Category != "food" || !slices.Equal(after.Tags, []string{"daily"}) {
+		t.Errorf("audit entry of the reclassified transaction = %s %s -> %s", last.Action, last.Before, last.After)
+	}
+
+	again, err := ApplyRules(ctxTest, user.ID, false)
+	if err != nil || again.Changed != 0 {
+		t.Errorf("second ApplyRules() = %+v, %v; want no changes", again, err)
+	}
+
+	if _, err := ApplyRules(ctxTest, 999999999, true); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ApplyRules() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestRules_BelongToTheirUser(t *testing.T) {
+	owner, _ := CreateUser(ctxTest, model.User{UserName: "rule-owner"})
+	other, _ := CreateUser(ctxTest, model.User{UserName: "rule-other"})
+
+	rule, err := CreateRule(ctxTest, owner.ID, model.Rule{Name: "fuel", DescriptionContains: "shell", Category: "car"})
+	if err != nil {
+		t.Fatalf("CreateRule() returned error: %v", err)
+	}
+
+	name := "renamed"
+	if _, err := UpdateRule(ctxTest, other.ID, rule.ID, &model.RuleUpdate{Name: &name}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateRule() by another user error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := DeleteRule(ctxTest, other.ID, rule.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteRule() by another user error = %v, want sql.ErrNoRows", err)
+	}
+
+	empty := ""
+	if _, err := UpdateRule(ctxTest, owner.ID, rule.ID, &model.RuleUpdate{DescriptionContains: &empty}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("UpdateRule() removing the only condition error = %v, want ErrInvalidRule", err)
+	}
+	updated, err := UpdateRule(ctxTest, owner.ID, rule.ID, &model.RuleUpdate{Name: &name})
+	if err != nil || updated.Name != "renamed" || updated.DescriptionContains != "shell" {
+		t.Errorf("UpdateRule() = %+v, %v", updated, err)
+	}
+
+	rules, err := GetRules(ctxTest, owner.ID)
+	if err != nil || len(rules) != 1 {
+		t.Errorf("GetRules() = %+v, %v", rules, err)
+	}
+	if rows, err := DeleteRule(ctxTest, owner.ID, rule.ID); err != nil || rows != 1 {
+		t.Errorf("DeleteRule() = %d, %v", rows, err)
+	}
+}
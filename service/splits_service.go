@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// ErrInvalidSplit is returned when the splits of a transaction have non-positive amounts or do not add up
+// to the amount of the transaction.
+var ErrInvalidSplit = model.NewError(model.KindValidation, "invalid split")
+
+// validateSplits normalises the splits of a transaction of the given amount and checks that every split is
+// positive and that together they add up to the amount. No splits at all is valid.
+func validateSplits(amount utils.Money, splits []model.Split) ([]model.Split, error) {
+	normalized := make([]model.Split, 0, len(splits))
+	var sum utils.Money
+	for i, s := range splits {
+		if s.Amount <= 0 {
+			return nil, fmt.Errorf("%w: split %d must have a positive amount", ErrInvalidSplit, i+1)
+		}
+		sum += s.Amount
+		normalized = append(normalized, model.Split{
+			Amount:   s.Amount,
+			Category: strings.TrimSpace(s.Category),
+			Note:     strings.TrimSpace(s.Note),
+		})
+	}
+
+	if len(normalized) > 0 && sum != amount {
+		return nil, fmt.Errorf("%w: splits add up to %s but the transaction amount is %s", ErrInvalidSplit, formatMoney(sum), formatMoney(amount))
+	}
+
+	return normalized, nil
+}
+
+// GetCategoryReport totals the income and expenses of a user per category, counting each split of a split
+// transaction under its own category. The optional from and to, "YYYY-MM-DD" dates in UTC, bound the
+// transactions counted, both inclusive.
+func GetCategoryReport(ctx context.Context, userID int64, from, to string) (*model.CategoryReport, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	start, end, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	totals, err := dbsqlite.GetCategoryTotals(ctx, userID, start, end, db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range totals {
+		totals[i].Net = totals[i].Income - totals[i].Expenses
+	}
+
+	return &model.CategoryReport{UserID: userID, From: from, To: to, Categories: totals}, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+func TestValidateSplits(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  utils.Money
+		splits  []model.Split
+		wantErr bool
+	}{
+		{"no splits", 1000, nil, false},
+		{"adds up", 1000, []model.Split{{Amount: 600, Category: " food "}, {Amount: 400}}, false},
+		{"short", 1000, []model.Split{{Amount: 600}, {Amount: 300}}, true},
+		{"over", 1000, []model.Split{{Amount: 1200}}, true},
+		{"zero amount", 1000, []model.Split{{Amount: 1000}, {Amount: 0}}, true},
+		{"negative amount", 1000, []model.Split{{Amount: 1500}, {Amount: -500}}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := validateSplits(tc.amount, tc.splits)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateSplits() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSplit) {
+				t.Errorf("validateSplits() error = %v, want ErrInvalidSplit", err)
+			}
+			if err == nil && len(got) > 0 && got[0].Category != "food" {
+				t.Errorf("validateSplits() did not trim the category: %+v", got)
+			}
+		})
+	}
+}
+
+func TestSplits_TransactionsAndCategoryReport(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "splits-user", CurrentAmount: 100000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	splits := []model.Split{
+		{Amount: 6000, Category: "food"},
+		{Amount: 2500, Category: "cleaning", Note: "detergent"},
+		{Amount: 1500, Category: "pharmacy"},
+	}
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "receipt", Amount: 9000, IsDebt: true, UserID: user.ID, Splits: splits}); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("CreateTransaction() with splits not adding up error = %v, want ErrInvalidSplit", err)
+	}
+
+	receipt, err := CreateTransaction(ctxTest, model.Transaction{Desc: "receipt", Amount: 10000, IsDebt: true, UserID: user.ID, Category: "groceries", Splits: splits})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if len(receipt.Splits) != 3 || receipt.Splits[0].ID == 0 || receipt.Splits[0].TransactionID != receipt.ID {
+		t.Errorf("CreateTransaction() splits = %+v", receipt.Splits)
+	}
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "pharmacy", Amount: 500, IsDebt: true, UserID: user.ID, Category: "pharmacy"}); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+
+	report, err := GetCategoryReport(ctxTest, user.ID, "", "")
+	if err != nil {
+		t.Fatalf("GetCategoryReport() returned error: %v", err)
+	}
+	want := []model.CategoryTotal{
+		{Category: "cleaning", Transactions: 1, Expenses: 2500, Net: -2500},
+		{Category: "food", Transactions: 1, Expenses: 6000, Net: -6000},
+		{Category: "pharmacy", Transactions: 2, Expenses: 2000, Net: -2000},
+	}
+	if !slices.Equal(report.Categories, want) {
+		t.Errorf("GetCategoryReport() = %+v, want %+v", report.Categories, want)
+	}
+
+	// Changing the amount alone would leave the splits adding up to something else
+	amount := utils.Money(12000)
+	if _, err := UpdateTransactionByID(ctxTest, receipt.ID, &model.TransactionUpdate{Amount: &amount}); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("UpdateTransactionByID() with a new amount only error = %v, want ErrInvalidSplit", err)
+	}
+	resplit := []model.Split{{Amount: 12000, Category: "food"}}
+	updated, err := UpdateTransactionByID(ctxTest, receipt.ID, &model.TransactionUpdate{Amount: &amount, Splits: &resplit})
+	if err != nil || len(updated.Splits) != 1 || updated.Amount != 12000 {
+		t.Fatalf("UpdateTransactionByID() = %+v, %v", updated, err)
+	}
+
+	// Removing the splits puts the transaction back under its own category
+	none := []model.Split{}
+	if _, err := UpdateTransactionByID(ctxTest, receipt.ID, &model.TransactionUpdate{Splits: &none}); err != nil {
+		t.Fatalf("UpdateTransactionByID() removing the splits returned error: %v", err)
+	}
+	stored, err := GetTransactionByID(ctxTest, receipt.ID)
+	if err != nil || len(stored.Splits) != 0 {
+		t.Errorf("GetTransactionByID() = %+v, %v; want no splits", stored, err)
+	}
+	report, _ = GetCategoryReport(ctxTest, user.ID, "", "")
+	if len(report.Categories) != 2 || report.Categories[0].Category != "groceries" || report.Categories[0].Expenses != 12000 {
+		t.Errorf("GetCategoryReport() after removing the splits = %+v", report.Categories)
+	}
+
+	if _, err := GetCategoryReport(ctxTest, 999999999, "", ""); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetCategoryReport() for a missing user error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := GetCategoryReport(ctxTest, user.ID, "2030-02-01", "2030-01-01"); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetCategoryReport() with from after to error = %v, want ErrInvalidReport", err)
+	}
+}
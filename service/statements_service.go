@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/pdf"
+	"natan/fingo/utils"
+)
+
+//go:embed templates/statement.html
+var statementTemplates embed.FS
+
+// statementFuncs are the helpers the statement template formats its figures with.
+var statementFuncs = template.FuncMap{
+	"money":    formatMoney,
+	"signed":   signedAmount,
+	"date":     statementDate,
+	"progress": formatProgress,
+	"net": func(e *model.AdjustmentEntry) utils.Money {
+		return e.BalanceAfter - e.BalanceBefore
+	},
+}
+
+var statementTemplate = template.Must(template.New("statement.html").Funcs(statementFuncs).ParseFS(statementTemplates, "templates/statement.html"))
+
+// signedAmount formats the amount of a transaction, negative for debts.
+func signedAmount(t model.Transaction) string {
+	if t.IsDebt {
+		return formatMoney(-t.Amount)
+	}
+	return formatMoney(t.Amount)
+}
+
+// statementDate keeps the date of a "YYYY-MM-DD HH:MM:SS" timestamp.
+func statementDate(timestamp string) string {
+	if len(timestamp) > len("2006-01-02") {
+		return timestamp[:len("2006-01-02")]
+	}
+	return timestamp
+}
+
+// formatProgress formats a goal progress percentage, "-" when the goal has no price.
+func formatProgress(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*p, 'f', 2, 64) + "%"
+}
+
+// GetStatement returns the monthly statement of a user for yearMonth ("YYYY-MM"). Months are delimited in
+// UTC, like the stored timestamps, and statements can not be requested for months after the current one.
+func GetStatement(ctx context.Context, userID int64, yearMonth string) (*model.Statement, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	start, err := time.Parse("2006-01", yearMonth)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the month must be formatted as YYYY-MM", ErrInvalidReport)
+	}
+	if yearMonth > currentYearMonth() {
+		return nil, fmt.Errorf("%w: %s has not started yet", ErrInvalidReport, yearMonth)
+	}
+	end := start.AddDate(0, 1, 0)
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	s, err := dbsqlite.GetStatement(ctx, userID, yearMonth, start.Format(dbsqlite.TimestampLayout), end.Format(dbsqlite.TimestampLayout), db)
+	if err != nil {
+		return nil, err
+	}
+
+	s.From = start.Format("2006-01-02")
+	s.To = end.AddDate(0, 0, -1).Format("2006-01-02")
+	s.GeneratedAt = currentTime().Format(time.RFC3339)
+	s.OpeningBalance = s.ClosingBalance - (s.Income - s.Expenses + s.Adjustments)
+	for i := range s.Goals {
+		s.Goals[i].Progress = percent(s.Goals[i].Contributed, s.Goals[i].Price)
+	}
+
+	return s, nil
+}
+
+// RenderStatementHTML writes a statement as a printable HTML page.
+func RenderStatementHTML(w io.Writer, s *model.Statement) error {
+	if err := statementTemplate.Execute(w, s); err != nil {
+		return fmt.Errorf("could not render the statement: %w", err)
+	}
+	return nil
+}
+
+// Layout of the PDF statement, in points
+const (
+	pdfMargin       = 50.0
+	pdfRight        = pdf.A4Width - pdfMargin
+	pdfLineHeight   = 14.0
+	pdfBodySize     = 10.0
+	pdfMaxDescLen   = 60
+	pdfDescriptionX = pdfMargin + 75
+)
+
+// statementPDF lays a statement out line by line, starting a new page when the current one is full.
+type statementPDF struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func (sp *statementPDF) newPage() {
+	sp.page = sp.doc.AddPage()
+	sp.y = pdf.A4Height - pdfMargin
+}
+
+// next moves to the next line, first starting a new page when fewer than lines are left on this one.
+func (sp *statementPDF) next(lines int) {
+	if sp.y-float64(lines)*pdfLineHeight < pdfMargin {
+		sp.newPage()
+	}
+	sp.y -= pdfLineHeight
+}
+
+func (sp *statementPDF) heading(text string) {
+	sp.next(3)
+	sp.y -= pdfLineHeight / 2
+	sp.page.Text(pdfMargin, sp.y, pdf.HelveticaBold, 12, text)
+	sp.page.Line(pdfMargin, sp.y-4, pdfRight, sp.y-4, 0.5)
+	sp.y -= 4
+}
+
+func (sp *statementPDF) row(label string, amount utils.Money, bold bool) {
+	sp.next(1)
+	font := pdf.Helvetica
+	if bold {
+		font = pdf.HelveticaBold
+	}
+	sp.page.Text(pdfMargin, sp.y, font, pdfBodySize, label)
+	sp.page.TextRight(pdfRight, sp.y, pdfBodySize, formatMoney(amount))
+}
+
+func (sp *statementPDF) note(text string) {
+	sp.next(1)
+	sp.page.Text(pdfMargin, sp.y, pdf.Helvetica, pdfBodySize, text)
+}
+
+// RenderStatementPDF writes a statement as an A4 PDF document.
+func RenderStatementPDF(w io.Writer, s *model.Statement) error {
+	sp := &statementPDF{doc: pdf.New(fmt.Sprintf("Statement %s - %s", s.YearMonth, s.UserName))}
+	sp.newPage()
+
+	sp.y -= 8
+	sp.page.Text(pdfMargin, sp.y, pdf.HelveticaBold, 18, "Monthly statement - "+s.YearMonth)
+	sp.next(1)
+	sp.page.Text(pdfMargin, sp.y, pdf.Helvetica, 9, fmt.Sprintf("%s - %s to %s - generated %s", s.UserName, s.From, s.To, s.GeneratedAt))
+
+	sp.heading("Summary")
+	sp.row("Opening balance", s.OpeningBalance, false)
+	sp.row("Income", s.Income, false)
+	sp.row("Expenses", -s.Expenses, false)
+	sp.row("Monthly adjustments", s.Adjustments, false)
+	sp.row("Closing balance", s.ClosingBalance, true)
+
+	sp.heading("Transactions")
+	if len(s.Transactions) == 0 {
+		sp.note("No transactions this month.")
+	}
+	for _, t := range s.Transactions {
+		desc := []rune(t.Desc)
+		if len(desc) > pdfMaxDescLen {
+			desc = append(desc[:pdfMaxDescLen-3], []rune("...")...)
+		}
+		sp.next(1)
+		sp.page.Text(pdfMargin, sp.y, pdf.Helvetica, pdfBodySize, statementDate(t.CreatedAt))
+		sp.page.Text(pdfDescriptionX, sp.y, pdf.Helvetica, pdfBodySize, string(desc))
+		sp.page.TextRight(pdfRight, sp.y, pdfBodySize, signedAmount(t))
+	}
+
+	sp.heading("Monthly adjustment")
+	if a := s.Adjustment; a != nil {
+		sp.row("Inputs", a.InputsApplied, false)
+		sp.row("Outputs", -a.OutputsApplied, false)
+		sp.row("Applied on "+statementDate(a.AppliedAt), a.BalanceAfter-a.BalanceBefore, true)
+	} else {
+		sp.note("No monthly adjustment was applied for this month.")
+	}
+
+	sp.heading("Goals")
+	if len(s.Goals) == 0 {
+		sp.note("No goals.")
+	}
+	for _, g := range s.Goals {
+		name := g.Name
+		if g.Shared {
+			name += " (shared)"
+		}
+		sp.next(1)
+		sp.page.Text(pdfMargin, sp.y, pdf.Helvetica, pdfBodySize, name)
+		sp.page.Text(pdfMargin+230, sp.y, pdf.Helvetica, pdfBodySize, g.Deadline)
+		sp.page.TextRight(pdfRight, sp.y, pdfBodySize, fmt.Sprintf("%s / %s  %8s", formatMoney(g.Contributed), formatMoney(g.Price), formatProgress(g.Progress)))
+	}
+
+	if _, err := sp.doc.WriteTo(w); err != nil {
+		return fmt.Errorf("could not write the statement PDF: %w", err)
+	}
+	return nil
+}
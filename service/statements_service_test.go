@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestGetStatement(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "statement-user", CurrentAmount: 50000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	useFakeClock(t, time.Date(2032, 9, 10, 12, 0, 0, 0, time.UTC))
+
+	insertTransactionAt(t, user.ID, 1000, true, "2032-07-31 23:59:59")
+	insertTransactionAt(t, user.ID, 30000, false, "2032-08-01 00:00:00")
+	insertTransactionAt(t, user.ID, 4550, true, "2032-08-15 10:00:00")
+	insertTransactionAt(t, user.ID, 2000, true, "2032-09-02 10:00:00")
+
+	s, err := GetStatement(ctxTest, user.ID, "2032-08")
+	if err != nil {
+		t.Fatalf("GetStatement() unexpected error: %v", err)
+	}
+
+	// Closing undoes September's expense, opening undoes August's net
+	if s.ClosingBalance != 52000 || s.OpeningBalance != 52000-30000+4550 {
+		t.Errorf("opening, closing = %d, %d; want %d, 52000", s.OpeningBalance, s.ClosingBalance, 52000-30000+4550)
+	}
+	if s.From != "2032-08-01" || s.To != "2032-08-31" || s.Income != 30000 || s.Expenses != 4550 || len(s.Transactions) != 2 {
+		t.Errorf("unexpected statement: %+v", s)
+	}
+
+	if _, err := GetStatement(ctxTest, user.ID, "2032-10"); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetStatement() for a future month error = %v, want ErrInvalidReport", err)
+	}
+	if _, err := GetStatement(ctxTest, user.ID, "08-2032"); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetStatement() with a malformed month error = %v, want ErrInvalidReport", err)
+	}
+	if _, err := GetStatement(ctxTest, 999999999, "2032-08"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetStatement() for missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func statementFixture() *model.Statement {
+	progress := 25.0
+	return &model.Statement{
+		UserID:         1,
+		UserName:       "Ana <admin>",
+		YearMonth:      "2032-08",
+		From:           "2032-08-01",
+		To:             "2032-08-31",
+		GeneratedAt:    "2032-09-10T12:00:00Z",
+		OpeningBalance: 26550,
+		Income:         30000,
+		Expenses:       4550,
+		ClosingBalance: 52000,
+		Transactions: []model.Transaction{
+			{ID: 1, Desc: "Salary", Amount: 30000, CreatedAt: "2032-08-01 00:00:00"},
+			{ID: 2, Desc: "<script>alert(1)</script>", Amount: 4550, IsDebt: true, CreatedAt: "2032-08-15 10:00:00"},
+		},
+		Adjustment: &model.AdjustmentEntry{YearMonth: "2032-08", InputsApplied: 3000, OutputsApplied: 1000, BalanceBefore: 100, BalanceAfter: 2100, AppliedAt: "2032-08-01 00:00:05"},
+		Goals:      []model.GoalProgress{{GoalID: 1, Name: "Bike", Price: 10000, Contributed: 2500, Progress: &progress, Deadline: "2032-12-31"}},
+	}
+}
+
+func TestRenderStatementHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderStatementHTML(&buf, statementFixture()); err != nil {
+		t.Fatalf("RenderStatementHTML() returned error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{"Monthly statement - 2032-08", "Ana &lt;admin&gt;", "265.50", "520.00", "-45.50", "Applied on 2032-08-01", "20.00", "Bike", "25.00%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML statement does not contain %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Errorf("transaction descriptions must be escaped")
+	}
+
+	empty := &model.Statement{YearMonth: "2032-08"}
+	buf.Reset()
+	if err := RenderStatementHTML(&buf, empty); err != nil {
+		t.Fatalf("RenderStatementHTML() returned error: %v", err)
+	}
+	for _, want := range []string{"No transactions this month.", "No monthly adjustment", "No goals."} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("empty HTML statement does not contain %q", want)
+		}
+	}
+}
+
+func TestRenderStatementPDF(t *testing.T) {
+	s := statementFixture()
+	// Enough transactions to need a second page
+	for i := 0; i < 80; i++ {
+		s.Transactions = append(s.Transactions, model.Transaction{Desc: strings.Repeat("x", 100), Amount: 100, IsDebt: true, CreatedAt: "2032-08-20 10:00:00"})
+	}
+
+	var buf bytes.Buffer
+	if err := RenderStatementPDF(&buf, s); err != nil {
+		t.Fatalf("RenderStatementPDF() returned error: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "%PDF-") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("output is not a PDF document")
+	}
+	if !strings.Contains(out, "/Count 2") {
+		t.Errorf("expected the statement to span 2 pages")
+	}
+	if !strings.Contains(out, "/Title (Statement 2032-08 - Ana <admin>)") {
+		t.Errorf("expected the document title to name the month and user")
+	}
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// maxTagLength caps how long a tag name may be.
+const maxTagLength = 50
+
+var (
+	// ErrInvalidTag is returned when a tag name is empty, too long or contains a comma.
+	ErrInvalidTag = model.NewError(model.KindValidation, "invalid tag")
+	// ErrDuplicateTag is returned when a user already has a tag with the requested name.
+	ErrDuplicateTag = model.NewError(model.KindConflict, "tag already exists")
+)
+
+// validateTagName checks a normalised tag name. Commas are rejected because listings are filtered by
+// comma-separated tags.
+func validateTagName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidTag)
+	case len(name) > maxTagLength:
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, name, maxTagLength)
+	case strings.Contains(name, ","):
+		return fmt.Errorf("%w: %q contains a comma", ErrInvalidTag, name)
+	}
+	return nil
+}
+
+// validateTags normalises tag names and checks every one of them.
+func validateTags(tags []string) ([]string, error) {
+	normalized := normalizeTags(tags)
+	for _, tag := range normalized {
+		if err := validateTagName(tag); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
+}
+
+// ParseTagFilter splits a comma-separated list of tags, as given in a query string, into normalised names.
+func ParseTagFilter(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return normalizeTags(strings.Split(s, ","))
+}
+
+// hasAllTags reports whether tags contains every tag of filter.
+func hasAllTags(tags, filter []string) bool {
+	for _, f := range filter {
+		if !slices.Contains(tags, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// getUserTag returns the tag with the given ID, or sql.ErrNoRows when it does not belong to the user.
+func getUserTag(ctx context.Context, userID, tagID int64, db *sql.DB) (*model.Tag, error) {
+	tag, err := dbsqlite.GetTagByID(ctx, tagID, db)
+	if err != nil {
+		return nil, err
+	}
+	if tag.UserID != userID {
+		return nil, model.NotFoundError(fmt.Sprintf("tag %d of user %d", tagID, userID), sql.ErrNoRows)
+	}
+	return tag, nil
+}
+
+// checkTagNameFree fails with ErrDuplicateTag when the user already has a tag with the given name.
+func checkTagNameFree(ctx context.Context, userID int64, name string, db *sql.DB) error {
+	_, err := dbsqlite.GetTagByName(ctx, userID, name, db)
+	if err == nil {
+		return fmt.Errorf("%w: %q", ErrDuplicateTag, name)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// GetTags returns the tags of a user with how many transactions and goals carry each.
+func GetTags(ctx context.Context, userID int64) ([]model.Tag, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.GetTagsByUserID(ctx, userID, db)
+}
+
+// CreateTag adds a tag to a user. Names are trimmed and lowercased.
+func CreateTag(ctx context.Context, userID int64, name string) (*model.Tag, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if err := validateTagName(name); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+	if err := checkTagNameFree(ctx, userID, name, db); err != nil {
+		return nil, err
+	}
+
+	return dbsqlite.CreateTag(ctx, userID, name, db)
+}
+
+// taggedRecords holds the transactions and goals carrying a tag, with their tags, to write the change of
+// the tag to the audit log of each of them.
+type taggedRecords struct {
+	transactions []*model.Transaction
+	goals        []*model.Goal
+}
+
+// auditTransaction reads a transaction, in the trash or not, with its tags as the audit log records it.
+func auditTransaction(ctx context.Context, id int64, db *sql.DB) (*model.Transaction, error) {
+	t, err := dbsqlite.GetTransactionByID(ctx, id, db)
+	if errors.Is(err, model.KindNotFound) {
+		t, err = dbsqlite.GetDeletedTransactionByID(ctx, id, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db)
+	return t, err
+}
+
+// auditGoal reads a goal, in the trash or not, with its tags as the audit log records it.
+func auditGoal(ctx context.Context, id int64, db *sql.DB) (*model.Goal, error) {
+	g, err := dbsqlite.GetGoalByID(ctx, id, db)
+	if errors.Is(err, model.KindNotFound) {
+		g, err = dbsqlite.GetDeletedGoalByID(ctx, id, db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.Tags, err = dbsqlite.GetGoalTags(ctx, id, db)
+	return g, err
+}
+
+// getTaggedRecords reads the transactions and goals carrying a tag as they are before it changes.
+func getTaggedRecords(ctx context.Context, tagID int64, db *sql.DB) (*taggedRecords, error) {
+	transactionIDs, err := dbsqlite.GetTaggedTransactionIDs(ctx, tagID, db)
+	if err != nil {
+		return nil, err
+	}
+	goalIDs, err := dbsqlite.GetTaggedGoalIDs(ctx, tagID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagged taggedRecords
+	for _, id := range transactionIDs {
+		t, err := auditTransaction(ctx, id, db)
+		if err != nil {
+			return nil, err
+		}
+		tagged.transactions = append(tagged.transactions, t)
+	}
+	for _, id := range goalIDs {
+		g, err := auditGoal(ctx, id, db)
+		if err != nil {
+			return nil, err
+		}
+		tagged.goals = append(tagged.goals, g)
+	}
+	return &tagged, nil
+}
+
+// audit writes an update to the audit log for every transaction and goal that carried the tag, from the
+// records read before the tag changed to the same records read again now.
+func (tagged *taggedRecords) audit(ctx context.Context, db *sql.DB) error {
+	for _, before := range tagged.transactions {
+		after, err := auditTransaction(ctx, before.ID, db)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, model.AuditEntityTransaction, before.ID, model.AuditActionUpdate, before, after, db); err != nil {
+			return err
+		}
+	}
+	for _, before := range tagged.goals {
+		after, err := auditGoal(ctx, before.ID, db)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, model.AuditEntityGoal, before.ID, model.AuditActionUpdate, before, after, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameTag renames a tag of a user; everything carrying the tag follows, and each of them gets an update
+// in the audit log.
+func RenameTag(ctx context.Context, userID, tagID int64, name string) (*model.Tag, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if err := validateTagName(name); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tag, err := getUserTag(ctx, userID, tagID, db)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == name {
+		return tag, nil
+	}
+	if err := checkTagNameFree(ctx, userID, name, db); err != nil {
+		return nil, err
+	}
+	tagged, err := getTaggedRecords(ctx, tagID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed, err := dbsqlite.RenameTag(ctx, tagID, name, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := tagged.audit(ctx, db); err != nil {
+		return nil, err
+	}
+
+	return renamed, nil
+}
+
+// DeleteTag removes a tag of a user from everything carrying it, writing an update to the audit log of each,
+// and deletes it.
+func DeleteTag(ctx context.Context, userID, tagID int64) (int64, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := getUserTag(ctx, userID, tagID, db); err != nil {
+		return 0, err
+	}
+	tagged, err := getTaggedRecords(ctx, tagID, db)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := dbsqlite.DeleteTagByID(ctx, tagID, db)
+	if err != nil {
+		return 0, err
+	}
+	if err := tagged.audit(ctx, db); err != nil {
+		return rows, err
+	}
+
+	return rows, nil
+}
+
+// GetTagReport totals, for every tag of a user, the income and expenses of the transactions carrying it and
+// the price of the goals tagged with it. The optional from and to, "YYYY-MM-DD" dates in UTC, bound the
+// transactions counted, both inclusive.
+func GetTagReport(ctx context.Context, userID int64, from, to string) (*model.TagReport, error) {
+	if err := authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	start, end, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := dbsqlite.GetUserByID(ctx, userID, db); err != nil {
+		return nil, err
+	}
+
+	totals, err := dbsqlite.GetTagTotals(ctx, userID, start, end, db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range totals {
+		totals[i].Net = totals[i].Income - totals[i].Expenses
+	}
+
+	return &model.TagReport{UserID: userID, From: from, To: to, Tags: totals}, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{"normalised", []string{" Vacation-2026", "vacation-2026", "", "Reimbursable"}, []string{"vacation-2026", "reimbursable"}, false},
+		{"comma", []string{"a,b"}, nil, true},
+		{"too long", []string{strings.Repeat("x", maxTagLength+1)}, nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := validateTags(tc.tags)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateTags() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTag) {
+				t.Errorf("validateTags() error = %v, want ErrInvalidTag", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("validateTags() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTags_FilterListings(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "tags-filter", CurrentAmount: 100000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	flight, err := CreateTransaction(ctxTest, model.Transaction{Desc: "flight", Amount: 40000, IsDebt: true, UserID: user.ID, Tags: []string{"Vacation-2026", "reimbursable"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "museum", Amount: 3000, IsDebt: true, UserID: user.ID, Tags: []string{"vacation-2026"}}); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "bad", Amount: 1, UserID: user.ID, Tags: []string{"a,b"}}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("CreateTransaction() with an invalid tag error = %v, want ErrInvalidTag", err)
+	}
+
+	both, err := GetAllTransactionsByUserID(ctxTest, user.ID, ParseTagFilter("vacation-2026, Reimbursable"))
+	if err != nil {
+		t.Fatalf("GetAllTransactionsByUserID() returned error: %v", err)
+	}
+	if len(both) != 1 || both[0].ID != flight.ID || !slices.Equal(both[0].Tags, []string{"reimbursable", "vacation-2026"}) {
+		t.Errorf("filtering by both tags = %+v, want only the flight", both)
+	}
+	if all, _ := GetAllTransactionsByUserID(ctxTest, user.ID, []string{"vacation-2026"}); len(all) != 2 {
+		t.Errorf("filtering by one tag returned %d transactions, want 2", len(all))
+	}
+
+	// Tags given on update replace the current ones
+	none := []string{}
+	updated, err := UpdateTransactionByID(ctxTest, flight.ID, &model.TransactionUpdate{Tags: &none})
+	if err != nil || len(updated.Tags) != 0 {
+		t.Fatalf("UpdateTransactionByID() = %+v, %v", updated, err)
+	}
+	if tagged, _ := GetAllTransactions(ctxTest, []string{"reimbursable"}); slices.ContainsFunc(tagged, func(t model.Transaction) bool { return t.ID == flight.ID }) {
+		t.Errorf("GetAllTransactions() still finds the untagged flight")
+	}
+
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "hotel", Price: 80000, UserID: user.ID, Deadline: "2030-01-01", Tags: []string{"vacation-2026"}})
+	if err != nil || !slices.Equal(goal.Tags, []string{"vacation-2026"}) {
+		t.Fatalf("CreateGoal() = %+v, %v", goal, err)
+	}
+	if _, err := CreateGoal(ctxTest, model.Goal{Name: "untagged", UserID: user.ID, Tags: []string{""}}); err != nil {
+		t.Errorf("CreateGoal() with only empty tags returned error: %v", err)
+	}
+	goals, err := GetAllGoalsByUserID(ctxTest, user.ID, []string{"vacation-2026"})
+	if err != nil || len(goals) != 1 || goals[0].ID != goal.ID {
+		t.Errorf("GetAllGoalsByUserID() filtered = %+v, %v", goals, err)
+	}
+	tags := []string{"dream"}
+	if updatedGoal, err := UpdateGoalByID(ctxTest, goal.ID, &model.GoalUpdate{Tags: &tags}); err != nil || !slices.Equal(updatedGoal.Tags, tags) {
+		t.Errorf("UpdateGoalByID() = %+v, %v", updatedGoal, err)
+	}
+	if stored, _ := GetGoalByID(ctxTest, goal.ID); !slices.Equal(stored.Tags, tags) {
+		t.Errorf("GetGoalByID() tags = %v, want %v", stored.Tags, tags)
+	}
+}
+
+func TestTags_CRUDAndReport(t *testing.T) {
+	owner, _ := CreateUser(ctxTest, model.User{UserName: "tags-owner", CurrentAmount: 500000})
+	other, _ := CreateUser(ctxTest, model.User{UserName: "tags-other"})
+
+	tag, err := CreateTag(ctxTest, owner.ID, " Trip ")
+	if err != nil || tag.Name != "trip" {
+		t.Fatalf("CreateTag() = %+v, %v", tag, err)
+	}
+	if _, err := CreateTag(ctxTest, owner.ID, "TRIP"); !errors.Is(err, ErrDuplicateTag) {
+		t.Errorf("CreateTag() with a taken name error = %v, want ErrDuplicateTag", err)
+	}
+	if _, err := CreateTag(ctxTest, owner.ID, " "); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("CreateTag() with an empty name error = %v, want ErrInvalidTag", err)
+	}
+	if _, err := CreateTag(ctxTest, 999999999, "x"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("CreateTag() for a missing user error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := RenameTag(ctxTest, other.ID, tag.ID, "mine"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RenameTag() by another user error = %v, want sql.ErrNoRows", err)
+	}
+
+	insertTransactionAt(t, owner.ID, 1000, true, "2031-05-01 10:00:00")
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "train", Amount: 2500, IsDebt: true, UserID: owner.ID, Tags: []string{"trip"}}); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if _, err := CreateTransaction(ctxTest, model.Transaction{Desc: "refund", Amount: 500, UserID: owner.ID, Tags: []string{"trip"}}); err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+
+	renamed, err := RenameTag(ctxTest, owner.ID, tag.ID, "Trip-2026")
+	if err != nil || renamed.Name != "trip-2026" || renamed.Transactions != 2 {
+		t.Fatalf("RenameTag() = %+v, %v", renamed, err)
+	}
+
+	report, err := GetTagReport(ctxTest, owner.ID, "", "")
+	if err != nil {
+		t.Fatalf("GetTagReport() returned error: %v", err)
+	}
+	want := []model.TagTotal{{Tag: "trip-2026", Transactions: 2, Income: 500, Expenses: 2500, Net: -2000}}
+	if !slices.Equal(report.Tags, want) {
+		t.Errorf("GetTagReport() = %+v, want %+v", report.Tags, want)
+	}
+	// The transactions were recorded now, long before this range
+	if ranged, err := GetTagReport(ctxTest, owner.ID, "2031-01-01", "2031-12-31"); err != nil || ranged.Tags[0].Transactions != 0 {
+		t.Errorf("GetTagReport() for a later range = %+v, %v", ranged, err)
+	}
+	if _, err := GetTagReport(ctxTest, owner.ID, "2031-12-31", "2031-01-01"); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetTagReport() with from after to error = %v, want ErrInvalidReport", err)
+	}
+	if _, err := GetTagReport(ctxTest, owner.ID, "May", ""); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("GetTagReport() with a malformed date error = %v, want ErrInvalidReport", err)
+	}
+
+	if _, err := DeleteTag(ctxTest, other.ID, tag.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteTag() by another user error = %v, want sql.ErrNoRows", err)
+	}
+	if rows, err := DeleteTag(ctxTest, owner.ID, tag.ID); err != nil || rows != 1 {
+		t.Errorf("DeleteTag() = %d, %v", rows, err)
+	}
+	if tags, err := GetTags(ctxTest, owner.ID); err != nil || len(tags) != 0 {
+		t.Errorf("GetTags() after delete = %+v, %v", tags, err)
+	}
+}
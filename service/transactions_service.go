@@ -1,10 +1,13 @@
 package service
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
 	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
 	"natan/fingo/utils"
+	"slices"
 )
 
 // moneyPtr returns a pointer to the given Money value.
@@ -21,7 +24,20 @@ func applyBalanceDelta(current utils.Money, amount utils.Money, isDebt bool) uti
 	return current + amount
 }
 
-// GetTransactionByID returns the transaction with the given ID.
+// setBalance sets the balance of the user and records the change in the audit log.
+func setBalance(ctx context.Context, user *model.User, balance utils.Money, db *sql.DB) error {
+	updated, err := dbsqlite.UpdateUserPartialByID(ctx, user.ID, &model.UserUpdate{
+		CurrentAmount: moneyPtr(balance),
+	}, db)
+	if err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, model.AuditEntityUser, user.ID, model.AuditActionUpdate, user, updated, db)
+}
+
+// GetTransactionByID returns the transaction with the given ID to its owner, the members of their household
+// and admins.
 func GetTransactionByID(ctx context.Context, id int64) (*model.Transaction, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -29,34 +45,128 @@ func GetTransactionByID(ctx context.Context, id int64) (*model.Transaction, erro
 	}
 	defer db.Close()
 
-	return dbsqlite.GetTransactionByID(ctx, id, db)
+	transaction, err := authorizeTransactionRead(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.Splits, err = dbsqlite.GetTransactionSplits(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
 }
 
-// GetAllTransactions returns all transactions in the database.
-func GetAllTransactions(ctx context.Context) ([]model.Transaction, error) {
+// GetAllTransactions returns all transactions in the database with their tags and splits to admins, and the
+// transactions of the members of their household to anyone else. When tags is not empty, only the
+// transactions carrying every one of them are returned.
+func GetAllTransactions(ctx context.Context, tags []string) ([]model.Transaction, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	return dbsqlite.GetAllTransactions(ctx, db)
+	ids, scoped, err := listingScope(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if scoped {
+		transactions := []model.Transaction{}
+		for _, id := range ids {
+			memberTransactions, err := userTransactions(ctx, id, tags, db)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, memberTransactions...)
+		}
+		slices.SortFunc(transactions, func(a, b model.Transaction) int { return cmp.Compare(a.ID, b.ID) })
+		return transactions, nil
+	}
+
+	transactions, err := dbsqlite.GetAllTransactions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionTags, err := dbsqlite.GetAllTransactionTags(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	splits, err := dbsqlite.GetAllTransactionSplits(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return withTransactionDetails(transactions, transactionTags, splits, tags), nil
+}
+
+// withTransactionDetails fills the tags and splits of transactions and keeps the ones carrying every tag
+// of filter.
+func withTransactionDetails(transactions []model.Transaction, transactionTags map[int64][]string, splits map[int64][]model.Split, filter []string) []model.Transaction {
+	filtered := make([]model.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		t.Tags = transactionTags[t.ID]
+		t.Splits = splits[t.ID]
+		if hasAllTags(t.Tags, filter) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
 }
 
 // CreateTransaction persists a new transaction and updates the owner's balance accordingly.
-// Debts decrease the balance; credits increase it.
+// Debts decrease the balance; credits increase it. The owner's categorisation rules fill the category,
+// unless one is given, and add their tags. Splits, when given, must add up to the amount.
 func CreateTransaction(ctx context.Context, transaction model.Transaction) (*model.Transaction, error) {
+	if err := authorizeUser(ctx, transaction.UserID); err != nil {
+		return nil, err
+	}
+
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
+	if err := validateTransaction(ctx, &transaction, db); err != nil {
+		return nil, err
+	}
+	if err := categorize(ctx, &transaction, db); err != nil {
+		return nil, err
+	}
+
 	created, err := dbsqlite.CreateTransaction(ctx, transaction, db)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(created.Tags) > 0 {
+		if err := dbsqlite.AddTransactionTags(ctx, created.ID, created.UserID, created.Tags, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(created.Splits) > 0 {
+		if err := dbsqlite.SetTransactionSplits(ctx, created.ID, created.Splits, db); err != nil {
+			return nil, err
+		}
+		if created.Splits, err = dbsqlite.GetTransactionSplits(ctx, created.ID, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityTransaction, created.ID, model.AuditActionCreate, nil, created, db); err != nil {
+		return nil, err
+	}
+
 	user, err := dbsqlite.GetUserByID(ctx, created.UserID, db)
 	if err != nil {
 		return nil, err
@@ -64,10 +174,7 @@ func CreateTransaction(ctx context.Context, transaction model.Transaction) (*mod
 
 	newBalance := applyBalanceDelta(user.CurrentAmount, created.Amount, created.IsDebt)
 
-	_, err = dbsqlite.UpdateUserPartialByID(ctx, created.UserID, &model.UserUpdate{
-		CurrentAmount: moneyPtr(newBalance),
-	}, db)
-	if err != nil {
+	if err := setBalance(ctx, user, newBalance, db); err != nil {
 		return nil, err
 	}
 
@@ -76,6 +183,8 @@ func CreateTransaction(ctx context.Context, transaction model.Transaction) (*mod
 
 // UpdateTransactionByID applies a partial update to the transaction with the given ID.
 // If IsDebt changes, the owner's balance is adjusted to reflect the new transaction type.
+// Tags and splits, when given, replace those of the transaction. The splits, new or kept, must add up to
+// the resulting amount.
 func UpdateTransactionByID(ctx context.Context, id int64, update *model.TransactionUpdate) (*model.Transaction, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -83,7 +192,18 @@ func UpdateTransactionByID(ctx context.Context, id int64, update *model.Transact
 	}
 	defer db.Close()
 
-	original, err := dbsqlite.GetTransactionByID(ctx, id, db)
+	original, err := authorizeTransaction(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if original.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	if original.Splits, err = dbsqlite.GetTransactionSplits(ctx, id, db); err != nil {
+		return nil, err
+	}
+	tags, splits, err := validateTransactionUpdate(update, original)
 	if err != nil {
 		return nil, err
 	}
@@ -102,19 +222,41 @@ func UpdateTransactionByID(ctx context.Context, id int64, update *model.Transact
 		balanceWithoutOld := applyBalanceDelta(user.CurrentAmount, original.Amount, !original.IsDebt)
 		newBalance := applyBalanceDelta(balanceWithoutOld, updated.Amount, updated.IsDebt)
 
-		_, err = dbsqlite.UpdateUserPartialByID(ctx, updated.UserID, &model.UserUpdate{
-			CurrentAmount: moneyPtr(newBalance),
-		}, db)
-		if err != nil {
+		if err := setBalance(ctx, user, newBalance, db); err != nil {
 			return nil, err
 		}
 	}
 
+	if update.Tags != nil {
+		if err := dbsqlite.SetTransactionTags(ctx, id, updated.UserID, tags, db); err != nil {
+			return nil, err
+		}
+	}
+
+	updated.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Splits != nil {
+		if err := dbsqlite.SetTransactionSplits(ctx, id, splits, db); err != nil {
+			return nil, err
+		}
+	}
+	updated.Splits, err = dbsqlite.GetTransactionSplits(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityTransaction, id, model.AuditActionUpdate, original, updated, db); err != nil {
+		return nil, err
+	}
+
 	return updated, nil
 }
 
-// DeleteTransactionByID removes the transaction with the given ID and reverts its effect on the owner's balance.
-// Returns 0 with no error if the transaction does not exist.
+// DeleteTransactionByID moves the transaction with the given ID to the trash, where its attachments stay
+// until it is purged, and reverts its effect on the owner's balance.
 func DeleteTransactionByID(ctx context.Context, id int64) (int64, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -124,10 +266,19 @@ func DeleteTransactionByID(ctx context.Context, id int64) (int64, error) {
 
 	tx, err := dbsqlite.GetTransactionByID(ctx, id, db)
 	if err != nil {
-		return 0, nil
+		return 0, err
+	}
+	if err := authorizeUser(ctx, tx.UserID); err != nil {
+		return 0, err
+	}
+	if tx.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db); err != nil {
+		return 0, err
+	}
+	if tx.Splits, err = dbsqlite.GetTransactionSplits(ctx, id, db); err != nil {
+		return 0, err
 	}
 
-	rows, err := dbsqlite.DeleteTransactionByID(ctx, id, db)
+	rows, err := dbsqlite.SoftDeleteTransaction(ctx, id, deletionTime(), db)
 	if err != nil {
 		return 0, err
 	}
@@ -136,6 +287,10 @@ func DeleteTransactionByID(ctx context.Context, id int64) (int64, error) {
 		return 0, nil
 	}
 
+	if err := recordAudit(ctx, model.AuditEntityTransaction, id, model.AuditActionDelete, tx, nil, db); err != nil {
+		return 0, err
+	}
+
 	user, err := dbsqlite.GetUserByID(ctx, tx.UserID, db)
 	if err != nil {
 		return 0, err
@@ -143,10 +298,7 @@ func DeleteTransactionByID(ctx context.Context, id int64) (int64, error) {
 
 	newBalance := applyBalanceDelta(user.CurrentAmount, tx.Amount, !tx.IsDebt)
 
-	_, err = dbsqlite.UpdateUserPartialByID(ctx, tx.UserID, &model.UserUpdate{
-		CurrentAmount: moneyPtr(newBalance),
-	}, db)
-	if err != nil {
+	if err := setBalance(ctx, user, newBalance, db); err != nil {
 		return 0, err
 	}
 
@@ -98,7 +98,7 @@ func TestGetAllTransactions(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			txs, err := GetAllTransactions(ctxTest)
+			txs, err := GetAllTransactions(ctxTest, nil)
 			if err != nil {
 				t.Fatalf("GetAllTransactions() unexpected error: %v", err)
 			}
@@ -287,14 +287,14 @@ func TestDeleteTransactionByID(t *testing.T) {
 			setupFn: func(t *testing.T) (int64, int64, utils.Money) {
 				return 999999999, 0, 0
 			},
-			wantRows: 0,
+			wantErr: true,
 		},
 		{
 			name: "delete_zero_id",
 			setupFn: func(t *testing.T) (int64, int64, utils.Money) {
 				return 0, 0, 0
 			},
-			wantRows: 0,
+			wantErr: true,
 		},
 	}
 
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+)
+
+// JobTrashPurge is the name of the background job that empties the trash of what stayed there past the
+// retention period.
+const JobTrashPurge = "trash-purge"
+
+// ErrOwnerDeleted is returned when restoring a transaction or goal whose owner is still in the trash.
+var ErrOwnerDeleted = model.NewError(model.KindConflict, "the owner is deleted")
+
+var (
+	trashMu        sync.RWMutex
+	trashRetention = 30 * 24 * time.Hour
+)
+
+// SetTrashRetention sets how long deleted users, transactions and goals stay in the trash before the purge
+// job deletes them for good. Non-positive durations are ignored.
+func SetTrashRetention(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	trashMu.Lock()
+	defer trashMu.Unlock()
+	trashRetention = d
+}
+
+// currentTrashRetention returns how long deleted records stay in the trash.
+func currentTrashRetention() time.Duration {
+	trashMu.RLock()
+	defer trashMu.RUnlock()
+	return trashRetention
+}
+
+// deletionTime returns the time records moved to the trash now are marked with.
+func deletionTime() string {
+	return currentTime().UTC().Format(dbsqlite.TimestampLayout)
+}
+
+// GetTrash returns the deleted users, transactions and goals that can still be restored: everybody's to
+// admins, and their own to anyone else.
+func GetTrash(ctx context.Context) (*model.Trash, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := p.UserID
+	if p.Role == model.RoleAdmin {
+		userID = 0
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return dbsqlite.GetTrash(ctx, userID, db)
+}
+
+// RestoreUserByID takes the user with the given ID out of the trash, along with the transactions and goals
+// deleted with them, and returns the restored user.
+func RestoreUserByID(ctx context.Context, id int64) (*model.User, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return nil, err
+	}
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	deleted, err := dbsqlite.GetDeletedUserByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dbsqlite.RestoreUser(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	restored, err := dbsqlite.GetUserByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityUser, id, model.AuditActionRestore, deleted, restored, db); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// RestoreTransactionByID takes the transaction with the given ID out of the trash and applies it to the
+// owner's balance again. The owner must not be in the trash.
+func RestoreTransactionByID(ctx context.Context, id int64) (*model.Transaction, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := principal(ctx); err != nil {
+		return nil, err
+	}
+	deleted, err := dbsqlite.GetDeletedTransactionByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeUser(ctx, deleted.UserID); err != nil {
+		return nil, err
+	}
+
+	user, err := dbsqlite.GetUserByID(ctx, deleted.UserID, db)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: restore user %d first", ErrOwnerDeleted, deleted.UserID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dbsqlite.RestoreTransaction(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	restored, err := dbsqlite.GetTransactionByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if restored.Tags, err = dbsqlite.GetTransactionTags(ctx, id, db); err != nil {
+		return nil, err
+	}
+	if restored.Splits, err = dbsqlite.GetTransactionSplits(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityTransaction, id, model.AuditActionRestore, deleted, restored, db); err != nil {
+		return nil, err
+	}
+
+	newBalance := applyBalanceDelta(user.CurrentAmount, restored.Amount, restored.IsDebt)
+
+	if err := setBalance(ctx, user, newBalance, db); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// RestoreGoalByID takes the goal with the given ID out of the trash. The owner must not be in the trash.
+func RestoreGoalByID(ctx context.Context, id int64) (*model.Goal, error) {
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := principal(ctx); err != nil {
+		return nil, err
+	}
+	deleted, err := dbsqlite.GetDeletedGoalByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeUser(ctx, deleted.UserID); err != nil {
+		return nil, err
+	}
+
+	if _, err := dbsqlite.GetUserByID(ctx, deleted.UserID, db); errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: restore user %d first", ErrOwnerDeleted, deleted.UserID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err := dbsqlite.RestoreGoal(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	restored, err := dbsqlite.GetGoalByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+	if restored.Tags, err = dbsqlite.GetGoalTags(ctx, id, db); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(ctx, model.AuditEntityGoal, id, model.AuditActionRestore, deleted, restored, db); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash permanently deletes the users, transactions and goals that have been in the trash for longer
+// than the retention period, with the attachment content nothing refers to anymore. It stops when ctx is
+// canceled.
+func PurgeTrash(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	db, err := dbsqlite.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	before := currentTime().UTC().Add(-currentTrashRetention()).Format(dbsqlite.TimestampLayout)
+
+	hashes, err := dbsqlite.GetPurgeableAttachmentHashes(ctx, before, db)
+	if err != nil {
+		return err
+	}
+	trash, err := dbsqlite.GetTrash(ctx, 0, db)
+	if err != nil {
+		return err
+	}
+
+	result, err := dbsqlite.PurgeDeleted(ctx, before, db)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		releaseAttachmentContent(ctx, hash, db)
+	}
+	if err := auditPurge(ctx, trash, before, db); err != nil {
+		return err
+	}
+
+	if total := result.Users + result.Transactions + result.Goals; total > 0 {
+		log.Printf("[Trash] Purged %d user(s), %d transaction(s) and %d goal(s).", result.Users, result.Transactions, result.Goals)
+	}
+
+	return nil
+}
+
+// auditPurge writes a purge to the audit log for every record of trash deleted before the purge cutoff.
+func auditPurge(ctx context.Context, trash *model.Trash, before string, db *sql.DB) error {
+	for _, u := range trash.Users {
+		if u.DeletedAt < before {
+			if err := recordAudit(ctx, model.AuditEntityUser, u.ID, model.AuditActionPurge, &u, nil, db); err != nil {
+				return err
+			}
+		}
+	}
+	for _, t := range trash.Transactions {
+		if t.DeletedAt < before {
+			if err := recordAudit(ctx, model.AuditEntityTransaction, t.ID, model.AuditActionPurge, &t, nil, db); err != nil {
+				return err
+			}
+		}
+	}
+	for _, g := range trash.Goals {
+		if g.DeletedAt < before {
+			if err := recordAudit(ctx, model.AuditEntityGoal, g.ID, model.AuditActionPurge, &g, nil, db); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"natan/fingo/model"
+)
+
+func TestTrash_TransactionRestoreReappliesBalance(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "trash-balance", CurrentAmount: 10000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	asOwner := asUser(user.ID)
+
+	tx, err := CreateTransaction(asOwner, model.Transaction{Desc: "rent", Amount: 4000, IsDebt: true, UserID: user.ID, Tags: []string{"home"}})
+	if err != nil {
+		t.Fatalf("CreateTransaction() returned error: %v", err)
+	}
+	if rows, err := DeleteTransactionByID(asOwner, tx.ID); err != nil || rows != 1 {
+		t.Fatalf("DeleteTransactionByID() = %d, %v; want 1, nil", rows, err)
+	}
+
+	if got, _ := GetUserByID(asOwner, user.ID); got.CurrentAmount != 10000 {
+		t.Errorf("balance after deleting = %d, want 10000", got.CurrentAmount)
+	}
+	if _, err := GetTransactionByID(asOwner, tx.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTransactionByID() of a deleted transaction error = %v, want sql.ErrNoRows", err)
+	}
+
+	trash, err := GetTrash(asOwner)
+	if err != nil {
+		t.Fatalf("GetTrash() returned error: %v", err)
+	}
+	if len(trash.Transactions) != 1 || trash.Transactions[0].ID != tx.ID || trash.Transactions[0].DeletedAt == "" {
+		t.Errorf("GetTrash() transactions = %+v, want the deleted transaction", trash.Transactions)
+	}
+
+	if _, err := RestoreTransactionByID(asUser(user.ID+1000), tx.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("RestoreTransactionByID() by another user error = %v, want ErrForbidden", err)
+	}
+
+	restored, err := RestoreTransactionByID(asOwner, tx.ID)
+	if err != nil {
+		t.Fatalf("RestoreTransactionByID() returned error: %v", err)
+	}
+	if restored.DeletedAt != "" || len(restored.Tags) != 1 || restored.Tags[0] != "home" {
+		t.Errorf("RestoreTransactionByID() = %+v", restored)
+	}
+	if got, _ := GetUserByID(asOwner, user.ID); got.CurrentAmount != 6000 {
+		t.Errorf("balance after restoring = %d, want 6000", got.CurrentAmount)
+	}
+	if _, err := RestoreTransactionByID(asOwner, tx.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreTransactionByID() of an active transaction error = %v, want sql.ErrNoRows", err)
+	}
+
+	entries, err := GetAuditLog(ctxTest, model.AuditEntityTransaction, tx.ID)
+	if err != nil || len(entries) != 3 || entries[2].Action != model.AuditActionRestore {
+		t.Errorf("GetAuditLog() = %+v, %v; want create, delete and restore", entries, err)
+	}
+}
+
+func TestTrash_UserRestoreBringsBackTheirRecords(t *testing.T) {
+	fake := useFakeClock(t, time.Now().UTC())
+
+	user, err := CreateUser(ctxTest, model.User{UserName: "trash-user", CurrentAmount: 50000})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	asOwner := asUser(user.ID)
+
+	tx, _ := CreateTransaction(asOwner, model.Transaction{Desc: "salary", Amount: 20000, IsDebt: false, UserID: user.ID})
+	goal, _ := CreateGoal(asOwner, model.Goal{Name: "bike", Price: 90000, UserID: user.ID})
+	oldGoal, _ := CreateGoal(asOwner, model.Goal{Name: "phone", Price: 30000, UserID: user.ID})
+	if _, err := DeleteGoalByID(asOwner, oldGoal.ID); err != nil {
+		t.Fatalf("DeleteGoalByID() returned error: %v", err)
+	}
+	fake.Advance(time.Minute)
+
+	if rows, err := DeleteUserByID(ctxTest, user.ID); err != nil || rows != 1 {
+		t.Fatalf("DeleteUserByID() = %d, %v; want 1, nil", rows, err)
+	}
+	if _, err := GetGoalByID(ctxTest, goal.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetGoalByID() of a deleted user's goal error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Records deleted with their owner wait for the owner
+	if _, err := RestoreTransactionByID(ctxTest, tx.ID); !errors.Is(err, ErrOwnerDeleted) {
+		t.Errorf("RestoreTransactionByID() of a deleted user's transaction error = %v, want ErrOwnerDeleted", err)
+	}
+	if _, err := RestoreGoalByID(ctxTest, goal.ID); !errors.Is(err, ErrOwnerDeleted) {
+		t.Errorf("RestoreGoalByID() of a deleted user's goal error = %v, want ErrOwnerDeleted", err)
+	}
+
+	restored, err := RestoreUserByID(ctxTest, user.ID)
+	if err != nil {
+		t.Fatalf("RestoreUserByID() returned error: %v", err)
+	}
+	if restored.CurrentAmount != 70000 || restored.DeletedAt != "" {
+		t.Errorf("RestoreUserByID() = %+v, want the balance untouched", restored)
+	}
+	if _, err := GetTransactionByID(asOwner, tx.ID); err != nil {
+		t.Errorf("GetTransactionByID() after restoring the user returned error: %v", err)
+	}
+	if _, err := GetGoalByID(asOwner, goal.ID); err != nil {
+		t.Errorf("GetGoalByID() after restoring the user returned error: %v", err)
+	}
+
+	// The goal deleted on its own stays in the trash
+	if _, err := GetGoalByID(asOwner, oldGoal.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetGoalByID() of a goal deleted before its owner error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := RestoreGoalByID(asOwner, oldGoal.ID); err != nil {
+		t.Errorf("RestoreGoalByID() returned error: %v", err)
+	}
+}
+
+func TestTrash_ScopedToCaller(t *testing.T) {
+	alice, _ := CreateUser(ctxTest, model.User{UserName: "trash-alice"})
+	bob, _ := CreateUser(ctxTest, model.User{UserName: "trash-bob"})
+	aliceGoal, _ := CreateGoal(asUser(alice.ID), model.Goal{Name: "trip", Price: 1000, UserID: alice.ID})
+	bobGoal, _ := CreateGoal(asUser(bob.ID), model.Goal{Name: "tv", Price: 1000, UserID: bob.ID})
+	DeleteGoalByID(asUser(alice.ID), aliceGoal.ID)
+	DeleteGoalByID(asUser(bob.ID), bobGoal.ID)
+
+	trash, err := GetTrash(asUser(alice.ID))
+	if err != nil {
+		t.Fatalf("GetTrash() returned error: %v", err)
+	}
+	if len(trash.Goals) != 1 || trash.Goals[0].ID != aliceGoal.ID {
+		t.Errorf("GetTrash() goals for alice = %+v, want only her goal", trash.Goals)
+	}
+
+	all, err := GetTrash(ctxTest)
+	if err != nil {
+		t.Fatalf("GetTrash() as admin returned error: %v", err)
+	}
+	found := 0
+	for _, g := range all.Goals {
+		if g.ID == aliceGoal.ID || g.ID == bobGoal.ID {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("GetTrash() as admin = %+v, want both goals", all.Goals)
+	}
+}
+
+func TestPurgeTrash_AfterRetention(t *testing.T) {
+	fake := useFakeClock(t, time.Now().UTC())
+
+	user, _ := CreateUser(ctxTest, model.User{UserName: "trash-purged"})
+	goal, _ := CreateGoal(asUser(user.ID), model.Goal{Name: "boat", Price: 1000, UserID: user.ID})
+	if _, err := DeleteUserByID(ctxTest, user.ID); err != nil {
+		t.Fatalf("DeleteUserByID() returned error: %v", err)
+	}
+
+	// Within the retention period the user can still be restored
+	fake.Advance(currentTrashRetention() - time.Hour)
+	if err := PurgeTrash(context.Background()); err != nil {
+		t.Fatalf("PurgeTrash() returned error: %v", err)
+	}
+	trash, _ := GetTrash(ctxTest)
+	if !trashHasUser(trash, user.ID) {
+		t.Fatalf("user %d purged before the end of the retention period", user.ID)
+	}
+
+	// A purge whose job was canceled leaves the trash alone
+	fake.Advance(2 * time.Hour)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := PurgeTrash(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("PurgeTrash() with a canceled context error = %v, want context.Canceled", err)
+	}
+	if trash, _ := GetTrash(ctxTest); !trashHasUser(trash, user.ID) {
+		t.Fatalf("user %d purged by a canceled job", user.ID)
+	}
+
+	if err := PurgeTrash(context.Background()); err != nil {
+		t.Fatalf("PurgeTrash() returned error: %v", err)
+	}
+	trash, _ = GetTrash(ctxTest)
+	if trashHasUser(trash, user.ID) {
+		t.Errorf("user %d still in the trash after the retention period", user.ID)
+	}
+	if _, err := RestoreUserByID(ctxTest, user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreUserByID() of a purged user error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := RestoreGoalByID(ctxTest, goal.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreGoalByID() of a purged goal error = %v, want sql.ErrNoRows", err)
+	}
+
+	for entity, id := range map[string]int64{model.AuditEntityUser: user.ID, model.AuditEntityGoal: goal.ID} {
+		entries, err := GetAuditLog(ctxTest, entity, id)
+		if err != nil || len(entries) == 0 {
+			t.Fatalf("GetAuditLog(%s %d) = %+v, %v", entity, id, entries, err)
+		}
+		if last := entries[len(entries)-1]; last.Action != model.AuditActionPurge || last.ActorRole != model.AuditActorSystem || last.Before == nil || last.After != nil {
+			t.Errorf("last audit entry of %s %d = %+v, want a purge by the system", entity, id, last)
+		}
+	}
+}
+
+func trashHasUser(trash *model.Trash, id int64) bool {
+	for _, u := range trash.Users {
+		if u.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -2,12 +2,20 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"natan/fingo/dbsqlite"
 	"natan/fingo/model"
 )
 
-// CreateUser persists a new user and returns the created record.
+// CreateUser persists a new user and returns the created record. Only admins may create users.
 func CreateUser(ctx context.Context, user model.User) (*model.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
@@ -19,10 +27,14 @@ func CreateUser(ctx context.Context, user model.User) (*model.User, error) {
 		return nil, err
 	}
 
+	if err := recordAudit(ctx, model.AuditEntityUser, u.ID, model.AuditActionCreate, nil, u, db); err != nil {
+		return nil, err
+	}
+
 	return u, nil
 }
 
-// GetUserByID returns the user with the given ID.
+// GetUserByID returns the user with the given ID to themselves, the members of their household and admins.
 func GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -30,6 +42,10 @@ func GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	}
 	defer db.Close()
 
+	if err := authorizeHouseholdUser(ctx, id, db); err != nil {
+		return nil, err
+	}
+
 	u, err := dbsqlite.GetUserByID(ctx, id, db)
 	if err != nil {
 		return nil, err
@@ -38,7 +54,7 @@ func GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	return u, nil
 }
 
-// GetAllUsers returns all users in the database.
+// GetAllUsers returns all users in the database to admins, and the users of their household to anyone else.
 func GetAllUsers(ctx context.Context) ([]model.User, error) {
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
@@ -46,6 +62,23 @@ func GetAllUsers(ctx context.Context) ([]model.User, error) {
 	}
 	defer db.Close()
 
+	ids, scoped, err := listingScope(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if scoped {
+		users := make([]model.User, 0, len(ids))
+		for _, id := range ids {
+			user, err := dbsqlite.GetUserByID(ctx, id, db)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, *user)
+		}
+		return users, nil
+	}
+
 	users, err := dbsqlite.GetAllUsers(ctx, db)
 	if err != nil {
 		return nil, err
@@ -54,7 +87,9 @@ func GetAllUsers(ctx context.Context) ([]model.User, error) {
 	return users, nil
 }
 
-func GetAllTransactionsByUserID(ctx context.Context, id int64)([]model.Transaction, error){
+// GetAllTransactionsByUserID returns the transactions of a user with their tags and splits. When tags is not empty,
+// only the transactions carrying every one of them are returned.
+func GetAllTransactionsByUserID(ctx context.Context, id int64, tags []string)([]model.Transaction, error){
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil{
 		return nil, err
@@ -62,15 +97,37 @@ func GetAllTransactionsByUserID(ctx context.Context, id int64)([]model.Transacti
 	
 	defer db.Close()
 	
+	if err := authorizeHouseholdUser(ctx, id, db); err != nil {
+		return nil, err
+	}
+	
+	return userTransactions(ctx, id, tags, db)
+}
+
+// userTransactions returns the transactions of a user with their tags and splits, keeping the ones carrying
+// every tag of filter.
+func userTransactions(ctx context.Context, id int64, filter []string, db *sql.DB) ([]model.Transaction, error) {
 	transactions, err := dbsqlite.GetAllTransactionsByUserID(ctx, id, db)
 	if err != nil{
 		return nil, err
 	}
 	
-	return transactions, nil
+	transactionTags, err := dbsqlite.GetTransactionTagsByUserID(ctx, id, db)
+	if err != nil{
+		return nil, err
+	}
+	
+	splits, err := dbsqlite.GetTransactionSplitsByUserID(ctx, id, db)
+	if err != nil{
+		return nil, err
+	}
+	
+	return withTransactionDetails(transactions, transactionTags, splits, filter), nil
 }
 
-func GetAllGoalsByUserID(ctx context.Context, id int64)([]model.Goal, error){
+// GetAllGoalsByUserID returns the goals a user owns or participates in with their tags. When tags is not
+// empty, only the goals carrying every one of them are returned.
+func GetAllGoalsByUserID(ctx context.Context, id int64, tags []string)([]model.Goal, error){
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil{
 		return nil, err
@@ -78,42 +135,89 @@ func GetAllGoalsByUserID(ctx context.Context, id int64)([]model.Goal, error){
 	
 	defer db.Close()
 	
+	if err := authorizeHouseholdUser(ctx, id, db); err != nil {
+		return nil, err
+	}
+	
+	return userGoals(ctx, id, tags, db)
+}
+
+// userGoals returns the goals a user owns or participates in with their tags, keeping the ones carrying
+// every tag of filter.
+func userGoals(ctx context.Context, id int64, filter []string, db *sql.DB) ([]model.Goal, error) {
 	goals, err := dbsqlite.GetAllGoalsByUserID(ctx, id, db)
 	if err != nil{
 		return nil, err
 	}
 	
-	return goals, err
+	goalTags, err := dbsqlite.GetGoalTagsByUserID(ctx, id, db)
+	if err != nil{
+		return nil, err
+	}
+	
+	return withGoalTags(goals, goalTags, filter), nil
 }
 
-// DeleteUserByID removes the user with the given ID and returns the number of affected rows.
+// DeleteUserByID moves the user with the given ID, with their transactions and goals, to the trash and
+// returns the number of affected rows.
 func DeleteUserByID(ctx context.Context, id int64) (int64, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return 0, err
+	}
+
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return 0, err
 	}
 	defer db.Close()
 
-	rows, err := dbsqlite.DeleteUserByID(ctx, id, db)
+	before, err := dbsqlite.GetUserByID(ctx, id, db)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := dbsqlite.SoftDeleteUser(ctx, id, deletionTime(), db)
 	if err != nil {
 		return rows, err
 	}
 
+	if rows > 0 {
+		if err := recordAudit(ctx, model.AuditEntityUser, id, model.AuditActionDelete, before, nil, db); err != nil {
+			return rows, err
+		}
+	}
+
 	return rows, nil
 }
 
 // UpdateUserByID applies a partial update to the user with the given ID and returns the updated record.
 func UpdateUserByID(ctx context.Context, id int64, user *model.UserUpdate) (*model.User, error) {
+	if err := authorizeUser(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := validateUserUpdate(user); err != nil {
+		return nil, err
+	}
+
 	db, err := dbsqlite.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
+	before, err := dbsqlite.GetUserByID(ctx, id, db)
+	if err != nil {
+		return nil, err
+	}
+
 	u, err := dbsqlite.UpdateUserPartialByID(ctx, id, user, db)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := recordAudit(ctx, model.AuditEntityUser, id, model.AuditActionUpdate, before, u, db); err != nil {
+		return nil, err
+	}
+
 	return u, nil
 }
@@ -7,7 +7,9 @@ import (
 	"natan/fingo/model"
 )
 
-var ctxTest = context.Background()
+// ctxTest acts as an admin, who may see and change every record; authorization_service_test.go covers
+// everyone else.
+var ctxTest = WithPrincipal(context.Background(), model.Principal{Role: model.RoleAdmin})
 
 func strPtr(s string) *string {
 	return &s
@@ -35,12 +37,11 @@ func TestCreateUser(t *testing.T) {
 			wantHasUser: true,
 		},
 		{
-			name: "empty_username_still_ok",
+			name: "empty_username_rejected",
 			input: model.User{
-				UserName: "",
+				UserName: "  ",
 			},
-			wantErr:     false,
-			wantHasUser: true,
+			wantErr: true,
 		},
 	}
 
@@ -264,16 +265,14 @@ func TestDeleteUserByID(t *testing.T) {
 			wantRows: 1,
 		},
 		{
-			name:     "delete_non_existing_user",
-			id:       base.ID + 999999,
-			wantErr:  false,
-			wantRows: 0,
+			name:    "delete_non_existing_user",
+			id:      base.ID + 999999,
+			wantErr: true,
 		},
 		{
-			name:     "delete_zero_id",
-			id:       0,
-			wantErr:  false,
-			wantRows: 0,
+			name:    "delete_zero_id",
+			id:      0,
+			wantErr: true,
 		},
 	}
 
@@ -361,7 +360,7 @@ func TestGetAllTransactionsByUserID(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			txs, err := GetAllTransactionsByUserID(ctxTest, tc.id)
+			txs, err := GetAllTransactionsByUserID(ctxTest, tc.id, nil)
 
 			if tc.wantErr {
 				if err == nil {
@@ -410,7 +409,7 @@ func TestGetAllTransactionsByUserID_Isolation(t *testing.T) {
 		t.Fatalf("failed to create transaction for user2: %v", err)
 	}
 
-	txs, err := GetAllTransactionsByUserID(ctxTest, user1.ID)
+	txs, err := GetAllTransactionsByUserID(ctxTest, user1.ID, nil)
 	if err != nil {
 		t.Fatalf("GetAllTransactionsByUserID() unexpected error: %v", err)
 	}
@@ -482,7 +481,7 @@ func TestGetAllGoalsByUserID(t *testing.T) {
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			goals, err := GetAllGoalsByUserID(ctxTest, tc.id)
+			goals, err := GetAllGoalsByUserID(ctxTest, tc.id, nil)
 
 			if tc.wantErr {
 				if err == nil {
@@ -531,7 +530,7 @@ func TestGetAllGoalsByUserID_Isolation(t *testing.T) {
 		t.Fatalf("failed to create goal for user2: %v", err)
 	}
 
-	goals, err := GetAllGoalsByUserID(ctxTest, user1.ID)
+	goals, err := GetAllGoalsByUserID(ctxTest, user1.ID, nil)
 	if err != nil {
 		t.Fatalf("GetAllGoalsByUserID() unexpected error: %v", err)
 	}
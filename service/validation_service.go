@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"natan/fingo/dbsqlite"
+	"natan/fingo/model"
+	"natan/fingo/utils"
+)
+
+// Limits on the text fields of users, transactions and goals.
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 500
+	maxCategoryLength    = 50
+)
+
+// deadlineLayout is the format of goal deadlines.
+const deadlineLayout = "2006-01-02"
+
+// fieldErrors collects what is wrong with the fields of a request, so every problem is reported at once.
+type fieldErrors struct {
+	fields []model.FieldError
+	causes []error
+}
+
+// add records a problem with a field.
+func (f *fieldErrors) add(field, format string, args ...any) {
+	f.fields = append(f.fields, model.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// addErr records a field rejected by another validation, such as that of tags, keeping its error so
+// callers can still match it with errors.Is.
+func (f *fieldErrors) addErr(field string, err error) {
+	f.fields = append(f.fields, model.FieldError{Field: field, Message: err.Error()})
+	f.causes = append(f.causes, err)
+}
+
+// err returns the validation error listing every recorded problem, or nil when there is none.
+func (f *fieldErrors) err() error {
+	if len(f.fields) == 0 {
+		return nil
+	}
+	e := model.ValidationError(f.fields...)
+	e.Err = errors.Join(f.causes...)
+	return e
+}
+
+// checkName records a problem with a required name that is empty or too long.
+func (f *fieldErrors) checkName(field, name string) {
+	switch name = strings.TrimSpace(name); {
+	case name == "":
+		f.add(field, "is required")
+	case len(name) > maxNameLength:
+		f.add(field, "must not be longer than %d characters", maxNameLength)
+	}
+}
+
+// checkLength records a problem with an optional text longer than max characters.
+func (f *fieldErrors) checkLength(field, s string, max int) {
+	if len(s) > max {
+		f.add(field, "must not be longer than %d characters", max)
+	}
+}
+
+// checkNotNegative records a problem with an amount below zero.
+func (f *fieldErrors) checkNotNegative(field string, m utils.Money) {
+	if m < 0 {
+		f.add(field, "must not be negative")
+	}
+}
+
+// checkDeadline records a problem with a deadline that is neither empty nor a "YYYY-MM-DD" date.
+func (f *fieldErrors) checkDeadline(deadline string) {
+	if deadline == "" {
+		return
+	}
+	if _, err := time.Parse(deadlineLayout, deadline); err != nil {
+		f.add("deadline", "must be a date in the YYYY-MM-DD format")
+	}
+}
+
+// checkUserExists records a problem with a user_id that refers to no active user.
+func (f *fieldErrors) checkUserExists(ctx context.Context, userID int64, db *sql.DB) error {
+	if userID <= 0 {
+		f.add("user_id", "is required")
+		return nil
+	}
+	_, err := dbsqlite.GetUserByID(ctx, userID, db)
+	if errors.Is(err, model.KindNotFound) {
+		f.add("user_id", "user %d does not exist", userID)
+		return nil
+	}
+	return err
+}
+
+// validateUser checks a new user: a name, and monthly inputs and outputs that are not negative.
+func validateUser(user model.User) error {
+	var f fieldErrors
+	f.checkName("user_name", user.UserName)
+	f.checkNotNegative("monthly_inputs", user.MonthlyInputs)
+	f.checkNotNegative("monthly_outputs", user.MonthlyOutputs)
+	return f.err()
+}
+
+// validateUserUpdate checks the fields given in a partial update of a user by the rules of validateUser.
+func validateUserUpdate(update *model.UserUpdate) error {
+	if update == nil {
+		return nil
+	}
+
+	var f fieldErrors
+	if update.UserName != nil {
+		f.checkName("user_name", *update.UserName)
+	}
+	if update.MonthlyInputs != nil {
+		f.checkNotNegative("monthly_inputs", *update.MonthlyInputs)
+	}
+	if update.MonthlyOutputs != nil {
+		f.checkNotNegative("monthly_outputs", *update.MonthlyOutputs)
+	}
+	return f.err()
+}
+
+// validateTransaction checks a new transaction, normalising its category, tags and splits: an amount that
+// is not negative, texts within their limits, valid tags and splits, and an owner that exists.
+func validateTransaction(ctx context.Context, transaction *model.Transaction, db *sql.DB) error {
+	var f fieldErrors
+	transaction.Category = strings.TrimSpace(transaction.Category)
+	f.checkNotNegative("amount", transaction.Amount)
+	f.checkLength("description", transaction.Desc, maxDescriptionLength)
+	f.checkLength("category", transaction.Category, maxCategoryLength)
+
+	var err error
+	if transaction.Tags, err = validateTags(transaction.Tags); err != nil {
+		f.addErr("tags", err)
+	}
+	if transaction.Splits, err = validateSplits(transaction.Amount, transaction.Splits); err != nil {
+		f.addErr("splits", err)
+	}
+	if err := f.checkUserExists(ctx, transaction.UserID, db); err != nil {
+		return err
+	}
+	return f.err()
+}
+
+// validateTransactionUpdate checks the fields given in a partial update of the original transaction by the
+// rules of validateTransaction and returns its normalised tags and the splits the transaction will have,
+// given or kept, which must add up to the resulting amount.
+func validateTransactionUpdate(update *model.TransactionUpdate, original *model.Transaction) ([]string, []model.Split, error) {
+	var f fieldErrors
+	amount, splits := original.Amount, original.Splits
+	var tags []string
+	if update != nil {
+		if update.Amount != nil {
+			amount = *update.Amount
+			f.checkNotNegative("amount", amount)
+		}
+		if update.Desc != nil {
+			f.checkLength("description", *update.Desc, maxDescriptionLength)
+		}
+		if update.Category != nil {
+			f.checkLength("category", strings.TrimSpace(*update.Category), maxCategoryLength)
+		}
+		if update.Tags != nil {
+			var err error
+			if tags, err = validateTags(*update.Tags); err != nil {
+				f.addErr("tags", err)
+			}
+		}
+		if update.Splits != nil {
+			splits = *update.Splits
+		}
+	}
+
+	splits, err := validateSplits(amount, splits)
+	if err != nil {
+		f.addErr("splits", err)
+	}
+	return tags, splits, f.err()
+}
+
+// validateGoal checks a new goal, normalising its tags: a name, a price that is not negative, texts within
+// their limits, a deadline in the YYYY-MM-DD format if any, valid tags, and an owner that exists.
+func validateGoal(ctx context.Context, goal *model.Goal, db *sql.DB) error {
+	var f fieldErrors
+	f.checkName("name", goal.Name)
+	f.checkNotNegative("price", goal.Price)
+	f.checkLength("description", goal.Desc, maxDescriptionLength)
+	f.checkLength("pros", goal.Pros, maxDescriptionLength)
+	f.checkLength("cons", goal.Cons, maxDescriptionLength)
+	f.checkDeadline(goal.Deadline)
+
+	var err error
+	if goal.Tags, err = validateTags(goal.Tags); err != nil {
+		f.addErr("tags", err)
+	}
+	if err := f.checkUserExists(ctx, goal.UserID, db); err != nil {
+		return err
+	}
+	return f.err()
+}
+
+// validateGoalUpdate checks the fields given in a partial update of a goal by the rules of validateGoal and
+// returns its normalised tags.
+func validateGoalUpdate(update *model.GoalUpdate) ([]string, error) {
+	if update == nil {
+		return nil, nil
+	}
+
+	var f fieldErrors
+	if update.Name != nil {
+		f.checkName("name", *update.Name)
+	}
+	if update.Price != nil {
+		f.checkNotNegative("price", *update.Price)
+	}
+	if update.Desc != nil {
+		f.checkLength("description", *update.Desc, maxDescriptionLength)
+	}
+	if update.Pros != nil {
+		f.checkLength("pros", *update.Pros, maxDescriptionLength)
+	}
+	if update.Cons != nil {
+		f.checkLength("cons", *update.Cons, maxDescriptionLength)
+	}
+	if update.Deadline != nil {
+		f.checkDeadline(*update.Deadline)
+	}
+
+	var tags []string
+	if update.Tags != nil {
+		var err error
+		if tags, err = validateTags(*update.Tags); err != nil {
+			f.addErr("tags", err)
+		}
+	}
+	return tags, f.err()
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"natan/fingo/model"
+)
+
+// invalidFields returns the fields a validation error names, failing the test if err is not one.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	var domainErr *model.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind != model.KindValidation {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	fields := make([]string, 0, len(domainErr.Fields))
+	for _, f := range domainErr.Fields {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func TestValidation_ReportsEveryFieldAtOnce(t *testing.T) {
+	user, err := CreateUser(ctxTest, model.User{UserName: "validation-user"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	_, err = CreateUser(ctxTest, model.User{UserName: " ", MonthlyInputs: -1, MonthlyOutputs: -1})
+	if got, want := invalidFields(t, err), []string{"user_name", "monthly_inputs", "monthly_outputs"}; !slices.Equal(got, want) {
+		t.Errorf("CreateUser() invalid fields = %v, want %v", got, want)
+	}
+
+	_, err = CreateTransaction(ctxTest, model.Transaction{Amount: -100, Desc: strings.Repeat("x", maxDescriptionLength+1), UserID: 999999, Tags: []string{"a,b"}})
+	if got, want := invalidFields(t, err), []string{"amount", "description", "tags", "user_id"}; !slices.Equal(got, want) {
+		t.Errorf("CreateTransaction() invalid fields = %v, want %v", got, want)
+	}
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("CreateTransaction() error = %v, want it to wrap ErrInvalidTag", err)
+	}
+
+	_, err = CreateGoal(ctxTest, model.Goal{Price: -1, Deadline: "31/12/2030", UserID: 999999})
+	if got, want := invalidFields(t, err), []string{"name", "price", "deadline", "user_id"}; !slices.Equal(got, want) {
+		t.Errorf("CreateGoal() invalid fields = %v, want %v", got, want)
+	}
+
+	goal, err := CreateGoal(ctxTest, model.Goal{Name: "bike", Price: 50000, Deadline: "2030-12-31", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateGoal() unexpected error: %v", err)
+	}
+	name, deadline := "", "2030-02-30"
+	_, err = UpdateGoalByID(ctxTest, goal.ID, &model.GoalUpdate{Name: &name, Deadline: &deadline})
+	if got, want := invalidFields(t, err), []string{"name", "deadline"}; !slices.Equal(got, want) {
+		t.Errorf("UpdateGoalByID() invalid fields = %v, want %v", got, want)
+	}
+
+	stored, err := GetGoalByID(ctxTest, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID() unexpected error: %v", err)
+	}
+	if stored.Name != "bike" || stored.Deadline != "2030-12-31" {
+		t.Errorf("invalid update changed the goal: %+v", stored)
+	}
+}
@@ -16,6 +16,7 @@ const translations = {
   en: {
     // Header
     subtitle: "Personal Financial Manager",
+    btn_logout: "Sign out",
 
     // Tabs
     tab_users: "Users",
@@ -140,6 +141,7 @@ const translations = {
   pt: {
     // Header
     subtitle: "Gerenciador Financeiro Pessoal",
+    btn_logout: "Sair",
 
     // Tabs
     tab_users: "Usuários",
@@ -422,9 +424,14 @@ async function apiFetch(url, options = {}) {
       headers: { "Content-Type": "application/json" },
       ...options,
     });
+    if (res.status === 401) {
+      window.location.href = "/login";
+      throw new Error("authentication required");
+    }
     const data = await res.json();
     if (!res.ok) {
-      throw new Error(data.error || `HTTP ${res.status}`);
+      const fields = (data.fields || []).map((f) => `${f.field} ${f.message}`);
+      throw new Error(fields.length ? fields.join("; ") : data.message || `HTTP ${res.status}`);
     }
     return data;
   } catch (err) {
@@ -801,7 +808,7 @@ async function loadTransactions() {
 
   try {
     const transactions = await apiFetch(
-      `${API.users}/transactions/${selectedUser.id}`,
+      `${API.users}/${selectedUser.id}/transactions`,
     );
     renderTransactions(transactions);
   } catch {
@@ -962,7 +969,7 @@ async function loadGoals() {
   if (!selectedUser) return;
 
   try {
-    const goals = await apiFetch(`${API.users}/goals/${selectedUser.id}`);
+    const goals = await apiFetch(`${API.users}/${selectedUser.id}/goals`);
     renderGoals(goals);
   } catch {
     goalsTbody.innerHTML = `<tr><td colspan="9" class="empty-msg">${escapeHtml(t("fail_goals"))}</td></tr>`;
@@ -1051,6 +1058,11 @@ window.deleteGoal = function (id, name) {
 //  LANGUAGE SWITCHER EVENT LISTENERS
 // ========================================================================
 
+document.getElementById("logout-btn").addEventListener("click", async () => {
+  await fetch("/auth/logout", { method: "POST" });
+  window.location.href = "/login";
+});
+
 document.getElementById("lang-en").addEventListener("click", () => {
   setLanguage("en");
 });
@@ -0,0 +1,30 @@
+// ===== Sign in =====
+
+function showLoginError(message) {
+  const toast = document.getElementById("toast");
+  toast.textContent = message;
+  toast.className = "toast error";
+  setTimeout(() => toast.classList.add("hidden"), 3000);
+}
+
+document.getElementById("login-form").addEventListener("submit", async (e) => {
+  e.preventDefault();
+
+  try {
+    const res = await fetch("/auth/login", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        login: document.getElementById("login").value,
+        password: document.getElementById("password").value,
+      }),
+    });
+    if (!res.ok) {
+      const data = await res.json();
+      throw new Error(data.message || `HTTP ${res.status}`);
+    }
+    window.location.href = "/";
+  } catch (err) {
+    showLoginError(err.message || "Network error");
+  }
+});